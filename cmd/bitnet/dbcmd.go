@@ -14,6 +14,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -27,12 +28,16 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli/v2"
@@ -65,6 +70,8 @@ Remove blockchain and state databases`,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
+			dbBackfillIssuanceCmd,
+			dbIndexAddressesCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -189,6 +196,34 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbBackfillIssuanceCmd = &cli.Command{
+		Action: backfillIssuance,
+		Name:   "backfill-issuance",
+		Usage:  "Compute the issuance records of the canonical chain",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command computes the coins minted and burned by each block of the canonical
+chain, and the resulting total supply, from the genesis block up to the current head.
+It is needed once for the supply to be tracked on chains synced before it was
+introduced, or snap synced. Blocks imported afterwards are tracked as they are
+inserted.`,
+	}
+	dbIndexAddressesCmd = &cli.Command{
+		Action: indexAddresses,
+		Name:   "index-addresses",
+		Usage:  "Build the address index of the canonical chain",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			utils.AddressIndexInternalFlag,
+			utils.AddressIndexTailFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command indexes the transactions of the canonical chain by the addresses
+sending, receiving or creating them, up to the current head, resuming from the
+progress of a previous run or of the node. It builds offline the index maintained
+by a node running with --addressindex, which needs the same flags. Indexing the
+internal calls requires the historical states of the chain.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -709,3 +744,64 @@ func showMetaData(ctx *cli.Context) error {
 	table.Render()
 	return nil
 }
+
+func backfillIssuance(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, false)
+	defer db.Close()
+	defer chain.Stop()
+
+	return chain.BackfillIssuance()
+}
+
+func indexAddresses(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, false)
+	defer db.Close()
+	defer chain.Stop()
+
+	var tracer core.AddressTracer
+	if ctx.Bool(utils.AddressIndexInternalFlag.Name) {
+		tracer = core.NewInternalCallTracer(chain, func(ctx context.Context, parent *types.Block) (*state.StateDB, func(), error) {
+			statedb, err := chain.StateAt(parent.Root())
+			return statedb, func() {}, err
+		})
+	}
+	indexer := core.NewAddressIndexer(db, chain.Config(), params.AddressIndexBlocks, params.AddressIndexConfirms, ctx.Uint64(utils.AddressIndexTailFlag.Name), tracer)
+	defer indexer.Close()
+
+	var (
+		head     = chain.CurrentBlock().Number.Uint64()
+		target   uint64
+		interval = time.NewTicker(8 * time.Second)
+		start    = time.Now()
+	)
+	defer interval.Stop()
+	if head+1 > params.AddressIndexConfirms {
+		target = (head + 1 - params.AddressIndexConfirms) / params.AddressIndexBlocks
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	indexer.Start(chain)
+	for {
+		sections, _, _ := indexer.Sections()
+		if sections >= target {
+			log.Info("Indexed addresses", "blocks", sections*params.AddressIndexBlocks, "elapsed", common.PrettyDuration(time.Since(start)))
+			return nil
+		}
+		select {
+		case <-interval.C:
+			log.Info("Indexing addresses", "blocks", sections*params.AddressIndexBlocks, "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+		case <-interrupt:
+			log.Info("Interrupted during address indexing", "blocks", sections*params.AddressIndexBlocks)
+			return nil
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -37,13 +37,21 @@ var (
 		Usage: "Version to check",
 		Value: version.ClientName(clientIdentifier),
 	}
+	ECIP1099BlockFlag = &cli.Uint64Flag{
+		Name:  "ecip1099",
+		Usage: "Block number from which ethash epochs are twice as long (ECIP-1099)",
+	}
 	makecacheCommand = &cli.Command{
 		Action:    makecache,
 		Name:      "makecache",
 		Usage:     "Generate ethash verification cache (for testing)",
 		ArgsUsage: "<blockNum> <outputDir>",
+		Flags: []cli.Flag{
+			ECIP1099BlockFlag,
+		},
 		Description: `
-The makecache command generates an ethash cache in <outputDir>.
+The makecache command generates an ethash cache in <outputDir>. If the chain
+lengthens the ethash epochs, pass the fork block with --ecip1099.
 
 This command exists to support the system testing project.
 Regular users do not need to execute it.
@@ -54,8 +62,12 @@ Regular users do not need to execute it.
 		Name:      "makedag",
 		Usage:     "Generate ethash mining DAG (for testing)",
 		ArgsUsage: "<blockNum> <outputDir>",
+		Flags: []cli.Flag{
+			ECIP1099BlockFlag,
+		},
 		Description: `
-The makedag command generates an ethash DAG in <outputDir>.
+The makedag command generates an ethash DAG in <outputDir>. If the chain
+lengthens the ethash epochs, pass the fork block with --ecip1099.
 
 This command exists to support the system testing project.
 Regular users do not need to execute it.
@@ -102,7 +114,7 @@ func makecache(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Invalid block number: %v", err)
 	}
-	ethash.MakeCache(block, args[1])
+	ethash.MakeCache(block, ecip1099Block(ctx), args[1])
 
 	return nil
 }
@@ -117,11 +129,21 @@ func makedag(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Invalid block number: %v", err)
 	}
-	ethash.MakeDataset(block, args[1])
+	ethash.MakeDataset(block, ecip1099Block(ctx), args[1])
 
 	return nil
 }
 
+// ecip1099Block returns the ECIP-1099 fork block requested on the command line,
+// if any.
+func ecip1099Block(ctx *cli.Context) *uint64 {
+	if !ctx.IsSet(ECIP1099BlockFlag.Name) {
+		return nil
+	}
+	block := ctx.Uint64(ECIP1099BlockFlag.Name)
+	return &block
+}
+
 func printVersion(ctx *cli.Context) error {
 	git, _ := version.VCS()
 
@@ -162,6 +162,10 @@ func pruneState(ctx *cli.Context) error {
 	chaindb := utils.MakeChainDatabase(ctx, stack, false)
 	defer chaindb.Close()
 
+	if rawdb.ReadStateScheme(chaindb) == rawdb.PathScheme {
+		log.Error("State pruning is not needed with the path-based scheme")
+		return errors.New("path-based scheme not supported")
+	}
 	prunerconfig := pruner.Config{
 		Datadir:   stack.ResolvePath(""),
 		Cachedir:  stack.ResolvePath(config.Eth.TrieCleanCacheJournal),
@@ -61,6 +61,13 @@ type ommer struct {
 	Address common.Address `json:"address"`
 }
 
+// ancestor is a block preceding the parent, needed by windowed difficulty
+// algorithms to calculate the current difficulty.
+type ancestor struct {
+	Timestamp  math.HexOrDecimal64   `json:"timestamp"`
+	Difficulty *math.HexOrDecimal256 `json:"difficulty"`
+}
+
 //go:generate go run github.com/fjl/gencodec -type stEnv -field-override stEnvMarshaling -out gen_stenv.go
 type stEnv struct {
 	Coinbase         common.Address                      `json:"currentCoinbase"   gencodec:"required"`
@@ -76,6 +83,7 @@ type stEnv struct {
 	ParentTimestamp  uint64                              `json:"parentTimestamp,omitempty"`
 	BlockHashes      map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
 	Ommers           []ommer                             `json:"ommers,omitempty"`
+	Ancestors        []ancestor                          `json:"parentAncestors,omitempty"`
 	Withdrawals      []*types.Withdrawal                 `json:"withdrawals,omitempty"`
 	BaseFee          *big.Int                            `json:"currentBaseFee,omitempty"`
 	ParentUncleHash  common.Hash                         `json:"parentUncleHash"`
@@ -311,16 +319,31 @@ func rlpHash(x interface{}) (h common.Hash) {
 	return h
 }
 
-// calcDifficulty is based on ethash.CalcDifficulty. This method is used in case
-// the caller does not provide an explicit difficulty, but instead provides only
-// parent timestamp + difficulty.
+// calcDifficulty is based on ethash.CalcDifficultyWithChain. This method is used
+// in case the caller does not provide an explicit difficulty, but instead provides
+// only parent timestamp + difficulty, and the ancestors of the parent if the
+// configured difficulty algorithm is windowed.
 // Note: this method only works for ethash engine.
 func calcDifficulty(config *params.ChainConfig, number, currentTime, parentTime uint64,
-	parentDifficulty *big.Int, parentUncleHash common.Hash) *big.Int {
+	parentDifficulty *big.Int, parentUncleHash common.Hash, ancestors []ancestor) *big.Int {
 	uncleHash := parentUncleHash
 	if uncleHash == (common.Hash{}) {
 		uncleHash = types.EmptyUncleHash
 	}
+	// Link up the ancestors (oldest first) into a header chain ending at the parent
+	chain := &ancestorChain{config: config}
+	for i, a := range ancestors {
+		header := &types.Header{
+			UncleHash:  types.EmptyUncleHash,
+			Difficulty: (*big.Int)(a.Difficulty),
+			Number:     new(big.Int).SetUint64(number - 1 - uint64(len(ancestors)-i)),
+			Time:       uint64(a.Timestamp),
+		}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
 	parent := &types.Header{
 		ParentHash: common.Hash{},
 		UncleHash:  uncleHash,
@@ -328,5 +351,45 @@ func calcDifficulty(config *params.ChainConfig, number, currentTime, parentTime
 		Number:     new(big.Int).SetUint64(number - 1),
 		Time:       parentTime,
 	}
-	return ethash.CalcDifficulty(config, currentTime, parent)
+	if len(chain.headers) > 0 {
+		parent.ParentHash = chain.headers[len(chain.headers)-1].Hash()
+	}
+	return ethash.CalcDifficultyWithChain(chain, currentTime, parent)
+}
+
+// ancestorChain is a consensus.ChainHeaderReader serving the ancestors of the
+// parent block provided in the environment.
+type ancestorChain struct {
+	config  *params.ChainConfig
+	headers []*types.Header
+}
+
+func (c *ancestorChain) Config() *params.ChainConfig                    { return c.config }
+func (c *ancestorChain) CurrentHeader() *types.Header                   { return nil }
+func (c *ancestorChain) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
+
+func (c *ancestorChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c *ancestorChain) GetHeaderByNumber(number uint64) *types.Header {
+	if len(c.headers) == 0 || number < c.headers[0].Number.Uint64() {
+		return nil
+	}
+	if index := number - c.headers[0].Number.Uint64(); index < uint64(len(c.headers)) {
+		return c.headers[index]
+	}
+	return nil
+}
+
+func (c *ancestorChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
 }
@@ -30,6 +30,7 @@ func (s stEnv) MarshalJSON() ([]byte, error) {
 		ParentTimestamp  math.HexOrDecimal64                 `json:"parentTimestamp,omitempty"`
 		BlockHashes      map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
 		Ommers           []ommer                             `json:"ommers,omitempty"`
+		Ancestors        []ancestor                          `json:"parentAncestors,omitempty"`
 		Withdrawals      []*types.Withdrawal                 `json:"withdrawals,omitempty"`
 		BaseFee          *math.HexOrDecimal256               `json:"currentBaseFee,omitempty"`
 		ParentUncleHash  common.Hash                         `json:"parentUncleHash"`
@@ -48,6 +49,7 @@ func (s stEnv) MarshalJSON() ([]byte, error) {
 	enc.ParentTimestamp = math.HexOrDecimal64(s.ParentTimestamp)
 	enc.BlockHashes = s.BlockHashes
 	enc.Ommers = s.Ommers
+	enc.Ancestors = s.Ancestors
 	enc.Withdrawals = s.Withdrawals
 	enc.BaseFee = (*math.HexOrDecimal256)(s.BaseFee)
 	enc.ParentUncleHash = s.ParentUncleHash
@@ -70,6 +72,7 @@ func (s *stEnv) UnmarshalJSON(input []byte) error {
 		ParentTimestamp  *math.HexOrDecimal64                `json:"parentTimestamp,omitempty"`
 		BlockHashes      map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
 		Ommers           []ommer                             `json:"ommers,omitempty"`
+		Ancestors        []ancestor                          `json:"parentAncestors,omitempty"`
 		Withdrawals      []*types.Withdrawal                 `json:"withdrawals,omitempty"`
 		BaseFee          *math.HexOrDecimal256               `json:"currentBaseFee,omitempty"`
 		ParentUncleHash  *common.Hash                        `json:"parentUncleHash"`
@@ -121,6 +124,9 @@ func (s *stEnv) UnmarshalJSON(input []byte) error {
 	if dec.Ommers != nil {
 		s.Ommers = dec.Ommers
 	}
+	if dec.Ancestors != nil {
+		s.Ancestors = dec.Ancestors
+	}
 	if dec.Withdrawals != nil {
 		s.Withdrawals = dec.Withdrawals
 	}
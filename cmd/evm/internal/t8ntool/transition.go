@@ -284,9 +284,17 @@ func Transition(ctx *cli.Context) error {
 		case env.Timestamp <= env.ParentTimestamp:
 			return NewError(ErrorConfig, fmt.Errorf("currentDifficulty cannot be calculated -- currentTime (%d) needs to be after parent time (%d)",
 				env.Timestamp, env.ParentTimestamp))
+		case uint64(len(env.Ancestors)) > env.Number-1:
+			return NewError(ErrorConfig, fmt.Errorf("currentDifficulty cannot be calculated -- %d parent ancestors provided for block number %d",
+				len(env.Ancestors), env.Number))
+		}
+		for i, a := range env.Ancestors {
+			if a.Difficulty == nil {
+				return NewError(ErrorConfig, fmt.Errorf("currentDifficulty cannot be calculated -- missing difficulty of parent ancestor %d", i))
+			}
 		}
 		prestate.Env.Difficulty = calcDifficulty(chainConfig, env.Number, env.Timestamp,
-			env.ParentTimestamp, env.ParentDifficulty, env.ParentUncleHash)
+			env.ParentTimestamp, env.ParentDifficulty, env.ParentUncleHash, env.Ancestors)
 	}
 	// Run the test and aggregate the result
 	s, result, err := prestate.Apply(vmConfig, chainConfig, txs, ctx.Int64(RewardFlag.Name), getTracer)
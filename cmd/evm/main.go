@@ -36,6 +36,10 @@ var (
 		Name:  "cpuprofile",
 		Usage: "creates a CPU profile at the given path",
 	}
+	ProfileFlag = &cli.StringFlag{
+		Name:  "profile",
+		Usage: "creates a gas profile of the executed code at the given path (pprof, with folded stacks at <path>.folded)",
+	}
 	StatDumpFlag = &cli.BoolFlag{
 		Name:  "statdump",
 		Usage: "displays stack and heap memory information",
@@ -200,6 +204,7 @@ func init() {
 		InputFileFlag,
 		MemProfileFlag,
 		CPUProfileFlag,
+		ProfileFlag,
 		StatDumpFlag,
 		GenesisFlag,
 		MachineFlag,
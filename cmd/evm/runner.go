@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/core/vm/runtime"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/eth/tracers/native"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -132,6 +133,13 @@ func runCmd(ctx *cli.Context) error {
 	} else {
 		debugLogger = logger.NewStructLogger(logconfig)
 	}
+	var profiler *native.GasProfiler
+	if ctx.String(ProfileFlag.Name) != "" {
+		if tracer != nil {
+			utils.Fatalf("--%s cannot be combined with --%s or --%s", ProfileFlag.Name, MachineFlag.Name, DebugFlag.Name)
+		}
+		profiler, _ = native.NewGasProfiler(nil)
+	}
 	if ctx.String(GenesisFlag.Name) != "" {
 		gen := readGenesis(ctx.String(GenesisFlag.Name))
 		genesisConfig = gen
@@ -216,6 +224,9 @@ func runCmd(ctx *cli.Context) error {
 			Tracer: tracer,
 		},
 	}
+	if profiler != nil {
+		runtimeConfig.EVMConfig.Tracer = profiler
+	}
 
 	if cpuProfilePath := ctx.String(CPUProfileFlag.Name); cpuProfilePath != "" {
 		f, err := os.Create(cpuProfilePath)
@@ -291,6 +302,13 @@ func runCmd(ctx *cli.Context) error {
 		f.Close()
 	}
 
+	if profilePath := ctx.String(ProfileFlag.Name); profilePath != "" {
+		if err := writeGasProfile(profiler, profilePath); err != nil {
+			fmt.Println("could not write gas profile: ", err)
+			os.Exit(1)
+		}
+	}
+
 	if ctx.Bool(DebugFlag.Name) {
 		if debugLogger != nil {
 			fmt.Fprintln(os.Stderr, "#### TRACE ####")
@@ -316,3 +334,27 @@ allocated bytes: %d
 
 	return nil
 }
+
+// writeGasProfile writes the pprof profile gathered by the gas profiler to the
+// given path, and its folded stacks next to it for flamegraph tools.
+func writeGasProfile(profiler *native.GasProfiler, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := profiler.WriteProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if f, err = os.Create(path + ".folded"); err != nil {
+		return err
+	}
+	if err := profiler.WriteFolded(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
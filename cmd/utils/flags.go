@@ -237,12 +237,53 @@ var (
 		Value:    true,
 		Category: flags.EthCategory,
 	}
+	StateSchemeFlag = &cli.StringFlag{
+		Name:     "state.scheme",
+		Usage:    `Scheme used to store the state trie nodes ("hash", "path"; default = stored scheme or "hash")`,
+		Category: flags.EthCategory,
+	}
+	StateHistoryFlag = &cli.Uint64Flag{
+		Name:     "state.history",
+		Usage:    "Number of recent blocks whose state can be rolled back to with the path-based scheme (0 = entire chain)",
+		Value:    ethconfig.Defaults.StateHistory,
+		Category: flags.EthCategory,
+	}
 	TxLookupLimitFlag = &cli.Uint64Flag{
 		Name:     "txlookuplimit",
 		Usage:    "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value:    ethconfig.Defaults.TxLookupLimit,
 		Category: flags.EthCategory,
 	}
+	AddressIndexFlag = &cli.BoolFlag{
+		Name:     "addressindex",
+		Usage:    "Enables indexing the transactions by the addresses sending, receiving or creating them",
+		Category: flags.EthCategory,
+	}
+	AddressIndexInternalFlag = &cli.BoolFlag{
+		Name:     "addressindex.internal",
+		Usage:    "Also index the addresses called internally by transactions (requires the historical states)",
+		Category: flags.EthCategory,
+	}
+	AddressIndexTailFlag = &cli.Uint64Flag{
+		Name:     "addressindex.tail",
+		Usage:    "Number of recent blocks to maintain the address index for (0 = entire chain)",
+		Category: flags.EthCategory,
+	}
+	TraceStoreFlag = &cli.StringFlag{
+		Name:     "tracestore",
+		Usage:    "Enables storing the traces of the imported blocks produced by the given native tracer (e.g. callTracer)",
+		Category: flags.EthCategory,
+	}
+	TraceStoreConfigFlag = &cli.StringFlag{
+		Name:     "tracestore.config",
+		Usage:    "JSON configuration of the trace store tracer",
+		Category: flags.EthCategory,
+	}
+	TraceStoreTailFlag = &cli.Uint64Flag{
+		Name:     "tracestore.tail",
+		Usage:    "Number of recent blocks to keep the traces of (0 = entire chain)",
+		Category: flags.EthCategory,
+	}
 	LightKDFFlag = &cli.BoolFlag{
 		Name:     "lightkdf",
 		Usage:    "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -253,6 +294,16 @@ var (
 		Usage:    "Comma separated block number-to-hash mappings to require for peering (<number>=<hash>)",
 		Category: flags.EthCategory,
 	}
+	FinalityMaxReorgDepthFlag = &cli.Uint64Flag{
+		Name:     "finality.maxreorgdepth",
+		Usage:    "Maximum number of canonical blocks a chain reorg may drop (0 = unlimited)",
+		Category: flags.EthCategory,
+	}
+	FinalityMESSFlag = &cli.BoolFlag{
+		Name:     "finality.mess",
+		Usage:    "Penalize late-arriving chain forks by their age (modified exponential subjective scoring)",
+		Category: flags.EthCategory,
+	}
 	LegacyWhitelistFlag = &cli.StringFlag{
 		Name:     "whitelist",
 		Usage:    "Comma separated block number-to-hash mappings to enforce (<number>=<hash>) (deprecated in favor of --eth.requiredblocks)",
@@ -264,6 +315,11 @@ var (
 		Value:    2048,
 		Category: flags.EthCategory,
 	}
+	OverrideLondon = &cli.Uint64Flag{
+		Name:     "override.london",
+		Usage:    "Manually specify the London fork block, overriding the bundled setting",
+		Category: flags.EthCategory,
+	}
 	OverrideShanghai = &cli.Uint64Flag{
 		Name:     "override.shanghai",
 		Usage:    "Manually specify the Shanghai fork timestamp, overriding the bundled setting",
@@ -392,6 +448,24 @@ var (
 		Value:    txpool.DefaultConfig.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolSnapshotFlag = &cli.StringFlag{
+		Name:     "txpool.snapshot",
+		Usage:    "Disk snapshot of all pooled transactions to survive node restarts (disabled if empty)",
+		Value:    txpool.DefaultConfig.Snapshot,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSnapshotIntervalFlag = &cli.DurationFlag{
+		Name:     "txpool.snapshotinterval",
+		Usage:    "Time interval to regenerate the transaction pool snapshot",
+		Value:    txpool.DefaultConfig.SnapshotInterval,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSnapshotLimitFlag = &cli.Uint64Flag{
+		Name:     "txpool.snapshotlimit",
+		Usage:    "Maximum size of the transaction pool snapshot in bytes (0 = unlimited)",
+		Value:    txpool.DefaultConfig.SnapshotLimit,
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
 		Name:     "txpool.pricelimit",
 		Usage:    "Minimum gas price limit to enforce for acceptance into the pool",
@@ -434,6 +508,12 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolDropHistoryFlag = &cli.Uint64Flag{
+		Name:     "txpool.drophistory",
+		Usage:    "Number of dropped transactions remembered for inspection",
+		Value:    txpool.DefaultConfig.DropHistory,
+		Category: flags.TxPoolCategory,
+	}
 
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
@@ -522,6 +602,17 @@ var (
 		Usage:    "Notify with pending block headers instead of work packages",
 		Category: flags.MinerCategory,
 	}
+	MinerStratumFlag = &cli.StringFlag{
+		Name:     "miner.stratum",
+		Usage:    "Listening address of the stratum server for remote miners (e.g. 0.0.0.0:8008)",
+		Category: flags.MinerCategory,
+	}
+	MinerStratumDifficultyFlag = &cli.Float64Flag{
+		Name:     "miner.stratum.difficulty",
+		Usage:    "Default and minimum share difficulty of stratum connections (1 = 2^32 hashes)",
+		Value:    ethconfig.Defaults.Miner.StratumDifficulty,
+		Category: flags.MinerCategory,
+	}
 	MinerGasLimitFlag = &cli.Uint64Flag{
 		Name:     "miner.gaslimit",
 		Usage:    "Target gas ceiling for mined blocks",
@@ -607,6 +698,24 @@ var (
 		Value:    ethconfig.Defaults.RPCEVMTimeout,
 		Category: flags.APICategory,
 	}
+	RPCLogRangeLimitFlag = &cli.Uint64Flag{
+		Name:     "rpc.logs.rangelimit",
+		Usage:    "Maximum number of blocks a log query may span, paginated queries are cut short (0=no limit)",
+		Value:    ethconfig.Defaults.FilterRangeLimit,
+		Category: flags.APICategory,
+	}
+	RPCTraceRangeLimitFlag = &cli.Uint64Flag{
+		Name:     "rpc.trace.rangelimit",
+		Usage:    "Maximum number of blocks a trace_filter query may span (0=no limit)",
+		Value:    ethconfig.Defaults.TraceFilterRangeLimit,
+		Category: flags.APICategory,
+	}
+	RPCLogLimitFlag = &cli.IntFlag{
+		Name:     "rpc.logs.limit",
+		Usage:    "Maximum number of logs a log query may return, paginated queries are cut short (0=no limit)",
+		Value:    ethconfig.Defaults.FilterLogLimit,
+		Category: flags.APICategory,
+	}
 	RPCGlobalTxFeeCapFlag = &cli.Float64Flag{
 		Name:     "rpc.txfeecap",
 		Usage:    "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
@@ -732,6 +841,31 @@ var (
 		Value:    strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 		Category: flags.APICategory,
 	}
+	GraphQLMaxDepthFlag = &cli.IntFlag{
+		Name:     "graphql.maxdepth",
+		Usage:    "Maximum nesting depth of the fields of a GraphQL query (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	GraphQLMaxCostFlag = &cli.IntFlag{
+		Name:     "graphql.maxcost",
+		Usage:    "Maximum total cost of the fields resolved for a GraphQL query (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	GraphQLTimeoutFlag = &cli.DurationFlag{
+		Name:     "graphql.timeout",
+		Usage:    "Maximum execution time of a GraphQL query (0 = HTTP timeouts only)",
+		Category: flags.APICategory,
+	}
+	GraphQLPersistedQueriesFlag = &cli.StringFlag{
+		Name:     "graphql.persistedqueries",
+		Usage:    "JSON file of GraphQL queries, keyed by their SHA-256 hash, that clients may reference by hash",
+		Category: flags.APICategory,
+	}
+	GraphQLAutoPersistFlag = &cli.IntFlag{
+		Name:     "graphql.autopersist",
+		Usage:    "Number of GraphQL queries persisted automatically when sent along with their hash (0 = disabled)",
+		Category: flags.APICategory,
+	}
 	WSEnabledFlag = &cli.BoolFlag{
 		Name:     "ws",
 		Usage:    "Enable the WS-RPC server",
@@ -1216,6 +1350,21 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = SplitAndTrim(ctx.String(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.IsSet(GraphQLMaxDepthFlag.Name) {
+		cfg.GraphQLMaxDepth = ctx.Int(GraphQLMaxDepthFlag.Name)
+	}
+	if ctx.IsSet(GraphQLMaxCostFlag.Name) {
+		cfg.GraphQLMaxCost = ctx.Int(GraphQLMaxCostFlag.Name)
+	}
+	if ctx.IsSet(GraphQLTimeoutFlag.Name) {
+		cfg.GraphQLTimeout = ctx.Duration(GraphQLTimeoutFlag.Name)
+	}
+	if ctx.IsSet(GraphQLPersistedQueriesFlag.Name) {
+		cfg.GraphQLPersistedQueries = ctx.String(GraphQLPersistedQueriesFlag.Name)
+	}
+	if ctx.IsSet(GraphQLAutoPersistFlag.Name) {
+		cfg.GraphQLAutoPersist = ctx.Int(GraphQLAutoPersistFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -1577,6 +1726,15 @@ func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
 	if ctx.IsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.IsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.String(TxPoolSnapshotFlag.Name)
+	}
+	if ctx.IsSet(TxPoolSnapshotIntervalFlag.Name) {
+		cfg.SnapshotInterval = ctx.Duration(TxPoolSnapshotIntervalFlag.Name)
+	}
+	if ctx.IsSet(TxPoolSnapshotLimitFlag.Name) {
+		cfg.SnapshotLimit = ctx.Uint64(TxPoolSnapshotLimitFlag.Name)
+	}
 	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1598,6 +1756,9 @@ func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolDropHistoryFlag.Name) {
+		cfg.DropHistory = ctx.Uint64(TxPoolDropHistoryFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1632,6 +1793,12 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 		cfg.Notify = strings.Split(ctx.String(MinerNotifyFlag.Name), ",")
 	}
 	cfg.NotifyFull = ctx.Bool(MinerNotifyFullFlag.Name)
+	if ctx.IsSet(MinerStratumFlag.Name) {
+		cfg.Stratum = ctx.String(MinerStratumFlag.Name)
+	}
+	if ctx.IsSet(MinerStratumDifficultyFlag.Name) {
+		cfg.StratumDifficulty = ctx.Float64(MinerStratumDifficultyFlag.Name)
+	}
 	if ctx.IsSet(MinerExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.String(MinerExtraDataFlag.Name))
 	}
@@ -1782,6 +1949,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(GCModeFlag.Name) {
 		cfg.NoPruning = ctx.String(GCModeFlag.Name) == "archive"
 	}
+	if ctx.IsSet(StateSchemeFlag.Name) {
+		cfg.StateScheme = parseStateScheme(ctx)
+	}
+	if cfg.StateScheme == rawdb.PathScheme && cfg.NoPruning {
+		Fatalf("--%s=path is not compatible with --%s=archive", StateSchemeFlag.Name, GCModeFlag.Name)
+	}
+	if ctx.IsSet(StateHistoryFlag.Name) {
+		cfg.StateHistory = ctx.Uint64(StateHistoryFlag.Name)
+	}
 	if ctx.IsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.Bool(CacheNoPrefetchFlag.Name)
 	}
@@ -1794,6 +1970,30 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(AddressIndexFlag.Name) {
+		cfg.AddressIndex = ctx.Bool(AddressIndexFlag.Name)
+	}
+	if ctx.IsSet(AddressIndexInternalFlag.Name) {
+		cfg.AddressIndexInternal = ctx.Bool(AddressIndexInternalFlag.Name)
+	}
+	if ctx.IsSet(AddressIndexTailFlag.Name) {
+		cfg.AddressIndexTail = ctx.Uint64(AddressIndexTailFlag.Name)
+	}
+	if ctx.IsSet(TraceStoreFlag.Name) {
+		cfg.TraceStore = ctx.String(TraceStoreFlag.Name)
+	}
+	if ctx.IsSet(TraceStoreConfigFlag.Name) {
+		cfg.TraceStoreConfig = ctx.String(TraceStoreConfigFlag.Name)
+	}
+	if ctx.IsSet(TraceStoreTailFlag.Name) {
+		cfg.TraceStoreTail = ctx.Uint64(TraceStoreTailFlag.Name)
+	}
+	if ctx.IsSet(FinalityMaxReorgDepthFlag.Name) {
+		cfg.Finality.MaxReorgDepth = ctx.Uint64(FinalityMaxReorgDepthFlag.Name)
+	}
+	if ctx.IsSet(FinalityMESSFlag.Name) {
+		cfg.Finality.MESS = ctx.Bool(FinalityMESSFlag.Name)
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
 	}
@@ -1812,6 +2012,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheLogSizeFlag.Name) {
 		cfg.FilterLogCacheSize = ctx.Int(CacheLogSizeFlag.Name)
 	}
+	if ctx.IsSet(RPCLogRangeLimitFlag.Name) {
+		cfg.FilterRangeLimit = ctx.Uint64(RPCLogRangeLimitFlag.Name)
+	}
+	if ctx.IsSet(RPCLogLimitFlag.Name) {
+		cfg.FilterLogLimit = ctx.Int(RPCLogLimitFlag.Name)
+	}
+	if ctx.IsSet(RPCTraceRangeLimitFlag.Name) {
+		cfg.TraceFilterRangeLimit = ctx.Uint64(RPCTraceRangeLimitFlag.Name)
+	}
 	if !ctx.Bool(SnapshotFlag.Name) {
 		// If snap-sync is requested, this flag is also required
 		if cfg.SyncMode == downloader.SnapSync {
@@ -2022,7 +2231,13 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 
 // RegisterGraphQLService adds the GraphQL API to the node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cfg *node.Config) {
-	err := graphql.New(stack, backend, filterSystem, cfg.GraphQLCors, cfg.GraphQLVirtualHosts)
+	err := graphql.New(stack, backend, filterSystem, cfg.GraphQLCors, cfg.GraphQLVirtualHosts, graphql.Config{
+		MaxDepth:         cfg.GraphQLMaxDepth,
+		MaxCost:          cfg.GraphQLMaxCost,
+		Timeout:          cfg.GraphQLTimeout,
+		PersistedQueries: cfg.GraphQLPersistedQueries,
+		AutoPersist:      cfg.GraphQLAutoPersist,
+	})
 	if err != nil {
 		Fatalf("Failed to register the GraphQL service: %v", err)
 	}
@@ -2033,6 +2248,8 @@ func RegisterFilterAPI(stack *node.Node, backend ethapi.Backend, ethcfg *ethconf
 	isLightClient := ethcfg.SyncMode == downloader.LightSync
 	filterSystem := filters.NewFilterSystem(backend, filters.Config{
 		LogCacheSize: ethcfg.FilterLogCacheSize,
+		RangeLimit:   ethcfg.FilterRangeLimit,
+		LogLimit:     ethcfg.FilterLogLimit,
 	})
 	stack.RegisterAPIs([]rpc.API{{
 		Namespace: "eth",
@@ -2216,13 +2433,27 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	return genesis
 }
 
+// parseStateScheme converts the state scheme set on the command line into its
+// database identifier.
+func parseStateScheme(ctx *cli.Context) string {
+	switch scheme := ctx.String(StateSchemeFlag.Name); scheme {
+	case "hash":
+		return rawdb.HashScheme
+	case "path":
+		return rawdb.PathScheme
+	default:
+		Fatalf("--%s must be either 'hash' or 'path'", StateSchemeFlag.Name)
+		return ""
+	}
+}
+
 // MakeChain creates a chain manager from set command line flags.
 func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockChain, ethdb.Database) {
 	var (
 		gspec   = MakeGenesis(ctx)
 		chainDb = MakeChainDatabase(ctx, stack, readonly)
 	)
-	cliqueConfig, err := core.LoadCliqueConfig(chainDb, gspec)
+	chainConfig, err := core.LoadChainConfig(chainDb, gspec)
 	if err != nil {
 		Fatalf("%v", err)
 	}
@@ -2230,7 +2461,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	if ctx.Bool(FakePoWFlag.Name) {
 		ethashConfig.PowMode = ethash.ModeFake
 	}
-	engine := ethconfig.CreateConsensusEngine(stack, &ethashConfig, cliqueConfig, nil, false, chainDb)
+	engine := ethconfig.CreateConsensusEngine(stack, &ethashConfig, chainConfig, nil, false, chainDb)
 	if gcmode := ctx.String(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
@@ -2242,6 +2473,14 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
 		SnapshotLimit:       ethconfig.Defaults.SnapshotCache,
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
+		StateScheme:         rawdb.ReadStateScheme(chainDb),
+		StateHistory:        ctx.Uint64(StateHistoryFlag.Name),
+	}
+	if ctx.IsSet(StateSchemeFlag.Name) {
+		cache.StateScheme = parseStateScheme(ctx)
+	}
+	if cache.StateScheme == rawdb.PathScheme && cache.TrieDirtyDisabled {
+		Fatalf("--%s=path is not compatible with --%s=archive", StateSchemeFlag.Name, GCModeFlag.Name)
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
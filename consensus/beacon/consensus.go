@@ -389,6 +389,15 @@ func (beacon *Beacon) Seal(chain consensus.ChainHeaderReader, block *types.Block
 	return nil
 }
 
+// BlockRewards implements consensus.Issuer, returning the rewards minted by the
+// eth1 engine before the merge. No reward is minted after it.
+func (beacon *Beacon) BlockRewards(chain consensus.ChainHeaderReader, header *types.Header, uncles []*types.Header) (*big.Int, *big.Int) {
+	if issuer, ok := beacon.ethone.(consensus.Issuer); ok && !beacon.IsPoSHeader(header) {
+		return issuer.BlockRewards(chain, header, uncles)
+	}
+	return new(big.Int), new(big.Int)
+}
+
 // SealHash returns the hash of a block prior to it being sealed.
 func (beacon *Beacon) SealHash(header *types.Header) common.Hash {
 	return beacon.ethone.SealHash(header)
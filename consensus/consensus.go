@@ -124,3 +124,12 @@ type PoW interface {
 	// Hashrate returns the current mining hashrate of a PoW consensus engine.
 	Hashrate() float64
 }
+
+// Issuer is a consensus engine minting new coins as block rewards.
+type Issuer interface {
+	Engine
+
+	// BlockRewards returns the coins minted when finalizing a block: the reward
+	// of its miner and the total reward of the miners of its uncles.
+	BlockRewards(chain ChainHeaderReader, header *types.Header, uncles []*types.Header) (reward *big.Int, uncleRewards *big.Int)
+}
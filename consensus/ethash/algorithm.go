@@ -31,27 +31,43 @@ import (
 )
 
 const (
-	datasetInitBytes   = 1 << 30 // Bytes in dataset at genesis
-	datasetGrowthBytes = 1 << 23 // Dataset growth per epoch
-	cacheInitBytes     = 1 << 24 // Bytes in cache at genesis
-	cacheGrowthBytes   = 1 << 17 // Cache growth per epoch
-	epochLength        = 30000   // Blocks per epoch
-	mixBytes           = 128     // Width of mix
-	hashBytes          = 64      // Hash length in bytes
-	hashWords          = 16      // Number of 32 bit ints in a hash
-	datasetParents     = 256     // Number of parents of each dataset element
-	cacheRounds        = 3       // Number of rounds in cache production
-	loopAccesses       = 64      // Number of accesses in hashimoto loop
+	datasetInitBytes    = 1 << 30 // Bytes in dataset at genesis
+	datasetGrowthBytes  = 1 << 23 // Dataset growth per epoch
+	cacheInitBytes      = 1 << 24 // Bytes in cache at genesis
+	cacheGrowthBytes    = 1 << 17 // Cache growth per epoch
+	epochLength         = 30000   // Blocks per epoch
+	epochLengthECIP1099 = 60000   // Blocks per epoch after the ECIP-1099 fork
+	mixBytes            = 128     // Width of mix
+	hashBytes           = 64      // Hash length in bytes
+	hashWords           = 16      // Number of 32 bit ints in a hash
+	datasetParents      = 256     // Number of parents of each dataset element
+	cacheRounds         = 3       // Number of rounds in cache production
+	loopAccesses        = 64      // Number of accesses in hashimoto loop
 )
 
+// calcEpochLength returns the number of blocks per epoch at a certain block
+// number, which doubles from the ECIP-1099 fork block on, if one is scheduled.
+func calcEpochLength(block uint64, ecip1099 *uint64) uint64 {
+	if ecip1099 != nil && block >= *ecip1099 {
+		return epochLengthECIP1099
+	}
+	return epochLength
+}
+
+// calcEpoch returns the epoch that a certain block number belongs to, along with
+// the number of blocks per epoch at it.
+func calcEpoch(block uint64, ecip1099 *uint64) (uint64, uint64) {
+	length := calcEpochLength(block, ecip1099)
+	return block / length, length
+}
+
 // cacheSize returns the size of the ethash verification cache that belongs to a certain
-// block number.
-func cacheSize(block uint64) uint64 {
-	epoch := int(block / epochLength)
+// epoch.
+func cacheSize(epoch uint64) uint64 {
 	if epoch < maxEpoch {
 		return cacheSizes[epoch]
 	}
-	return calcCacheSize(epoch)
+	return calcCacheSize(int(epoch))
 }
 
 // calcCacheSize calculates the cache size for epoch. The cache size grows linearly,
@@ -66,13 +82,12 @@ func calcCacheSize(epoch int) uint64 {
 }
 
 // datasetSize returns the size of the ethash mining dataset that belongs to a certain
-// block number.
-func datasetSize(block uint64) uint64 {
-	epoch := int(block / epochLength)
+// epoch.
+func datasetSize(epoch uint64) uint64 {
 	if epoch < maxEpoch {
 		return datasetSizes[epoch]
 	}
-	return calcDatasetSize(epoch)
+	return calcDatasetSize(int(epoch))
 }
 
 // calcDatasetSize calculates the dataset size for epoch. The dataset size grows linearly,
@@ -113,9 +128,12 @@ func makeHasher(h hash.Hash) hasher {
 }
 
 // seedHash is the seed to use for generating a verification cache and the mining
-// dataset.
-func seedHash(block uint64) []byte {
+// dataset of an epoch with the given number of blocks. The seed is rehashed once
+// for every 30000 blocks preceding the epoch, so a lengthened epoch shares it with
+// the legacy epoch starting at the same block.
+func seedHash(epoch uint64, length uint64) []byte {
 	seed := make([]byte, 32)
+	block := epoch * length
 	if block < epochLength {
 		return seed
 	}
@@ -51,6 +51,57 @@ func TestSizeCalculations(t *testing.T) {
 	}
 }
 
+// Tests that the epochs are lengthened from the ECIP-1099 fork block on, reusing
+// the seeds of the legacy epochs starting at the same blocks.
+func TestEpochLengthening(t *testing.T) {
+	fork := uint64(11_700_000)
+
+	tests := []struct {
+		block    uint64
+		ecip1099 *uint64
+		epoch    uint64
+		length   uint64
+		seed     uint64 // Legacy epoch sharing the seed
+	}{
+		{0, nil, 0, epochLength, 0},
+		{11_699_999, nil, 389, epochLength, 389},
+		{11_700_000, nil, 390, epochLength, 390},
+		{0, &fork, 0, epochLength, 0},
+		{11_699_999, &fork, 389, epochLength, 389},
+		{11_700_000, &fork, 195, epochLengthECIP1099, 390},
+		{11_759_999, &fork, 195, epochLengthECIP1099, 390},
+		{11_760_000, &fork, 196, epochLengthECIP1099, 392},
+	}
+	for i, tt := range tests {
+		epoch, length := calcEpoch(tt.block, tt.ecip1099)
+		if epoch != tt.epoch || length != tt.length {
+			t.Errorf("test %d: epoch mismatch: have %d/%d, want %d/%d", i, epoch, length, tt.epoch, tt.length)
+		}
+		if seed, want := SeedHash(tt.block, tt.ecip1099), seedHash(tt.seed, epochLength); !bytes.Equal(seed, want) {
+			t.Errorf("test %d: seed mismatch: have %x, want %x", i, seed, want)
+		}
+	}
+	// The DAG shrinks to the size of the lengthened epoch number at the fork
+	if have, want := datasetSize(195), datasetSizes[195]; have != want {
+		t.Errorf("dataset size mismatch: have %d, want %d", have, want)
+	}
+	// Stale dumps include the legacy epochs before the lengthened ones
+	var lengthened, legacy []uint64
+	staleEpochs(197, epochLengthECIP1099, 2, func(epoch uint64, length uint64) {
+		if length == epochLength {
+			legacy = append(legacy, epoch)
+		} else {
+			lengthened = append(lengthened, epoch)
+		}
+	})
+	if len(lengthened) != 196 || lengthened[0] != 195 {
+		t.Errorf("stale lengthened epochs mismatch: have %d from %d, want 196 from 195", len(lengthened), lengthened[0])
+	}
+	if len(legacy) != 392 || legacy[0] != 391 {
+		t.Errorf("stale legacy epochs mismatch: have %d from %d, want 392 from 391", len(legacy), legacy[0])
+	}
+}
+
 // Tests that verification caches can be correctly generated.
 func TestCacheGeneration(t *testing.T) {
 	tests := []struct {
@@ -103,7 +154,7 @@ func TestCacheGeneration(t *testing.T) {
 	}
 	for i, tt := range tests {
 		cache := make([]uint32, tt.size/4)
-		generateCache(cache, tt.epoch, seedHash(tt.epoch*epochLength+1))
+		generateCache(cache, tt.epoch, seedHash(tt.epoch, epochLength))
 
 		want := make([]uint32, tt.size/4)
 		prepare(want, tt.cache)
@@ -643,7 +694,7 @@ func TestDatasetGeneration(t *testing.T) {
 	}
 	for i, tt := range tests {
 		cache := make([]uint32, tt.cacheSize/4)
-		generateCache(cache, tt.epoch, seedHash(tt.epoch*epochLength+1))
+		generateCache(cache, tt.epoch, seedHash(tt.epoch, epochLength))
 
 		dataset := make([]uint32, tt.datasetSize/4)
 		generateDataset(dataset, tt.epoch, cache)
@@ -743,7 +794,7 @@ func TestConcurrentDiskCacheGeneration(t *testing.T) {
 // Benchmarks the cache generation performance.
 func BenchmarkCacheGeneration(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		cache := make([]uint32, cacheSize(1)/4)
+		cache := make([]uint32, cacheSize(0)/4)
 		generateCache(cache, 0, make([]byte, 32))
 	}
 }
@@ -762,14 +813,14 @@ func BenchmarkSmallDatasetGeneration(b *testing.B) {
 
 // Benchmarks the light verification performance.
 func BenchmarkHashimotoLight(b *testing.B) {
-	cache := make([]uint32, cacheSize(1)/4)
+	cache := make([]uint32, cacheSize(0)/4)
 	generateCache(cache, 0, make([]byte, 32))
 
 	hash := hexutil.MustDecode("0xc9149cc0386e689d789a1c2f3d5d169a61a6218ed30e74414dc736e442ef3d1f")
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		hashimotoLight(datasetSize(1), cache, hash, 0)
+		hashimotoLight(datasetSize(0), cache, hash, 0)
 	}
 }
 
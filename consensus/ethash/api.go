@@ -103,6 +103,21 @@ func (api *API) SubmitHashrate(rate hexutil.Uint64, id common.Hash) bool {
 	return true
 }
 
+// GetWorkers returns the mining records of the remote workers active within the
+// last hour, mining either over getwork or stratum. Getwork solutions carry no
+// worker identity, so they are all accounted to the worker with a zero id.
+func (api *API) GetWorkers() ([]WorkerStats, error) {
+	if api.ethash.remote == nil {
+		return nil, errors.New("not supported")
+	}
+	select {
+	case <-api.ethash.remote.exitCh:
+		return nil, errEthashStopped
+	default:
+		return api.ethash.remote.workers.stats(), nil
+	}
+}
+
 // GetHashrate returns the current hashrate for local CPU miner and remote miner.
 func (api *API) GetHashrate() uint64 {
 	return uint64(api.ethash.Hashrate())
@@ -192,9 +192,45 @@ func (ethash *Ethash) verifyHeaderWorker(chain consensus.ChainHeaderReader, head
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
+	if index > 0 {
+		// Windowed difficulty algorithms may need ancestors from the batch
+		chain = &batchHeaderReader{ChainHeaderReader: chain, headers: headers[:index]}
+	}
 	return ethash.verifyHeader(chain, headers[index], parent, false, seals[index], unixNow)
 }
 
+// batchHeaderReader is a chain reader which also resolves the headers of a batch
+// being verified, which may not have been imported yet.
+type batchHeaderReader struct {
+	consensus.ChainHeaderReader
+	headers []*types.Header
+}
+
+// GetHeader retrieves a header by hash and number, from the batch or the chain.
+func (r *batchHeaderReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := r.batchHeader(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return r.ChainHeaderReader.GetHeader(hash, number)
+}
+
+// GetHeaderByNumber retrieves a header by number, from the batch or the chain.
+func (r *batchHeaderReader) GetHeaderByNumber(number uint64) *types.Header {
+	if header := r.batchHeader(number); header != nil {
+		return header
+	}
+	return r.ChainHeaderReader.GetHeaderByNumber(number)
+}
+
+// batchHeader returns the header of the batch with the given number, if any.
+func (r *batchHeaderReader) batchHeader(number uint64) *types.Header {
+	first := r.headers[0].Number.Uint64()
+	if number < first || number-first >= uint64(len(r.headers)) {
+		return nil
+	}
+	return r.headers[number-first]
+}
+
 // VerifyUncles verifies that the given block's uncles conform to the consensus
 // rules of the stock Ethereum ethash engine.
 func (ethash *Ethash) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
@@ -329,12 +365,13 @@ func (ethash *Ethash) verifyHeader(chain consensus.ChainHeaderReader, header, pa
 // the difficulty that a new block should have when created at time
 // given the parent block's time and difficulty.
 func (ethash *Ethash) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
-	return CalcDifficulty(chain.Config(), time, parent)
+	return CalcDifficultyWithChain(chain, time, parent)
 }
 
-// CalcDifficulty is the difficulty adjustment algorithm. It returns
+// CalcDifficulty is the legacy difficulty adjustment algorithm. It returns
 // the difficulty that a new block should have when created at time
-// given the parent block's time and difficulty.
+// given the parent block's time and difficulty. It disregards any difficulty
+// algorithm switches of the config, see CalcDifficultyWithChain.
 func CalcDifficulty(config *params.ChainConfig, time uint64, parent *types.Header) *big.Int {
 	next := new(big.Int).Add(parent.Number, big1)
 	switch {
@@ -520,49 +557,49 @@ func (ethash *Ethash) verifySeal(chain consensus.ChainHeaderReader, header *type
 		return errInvalidDifficulty
 	}
 	// Recompute the digest and PoW values
-	number := header.Number.Uint64()
+	digest, result := ethash.hashimoto(header.Number.Uint64(), ethash.SealHash(header).Bytes(), header.Nonce.Uint64(), fulldag)
 
-	var (
-		digest []byte
-		result []byte
-	)
+	// Verify the calculated values against the ones provided in the header
+	if !bytes.Equal(header.MixDigest[:], digest) {
+		return errInvalidMixDigest
+	}
+	target := new(big.Int).Div(two256, header.Difficulty)
+	if new(big.Int).SetBytes(result).Cmp(target) > 0 {
+		return errInvalidPoW
+	}
+	return nil
+}
+
+// hashimoto computes the mix digest and PoW result of the given seal hash and
+// nonce. If fast-but-heavy computation is requested and the ethash dataset is
+// already generated it is used, otherwise the slow-but-light cache is.
+func (ethash *Ethash) hashimoto(number uint64, sealhash []byte, nonce uint64, fulldag bool) (digest []byte, result []byte) {
 	// If fast-but-heavy PoW verification was requested, use an ethash dataset
 	if fulldag {
 		dataset := ethash.dataset(number, true)
 		if dataset.generated() {
-			digest, result = hashimotoFull(dataset.dataset, ethash.SealHash(header).Bytes(), header.Nonce.Uint64())
+			digest, result = hashimotoFull(dataset.dataset, sealhash, nonce)
 
 			// Datasets are unmapped in a finalizer. Ensure that the dataset stays alive
 			// until after the call to hashimotoFull so it's not unmapped while being used.
 			runtime.KeepAlive(dataset)
-		} else {
-			// Dataset not yet generated, don't hang, use a cache instead
-			fulldag = false
+			return digest, result
 		}
 	}
 	// If slow-but-light PoW verification was requested (or DAG not yet ready), use an ethash cache
-	if !fulldag {
-		cache := ethash.cache(number)
-
-		size := datasetSize(number)
-		if ethash.config.PowMode == ModeTest {
-			size = 32 * 1024
-		}
-		digest, result = hashimotoLight(size, cache.cache, ethash.SealHash(header).Bytes(), header.Nonce.Uint64())
+	cache := ethash.cache(number)
 
-		// Caches are unmapped in a finalizer. Ensure that the cache stays alive
-		// until after the call to hashimotoLight so it's not unmapped while being used.
-		runtime.KeepAlive(cache)
-	}
-	// Verify the calculated values against the ones provided in the header
-	if !bytes.Equal(header.MixDigest[:], digest) {
-		return errInvalidMixDigest
+	epoch, _ := ethash.epoch(number)
+	size := datasetSize(epoch)
+	if ethash.config.PowMode == ModeTest {
+		size = 32 * 1024
 	}
-	target := new(big.Int).Div(two256, header.Difficulty)
-	if new(big.Int).SetBytes(result).Cmp(target) > 0 {
-		return errInvalidPoW
-	}
-	return nil
+	digest, result = hashimotoLight(size, cache.cache, sealhash, nonce)
+
+	// Caches are unmapped in a finalizer. Ensure that the cache stays alive
+	// until after the call to hashimotoLight so it's not unmapped while being used.
+	runtime.KeepAlive(cache)
+	return digest, result
 }
 
 // Prepare implements consensus.Engine, initializing the difficulty field of a
@@ -630,14 +667,39 @@ func (ethash *Ethash) SealHash(header *types.Header) (hash common.Hash) {
 
 // Some weird constants to avoid constant memory allocs for them.
 var (
-	big8  = big.NewInt(8)
-	big32 = big.NewInt(32)
+	big8   = big.NewInt(8)
+	big32  = big.NewInt(32)
+	big100 = big.NewInt(100)
 )
 
 // AccumulateRewards credits the coinbase of the given block with the mining
-// reward. The total reward consists of the static block reward and rewards for
+// reward. The total reward consists of the scheduled block reward and rewards for
 // included uncles. The coinbase of each uncle block is also rewarded.
+//
+// Transaction fees are not part of the reward: the tips, and the base fee if it
+// is not burned, are credited to their recipients as the transactions execute.
 func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+	reward, uncleRewards := blockRewards(config, header, uncles)
+	for i, uncle := range uncles {
+		state.AddBalance(uncle.Coinbase, uncleRewards[i])
+	}
+	state.AddBalance(header.Coinbase, reward)
+}
+
+// BlockRewards implements consensus.Issuer, returning the reward of the miner
+// of the block and the total reward of the miners of its uncles.
+func (ethash *Ethash) BlockRewards(chain consensus.ChainHeaderReader, header *types.Header, uncles []*types.Header) (*big.Int, *big.Int) {
+	reward, rewards := blockRewards(chain.Config(), header, uncles)
+	total := new(big.Int)
+	for _, r := range rewards {
+		total.Add(total, r)
+	}
+	return reward, total
+}
+
+// blockRewards returns the reward of the miner of the given block, and the ones
+// of the miners of each of its uncles.
+func blockRewards(config *params.ChainConfig, header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int) {
 	// Select the correct block reward based on chain progression
 	blockReward := FrontierBlockReward
 	if config.IsByzantium(header.Number) {
@@ -646,18 +708,29 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 	if config.IsConstantinople(header.Number) {
 		blockReward = ConstantinopleBlockReward
 	}
+	// Chains with a configured emission schedule override the legacy rewards
+	uncleRatio := uint64(100)
+	if config.Ethash != nil && config.Ethash.Emission != nil {
+		blockReward = config.Ethash.Emission.BlockReward(header.Number)
+		uncleRatio = config.Ethash.Emission.UncleRewardRatio()
+	}
 	// Accumulate the rewards for the miner and any included uncles
-	reward := new(big.Int).Set(blockReward)
-	r := new(big.Int)
-	for _, uncle := range uncles {
-		r.Add(uncle.Number, big8)
+	var (
+		reward       = new(big.Int).Set(blockReward)
+		uncleRewards = make([]*big.Int, len(uncles))
+	)
+	for i, uncle := range uncles {
+		r := new(big.Int).Add(uncle.Number, big8)
 		r.Sub(r, header.Number)
 		r.Mul(r, blockReward)
 		r.Div(r, big8)
-		state.AddBalance(uncle.Coinbase, r)
+		if uncleRatio != 100 {
+			r.Mul(r, new(big.Int).SetUint64(uncleRatio))
+			r.Div(r, big100)
+		}
+		uncleRewards[i] = r
 
-		r.Div(blockReward, big32)
-		reward.Add(reward, r)
+		reward.Add(reward, new(big.Int).Div(blockReward, big32))
 	}
-	state.AddBalance(header.Coinbase, reward)
+	return reward, uncleRewards
 }
@@ -24,6 +24,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -181,3 +183,34 @@ func BenchmarkDifficultyCalculator(b *testing.B) {
 		}
 	})
 }
+
+// Tests that the block rewards reported for issuance tracking match the ones
+// credited to the miners.
+func TestBlockRewards(t *testing.T) {
+	var (
+		header = &types.Header{Number: big.NewInt(10), Coinbase: common.Address{1}}
+		uncles = []*types.Header{
+			{Number: big.NewInt(9), Coinbase: common.Address{2}},
+			{Number: big.NewInt(8), Coinbase: common.Address{3}},
+		}
+		chain  = newRetargetTestChain(params.TestChainConfig, common.Big1, 0)
+		reward = ConstantinopleBlockReward
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	accumulateRewards(params.TestChainConfig, statedb, header, uncles)
+
+	miner, uncle := NewFaker().BlockRewards(chain, header, uncles)
+	if want := new(big.Int).Add(reward, new(big.Int).Div(reward, big.NewInt(16))); miner.Cmp(want) != 0 {
+		t.Errorf("miner reward mismatch: have %v, want %v", miner, want)
+	}
+	if have := statedb.GetBalance(header.Coinbase); have.Cmp(miner) != 0 {
+		t.Errorf("miner balance mismatch: have %v, want %v", have, miner)
+	}
+	credited := new(big.Int).Add(statedb.GetBalance(uncles[0].Coinbase), statedb.GetBalance(uncles[1].Coinbase))
+	if want := new(big.Int).Div(new(big.Int).Mul(reward, big.NewInt(13)), big.NewInt(8)); uncle.Cmp(want) != 0 {
+		t.Errorf("uncle rewards mismatch: have %v, want %v", uncle, want)
+	}
+	if credited.Cmp(uncle) != 0 {
+		t.Errorf("uncle balances mismatch: have %v, want %v", credited, uncle)
+	}
+}
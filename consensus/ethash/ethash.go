@@ -165,21 +165,42 @@ type cacheOrDataset interface {
 	*cache | *dataset
 }
 
+// epochKey identifies the verification cache or mining dataset of an epoch. The
+// epoch length is part of the key, as the epochs before and after the ECIP-1099
+// fork share their numbers.
+type epochKey struct {
+	epoch  uint64 // Number of the epoch
+	length uint64 // Number of blocks per epoch
+}
+
+// start returns the first block of the epoch.
+func (k epochKey) start() uint64 {
+	return k.epoch * k.length
+}
+
+// next returns the key of the epoch following this one, which is lengthened if
+// it starts at the ECIP-1099 fork.
+func (k epochKey) next(ecip1099 *uint64) epochKey {
+	epoch, length := calcEpoch(k.start()+k.length, ecip1099)
+	return epochKey{epoch, length}
+}
+
 // lru tracks caches or datasets by their last use time, keeping at most N of them.
 type lru[T cacheOrDataset] struct {
-	what string
-	new  func(epoch uint64) T
-	mu   sync.Mutex
+	what     string
+	new      func(epoch uint64, length uint64) T
+	ecip1099 *uint64 // Block from which epochs are lengthened (nil = never)
+	mu       sync.Mutex
 	// Items are kept in a LRU cache, but there is a special case:
-	// We always keep an item for (highest seen epoch) + 1 as the 'future item'.
-	cache      lrupkg.BasicLRU[uint64, T]
-	future     uint64
+	// We always keep an item for the epoch after the highest seen one as the 'future item'.
+	cache      lrupkg.BasicLRU[epochKey, T]
+	future     epochKey
 	futureItem T
 }
 
 // newlru create a new least-recently-used cache for either the verification caches
 // or the mining datasets.
-func newlru[T cacheOrDataset](maxItems int, new func(epoch uint64) T) *lru[T] {
+func newlru[T cacheOrDataset](maxItems int, new func(epoch uint64, length uint64) T, ecip1099 *uint64) *lru[T] {
 	var what string
 	switch any(T(nil)).(type) {
 	case *cache:
@@ -190,59 +211,88 @@ func newlru[T cacheOrDataset](maxItems int, new func(epoch uint64) T) *lru[T] {
 		panic("unknown type")
 	}
 	return &lru[T]{
-		what:  what,
-		new:   new,
-		cache: lrupkg.NewBasicLRU[uint64, T](maxItems),
+		what:     what,
+		new:      new,
+		ecip1099: ecip1099,
+		cache:    lrupkg.NewBasicLRU[epochKey, T](maxItems),
 	}
 }
 
-// get retrieves or creates an item for the given epoch. The first return value is always
-// non-nil. The second return value is non-nil if lru thinks that an item will be useful in
-// the near future.
-func (lru *lru[T]) get(epoch uint64) (item, future T) {
+// get retrieves or creates an item for the epoch of the given block. The first return
+// value is always non-nil. The second return value is non-nil if lru thinks that an
+// item will be useful in the near future.
+func (lru *lru[T]) get(block uint64) (item, future T) {
 	lru.mu.Lock()
 	defer lru.mu.Unlock()
 
 	// Get or create the item for the requested epoch.
-	item, ok := lru.cache.Get(epoch)
+	epoch, length := calcEpoch(block, lru.ecip1099)
+	key := epochKey{epoch, length}
+
+	item, ok := lru.cache.Get(key)
 	if !ok {
-		if lru.future > 0 && lru.future == epoch {
+		if lru.future.length > 0 && lru.future == key {
 			item = lru.futureItem
 		} else {
-			log.Trace("Requiring new ethash "+lru.what, "epoch", epoch)
-			item = lru.new(epoch)
+			log.Trace("Requiring new ethash "+lru.what, "epoch", epoch, "length", length)
+			item = lru.new(epoch, length)
 		}
-		lru.cache.Add(epoch, item)
+		lru.cache.Add(key, item)
 	}
-	// Update the 'future item' if epoch is larger than previously seen.
-	if epoch < maxEpoch-1 && lru.future < epoch+1 {
-		log.Trace("Requiring new future ethash "+lru.what, "epoch", epoch+1)
-		future = lru.new(epoch + 1)
-		lru.future = epoch + 1
+	// Update the 'future item' if epoch is later than previously seen.
+	if next := key.next(lru.ecip1099); next.epoch < maxEpoch && lru.future.start() < next.start() {
+		log.Trace("Requiring new future ethash "+lru.what, "epoch", next.epoch, "length", next.length)
+		future = lru.new(next.epoch, next.length)
+		lru.future = next
 		lru.futureItem = future
 	}
 	return item, future
 }
 
+// dumpName returns the file name of a cache or dataset dump for the epoch with the
+// given seed and length. Lengthened epochs are tagged, as their seeds match those
+// of legacy epochs with larger caches and datasets.
+func dumpName(what string, seed []byte, length uint64, endian string) string {
+	var tag string
+	if length != epochLength {
+		tag = fmt.Sprintf("-L%d", length)
+	}
+	return fmt.Sprintf("%s-R%d-%x%s%s", what, algorithmRevision, seed[:8], tag, endian)
+}
+
+// staleEpochs calls fn for all the epochs preceding the given one by more than
+// limit epochs, including the legacy epochs before a lengthened one.
+func staleEpochs(epoch uint64, length uint64, limit int, fn func(epoch uint64, length uint64)) {
+	for ep := int(epoch) - limit; ep >= 0; ep-- {
+		fn(uint64(ep), length)
+	}
+	if length != epochLength {
+		for ep := (int(epoch)-limit+1)*int(length/epochLength) - 1; ep >= 0; ep-- {
+			fn(uint64(ep), epochLength)
+		}
+	}
+}
+
 // cache wraps an ethash cache with some metadata to allow easier concurrent use.
 type cache struct {
-	epoch uint64    // Epoch for which this cache is relevant
-	dump  *os.File  // File descriptor of the memory mapped cache
-	mmap  mmap.MMap // Memory map itself to unmap before releasing
-	cache []uint32  // The actual cache data content (may be memory mapped)
-	once  sync.Once // Ensures the cache is generated only once
+	epoch  uint64    // Epoch for which this cache is relevant
+	length uint64    // Number of blocks in the epoch
+	dump   *os.File  // File descriptor of the memory mapped cache
+	mmap   mmap.MMap // Memory map itself to unmap before releasing
+	cache  []uint32  // The actual cache data content (may be memory mapped)
+	once   sync.Once // Ensures the cache is generated only once
 }
 
 // newCache creates a new ethash verification cache.
-func newCache(epoch uint64) *cache {
-	return &cache{epoch: epoch}
+func newCache(epoch uint64, length uint64) *cache {
+	return &cache{epoch: epoch, length: length}
 }
 
 // generate ensures that the cache content is generated before use.
 func (c *cache) generate(dir string, limit int, lock bool, test bool) {
 	c.once.Do(func() {
-		size := cacheSize(c.epoch*epochLength + 1)
-		seed := seedHash(c.epoch*epochLength + 1)
+		size := cacheSize(c.epoch)
+		seed := seedHash(c.epoch, c.length)
 		if test {
 			size = 1024
 		}
@@ -257,8 +307,8 @@ func (c *cache) generate(dir string, limit int, lock bool, test bool) {
 		if !isLittleEndian() {
 			endian = ".be"
 		}
-		path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", algorithmRevision, seed[:8], endian))
-		logger := log.New("epoch", c.epoch)
+		path := filepath.Join(dir, dumpName("cache", seed, c.length, endian))
+		logger := log.New("epoch", c.epoch, "length", c.length)
 
 		// We're about to mmap the file, ensure that the mapping is cleaned up when the
 		// cache becomes unused.
@@ -282,14 +332,13 @@ func (c *cache) generate(dir string, limit int, lock bool, test bool) {
 			generateCache(c.cache, c.epoch, seed)
 		}
 		// Iterate over all previous instances and delete old ones
-		for ep := int(c.epoch) - limit; ep >= 0; ep-- {
-			seed := seedHash(uint64(ep)*epochLength + 1)
-			path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s*", algorithmRevision, seed[:8], endian))
+		staleEpochs(c.epoch, c.length, limit, func(epoch uint64, length uint64) {
+			path := filepath.Join(dir, dumpName("cache", seedHash(epoch, length), length, endian)+"*")
 			files, _ := filepath.Glob(path) // find also the temp files that are generated.
 			for _, file := range files {
 				os.Remove(file)
 			}
-		}
+		})
 	})
 }
 
@@ -305,6 +354,7 @@ func (c *cache) finalizer() {
 // dataset wraps an ethash dataset with some metadata to allow easier concurrent use.
 type dataset struct {
 	epoch   uint64      // Epoch for which this cache is relevant
+	length  uint64      // Number of blocks in the epoch
 	dump    *os.File    // File descriptor of the memory mapped cache
 	mmap    mmap.MMap   // Memory map itself to unmap before releasing
 	dataset []uint32    // The actual cache data content
@@ -314,8 +364,8 @@ type dataset struct {
 
 // newDataset creates a new ethash mining dataset and returns it as a plain Go
 // interface to be usable in an LRU cache.
-func newDataset(epoch uint64) *dataset {
-	return &dataset{epoch: epoch}
+func newDataset(epoch uint64, length uint64) *dataset {
+	return &dataset{epoch: epoch, length: length}
 }
 
 // generate ensures that the dataset content is generated before use.
@@ -324,9 +374,9 @@ func (d *dataset) generate(dir string, limit int, lock bool, test bool) {
 		// Mark the dataset generated after we're done. This is needed for remote
 		defer d.done.Store(true)
 
-		csize := cacheSize(d.epoch*epochLength + 1)
-		dsize := datasetSize(d.epoch*epochLength + 1)
-		seed := seedHash(d.epoch*epochLength + 1)
+		csize := cacheSize(d.epoch)
+		dsize := datasetSize(d.epoch)
+		seed := seedHash(d.epoch, d.length)
 		if test {
 			csize = 1024
 			dsize = 32 * 1024
@@ -346,8 +396,8 @@ func (d *dataset) generate(dir string, limit int, lock bool, test bool) {
 		if !isLittleEndian() {
 			endian = ".be"
 		}
-		path := filepath.Join(dir, fmt.Sprintf("full-R%d-%x%s", algorithmRevision, seed[:8], endian))
-		logger := log.New("epoch", d.epoch)
+		path := filepath.Join(dir, dumpName("full", seed, d.length, endian))
+		logger := log.New("epoch", d.epoch, "length", d.length)
 
 		// We're about to mmap the file, ensure that the mapping is cleaned up when the
 		// cache becomes unused.
@@ -374,11 +424,9 @@ func (d *dataset) generate(dir string, limit int, lock bool, test bool) {
 			generateDataset(d.dataset, d.epoch, cache)
 		}
 		// Iterate over all previous instances and delete old ones
-		for ep := int(d.epoch) - limit; ep >= 0; ep-- {
-			seed := seedHash(uint64(ep)*epochLength + 1)
-			path := filepath.Join(dir, fmt.Sprintf("full-R%d-%x%s", algorithmRevision, seed[:8], endian))
-			os.Remove(path)
-		}
+		staleEpochs(d.epoch, d.length, limit, func(epoch uint64, length uint64) {
+			os.Remove(filepath.Join(dir, dumpName("full", seedHash(epoch, length), length, endian)))
+		})
 	})
 }
 
@@ -398,15 +446,19 @@ func (d *dataset) finalizer() {
 	}
 }
 
-// MakeCache generates a new ethash cache and optionally stores it to disk.
-func MakeCache(block uint64, dir string) {
-	c := cache{epoch: block / epochLength}
+// MakeCache generates a new ethash cache and optionally stores it to disk. If the
+// ECIP-1099 fork block is given, epochs are lengthened from it on.
+func MakeCache(block uint64, ecip1099 *uint64, dir string) {
+	epoch, length := calcEpoch(block, ecip1099)
+	c := cache{epoch: epoch, length: length}
 	c.generate(dir, math.MaxInt32, false, false)
 }
 
-// MakeDataset generates a new ethash dataset and optionally stores it to disk.
-func MakeDataset(block uint64, dir string) {
-	d := dataset{epoch: block / epochLength}
+// MakeDataset generates a new ethash dataset and optionally stores it to disk. If
+// the ECIP-1099 fork block is given, epochs are lengthened from it on.
+func MakeDataset(block uint64, ecip1099 *uint64, dir string) {
+	epoch, length := calcEpoch(block, ecip1099)
+	d := dataset{epoch: epoch, length: length}
 	d.generate(dir, math.MaxInt32, false, false)
 }
 
@@ -437,6 +489,19 @@ type Config struct {
 	// be block header JSON objects instead of work package arrays.
 	NotifyFull bool
 
+	// When set, the remote sealer also pushes work to miners connecting to
+	// this TCP address over the EthereumStratum/1.0 or 2.0 protocols.
+	StratumAddr string
+
+	// Default share difficulty assigned to stratum connections, where 1
+	// corresponds to roughly 2^32 hashes per share. Miners may only suggest
+	// higher ones.
+	StratumDifficulty float64
+
+	// Block from which the epochs are twice as long, as scheduled by the chain
+	// config (ECIP-1099). Nil if the epochs are never lengthened.
+	ECIP1099Block *uint64 `toml:"-"`
+
 	Log log.Logger `toml:"-"`
 }
 
@@ -483,12 +548,13 @@ func New(config Config, notify []string, noverify bool) *Ethash {
 	}
 	ethash := &Ethash{
 		config:   config,
-		caches:   newlru(config.CachesInMem, newCache),
-		datasets: newlru(config.DatasetsInMem, newDataset),
+		caches:   newlru(config.CachesInMem, newCache, config.ECIP1099Block),
+		datasets: newlru(config.DatasetsInMem, newDataset, config.ECIP1099Block),
 		update:   make(chan struct{}),
 		hashrate: metrics.NewMeterForced(),
 	}
-	if config.PowMode == ModeShared {
+	// The shared instance only knows the legacy epochs, don't use it with ECIP-1099
+	if config.PowMode == ModeShared && config.ECIP1099Block == nil {
 		ethash.shared = sharedEthash
 	}
 	ethash.remote = startRemoteSealer(ethash, notify, noverify)
@@ -578,8 +644,7 @@ func (ethash *Ethash) StopRemoteSealer() error {
 // by first checking against a list of in-memory caches, then against caches
 // stored on disk, and finally generating one if none can be found.
 func (ethash *Ethash) cache(block uint64) *cache {
-	epoch := block / epochLength
-	current, future := ethash.caches.get(epoch)
+	current, future := ethash.caches.get(block)
 
 	// Wait for generation finish.
 	current.generate(ethash.config.CacheDir, ethash.config.CachesOnDisk, ethash.config.CachesLockMmap, ethash.config.PowMode == ModeTest)
@@ -599,8 +664,7 @@ func (ethash *Ethash) cache(block uint64) *cache {
 // generates on a background thread.
 func (ethash *Ethash) dataset(block uint64, async bool) *dataset {
 	// Retrieve the requested ethash dataset
-	epoch := block / epochLength
-	current, future := ethash.datasets.get(epoch)
+	current, future := ethash.datasets.get(block)
 
 	// If async is specified, generate everything in a background thread
 	if async && !current.generated() {
@@ -690,7 +754,13 @@ func (ethash *Ethash) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 }
 
 // SeedHash is the seed to use for generating a verification cache and the mining
-// dataset.
-func SeedHash(block uint64) []byte {
-	return seedHash(block)
+// dataset. If the ECIP-1099 fork block is given, epochs are lengthened from it on.
+func SeedHash(block uint64, ecip1099 *uint64) []byte {
+	return seedHash(calcEpoch(block, ecip1099))
+}
+
+// epoch returns the epoch that a certain block number belongs to, along with the
+// number of blocks per epoch at it.
+func (ethash *Ethash) epoch(block uint64) (uint64, uint64) {
+	return calcEpoch(block, ethash.config.ECIP1099Block)
 }
@@ -49,6 +49,47 @@ func TestTestMode(t *testing.T) {
 	}
 }
 
+// Tests that blocks past the ECIP-1099 fork are sealed and verified with the
+// lengthened epochs, and that the remote sealer announces their seed.
+func TestECIP1099Mode(t *testing.T) {
+	var (
+		fork   = uint64(60000)
+		header = &types.Header{Number: big.NewInt(90000), Difficulty: big.NewInt(100)}
+	)
+	ethash := New(Config{PowMode: ModeTest, ECIP1099Block: &fork}, nil, false)
+	defer ethash.Close()
+
+	results := make(chan *types.Block)
+	if err := ethash.Seal(nil, types.NewBlockWithHeader(header), results, nil); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	select {
+	case block := <-results:
+		header.Nonce = types.EncodeNonce(block.Nonce())
+		header.MixDigest = block.MixDigest()
+	case <-time.NewTimer(4 * time.Second).C:
+		t.Fatalf("sealing result timeout")
+	}
+	if err := ethash.verifySeal(nil, header, false); err != nil {
+		t.Fatalf("unexpected verification error: %v", err)
+	}
+	// The block falls into legacy epoch 3 but lengthened epoch 1, whose seed is
+	// the one of legacy epoch 2
+	legacy := NewTester(nil, false)
+	defer legacy.Close()
+
+	if err := legacy.verifySeal(nil, header, false); err != errInvalidMixDigest {
+		t.Fatalf("legacy verification error mismatch: have %v, want %v", err, errInvalidMixDigest)
+	}
+	work, err := (&API{ethash}).GetWork()
+	if err != nil {
+		t.Fatalf("failed to retrieve work: %v", err)
+	}
+	if want := common.BytesToHash(seedHash(2, epochLength)).Hex(); work[1] != want {
+		t.Errorf("work packet seed mismatch: have %s, want %s", work[1], want)
+	}
+}
+
 // This test checks that cache lru logic doesn't crash under load.
 // It reproduces https://github.com/ethereum/go-ethereum/issues/14943
 func TestCacheFileEvict(t *testing.T) {
@@ -157,6 +198,80 @@ func TestHashrate(t *testing.T) {
 	}
 }
 
+// Tests that the remote sealer keeps the records of the getwork workers.
+func TestWorkerStats(t *testing.T) {
+	ethash := NewTester(nil, false)
+	defer ethash.Close()
+	ethash.SetThreads(-1)
+
+	api := &API{ethash}
+	ids := []common.Hash{common.HexToHash("a"), common.HexToHash("b")}
+	for i, id := range ids {
+		api.SubmitHashrate(hexutil.Uint64(100*(i+1)), id)
+	}
+	// Push some work and submit a valid, an invalid and a stale solution
+	results := make(chan *types.Block, 1)
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(100)}
+	ethash.Seal(nil, types.NewBlockWithHeader(header), results, nil)
+
+	var (
+		sealhash = ethash.SealHash(header)
+		target   = new(big.Int).Div(two256, header.Difficulty)
+		nonce    uint64
+		digest   []byte
+	)
+	for ; ; nonce++ {
+		var result []byte
+		if digest, result = ethash.hashimoto(1, sealhash.Bytes(), nonce, false); new(big.Int).SetBytes(result).Cmp(target) <= 0 {
+			break
+		}
+	}
+	if !api.SubmitWork(types.EncodeNonce(nonce), sealhash, common.BytesToHash(digest)) {
+		t.Fatalf("valid solution rejected")
+	}
+	if api.SubmitWork(types.EncodeNonce(nonce+1), sealhash, common.BytesToHash(digest)) {
+		t.Fatalf("invalid solution accepted")
+	}
+	if api.SubmitWork(types.EncodeNonce(nonce), common.Hash{0x01}, common.BytesToHash(digest)) {
+		t.Fatalf("stale solution accepted")
+	}
+	// Ensure hash rates and solutions are all tracked
+	workers, err := api.GetWorkers()
+	if err != nil {
+		t.Fatalf("failed to retrieve workers: %v", err)
+	}
+	want := []WorkerStats{
+		{Submitted: 3, Accepted: 1, Stale: 1, Invalid: 1, Blocks: 1},
+		{ID: ids[0], Hashrate: 100},
+		{ID: ids[1], Hashrate: 200},
+	}
+	if len(workers) != len(want) {
+		t.Fatalf("worker count mismatch: have %d, want %d", len(workers), len(want))
+	}
+	for i, worker := range workers {
+		if worker.LastSeen.IsZero() {
+			t.Errorf("worker %d: last seen time not set", i)
+		}
+		worker.LastSeen = time.Time{}
+		if worker != want[i] {
+			t.Errorf("worker %d: stats mismatch: have %+v, want %+v", i, worker, want[i])
+		}
+	}
+	// Ensure the hash rates expire from the total, but the records are retained
+	ethash.remote.workers.lock.Lock()
+	for _, w := range ethash.remote.workers.workers {
+		w.rated = w.rated.Add(-hashrateExpiry - time.Second)
+	}
+	ethash.remote.workers.lock.Unlock()
+	ethash.remote.workers.expire()
+	if rate := api.GetHashrate(); rate != 0 {
+		t.Errorf("expired hash rate accounted: have %d, want 0", rate)
+	}
+	if workers, _ := api.GetWorkers(); len(workers) != len(want) || workers[1].Hashrate != 0 {
+		t.Errorf("expired hash rate retained: %+v", workers)
+	}
+}
+
 func TestClosedRemoteSealer(t *testing.T) {
 	ethash := NewTester(nil, false)
 	time.Sleep(1 * time.Second) // ensure exit channel is listening
@@ -171,3 +286,40 @@ func TestClosedRemoteSealer(t *testing.T) {
 		t.Error("expect to return false when submit hashrate to a stopped ethash")
 	}
 }
+
+// Tests that the number of tracked remote workers is capped, forgetting the
+// least recently seen ones, and that workers with similar names don't share
+// their metrics.
+func TestRemoteWorkerLimit(t *testing.T) {
+	set := newWorkerSet()
+	defer func() {
+		for _, w := range set.workers {
+			w.unregister()
+		}
+	}()
+	start := time.Now()
+	for i := 0; i < maxRemoteWorkers; i++ {
+		w := set.worker(common.BigToHash(big.NewInt(int64(i))), "")
+		w.stats.LastSeen = start.Add(time.Duration(i) * time.Second)
+	}
+	set.workers[common.BigToHash(big.NewInt(1))].stats.LastSeen = start.Add(-time.Second)
+
+	set.reportHashrate(common.Hash{0xff}, "", 100)
+	if len(set.workers) != maxRemoteWorkers {
+		t.Fatalf("worker count mismatch: have %d, want %d", len(set.workers), maxRemoteWorkers)
+	}
+	if _, ok := set.workers[common.BigToHash(big.NewInt(1))]; ok {
+		t.Errorf("least recently seen worker retained")
+	}
+	if _, ok := set.workers[common.Hash{0xff}]; !ok {
+		t.Errorf("new worker not tracked")
+	}
+	// Names sanitized to the same label must not collide
+	a := newRemoteWorker(stratumWorkerID("rig.1"), "rig.1")
+	b := newRemoteWorker(stratumWorkerID("rig-1"), "rig-1")
+	defer a.unregister()
+	defer b.unregister()
+	if a.prefix == b.prefix {
+		t.Errorf("metric prefixes collide: %s", a.prefix)
+	}
+}
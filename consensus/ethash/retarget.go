@@ -0,0 +1,195 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package ethash
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// maxTarget is the easiest proof-of-work target a block may ever have,
+	// corresponding to the minimum difficulty.
+	maxTarget = new(big.Int).Div(two256, params.MinimumDifficulty)
+
+	// Coefficients of the cubic approximation of 2^x used by aserti3-2d, scaled
+	// by 2^48 for 16 bit fixed point fractional exponents.
+	asertCoeff1 = big.NewInt(195766423245049)
+	asertCoeff2 = big.NewInt(971821376)
+	asertCoeff3 = big.NewInt(5127)
+	asertRound  = new(big.Int).Lsh(big1, 47)
+)
+
+// CalcDifficultyWithChain is the difficulty adjustment algorithm. It returns
+// the difficulty that a new block should have when created at time given the
+// parent block, using the algorithm the chain config selects for it. Windowed
+// algorithms read the ancestors of the parent through the chain reader.
+func CalcDifficultyWithChain(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	config := chain.Config()
+	next := new(big.Int).Add(parent.Number, big1)
+
+	algo := config.DifficultyAlgorithm(next)
+	if algo == nil {
+		return CalcDifficulty(config, time, parent)
+	}
+	switch algo.Algorithm {
+	case params.DifficultyLWMA1:
+		return calcDifficultyLWMA1(chain, algo, parent)
+	case params.DifficultyASERT:
+		return calcDifficultyASERT(chain, algo, parent)
+	default:
+		return CalcDifficulty(config, time, parent)
+	}
+}
+
+// calcDifficultyLWMA1 is the LWMA-1 difficulty adjustment algorithm. It returns
+// the difficulty of the block following parent, targeting the average solve
+// time of the last N blocks, weighted linearly in favour of the recent ones:
+//
+//	L         = sum(j * min(6T, time[j] - time[j-1])) for j = 1..N
+//	next_diff = sum(diff[j]) * (N+1) * T / (2 * max(L, N*N*T/20))
+//
+// If fewer than N ancestors exist (or are known to the chain reader), the window
+// is shortened accordingly.
+func calcDifficultyLWMA1(chain consensus.ChainHeaderReader, config *params.DifficultyConfig, parent *types.Header) *big.Int {
+	var (
+		target = config.TargetTime
+		window = config.WindowSize()
+	)
+	if parent.Number.Uint64() < window {
+		window = parent.Number.Uint64()
+	}
+	// Gather the window of headers, oldest first, ending with the parent
+	headers := make([]*types.Header, window+1)
+	headers[window] = parent
+	for i := int(window) - 1; i >= 0; i-- {
+		header := chain.GetHeader(headers[i+1].ParentHash, headers[i+1].Number.Uint64()-1)
+		if header == nil {
+			headers = headers[i+1:]
+			break
+		}
+		headers[i] = header
+	}
+	window = uint64(len(headers) - 1)
+	if window == 0 {
+		return new(big.Int).Set(parent.Difficulty)
+	}
+	// Accumulate the weighted solve times and the window difficulty. Ethash
+	// enforces increasing timestamps, so solve times are always positive.
+	var (
+		weighted uint64
+		total    = new(big.Int)
+	)
+	for j := uint64(1); j <= window; j++ {
+		solvetime := headers[j].Time - headers[j-1].Time
+		if solvetime > 6*target {
+			solvetime = 6 * target
+		}
+		weighted += j * solvetime
+		total.Add(total, headers[j].Difficulty)
+	}
+	if floor := window * window * target / 20; weighted < floor {
+		weighted = floor
+	}
+	if weighted == 0 {
+		weighted = 1
+	}
+	// next_diff = sum(diff) * (N+1) * T / (2 * L)
+	next := new(big.Int).Mul(total, new(big.Int).SetUint64((window+1)*target))
+	next.Div(next, new(big.Int).SetUint64(2*weighted))
+
+	if next.Cmp(params.MinimumDifficulty) < 0 {
+		next.Set(params.MinimumDifficulty)
+	}
+	return next
+}
+
+// calcDifficultyASERT is the aserti3-2d difficulty adjustment algorithm. It
+// returns the difficulty of the block following parent, scaling the target of
+// the anchor block (the last one before the algorithm activated) exponentially
+// by how far the parent is ahead of or behind the ideal block schedule:
+//
+//	next_target = anchor_target * 2^((time_delta - T * (height_delta + 1)) / halflife)
+//
+// The anchor is resolved through the canonical chain. If it is not known to the
+// chain reader, the parent difficulty is retained.
+func calcDifficultyASERT(chain consensus.ChainHeaderReader, config *params.DifficultyConfig, parent *types.Header) *big.Int {
+	// Resolve the anchor block and the time of its parent
+	anchorNum := config.Block.Uint64()
+	if anchorNum > 0 {
+		anchorNum--
+	}
+	anchor := parent
+	if parent.Number.Uint64() != anchorNum {
+		anchor = chain.GetHeaderByNumber(anchorNum)
+	}
+	if anchor == nil || anchor.Difficulty.Sign() <= 0 {
+		return new(big.Int).Set(parent.Difficulty)
+	}
+	var anchorParentTime int64
+	if anchorNum == 0 {
+		anchorParentTime = int64(anchor.Time) - int64(config.TargetTime)
+	} else {
+		header := chain.GetHeader(anchor.ParentHash, anchorNum-1)
+		if header == nil {
+			return new(big.Int).Set(parent.Difficulty)
+		}
+		anchorParentTime = int64(header.Time)
+	}
+	// Calculate the 16.16 fixed point exponent of the target scaling. Go's signed
+	// division truncates towards zero, matching the reference implementation.
+	var (
+		timeDelta   = int64(parent.Time) - anchorParentTime
+		heightDelta = int64(parent.Number.Uint64() - anchorNum)
+		exponent    = ((timeDelta - int64(config.TargetTime)*(heightDelta+1)) * 65536) / int64(config.HalfLifeTime())
+		shifts      = exponent >> 16
+		frac        = big.NewInt(exponent - shifts*65536)
+	)
+	// factor = 65536 + (c1*frac + c2*frac^2 + c3*frac^3 + 2^47) >> 48
+	var (
+		factor = new(big.Int).Mul(asertCoeff1, frac)
+		square = new(big.Int).Mul(frac, frac)
+		cube   = new(big.Int).Mul(square, frac)
+	)
+	factor.Add(factor, square.Mul(square, asertCoeff2))
+	factor.Add(factor, cube.Mul(cube, asertCoeff3))
+	factor.Add(factor, asertRound)
+	factor.Rsh(factor, 48)
+	factor.Add(factor, big.NewInt(65536))
+
+	// Scale the anchor target, clamping it to the valid range. The scaled target
+	// is below 2^273, so larger left shifts always exceed the maximum.
+	target := new(big.Int).Div(two256, anchor.Difficulty)
+	target.Mul(target, factor)
+
+	switch shifts -= 16; {
+	case shifts > 256:
+		target.Set(maxTarget)
+	case shifts > 0:
+		target.Lsh(target, uint(shifts))
+	case shifts < -273:
+		target.SetUint64(0)
+	default:
+		target.Rsh(target, uint(-shifts))
+	}
+	if target.Sign() == 0 {
+		target.SetUint64(1)
+	}
+	if target.Cmp(maxTarget) > 0 {
+		target.Set(maxTarget)
+	}
+	return target.Div(two256, target)
+}
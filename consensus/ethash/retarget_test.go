@@ -0,0 +1,155 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package ethash
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// retargetTestChain is a chain reader over a list of headers starting at genesis.
+type retargetTestChain struct {
+	config  *params.ChainConfig
+	headers []*types.Header
+}
+
+func newRetargetTestChain(config *params.ChainConfig, difficulty *big.Int, times ...uint64) *retargetTestChain {
+	chain := &retargetTestChain{config: config}
+	for i, time := range times {
+		header := &types.Header{Number: big.NewInt(int64(i)), Time: time, Difficulty: difficulty}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	return chain
+}
+
+func (c *retargetTestChain) Config() *params.ChainConfig                    { return c.config }
+func (c *retargetTestChain) CurrentHeader() *types.Header                   { return c.headers[len(c.headers)-1] }
+func (c *retargetTestChain) GetHeaderByHash(hash common.Hash) *types.Header { return nil }
+func (c *retargetTestChain) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
+
+func (c *retargetTestChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c *retargetTestChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func retargetTestConfig(algo *params.DifficultyConfig) *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.Ethash = &params.EthashConfig{Difficulty: []*params.DifficultyConfig{algo}}
+	return &config
+}
+
+func TestDifficultyLWMA1(t *testing.T) {
+	config := retargetTestConfig(&params.DifficultyConfig{
+		Block: big.NewInt(1), Algorithm: params.DifficultyLWMA1, TargetTime: 10, Window: 5,
+	})
+	diff := big.NewInt(1 << 30)
+
+	tests := []struct {
+		times []uint64
+		want  *big.Int
+	}{
+		// Parent is genesis, nothing to average
+		{[]uint64{0}, diff},
+		// Blocks on target keep the difficulty
+		{[]uint64{0, 10, 20, 30, 40, 50, 60}, diff},
+		// Blocks twice as fast double it
+		{[]uint64{0, 5, 10, 15, 20, 25, 30}, new(big.Int).Mul(diff, big.NewInt(2))},
+		// Short chains average over the available blocks only
+		{[]uint64{0, 5, 10}, new(big.Int).Mul(diff, big.NewInt(2))},
+		// Recent blocks weigh more: only the last solve time is off target
+		{[]uint64{0, 10, 20, 30, 40, 45}, new(big.Int).Div(new(big.Int).Mul(diff, big.NewInt(150)), big.NewInt(125))},
+		// Solve times are capped at 6T
+		{[]uint64{0, 10, 20, 30, 40, 1000}, new(big.Int).Div(new(big.Int).Mul(diff, big.NewInt(3)), big.NewInt(8))},
+	}
+	for i, tt := range tests {
+		chain := newRetargetTestChain(config, diff, tt.times...)
+		parent := chain.CurrentHeader()
+		if have := CalcDifficultyWithChain(chain, parent.Time+10, parent); have.Cmp(tt.want) != 0 {
+			t.Errorf("test %d: difficulty mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Tiny weighted solve times are floored at N*N*T/20
+	floorConfig := retargetTestConfig(&params.DifficultyConfig{
+		Block: big.NewInt(1), Algorithm: params.DifficultyLWMA1, TargetTime: 60, Window: 5,
+	})
+	chain := newRetargetTestChain(floorConfig, diff, 0, 1, 2, 3, 4, 5)
+	if have, want := CalcDifficultyWithChain(chain, 6, chain.CurrentHeader()), new(big.Int).Mul(diff, big.NewInt(12)); have.Cmp(want) != 0 {
+		t.Errorf("floored difficulty mismatch: have %v, want %v", have, want)
+	}
+	// The difficulty may never drop below the minimum
+	chain = newRetargetTestChain(config, params.MinimumDifficulty, 0, 100, 200)
+	if have := CalcDifficultyWithChain(chain, 300, chain.CurrentHeader()); have.Cmp(params.MinimumDifficulty) != 0 {
+		t.Errorf("minimum difficulty mismatch: have %v, want %v", have, params.MinimumDifficulty)
+	}
+}
+
+func TestDifficultyASERT(t *testing.T) {
+	config := retargetTestConfig(&params.DifficultyConfig{
+		Block: big.NewInt(3), Algorithm: params.DifficultyASERT, TargetTime: 10, HalfLife: 100,
+	})
+	diff := big.NewInt(1 << 30)
+
+	ahead := []uint64{0, 10, 20}
+	for len(ahead) <= 22 {
+		ahead = append(ahead, ahead[len(ahead)-1]+5)
+	}
+	tests := []struct {
+		times []uint64
+		want  *big.Int
+	}{
+		// The anchor (block 2) is the parent, on schedule
+		{[]uint64{0, 10, 20}, diff},
+		// Blocks on schedule keep the anchor difficulty
+		{[]uint64{0, 10, 20, 30, 40, 50}, diff},
+		// One half-life behind schedule halves it
+		{[]uint64{0, 10, 20, 30, 40, 150}, new(big.Int).Rsh(diff, 1)},
+		// Two half-lives behind schedule quarter it
+		{[]uint64{0, 10, 20, 30, 40, 250}, new(big.Int).Rsh(diff, 2)},
+		// One half-life ahead of schedule doubles it
+		{ahead, new(big.Int).Lsh(diff, 1)},
+	}
+	for i, tt := range tests {
+		chain := newRetargetTestChain(config, diff, tt.times...)
+		parent := chain.CurrentHeader()
+		if have := CalcDifficultyWithChain(chain, parent.Time+10, parent); have.Cmp(tt.want) != 0 {
+			t.Errorf("test %d: difficulty mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Half a half-life behind schedule scales the difficulty by ~1/sqrt(2)
+	chain := newRetargetTestChain(config, diff, 0, 10, 20, 30, 40, 100)
+	have := CalcDifficultyWithChain(chain, 110, chain.CurrentHeader())
+	if ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(have), new(big.Float).SetInt(diff)).Float64(); ratio < 0.7070 || ratio > 0.7072 {
+		t.Errorf("fractional exponent mismatch: have ratio %v, want 0.7071", ratio)
+	}
+	// Blocks before the switch follow the legacy rules
+	chain = newRetargetTestChain(config, diff, 0, 10)
+	if have, want := CalcDifficultyWithChain(chain, 20, chain.CurrentHeader()), CalcDifficulty(config, 20, chain.CurrentHeader()); have.Cmp(want) != 0 {
+		t.Errorf("legacy difficulty mismatch: have %v, want %v", have, want)
+	}
+}
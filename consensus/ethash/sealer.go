@@ -188,7 +188,7 @@ const remoteSealerTimeout = 1 * time.Second
 
 type remoteSealer struct {
 	works        map[common.Hash]*types.Block
-	rates        map[common.Hash]hashrate
+	workers      *workerSet // Mining records of the remote workers
 	currentBlock *types.Block
 	currentWork  [4]string
 	notifyCtx    context.Context
@@ -198,6 +198,7 @@ type remoteSealer struct {
 	ethash       *Ethash
 	noverify     bool
 	notifyURLs   []string
+	stratum      *stratumServer // Optional stratum endpoint pushing work to miners
 	results      chan<- *types.Block
 	workCh       chan *sealTask   // Notification channel to push new work and relative result channel to remote sealer
 	fetchWorkCh  chan *sealWork   // Channel used for remote sealer to fetch mining work
@@ -219,6 +220,8 @@ type mineResult struct {
 	nonce     types.BlockNonce
 	mixDigest common.Hash
 	hash      common.Hash
+	worker    common.Hash // Identifier of the submitting worker, zero over getwork
+	name      string      // Name of the submitting worker, empty over getwork
 
 	errc chan error
 }
@@ -226,7 +229,7 @@ type mineResult struct {
 // hashrate wraps the hash rate submitted by the remote sealer.
 type hashrate struct {
 	id   common.Hash
-	ping time.Time
+	name string
 	rate uint64
 
 	done chan struct{}
@@ -247,7 +250,7 @@ func startRemoteSealer(ethash *Ethash, urls []string, noverify bool) *remoteSeal
 		notifyCtx:    ctx,
 		cancelNotify: cancel,
 		works:        make(map[common.Hash]*types.Block),
-		rates:        make(map[common.Hash]hashrate),
+		workers:      newWorkerSet(),
 		workCh:       make(chan *sealTask),
 		fetchWorkCh:  make(chan *sealWork),
 		submitWorkCh: make(chan *mineResult),
@@ -256,6 +259,14 @@ func startRemoteSealer(ethash *Ethash, urls []string, noverify bool) *remoteSeal
 		requestExit:  make(chan struct{}),
 		exitCh:       make(chan struct{}),
 	}
+	if addr := ethash.config.StratumAddr; addr != "" {
+		stratum, err := startStratumServer(s, addr, ethash.config.StratumDifficulty)
+		if err != nil {
+			ethash.config.Log.Error("Failed to start stratum server", "addr", addr, "err", err)
+		} else {
+			s.stratum = stratum
+		}
+	}
 	go s.loop()
 	return s
 }
@@ -265,6 +276,9 @@ func (s *remoteSealer) loop() {
 		s.ethash.config.Log.Trace("Ethash remote sealer is exiting")
 		s.cancelNotify()
 		s.reqWG.Wait()
+		if s.stratum != nil {
+			s.stratum.close()
+		}
 		close(s.exitCh)
 	}()
 
@@ -290,7 +304,10 @@ func (s *remoteSealer) loop() {
 
 		case result := <-s.submitWorkCh:
 			// Verify submitted PoW solution based on maintained mining blocks.
-			if s.submitWork(result.nonce, result.mixDigest, result.hash) {
+			outcome := s.submitWork(result.nonce, result.mixDigest, result.hash)
+			s.workers.reportSolution(result.worker, result.name, outcome, true)
+
+			if outcome == solutionAccepted {
 				result.errc <- nil
 			} else {
 				result.errc <- errInvalidSealResult
@@ -298,25 +315,16 @@ func (s *remoteSealer) loop() {
 
 		case result := <-s.submitRateCh:
 			// Trace remote sealer's hash rate by submitted value.
-			s.rates[result.id] = hashrate{rate: result.rate, ping: time.Now()}
+			s.workers.reportHashrate(result.id, result.name, result.rate)
 			close(result.done)
 
 		case req := <-s.fetchRateCh:
 			// Gather all hash rate submitted by remote sealer.
-			var total uint64
-			for _, rate := range s.rates {
-				// this could overflow
-				total += rate.rate
-			}
-			req <- total
+			req <- s.workers.hashrate()
 
 		case <-ticker.C:
-			// Clear stale submitted hash rate.
-			for id, rate := range s.rates {
-				if time.Since(rate.ping) > 10*time.Second {
-					delete(s.rates, id)
-				}
-			}
+			// Clear stale submitted hash rate and inactive workers.
+			s.workers.expire()
 			// Clear stale pending blocks
 			if s.currentBlock != nil {
 				for hash, block := range s.works {
@@ -343,7 +351,7 @@ func (s *remoteSealer) loop() {
 func (s *remoteSealer) makeWork(block *types.Block) {
 	hash := s.ethash.SealHash(block.Header())
 	s.currentWork[0] = hash.Hex()
-	s.currentWork[1] = common.BytesToHash(SeedHash(block.NumberU64())).Hex()
+	s.currentWork[1] = common.BytesToHash(SeedHash(block.NumberU64(), s.ethash.config.ECIP1099Block)).Hex()
 	s.currentWork[2] = common.BytesToHash(new(big.Int).Div(two256, block.Difficulty()).Bytes()).Hex()
 	s.currentWork[3] = hexutil.EncodeBig(block.Number())
 
@@ -352,10 +360,13 @@ func (s *remoteSealer) makeWork(block *types.Block) {
 	s.works[hash] = block
 }
 
-// notifyWork notifies all the specified mining endpoints of the availability of
-// new work to be processed.
+// notifyWork notifies all the specified mining endpoints and the connected
+// stratum miners of the availability of new work to be processed.
 func (s *remoteSealer) notifyWork() {
 	work := s.currentWork
+	if s.stratum != nil {
+		s.stratum.notify(s.currentBlock, common.HexToHash(work[0]))
+	}
 
 	// Encode the JSON payload of the notification. When NotifyFull is set,
 	// this is the complete block header, otherwise it is a JSON array.
@@ -394,19 +405,19 @@ func (s *remoteSealer) sendNotification(ctx context.Context, url string, json []
 	}
 }
 
-// submitWork verifies the submitted pow solution, returning
-// whether the solution was accepted or not (not can be both a bad pow as well as
-// any other error, like no pending work or stale mining result).
-func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash, sealhash common.Hash) bool {
+// submitWork verifies the submitted pow solution, returning whether the
+// solution was accepted or not, and if not, whether it was a bad pow, a stale
+// mining result or a valid solution that couldn't be delivered.
+func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash, sealhash common.Hash) solutionOutcome {
 	if s.currentBlock == nil {
 		s.ethash.config.Log.Error("Pending work without block", "sealhash", sealhash)
-		return false
+		return solutionStale
 	}
 	// Make sure the work submitted is present
 	block := s.works[sealhash]
 	if block == nil {
 		s.ethash.config.Log.Warn("Work submitted but none pending", "sealhash", sealhash, "curnumber", s.currentBlock.NumberU64())
-		return false
+		return solutionStale
 	}
 	// Verify the correctness of submitted result.
 	header := block.Header()
@@ -417,13 +428,13 @@ func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash,
 	if !s.noverify {
 		if err := s.ethash.verifySeal(nil, header, true); err != nil {
 			s.ethash.config.Log.Warn("Invalid proof-of-work submitted", "sealhash", sealhash, "elapsed", common.PrettyDuration(time.Since(start)), "err", err)
-			return false
+			return solutionInvalid
 		}
 	}
 	// Make sure the result channel is assigned.
 	if s.results == nil {
 		s.ethash.config.Log.Warn("Ethash result channel is empty, submitted mining result is rejected")
-		return false
+		return solutionDropped
 	}
 	s.ethash.config.Log.Trace("Verified correct proof-of-work", "sealhash", sealhash, "elapsed", common.PrettyDuration(time.Since(start)))
 
@@ -435,13 +446,13 @@ func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash,
 		select {
 		case s.results <- solution:
 			s.ethash.config.Log.Debug("Work submitted is acceptable", "number", solution.NumberU64(), "sealhash", sealhash, "hash", solution.Hash())
-			return true
+			return solutionAccepted
 		default:
 			s.ethash.config.Log.Warn("Sealing result is not read by miner", "mode", "remote", "sealhash", sealhash)
-			return false
+			return solutionDropped
 		}
 	}
 	// The submitted block is too old to accept, drop it.
 	s.ethash.config.Log.Warn("Work submitted is too old", "number", solution.NumberU64(), "sealhash", sealhash, "hash", solution.Hash())
-	return false
+	return solutionStale
 }
@@ -0,0 +1,845 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package ethash
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// stratumV1Proto and stratumV2Proto are the protocol identifiers exchanged
+	// with miners during the EthereumStratum/1.0 and 2.0 handshakes.
+	stratumV1Proto = "EthereumStratum/1.0.0"
+	stratumV2Proto = "EthereumStratum/2.0.0"
+
+	stratumExtranonceSize = 2                // Bytes of the nonce assigned to each connection
+	stratumMaxLineSize    = 4096             // Maximum size of a single newline delimited request
+	stratumIdleTimeout    = 10 * time.Minute // Time after which a silent connection is dropped
+	stratumWriteTimeout   = 10 * time.Second // Time allowed for a single message to be written
+	stratumSendQueue      = 64               // Number of queued messages before a slow miner is dropped
+	stratumMaxErrors      = 16               // Number of failed requests before a miner is dropped
+	stratumMaxJobShares   = 1 << 16          // Number of shares tracked per job for duplicate detection
+
+	// stratumDefaultDifficulty is the share difficulty used when none is configured.
+	stratumDefaultDifficulty = 1.0
+)
+
+var (
+	// stratumDiff1Target is the share target corresponding to a stratum
+	// difficulty of 1, i.e. roughly 2^32 hashes per share.
+	stratumDiff1Target = new(big.Int).Lsh(big.NewInt(0xffff), 208)
+
+	// stratumWorkerName is the format worker names need to satisfy to be authorized.
+	stratumWorkerName = regexp.MustCompile(`^[0-9A-Za-z_\-.]{1,64}$`)
+)
+
+// stratumError is a protocol level error reported back to the miner.
+type stratumError struct {
+	code    int
+	message string
+}
+
+func (e *stratumError) Error() string { return e.message }
+
+var (
+	errStratumUnknown       = &stratumError{20, "Other/Unknown"}
+	errStratumJobNotFound   = &stratumError{21, "Job not found (=stale)"}
+	errStratumDuplicate     = &stratumError{22, "Duplicate share"}
+	errStratumLowDifficulty = &stratumError{23, "Low difficulty share"}
+	errStratumUnauthorized  = &stratumError{24, "Unauthorized worker"}
+	errStratumNotSubscribed = &stratumError{25, "Not subscribed"}
+	errStratumBadParams     = &stratumError{26, "Invalid parameters"}
+	errStratumBadMethod     = &stratumError{27, "Method not found"}
+	errStratumRejected      = &stratumError{28, "Block solution rejected"}
+)
+
+// stratumRequest is a request sent by a miner.
+type stratumRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// stratumResponse is the reply to a miner request.
+type stratumResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result"`
+	Error  interface{}     `json:"error"`
+}
+
+// stratumNotification is a message pushed to a miner without a request.
+type stratumNotification struct {
+	ID     interface{} `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// stratumJob is a work package announced to stratum miners.
+type stratumJob struct {
+	id       string
+	sealhash common.Hash
+	seedhash common.Hash
+	number   uint64
+	epoch    epochKey            // Ethash epoch of the block, lengthened past ECIP-1099
+	target   *big.Int            // Block boundary, 2^256/difficulty
+	shares   map[uint64]struct{} // Nonces already submitted, for duplicate detection
+}
+
+// addShare records a submitted nonce, returning whether it was already
+// submitted, or whether the job tracks too many shares to accept it. The lock
+// of the server must be held.
+func (job *stratumJob) addShare(nonce uint64) (dup bool, full bool) {
+	if _, dup := job.shares[nonce]; dup {
+		return true, false
+	}
+	if len(job.shares) >= stratumMaxJobShares {
+		return false, true
+	}
+	job.shares[nonce] = struct{}{}
+	return false, false
+}
+
+// stratumServer is a TCP endpoint speaking EthereumStratum/1.0 and 2.0 which
+// pushes the work packages of the remote sealer to connected miners and feeds
+// their solutions back into it.
+type stratumServer struct {
+	sealer     *remoteSealer
+	listener   net.Listener
+	difficulty float64 // Default share difficulty of new connections
+	log        log.Logger
+
+	lock      sync.Mutex
+	sessions  map[*stratumSession]struct{}
+	nonces    map[uint16]struct{} // Extranonces in use by the live sessions
+	nextNonce uint16
+	nextID    uint64
+	jobs      map[string]*stratumJob
+	current   *stratumJob
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// startStratumServer opens the stratum listener on the given address and starts
+// accepting miner connections.
+func startStratumServer(sealer *remoteSealer, addr string, difficulty float64) (*stratumServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if difficulty <= 0 {
+		difficulty = stratumDefaultDifficulty
+	}
+	s := &stratumServer{
+		sealer:     sealer,
+		listener:   listener,
+		difficulty: difficulty,
+		log:        sealer.ethash.config.Log.New("module", "stratum"),
+		sessions:   make(map[*stratumSession]struct{}),
+		nonces:     make(map[uint16]struct{}),
+		jobs:       make(map[string]*stratumJob),
+		quit:       make(chan struct{}),
+	}
+	s.log.Info("Stratum server started", "addr", listener.Addr(), "difficulty", difficulty)
+
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// close stops accepting new miners and disconnects all existing ones.
+func (s *stratumServer) close() {
+	close(s.quit)
+	s.listener.Close()
+
+	s.lock.Lock()
+	for sess := range s.sessions {
+		sess.conn.Close()
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+	s.log.Info("Stratum server stopped")
+}
+
+// accept is the listener loop handing out incoming connections to sessions.
+func (s *stratumServer) accept() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				continue
+			}
+			s.log.Warn("Stratum listener failed", "err", err)
+			return
+		}
+		sess, err := s.register(conn)
+		if err != nil {
+			s.log.Warn("Rejected stratum connection", "remote", conn.RemoteAddr(), "err", err)
+			conn.Close()
+			continue
+		}
+		s.wg.Add(2)
+		go sess.readLoop()
+		go sess.writeLoop()
+	}
+}
+
+// register assigns a unique extranonce to a new connection and tracks it.
+func (s *stratumServer) register(conn net.Conn) (*stratumSession, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.nonces) > 0xffff {
+		return nil, errors.New("extranonce space exhausted")
+	}
+	for {
+		if _, ok := s.nonces[s.nextNonce]; !ok {
+			break
+		}
+		s.nextNonce++
+	}
+	nonce := s.nextNonce
+	s.nonces[nonce] = struct{}{}
+	s.nextNonce++
+	s.nextID++
+
+	sess := &stratumSession{
+		server:     s,
+		conn:       conn,
+		id:         fmt.Sprintf("%08x", s.nextID),
+		extranonce: make([]byte, stratumExtranonceSize),
+		difficulty: s.difficulty,
+		workers:    make(map[string]string),
+		send:       make(chan interface{}, stratumSendQueue),
+		closed:     make(chan struct{}),
+	}
+	binary.BigEndian.PutUint16(sess.extranonce, nonce)
+	sess.log = s.log.New("remote", conn.RemoteAddr(), "session", sess.id)
+
+	s.sessions[sess] = struct{}{}
+	sess.log.Debug("Stratum miner connected", "extranonce", hexutil.Encode(sess.extranonce))
+	return sess, nil
+}
+
+// unregister releases the resources of a disconnected session.
+func (s *stratumServer) unregister(sess *stratumSession) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.sessions, sess)
+	delete(s.nonces, binary.BigEndian.Uint16(sess.extranonce))
+}
+
+// notify announces a new work package created by the remote sealer to all the
+// connected miners. It is called from the sealer loop, so it must never block.
+func (s *stratumServer) notify(block *types.Block, sealhash common.Hash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// The same work may be pushed twice (e.g. when changing CPU threads), skip it
+	if s.current != nil && s.current.sealhash == sealhash {
+		return
+	}
+	s.nextID++
+	epoch, length := s.sealer.ethash.epoch(block.NumberU64())
+	job := &stratumJob{
+		id:       strconv.FormatUint(s.nextID, 16),
+		sealhash: sealhash,
+		seedhash: common.BytesToHash(seedHash(epoch, length)),
+		number:   block.NumberU64(),
+		epoch:    epochKey{epoch, length},
+		target:   new(big.Int).Div(two256, block.Difficulty()),
+		shares:   make(map[uint64]struct{}),
+	}
+	clean := s.current == nil || s.current.number != job.number
+
+	// Drop the jobs that the remote sealer would consider stale anyway
+	for id, old := range s.jobs {
+		if old.number+staleThreshold <= job.number {
+			delete(s.jobs, id)
+		}
+	}
+	s.jobs[job.id] = job
+	s.current = job
+
+	for sess := range s.sessions {
+		sess.pushJob(job, clean)
+	}
+}
+
+// job returns the announced work package with the given identifier.
+func (s *stratumServer) job(id string) *stratumJob {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.jobs[id]
+}
+
+// currentJob returns the most recently announced work package, if any.
+func (s *stratumServer) currentJob() *stratumJob {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.current
+}
+
+// submitWork forwards a block solution found by a worker to the remote sealer
+// for verification.
+func (s *stratumServer) submitWork(name string, nonce uint64, digest []byte, sealhash common.Hash) error {
+	errc := make(chan error, 1)
+	select {
+	case s.sealer.submitWorkCh <- &mineResult{
+		nonce:     types.EncodeNonce(nonce),
+		mixDigest: common.BytesToHash(digest),
+		hash:      sealhash,
+		worker:    stratumWorkerID(name),
+		name:      name,
+		errc:      errc,
+	}:
+	case <-s.sealer.exitCh:
+		return errEthashStopped
+	case <-s.quit:
+		return errEthashStopped
+	}
+	return <-errc
+}
+
+// submitHashrate forwards the hash rate reported by a stratum worker to the
+// remote sealer, so it is accounted for in the node's total.
+func (s *stratumServer) submitHashrate(name string, rate uint64) {
+	done := make(chan struct{})
+	select {
+	case s.sealer.submitRateCh <- &hashrate{done: done, rate: rate, id: stratumWorkerID(name), name: name}:
+	case <-s.sealer.exitCh:
+		return
+	case <-s.quit:
+		return
+	}
+	<-done
+}
+
+// reportShare records the outcome of a share submitted by a stratum worker.
+// Block solutions are recorded by the remote sealer instead.
+func (s *stratumServer) reportShare(name string, outcome solutionOutcome) {
+	s.sealer.workers.reportSolution(stratumWorkerID(name), name, outcome, false)
+}
+
+// stratumWorkerID returns the identifier the records of a stratum worker are
+// kept under. Workers are identified by name, so records survive reconnects.
+func stratumWorkerID(name string) common.Hash {
+	return crypto.Keccak256Hash([]byte(name))
+}
+
+// stratumSession is a single miner connection.
+type stratumSession struct {
+	server     *stratumServer
+	conn       net.Conn
+	id         string
+	extranonce []byte
+	log        log.Logger
+
+	lock       sync.Mutex
+	proto      string            // Negotiated protocol version
+	subscribed bool              // Whether the miner subscribed to work notifications
+	workers    map[string]string // Authorized workers, mapped from identifier to name
+	difficulty float64           // Requested share difficulty of the connection
+	target     *big.Int          // Share target last announced to the miner
+	epoch      epochKey          // Ethash epoch last announced to the miner (2.0 only)
+	failures   int               // Number of failed requests
+
+	send      chan interface{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// close tears down the connection, unblocking both the read and write loops.
+func (sess *stratumSession) close() {
+	sess.closeOnce.Do(func() {
+		close(sess.closed)
+		sess.conn.Close()
+	})
+}
+
+// write queues a message for delivery, dropping the miner if it can't keep up.
+func (sess *stratumSession) write(msg interface{}) {
+	select {
+	case sess.send <- msg:
+	case <-sess.closed:
+	default:
+		sess.log.Warn("Dropping slow stratum miner")
+		sess.close()
+	}
+}
+
+// writeLoop delivers the queued messages to the miner.
+func (sess *stratumSession) writeLoop() {
+	defer sess.server.wg.Done()
+
+	for {
+		select {
+		case msg := <-sess.send:
+			blob, err := json.Marshal(msg)
+			if err != nil {
+				sess.log.Error("Failed to encode stratum message", "err", err)
+				continue
+			}
+			sess.conn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+			if _, err := sess.conn.Write(append(blob, '\n')); err != nil {
+				sess.log.Debug("Failed to write to stratum miner", "err", err)
+				sess.close()
+				return
+			}
+		case <-sess.closed:
+			return
+		}
+	}
+}
+
+// readLoop reads newline delimited requests from the miner and handles them.
+func (sess *stratumSession) readLoop() {
+	defer func() {
+		sess.close()
+		sess.server.unregister(sess)
+		sess.server.wg.Done()
+		sess.log.Debug("Stratum miner disconnected")
+	}()
+	scanner := bufio.NewScanner(sess.conn)
+	scanner.Buffer(make([]byte, 0, 512), stratumMaxLineSize)
+
+	for {
+		sess.conn.SetReadDeadline(time.Now().Add(stratumIdleTimeout))
+		if !scanner.Scan() {
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req stratumRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			sess.log.Debug("Invalid stratum request", "err", err)
+			return
+		}
+		result, err := sess.handle(&req)
+		if err != nil {
+			sess.lock.Lock()
+			sess.failures++
+			failed := sess.failures
+			sess.lock.Unlock()
+
+			sess.log.Debug("Stratum request failed", "method", req.Method, "err", err)
+			sess.reply(req.ID, nil, err)
+			if failed > stratumMaxErrors {
+				sess.log.Warn("Dropping misbehaving stratum miner", "errors", failed)
+				return
+			}
+		} else if result != nil {
+			sess.reply(req.ID, result, nil)
+		}
+		if req.Method == "mining.bye" {
+			return
+		}
+	}
+}
+
+// reply sends the response to a request, formatting errors the way the
+// negotiated protocol version expects them.
+func (sess *stratumSession) reply(id json.RawMessage, result interface{}, err error) {
+	res := &stratumResponse{ID: id, Result: result}
+	if err != nil {
+		serr, ok := err.(*stratumError)
+		if !ok {
+			serr = &stratumError{errStratumUnknown.code, err.Error()}
+		}
+		if sess.version() == stratumV2Proto {
+			res.Error = map[string]interface{}{"code": serr.code, "message": serr.message}
+		} else {
+			res.Result = false
+			res.Error = []interface{}{serr.code, serr.message, nil}
+		}
+	}
+	sess.write(res)
+}
+
+// version returns the negotiated protocol version of the session.
+func (sess *stratumSession) version() string {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	return sess.proto
+}
+
+// handle dispatches a single request, returning the result to reply with. A
+// nil result and error means the request needs no reply.
+func (sess *stratumSession) handle(req *stratumRequest) (interface{}, error) {
+	switch req.Method {
+	case "mining.hello":
+		return sess.handleHello(req.Params)
+	case "mining.subscribe":
+		return sess.handleSubscribe(req.Params)
+	case "mining.extranonce.subscribe":
+		return true, nil
+	case "mining.authorize":
+		return sess.handleAuthorize(req.ID, req.Params)
+	case "mining.submit":
+		return sess.handleSubmit(req.Params)
+	case "mining.suggest_difficulty":
+		return sess.handleSuggestDifficulty(req.Params)
+	case "mining.hashrate":
+		return sess.handleHashrate(req.Params)
+	case "mining.noop", "mining.bye":
+		return true, nil
+	default:
+		return nil, errStratumBadMethod
+	}
+}
+
+// handleHello negotiates EthereumStratum/2.0.
+func (sess *stratumSession) handleHello(params json.RawMessage) (interface{}, error) {
+	var hello struct {
+		Agent string `json:"agent"`
+		Proto string `json:"proto"`
+	}
+	if err := json.Unmarshal(params, &hello); err != nil {
+		return nil, errStratumBadParams
+	}
+	if hello.Proto != stratumV2Proto {
+		return nil, &stratumError{errStratumBadParams.code, "Unsupported protocol " + hello.Proto}
+	}
+	sess.lock.Lock()
+	sess.proto = stratumV2Proto
+	sess.lock.Unlock()
+
+	sess.log.Debug("Stratum miner greeted", "agent", hello.Agent, "proto", hello.Proto)
+	return map[string]interface{}{
+		"proto":     stratumV2Proto,
+		"encoding":  "plain",
+		"resume":    "0",
+		"timeout":   strconv.FormatUint(uint64(stratumIdleTimeout/time.Second), 16),
+		"maxerrors": strconv.FormatUint(stratumMaxErrors, 16),
+		"node":      "Bitnet",
+	}, nil
+}
+
+// handleSubscribe subscribes the miner to work notifications. Miners that did
+// not negotiate EthereumStratum/2.0 before are served EthereumStratum/1.0.
+func (sess *stratumSession) handleSubscribe(params json.RawMessage) (interface{}, error) {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	if sess.proto == stratumV2Proto {
+		sess.subscribed = true
+		return sess.id, nil
+	}
+	var args []interface{}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, errStratumBadParams
+		}
+	}
+	if len(args) > 1 {
+		if proto, ok := args[1].(string); ok && proto != stratumV1Proto {
+			return nil, &stratumError{errStratumBadParams.code, "Unsupported protocol " + proto}
+		}
+	}
+	sess.proto = stratumV1Proto
+	sess.subscribed = true
+
+	return []interface{}{
+		[]string{"mining.notify", sess.id, stratumV1Proto},
+		stratumHex(sess.extranonce),
+	}, nil
+}
+
+// handleAuthorize authorizes a worker on the connection and starts pushing work
+// to it. The reply is sent directly, as it needs to precede the first job.
+func (sess *stratumSession) handleAuthorize(id json.RawMessage, params json.RawMessage) (interface{}, error) {
+	var args []string
+	if err := json.Unmarshal(params, &args); err != nil || len(args) == 0 {
+		return nil, errStratumBadParams
+	}
+	name := args[0]
+	if !stratumWorkerName.MatchString(name) {
+		return nil, errStratumUnauthorized
+	}
+	sess.lock.Lock()
+	if !sess.subscribed {
+		sess.lock.Unlock()
+		return nil, errStratumNotSubscribed
+	}
+	// Workers of 2.0 miners are referenced by a short identifier afterwards
+	var result interface{} = true
+	wid := name
+	if sess.proto == stratumV2Proto {
+		wid = strconv.Itoa(len(sess.workers))
+		for known, wname := range sess.workers {
+			if wname == name {
+				wid = known
+			}
+		}
+		result = wid
+	}
+	sess.workers[wid] = name
+	sess.lock.Unlock()
+
+	sess.log.Debug("Stratum worker authorized", "worker", name)
+	sess.reply(id, result, nil)
+
+	if job := sess.server.currentJob(); job != nil {
+		sess.pushJob(job, true)
+	}
+	return nil, nil
+}
+
+// handleSubmit validates a share submitted by the miner and, if it satisfies
+// the block's difficulty too, hands it to the remote sealer.
+func (sess *stratumSession) handleSubmit(params json.RawMessage) (interface{}, error) {
+	var args []string
+	if err := json.Unmarshal(params, &args); err != nil || len(args) < 3 {
+		return nil, errStratumBadParams
+	}
+	// The parameter order differs between the protocol versions:
+	//   1.0: [worker, job, nonce]
+	//   2.0: [job, nonce, worker]
+	var worker, jobID, nonceHex string
+	if sess.version() == stratumV2Proto {
+		jobID, nonceHex, worker = args[0], args[1], args[2]
+	} else {
+		worker, jobID, nonceHex = args[0], args[1], args[2]
+	}
+	sess.lock.Lock()
+	name, authorized := sess.workers[worker]
+	sess.lock.Unlock()
+	if !authorized {
+		return nil, errStratumUnauthorized
+	}
+	nonce, err := sess.parseNonce(nonceHex)
+	if err != nil {
+		return nil, errStratumBadParams
+	}
+	job := sess.server.job(jobID)
+	if job == nil {
+		sess.server.reportShare(name, solutionStale)
+		return nil, errStratumJobNotFound
+	}
+	sess.server.lock.Lock()
+	dup, full := job.addShare(nonce)
+	sess.server.lock.Unlock()
+	if full {
+		// Too many shares were submitted for the job, the miner needs to wait
+		// for the next one
+		sess.server.reportShare(name, solutionStale)
+		return nil, errStratumJobNotFound
+	}
+	if dup {
+		sess.server.reportShare(name, solutionInvalid)
+		return nil, errStratumDuplicate
+	}
+	// Recompute the PoW and check it against the share and block targets
+	ethash := sess.server.sealer.ethash
+	if ethash.shared != nil {
+		ethash = ethash.shared
+	}
+	digest, result := ethash.hashimoto(job.number, job.sealhash.Bytes(), nonce, true)
+	value := new(big.Int).SetBytes(result)
+
+	if value.Cmp(sess.shareTarget(job)) > 0 {
+		sess.server.reportShare(name, solutionInvalid)
+		return nil, errStratumLowDifficulty
+	}
+	if value.Cmp(job.target) > 0 {
+		sess.log.Trace("Accepted stratum share", "worker", name, "job", job.id, "nonce", nonce)
+		sess.server.reportShare(name, solutionAccepted)
+		return true, nil
+	}
+	if err := sess.server.submitWork(name, nonce, digest, job.sealhash); err != nil {
+		sess.log.Warn("Stratum block solution rejected", "worker", name, "number", job.number, "err", err)
+		return nil, errStratumRejected
+	}
+	sess.log.Info("Stratum block solution accepted", "worker", name, "number", job.number, "sealhash", job.sealhash)
+	return true, nil
+}
+
+// handleSuggestDifficulty changes the share difficulty of the connection. The
+// configured share difficulty is the minimum, so that miners can't make the
+// node verify more shares than intended.
+func (sess *stratumSession) handleSuggestDifficulty(params json.RawMessage) (interface{}, error) {
+	var args []float64
+	if err := json.Unmarshal(params, &args); err != nil || len(args) == 0 || args[0] <= 0 {
+		return nil, errStratumBadParams
+	}
+	if args[0] < sess.server.difficulty {
+		return nil, &stratumError{errStratumBadParams.code, fmt.Sprintf("Difficulty below minimum %g", sess.server.difficulty)}
+	}
+	sess.lock.Lock()
+	sess.difficulty = args[0]
+	sess.lock.Unlock()
+
+	if job := sess.server.currentJob(); job != nil {
+		sess.pushJob(job, false)
+	}
+	return true, nil
+}
+
+// handleHashrate records the hash rate reported by a worker.
+func (sess *stratumSession) handleHashrate(params json.RawMessage) (interface{}, error) {
+	var args []string
+	if err := json.Unmarshal(params, &args); err != nil || len(args) < 2 {
+		return nil, errStratumBadParams
+	}
+	rate, err := strconv.ParseUint(strings.TrimPrefix(args[0], "0x"), 16, 64)
+	if err != nil {
+		return nil, errStratumBadParams
+	}
+	sess.lock.Lock()
+	name, authorized := sess.workers[args[1]]
+	sess.lock.Unlock()
+	if !authorized {
+		return nil, errStratumUnauthorized
+	}
+	sess.server.submitHashrate(name, rate)
+	return true, nil
+}
+
+// parseNonce reconstructs the full 64 bit nonce from a submitted one, which
+// may or may not include the extranonce prefix assigned to the connection.
+func (sess *stratumSession) parseNonce(nonceHex string) (uint64, error) {
+	nonceHex = strings.TrimPrefix(nonceHex, "0x")
+	prefixHex := stratumHex(sess.extranonce)
+
+	switch len(nonceHex) {
+	case 16 - len(prefixHex):
+		nonceHex = prefixHex + nonceHex
+	case 16:
+		if !strings.EqualFold(nonceHex[:len(prefixHex)], prefixHex) {
+			return 0, errors.New("nonce outside of the assigned extranonce range")
+		}
+	default:
+		return 0, errors.New("invalid nonce length")
+	}
+	return strconv.ParseUint(nonceHex, 16, 64)
+}
+
+// shareTarget returns the target shares need to satisfy for a job. It never
+// exceeds the block target, otherwise miners wouldn't find the blocks.
+func (sess *stratumSession) shareTarget(job *stratumJob) *big.Int {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	return sess.shareTargetLocked(job)
+}
+
+func (sess *stratumSession) shareTargetLocked(job *stratumJob) *big.Int {
+	target := stratumTarget(sess.difficulty)
+	if target.Cmp(job.target) < 0 {
+		return job.target
+	}
+	return target
+}
+
+// pushJob announces a job to the miner, preceded by any changes to the share
+// difficulty or the ethash epoch.
+func (sess *stratumSession) pushJob(job *stratumJob, clean bool) {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	if !sess.subscribed || len(sess.workers) == 0 {
+		return
+	}
+	target := sess.shareTargetLocked(job)
+
+	switch sess.proto {
+	case stratumV2Proto:
+		// Miners derive the seed from the epoch, so lengthened epochs are
+		// announced as the etchash variant of the algorithm
+		set := make(map[string]interface{})
+		if sess.target == nil || sess.epoch.length != job.epoch.length {
+			set["algo"] = "ethash"
+			if job.epoch.length != epochLength {
+				set["algo"] = "etchash"
+			}
+		}
+		if sess.target == nil {
+			set["extranonce"] = stratumHex(sess.extranonce)
+		}
+		if sess.target == nil || sess.epoch != job.epoch {
+			set["epoch"] = strconv.FormatUint(job.epoch.epoch, 16)
+		}
+		if sess.target == nil || sess.target.Cmp(target) != 0 {
+			set["target"] = stratumHex(common.BigToHash(target).Bytes())
+		}
+		if len(set) > 0 {
+			sess.write(&stratumNotification{Method: "mining.set", Params: set})
+		}
+		sess.write(&stratumNotification{
+			Method: "mining.notify",
+			Params: []interface{}{job.id, strconv.FormatUint(job.number, 16), stratumHex(job.sealhash.Bytes()), clean},
+		})
+
+	default:
+		if sess.target == nil || sess.target.Cmp(target) != 0 {
+			sess.write(&stratumNotification{Method: "mining.set_difficulty", Params: []float64{stratumDifficulty(target)}})
+		}
+		sess.write(&stratumNotification{
+			Method: "mining.notify",
+			Params: []interface{}{job.id, stratumHex(job.seedhash.Bytes()), stratumHex(job.sealhash.Bytes()), clean},
+		})
+	}
+	sess.target, sess.epoch = target, job.epoch
+}
+
+// stratumTarget converts a stratum share difficulty into a hash target.
+func stratumTarget(difficulty float64) *big.Int {
+	target, _ := new(big.Float).Quo(new(big.Float).SetInt(stratumDiff1Target), big.NewFloat(difficulty)).Int(nil)
+	if target.Cmp(two256) >= 0 {
+		target.Sub(two256, common.Big1)
+	}
+	return target
+}
+
+// stratumDifficulty converts a hash target into a stratum share difficulty.
+func stratumDifficulty(target *big.Int) float64 {
+	difficulty, _ := new(big.Float).Quo(new(big.Float).SetInt(stratumDiff1Target), new(big.Float).SetInt(target)).Float64()
+	return difficulty
+}
+
+// stratumHex encodes a byte slice as hexadecimal without the 0x prefix stratum omits.
+func stratumHex(b []byte) string {
+	return strings.TrimPrefix(hexutil.Encode(b), "0x")
+}
@@ -0,0 +1,313 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package ethash
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// stratumTestClient is a minimal line based stratum miner used in tests.
+type stratumTestClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	id     int
+}
+
+// stratumTestMessage is the union of all the messages a stratum server sends.
+type stratumTestMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+func newStratumTestClient(t *testing.T, ethash *Ethash) *stratumTestClient {
+	if ethash.remote.stratum == nil {
+		t.Fatalf("stratum server not running")
+	}
+	conn, err := net.Dial("tcp", ethash.remote.stratum.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial stratum server: %v", err)
+	}
+	return &stratumTestClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *stratumTestClient) send(method string, params interface{}) {
+	c.id++
+	blob, _ := json.Marshal(map[string]interface{}{"id": c.id, "method": method, "params": params})
+	if _, err := c.conn.Write(append(blob, '\n')); err != nil {
+		c.t.Fatalf("failed to send %s: %v", method, err)
+	}
+}
+
+func (c *stratumTestClient) read() *stratumTestMessage {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatalf("failed to read stratum message: %v", err)
+	}
+	msg := new(stratumTestMessage)
+	if err := json.Unmarshal(line, msg); err != nil {
+		c.t.Fatalf("failed to decode stratum message %q: %v", line, err)
+	}
+	return msg
+}
+
+// call sends a request and waits for its response.
+func (c *stratumTestClient) call(method string, params interface{}) *stratumTestMessage {
+	c.send(method, params)
+	msg := c.read()
+	if msg.Method != "" {
+		c.t.Fatalf("expected %s response, got %s notification", method, msg.Method)
+	}
+	return msg
+}
+
+// expect waits for a notification of the given method.
+func (c *stratumTestClient) expect(method string, params interface{}) {
+	msg := c.read()
+	if msg.Method != method {
+		c.t.Fatalf("notification mismatch: have %q, want %q", msg.Method, method)
+	}
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		c.t.Fatalf("failed to decode %s params: %v", method, err)
+	}
+}
+
+// solveStratum searches for a nonce suffix within the given extranonce range
+// whose PoW value satisfies (or fails, if meets is false) the target.
+func solveStratum(ethash *Ethash, number uint64, sealhash common.Hash, extranonce uint64, target *big.Int, meets bool) string {
+	for suffix := uint64(0); ; suffix++ {
+		nonce := extranonce<<48 | suffix
+		_, result := ethash.hashimoto(number, sealhash.Bytes(), nonce, false)
+		if (new(big.Int).SetBytes(result).Cmp(target) <= 0) == meets {
+			return fmt.Sprintf("%012x", suffix)
+		}
+	}
+}
+
+func newStratumTester(t *testing.T) *Ethash {
+	config := Config{
+		PowMode:           ModeTest,
+		StratumAddr:       "127.0.0.1:0",
+		StratumDifficulty: 1e9, // Way above the block's, so shares get clamped to it
+		Log:               testlog.Logger(t, log.LvlWarn),
+	}
+	ethash := New(config, nil, false)
+	ethash.SetThreads(-1)
+	return ethash
+}
+
+// Tests that an EthereumStratum/1.0 miner receives work and that its block
+// solutions are validated and delivered.
+func TestStratumV1(t *testing.T) {
+	ethash := newStratumTester(t)
+	defer ethash.Close()
+
+	client := newStratumTestClient(t, ethash)
+	defer client.conn.Close()
+
+	// Subscribe and authorize the worker
+	var subscription []json.RawMessage
+	res := client.call("mining.subscribe", []string{"ethminer/0.19.0", stratumV1Proto})
+	if err := json.Unmarshal(res.Result, &subscription); err != nil || len(subscription) != 2 {
+		t.Fatalf("invalid subscription result: %s", res.Result)
+	}
+	var extranonceHex string
+	json.Unmarshal(subscription[1], &extranonceHex)
+	extranonce, err := strconv.ParseUint(extranonceHex, 16, 64)
+	if err != nil || len(extranonceHex) != 2*stratumExtranonceSize {
+		t.Fatalf("invalid extranonce %q", extranonceHex)
+	}
+	if res := client.call("mining.authorize", []string{"0x0000000000000000000000000000000000000001.rig", "x"}); string(res.Result) != "true" {
+		t.Fatalf("authorization failed: %s", res.Error)
+	}
+	if res := client.call("mining.authorize", []string{"bad worker!", "x"}); string(res.Result) == "true" {
+		t.Fatalf("invalid worker name authorized")
+	}
+	// Push some work and ensure it's announced
+	results := make(chan *types.Block, 1)
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(100)}
+	ethash.Seal(nil, types.NewBlockWithHeader(header), results, nil)
+
+	var difficulty []float64
+	client.expect("mining.set_difficulty", &difficulty)
+	target := new(big.Int).Div(two256, header.Difficulty)
+	if want := stratumDifficulty(target); len(difficulty) != 1 || difficulty[0] != want {
+		t.Fatalf("share difficulty mismatch: have %v, want %v", difficulty, want)
+	}
+	var notify []interface{}
+	client.expect("mining.notify", &notify)
+	sealhash := ethash.SealHash(header)
+	if len(notify) != 4 || notify[2] != stratumHex(sealhash.Bytes()) || notify[1] != stratumHex(SeedHash(1, nil)) || notify[3] != true {
+		t.Fatalf("invalid job notification: %v", notify)
+	}
+	job := notify[0].(string)
+	worker := "0x0000000000000000000000000000000000000001.rig"
+
+	// Submit invalid shares and ensure they are rejected
+	if res := client.call("mining.submit", []string{worker, job, solveStratum(ethash, 1, sealhash, extranonce, target, false)}); string(res.Result) == "true" {
+		t.Fatalf("low difficulty share accepted")
+	}
+	if res := client.call("mining.submit", []string{worker, "ffff", "000000000000"}); string(res.Result) == "true" {
+		t.Fatalf("share for unknown job accepted")
+	}
+	if res := client.call("mining.submit", []string{"unknown", job, "000000000000"}); string(res.Result) == "true" {
+		t.Fatalf("share from unauthorized worker accepted")
+	}
+	// Submit a valid solution and ensure it's sealed
+	nonce := solveStratum(ethash, 1, sealhash, extranonce, target, true)
+	if res := client.call("mining.submit", []string{worker, job, nonce}); string(res.Result) != "true" {
+		t.Fatalf("valid solution rejected: %s", res.Error)
+	}
+	select {
+	case block := <-results:
+		if want := extranonceHex + nonce; fmt.Sprintf("%016x", block.Nonce()) != want {
+			t.Errorf("sealed nonce mismatch: have %016x, want %s", block.Nonce(), want)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("sealed block timed out")
+	}
+	if res := client.call("mining.submit", []string{worker, job, nonce}); string(res.Result) == "true" {
+		t.Fatalf("duplicate share accepted")
+	}
+	// Ensure the submissions were recorded for the worker
+	workers, err := (&API{ethash}).GetWorkers()
+	if err != nil {
+		t.Fatalf("failed to retrieve workers: %v", err)
+	}
+	want := WorkerStats{ID: stratumWorkerID(worker), Name: worker, Submitted: 4, Accepted: 1, Stale: 1, Invalid: 2, Blocks: 1}
+	if len(workers) != 1 {
+		t.Fatalf("worker count mismatch: have %d, want 1", len(workers))
+	}
+	workers[0].LastSeen = time.Time{}
+	if workers[0] != want {
+		t.Errorf("worker stats mismatch: have %+v, want %+v", workers[0], want)
+	}
+}
+
+// Tests that an EthereumStratum/2.0 miner receives work and that its block
+// solutions are validated and delivered.
+func TestStratumV2(t *testing.T) {
+	ethash := newStratumTester(t)
+	defer ethash.Close()
+
+	// Push some work before the miner connects
+	results := make(chan *types.Block, 1)
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(100)}
+	ethash.Seal(nil, types.NewBlockWithHeader(header), results, nil)
+
+	client := newStratumTestClient(t, ethash)
+	defer client.conn.Close()
+
+	res := client.call("mining.hello", map[string]string{"agent": "ethminer/0.19.0", "host": "localhost", "port": "0", "proto": stratumV2Proto})
+	if res.Error != nil && string(res.Error) != "null" {
+		t.Fatalf("hello failed: %s", res.Error)
+	}
+	if res := client.call("mining.subscribe", []string{}); res.Error != nil && string(res.Error) != "null" {
+		t.Fatalf("subscription failed: %s", res.Error)
+	}
+	var worker string
+	if err := json.Unmarshal(client.call("mining.authorize", []string{"rig", "x"}).Result, &worker); err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	// The pending work should be announced right after authorization
+	var set map[string]string
+	client.expect("mining.set", &set)
+	target := new(big.Int).Div(two256, header.Difficulty)
+	if set["epoch"] != "0" || set["algo"] != "ethash" || set["target"] != stratumHex(common.BigToHash(target).Bytes()) {
+		t.Fatalf("invalid mining parameters: %v", set)
+	}
+	extranonce, err := strconv.ParseUint(set["extranonce"], 16, 64)
+	if err != nil {
+		t.Fatalf("invalid extranonce %q", set["extranonce"])
+	}
+	var notify []interface{}
+	client.expect("mining.notify", &notify)
+	sealhash := ethash.SealHash(header)
+	if len(notify) != 4 || notify[1] != "1" || notify[2] != stratumHex(sealhash.Bytes()) {
+		t.Fatalf("invalid job notification: %v", notify)
+	}
+	nonce := solveStratum(ethash, 1, sealhash, extranonce, target, true)
+	if res := client.call("mining.submit", []string{notify[0].(string), nonce, worker}); string(res.Result) != "true" {
+		t.Fatalf("valid solution rejected: %s", res.Error)
+	}
+	select {
+	case <-results:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("sealed block timed out")
+	}
+}
+
+// Tests that miners can't suggest a share difficulty below the configured one.
+func TestStratumSuggestDifficulty(t *testing.T) {
+	ethash := newStratumTester(t)
+	defer ethash.Close()
+
+	client := newStratumTestClient(t, ethash)
+	defer client.conn.Close()
+
+	client.call("mining.subscribe", []string{"ethminer/0.19.0", stratumV1Proto})
+	if res := client.call("mining.suggest_difficulty", []float64{1}); string(res.Result) == "true" {
+		t.Fatalf("difficulty below the minimum accepted")
+	}
+	if res := client.call("mining.suggest_difficulty", []float64{2e9}); string(res.Result) != "true" {
+		t.Fatalf("difficulty above the minimum rejected: %s", res.Error)
+	}
+	// Ensure the rejected suggestion didn't leak into the session
+	ethash.remote.stratum.lock.Lock()
+	defer ethash.remote.stratum.lock.Unlock()
+
+	for sess := range ethash.remote.stratum.sessions {
+		sess.lock.Lock()
+		difficulty := sess.difficulty
+		sess.lock.Unlock()
+
+		if difficulty != 2e9 {
+			t.Errorf("session difficulty mismatch: have %v, want %v", difficulty, 2e9)
+		}
+	}
+}
+
+// Tests that the shares tracked for duplicate detection are capped per job.
+func TestStratumJobShares(t *testing.T) {
+	job := &stratumJob{shares: make(map[uint64]struct{})}
+	for nonce := uint64(0); nonce < stratumMaxJobShares; nonce++ {
+		if dup, full := job.addShare(nonce); dup || full {
+			t.Fatalf("share %d: unexpected result: dup %v, full %v", nonce, dup, full)
+		}
+	}
+	if dup, full := job.addShare(0); !dup || full {
+		t.Errorf("duplicate share: unexpected result: dup %v, full %v", dup, full)
+	}
+	if dup, full := job.addShare(stratumMaxJobShares); dup || !full {
+		t.Errorf("share over the cap: unexpected result: dup %v, full %v", dup, full)
+	}
+	if len(job.shares) != stratumMaxJobShares {
+		t.Errorf("tracked share count mismatch: have %d, want %d", len(job.shares), stratumMaxJobShares)
+	}
+}
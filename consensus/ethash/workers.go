@@ -0,0 +1,234 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package ethash
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// hashrateExpiry is the time after which the hash rate reported by a remote
+	// worker is no longer accounted for in the total hash rate.
+	hashrateExpiry = 10 * time.Second
+
+	// workerExpiry is the time after which an inactive remote worker is forgotten.
+	workerExpiry = time.Hour
+
+	// maxRemoteWorkers is the number of remote workers tracked, beyond which
+	// the least recently seen ones are forgotten.
+	maxRemoteWorkers = 1024
+)
+
+// metricsUnsafeChars matches the characters of worker names not allowed in
+// metric names.
+var metricsUnsafeChars = regexp.MustCompile(`[^0-9A-Za-z_]`)
+
+// solutionOutcome is the verdict on a solution submitted by a remote worker.
+type solutionOutcome int
+
+const (
+	solutionAccepted solutionOutcome = iota // Valid share or block solution
+	solutionStale                           // Solution for work that is no longer pending
+	solutionInvalid                         // Solution not meeting its target, or submitted twice
+	solutionDropped                         // Valid solution the node could not make use of
+)
+
+// WorkerStats is the mining record of a remote worker.
+type WorkerStats struct {
+	ID        common.Hash    `json:"id"`             // Identifier the worker reports its hash rate with
+	Name      string         `json:"name,omitempty"` // Name the worker authorized with (stratum only)
+	Hashrate  hexutil.Uint64 `json:"hashrate"`       // Hash rate reported by the worker, zero if not recently
+	LastSeen  time.Time      `json:"lastSeen"`       // Time of the last report or submission
+	Submitted hexutil.Uint64 `json:"submitted"`      // Number of shares and block solutions submitted
+	Accepted  hexutil.Uint64 `json:"accepted"`       // Number of submissions accepted
+	Stale     hexutil.Uint64 `json:"stale"`          // Number of submissions for outdated work
+	Invalid   hexutil.Uint64 `json:"invalid"`        // Number of submissions failing verification
+	Blocks    hexutil.Uint64 `json:"blocks"`         // Number of blocks found
+}
+
+// remoteWorker is the mining record of a remote worker, along with the metrics
+// it is exported through.
+type remoteWorker struct {
+	stats WorkerStats
+	rated time.Time // Time the hash rate was last reported
+
+	prefix    string
+	hashrate  metrics.Gauge
+	submitted metrics.Counter
+	accepted  metrics.Counter
+	stale     metrics.Counter
+	invalid   metrics.Counter
+	blocks    metrics.Counter
+}
+
+// newRemoteWorker creates the record of a new remote worker and registers its
+// metrics under the prefix of its identifier, preceded by its name if it has
+// one. The identifier keeps names sanitized alike from sharing metrics.
+func newRemoteWorker(id common.Hash, name string) *remoteWorker {
+	label := fmt.Sprintf("%x", id[:8])
+	if name != "" {
+		label = metricsUnsafeChars.ReplaceAllString(name, "_") + "_" + fmt.Sprintf("%x", id[:4])
+	}
+	prefix := "ethash/workers/" + label + "/"
+	return &remoteWorker{
+		stats:     WorkerStats{ID: id, Name: name},
+		prefix:    prefix,
+		hashrate:  metrics.GetOrRegisterGauge(prefix+"hashrate", nil),
+		submitted: metrics.GetOrRegisterCounter(prefix+"submitted", nil),
+		accepted:  metrics.GetOrRegisterCounter(prefix+"accepted", nil),
+		stale:     metrics.GetOrRegisterCounter(prefix+"stale", nil),
+		invalid:   metrics.GetOrRegisterCounter(prefix+"invalid", nil),
+		blocks:    metrics.GetOrRegisterCounter(prefix+"blocks", nil),
+	}
+}
+
+// unregister removes the metrics of the worker.
+func (w *remoteWorker) unregister() {
+	for _, name := range []string{"hashrate", "submitted", "accepted", "stale", "invalid", "blocks"} {
+		metrics.DefaultRegistry.Unregister(w.prefix + name)
+	}
+}
+
+// workerSet tracks the remote workers mining through the remote sealer, either
+// over getwork or stratum.
+type workerSet struct {
+	workers map[common.Hash]*remoteWorker
+	lock    sync.Mutex
+}
+
+// newWorkerSet creates an empty set of remote workers.
+func newWorkerSet() *workerSet {
+	return &workerSet{
+		workers: make(map[common.Hash]*remoteWorker),
+	}
+}
+
+// worker retrieves the record of a remote worker, creating it if it's not yet
+// known, in which case the least recently seen worker is forgotten if too many
+// are tracked. The lock must be held.
+func (set *workerSet) worker(id common.Hash, name string) *remoteWorker {
+	w := set.workers[id]
+	if w == nil {
+		if len(set.workers) >= maxRemoteWorkers {
+			var oldest *remoteWorker
+			for _, candidate := range set.workers {
+				if oldest == nil || candidate.stats.LastSeen.Before(oldest.stats.LastSeen) {
+					oldest = candidate
+				}
+			}
+			oldest.unregister()
+			delete(set.workers, oldest.stats.ID)
+		}
+		w = newRemoteWorker(id, name)
+		set.workers[id] = w
+	}
+	w.stats.LastSeen = time.Now()
+	return w
+}
+
+// reportHashrate records the hash rate reported by a remote worker.
+func (set *workerSet) reportHashrate(id common.Hash, name string, rate uint64) {
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	w := set.worker(id, name)
+	w.stats.Hashrate, w.rated = hexutil.Uint64(rate), w.stats.LastSeen
+	w.hashrate.Update(int64(rate))
+}
+
+// reportSolution records the outcome of a share or block solution submitted by
+// a remote worker.
+func (set *workerSet) reportSolution(id common.Hash, name string, outcome solutionOutcome, block bool) {
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	w := set.worker(id, name)
+	w.stats.Submitted++
+	w.submitted.Inc(1)
+
+	switch outcome {
+	case solutionAccepted:
+		w.stats.Accepted++
+		w.accepted.Inc(1)
+		if block {
+			w.stats.Blocks++
+			w.blocks.Inc(1)
+		}
+	case solutionStale:
+		w.stats.Stale++
+		w.stale.Inc(1)
+	case solutionInvalid:
+		w.stats.Invalid++
+		w.invalid.Inc(1)
+	}
+}
+
+// hashrate returns the sum of the hash rates recently reported by the workers.
+func (set *workerSet) hashrate() uint64 {
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	var total uint64
+	for _, w := range set.workers {
+		if time.Since(w.rated) <= hashrateExpiry {
+			total += uint64(w.stats.Hashrate) // this could overflow
+		}
+	}
+	return total
+}
+
+// expire forgets the workers that have been inactive for too long, and stops
+// reporting the hash rate of the ones not reporting it anymore.
+func (set *workerSet) expire() {
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	for id, w := range set.workers {
+		if time.Since(w.stats.LastSeen) > workerExpiry {
+			w.unregister()
+			delete(set.workers, id)
+			continue
+		}
+		if w.stats.Hashrate != 0 && time.Since(w.rated) > hashrateExpiry {
+			w.stats.Hashrate = 0
+			w.hashrate.Update(0)
+		}
+	}
+}
+
+// stats returns the records of all the known workers, ordered by name and id.
+func (set *workerSet) stats() []WorkerStats {
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	stats := make([]WorkerStats, 0, len(set.workers))
+	for _, w := range set.workers {
+		stats = append(stats, w.stats)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Name != stats[j].Name {
+			return stats[i].Name < stats[j].Name
+		}
+		return bytes.Compare(stats[i].ID[:], stats[j].ID[:]) < 0
+	})
+	return stats
+}
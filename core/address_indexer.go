@@ -0,0 +1,279 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// addressThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	addressThrottling = 10 * time.Millisecond
+)
+
+// AddressTracer returns the addresses called internally by each transaction of
+// a block.
+type AddressTracer func(ctx context.Context, block *types.Block) ([][]common.Address, error)
+
+// AddressIndexer implements a core.ChainIndexer, indexing the transactions of
+// the canonical chain by the addresses taking part in them.
+type AddressIndexer struct {
+	size   uint64              // section size to index the addresses of
+	tail   uint64              // number of recent blocks to keep indexed, zero meaning all
+	db     ethdb.Database      // database instance to write index data and metadata into
+	config *params.ChainConfig // chain configuration to recover the transaction senders with
+	tracer AddressTracer       // tracer collecting the internal calls, nil if not indexed
+
+	section uint64      // Section is the section number being processed currently
+	skip    bool        // Whether the section is below the tail and left unindexed
+	missed  int         // Number of blocks of the section indexed without internal calls
+	batch   ethdb.Batch // Batch collecting the index data of the section
+}
+
+// NewAddressIndexer returns a chain indexer that indexes the transactions of the
+// canonical chain by the addresses sending, receiving or creating them, as well
+// as the addresses called internally if a tracer is given. Blocks older than
+// the tail are unindexed, unless it is zero.
+func NewAddressIndexer(db ethdb.Database, config *params.ChainConfig, size, confirms, tail uint64, tracer AddressTracer) *ChainIndexer {
+	backend := &AddressIndexer{
+		size:   size,
+		tail:   tail,
+		db:     db,
+		config: config,
+		tracer: tracer,
+	}
+	table := rawdb.NewTable(db, string(rawdb.AddressIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, addressThrottling, "addresses")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new address index
+// section. Sections entirely below the tail of the current chain are skipped.
+func (b *AddressIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.section, b.skip, b.missed = section, false, 0
+	b.batch = b.db.NewBatch()
+
+	if b.tail > 0 {
+		if head := rawdb.ReadHeaderNumber(b.db, rawdb.ReadHeadHeaderHash(b.db)); head != nil {
+			b.skip = (section+1)*b.size+b.tail <= *head
+		}
+	}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, replacing the index entries of
+// a block with the ones of the new header.
+func (b *AddressIndexer) Process(ctx context.Context, header *types.Header) error {
+	if b.skip {
+		return nil
+	}
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+	)
+	// Drop the entries of the block replaced by a reorg, if any
+	if entries := rawdb.ReadAddressIndexEntries(b.db, number); entries != nil {
+		rawdb.DeleteAddressIndexEntries(b.batch, number, entries)
+	}
+	body := rawdb.ReadBody(b.db, hash, number)
+	if body == nil {
+		return fmt.Errorf("block #%d [%x..] body not found", number, hash[:4])
+	}
+	if len(body.Transactions) == 0 {
+		return nil
+	}
+	block := types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles)
+
+	var internal [][]common.Address
+	if b.tracer != nil {
+		calls, err := b.tracer(ctx, block)
+		if err != nil {
+			log.Debug("Failed to trace internal calls", "number", number, "hash", hash, "err", err)
+			b.missed++
+		}
+		internal = calls
+	}
+	entries, err := AddressIndexEntries(b.db, b.config, block, internal)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteAddressIndexEntries(b.batch, number, entries)
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the index data of
+// the section and unindexing the blocks which fell below the tail.
+func (b *AddressIndexer) Commit() error {
+	if b.missed > 0 {
+		log.Warn("Indexed blocks without internal calls", "section", b.section, "blocks", b.missed)
+	}
+	if b.tail > 0 && (b.section+1)*b.size > b.tail {
+		var (
+			limit = (b.section+1)*b.size - b.tail
+			from  uint64
+		)
+		if tail := rawdb.ReadAddressIndexTail(b.db); tail != nil {
+			from = *tail
+		}
+		if from < limit {
+			for number := from; number < limit; number++ {
+				if entries := rawdb.ReadAddressIndexEntries(b.db, number); entries != nil {
+					rawdb.DeleteAddressIndexEntries(b.batch, number, entries)
+				}
+			}
+			rawdb.WriteAddressIndexTail(b.batch, limit)
+		}
+	}
+	return b.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *AddressIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// AddressIndexEntries returns the address index entries of a block, recording
+// the senders, recipients and created contracts of its transactions, along with
+// the addresses they called internally, if given.
+func AddressIndexEntries(db ethdb.Reader, config *params.ChainConfig, block *types.Block, internal [][]common.Address) ([]rawdb.AddressIndexEntry, error) {
+	var (
+		signer   = types.MakeSigner(config, block.Number())
+		receipts = rawdb.ReadRawReceipts(db, block.Hash(), block.NumberU64())
+		entries  []rawdb.AddressIndexEntry
+	)
+	for i, tx := range block.Transactions() {
+		roles := make(map[common.Address]uint8)
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x: %v", tx.Hash(), err)
+		}
+		roles[from] |= rawdb.AddressRoleSender
+		if to := tx.To(); to != nil {
+			roles[*to] |= rawdb.AddressRoleRecipient
+		} else if i >= len(receipts) || receipts[i].Status == types.ReceiptStatusSuccessful {
+			roles[crypto.CreateAddress(from, tx.Nonce())] |= rawdb.AddressRoleCreation
+		}
+		if i < len(internal) {
+			for _, addr := range internal[i] {
+				roles[addr] |= rawdb.AddressRoleInternal
+			}
+		}
+		start := len(entries)
+		for addr, role := range roles {
+			entries = append(entries, rawdb.AddressIndexEntry{Address: addr, Index: uint32(i), Roles: role})
+		}
+		// Keep the entries deterministic, the map order is random
+		sortAddressEntries(entries[start:])
+	}
+	return entries, nil
+}
+
+// sortAddressEntries sorts the entries of a transaction by address.
+func sortAddressEntries(entries []rawdb.AddressIndexEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Address[:], entries[j].Address[:]) < 0
+	})
+}
+
+// NewInternalCallTracer returns an AddressTracer re-executing the transactions
+// of blocks on top of the states of their parents, as returned by stateAt.
+func NewInternalCallTracer(chain *BlockChain, stateAt func(ctx context.Context, parent *types.Block) (*state.StateDB, func(), error)) AddressTracer {
+	return func(ctx context.Context, block *types.Block) ([][]common.Address, error) {
+		parent := chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+		if parent == nil {
+			return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+		}
+		statedb, release, err := stateAt(ctx, parent)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		var (
+			config    = chain.Config()
+			signer    = types.MakeSigner(config, block.Number())
+			context   = NewEVMBlockContext(block.Header(), chain, nil)
+			rules     = config.Rules(block.Number(), context.Random != nil, block.Time())
+			collector = newCallCollector(vm.ActivePrecompiles(rules))
+			vmenv     = vm.NewEVM(context, vm.TxContext{}, statedb, config, vm.Config{Tracer: collector})
+			calls     = make([][]common.Address, len(block.Transactions()))
+		)
+		for i, tx := range block.Transactions() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			msg, err := TransactionToMessage(tx, signer, block.BaseFee())
+			if err != nil {
+				return nil, fmt.Errorf("transaction %#x: %v", tx.Hash(), err)
+			}
+			vmenv.Reset(NewEVMTxContext(msg), statedb)
+			statedb.SetTxContext(tx.Hash(), i)
+			collector.calls = nil
+			if _, err := ApplyMessage(vmenv, msg, new(GasPool).AddGas(msg.GasLimit)); err != nil {
+				return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+			}
+			// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+			statedb.Finalise(config.IsEIP158(block.Number()))
+			calls[i] = collector.calls
+		}
+		return calls, nil
+	}
+}
+
+// callCollector is an EVM logger collecting the addresses of the internal call
+// frames of a transaction, precompiles excepted.
+type callCollector struct {
+	precompiles map[common.Address]struct{}
+	calls       []common.Address
+}
+
+func newCallCollector(precompiles []common.Address) *callCollector {
+	c := &callCollector{precompiles: make(map[common.Address]struct{})}
+	for _, addr := range precompiles {
+		c.precompiles[addr] = struct{}{}
+	}
+	return c
+}
+
+func (c *callCollector) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if _, ok := c.precompiles[to]; !ok {
+		c.calls = append(c.calls, to)
+	}
+}
+
+func (c *callCollector) CaptureTxStart(gasLimit uint64) {}
+func (c *callCollector) CaptureTxEnd(restGas uint64)    {}
+func (c *callCollector) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+func (c *callCollector) CaptureEnd(output []byte, gasUsed uint64, err error)  {}
+func (c *callCollector) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (c *callCollector) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+func (c *callCollector) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the address indexer indexes the senders, recipients, created
+// contracts and internal calls of the transactions, and unindexes the blocks
+// falling below its tail.
+func TestAddressIndexer(t *testing.T) {
+	var (
+		aa     = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		bb     = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		engine = ethash.NewFaker()
+
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer   = types.LatestSigner(gspec.Config)
+		contract = crypto.CreateAddress(addr, 1)
+	)
+	// Init code calling aa, then the identity precompile
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH20)}
+	code = append(code, aa.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL))
+	code = append(code, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 4)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 8, func(i int, b *BlockGen) {
+		switch i {
+		case 0:
+			b.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: b.TxNonce(addr), To: &aa, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: b.header.BaseFee}))
+		case 1:
+			b.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: b.TxNonce(addr), Gas: 200000, GasPrice: b.header.BaseFee, Data: code}))
+		case 4:
+			b.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: b.TxNonce(addr), To: &bb, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: b.header.BaseFee}))
+			b.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: b.TxNonce(addr), To: &addr, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: b.header.BaseFee}))
+		}
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := NewBlockChain(db, nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	tracer := NewInternalCallTracer(chain, func(ctx context.Context, parent *types.Block) (*state.StateDB, func(), error) {
+		statedb, err := chain.StateAt(parent.Root())
+		return statedb, func() {}, err
+	})
+	// index processes the sections of the chain with the given indexer.
+	index := func(indexer *AddressIndexer, sections ...uint64) {
+		for _, section := range sections {
+			if err := indexer.Reset(context.Background(), section, common.Hash{}); err != nil {
+				t.Fatalf("section %d: failed to reset: %v", section, err)
+			}
+			for number := section * indexer.size; number < (section+1)*indexer.size; number++ {
+				if err := indexer.Process(context.Background(), chain.GetHeaderByNumber(number)); err != nil {
+					t.Fatalf("block %d: failed to process: %v", number, err)
+				}
+			}
+			if err := indexer.Commit(); err != nil {
+				t.Fatalf("section %d: failed to commit: %v", section, err)
+			}
+		}
+	}
+	type entry struct {
+		number uint64
+		index  uint32
+		roles  uint8
+	}
+	// check verifies the indexed transactions of the addresses, newest first.
+	check := func(want map[common.Address][]entry) {
+		t.Helper()
+		for address, entries := range want {
+			var have []entry
+			rawdb.IterateAddressTxs(db, address, 8, ^uint32(0), func(number uint64, index uint32, roles uint8) bool {
+				have = append(have, entry{number, index, roles})
+				return true
+			})
+			if !reflect.DeepEqual(have, entries) {
+				t.Errorf("address %x: transactions mismatch: have %v, want %v", address, have, entries)
+			}
+		}
+	}
+	indexer := &AddressIndexer{size: 4, db: db, config: chain.Config(), tracer: tracer}
+	index(indexer, 0, 1, 0) // Processing a section again must not duplicate entries
+
+	var (
+		sender    = rawdb.AddressRoleSender
+		recipient = rawdb.AddressRoleRecipient
+		creation  = rawdb.AddressRoleCreation
+		internal  = rawdb.AddressRoleInternal
+	)
+	check(map[common.Address][]entry{
+		addr:                             {{5, 1, sender | recipient}, {5, 0, sender}, {2, 0, sender}, {1, 0, sender}},
+		aa:                               {{2, 0, internal}, {1, 0, recipient}},
+		bb:                               {{5, 0, recipient}},
+		contract:                         {{2, 0, creation}},
+		common.BytesToAddress([]byte{4}): nil,
+	})
+	// Without tracer, the internal calls are left out
+	index(&AddressIndexer{size: 4, db: db, config: chain.Config()}, 0)
+	check(map[common.Address][]entry{
+		aa: {{1, 0, recipient}},
+	})
+	// Blocks falling below the tail are unindexed, and not indexed again
+	tailed := &AddressIndexer{size: 4, tail: 4, db: db, config: chain.Config(), tracer: tracer}
+	index(tailed, 1, 0)
+
+	check(map[common.Address][]entry{
+		addr:     {{5, 1, sender | recipient}, {5, 0, sender}},
+		aa:       nil,
+		contract: nil,
+	})
+	if tail := rawdb.ReadAddressIndexTail(db); tail == nil || *tail != 4 {
+		t.Errorf("index tail mismatch: have %v, want 4", tail)
+	}
+}
@@ -132,6 +132,8 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	StateScheme         string        // Scheme used to store the state trie nodes, hash-based by default
+	StateHistory        uint64        // Number of recent blocks whose state can be rolled back to with the path-based scheme, 0 for all
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
@@ -230,11 +232,15 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		cacheConfig = defaultCacheConfig
 	}
 	// Open trie database with provided config
-	triedb := trie.NewDatabaseWithConfig(db, &trie.Config{
+	trieConfig := &trie.Config{
 		Cache:     cacheConfig.TrieCleanLimit,
 		Journal:   cacheConfig.TrieCleanJournal,
 		Preimages: cacheConfig.Preimages,
-	})
+	}
+	if cacheConfig.StateScheme == rawdb.PathScheme {
+		trieConfig.PathDB = &trie.PathConfig{StateHistory: cacheConfig.StateHistory}
+	}
+	triedb := trie.NewDatabaseWithConfig(db, trieConfig)
 	// Setup the genesis block, commit the provided genesis specification
 	// to database if the genesis block is not present yet, or load the
 	// stored one from database.
@@ -594,6 +600,18 @@ func (bc *BlockChain) SetSafe(header *types.Header) {
 	}
 }
 
+// SetFinalityPolicy sets the finality policy guarding the proof-of-work fork
+// choice against deep reorgs.
+func (bc *BlockChain) SetFinalityPolicy(config FinalityConfig) {
+	bc.forker.SetFinalityPolicy(config)
+}
+
+// FinalityPolicy returns the finality policy of the fork choice, along with the
+// statistics of the reorgs it refused.
+func (bc *BlockChain) FinalityPolicy() FinalityStatus {
+	return bc.forker.FinalityPolicy()
+}
+
 // setHeadBeyondRoot rewinds the local chain to a new head with the extra condition
 // that the rewind must pass the specified state root. This method is meant to be
 // used when rewinding with snapshots enabled to ensure that we go back further than
@@ -640,7 +658,9 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 					if root != (common.Hash{}) && !beyondRoot && newHeadBlock.Root() == root {
 						beyondRoot, rootNumber = true, newHeadBlock.NumberU64()
 					}
-					if !bc.HasState(newHeadBlock.Root()) {
+					// With the path-based scheme, the persistent state can be rolled
+					// back to the states covered by the state history.
+					if !bc.HasState(newHeadBlock.Root()) && !bc.triedb.Recoverable(newHeadBlock.Root()) {
 						log.Trace("Block state missing, rewinding further", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
 						if pivot == nil || newHeadBlock.NumberU64() > *pivot {
 							parent := bc.GetBlock(newHeadBlock.ParentHash(), newHeadBlock.NumberU64()-1)
@@ -662,13 +682,18 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 							// rewinding destination can be the earliest block stored in the chain
 							// if the historical chain pruning is enabled. In that case the logic
 							// needs to be improved here.
-							if !bc.HasState(bc.genesisBlock.Root()) {
+							if !bc.HasState(bc.genesisBlock.Root()) && !bc.triedb.Recoverable(bc.genesisBlock.Root()) {
 								if err := CommitGenesisState(bc.db, bc.triedb, bc.genesisBlock.Hash()); err != nil {
 									log.Crit("Failed to commit genesis state", "err", err)
 								}
 								log.Debug("Recommitted genesis state to disk")
 							}
 						}
+						if !bc.HasState(newHeadBlock.Root()) {
+							if err := bc.triedb.Recover(newHeadBlock.Root()); err != nil {
+								log.Crit("Failed to recover state", "number", newHeadBlock.NumberU64(), "root", newHeadBlock.Root(), "err", err)
+							}
+						}
 						log.Debug("Rewound to block with state", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
 						break
 					}
@@ -733,6 +758,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 			rawdb.DeleteBody(db, hash, num)
 			rawdb.DeleteReceipts(db, hash, num)
 		}
+		rawdb.DeleteIssuance(db, hash, num)
 		// Todo(rjl493456442) txlookup, bloombits, etc
 	}
 	// If SetHead was only called as a chain reparation method, try to skip
@@ -781,6 +807,11 @@ func (bc *BlockChain) SnapSyncCommitHead(hash common.Hash) error {
 		return fmt.Errorf("non existent block [%x..]", hash[:4])
 	}
 	root := block.Root()
+	// The path-based scheme is disabled during state sync, reopen it on top
+	// of the synced state.
+	if err := bc.triedb.Enable(root); err != nil {
+		return err
+	}
 	if !bc.HasState(root) {
 		return fmt.Errorf("non existent state [%x..]", root[:4])
 	}
@@ -951,7 +982,17 @@ func (bc *BlockChain) Stop() {
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
 	//  - HEAD-1:   So we don't do large reorgs if our HEAD becomes an uncle
 	//  - HEAD-127: So we have a hard limit on the number of blocks reexecuted
-	if !bc.cacheConfig.TrieDirtyDisabled {
+	//
+	// The path-based scheme persists a single state, rolled back with the state
+	// history if needed, so only HEAD is written.
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		if head := bc.CurrentBlock(); bc.HasState(head.Root) {
+			log.Info("Writing cached state to disk", "block", head.Number, "hash", head.Hash(), "root", head.Root)
+			if err := bc.triedb.Commit(head.Root, true); err != nil {
+				log.Error("Failed to commit recent state trie", "err", err)
+			}
+		}
+	} else if !bc.cacheConfig.TrieDirtyDisabled {
 		triedb := bc.triedb
 
 		for _, offset := range []uint64{0, 1, TriesInMemory - 1} {
@@ -986,6 +1027,9 @@ func (bc *BlockChain) Stop() {
 	if bc.cacheConfig.TrieCleanJournal != "" {
 		bc.triedb.SaveCache(bc.cacheConfig.TrieCleanJournal)
 	}
+	if err := bc.triedb.Close(); err != nil {
+		log.Error("Failed to close trie database", "err", err)
+	}
 	log.Info("Blockchain stopped")
 }
 
@@ -1137,6 +1181,7 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		// range. In this case, all tx indices of newly imported blocks should be
 		// generated.
 		var batch = bc.db.NewBatch()
+		bc.writeIssuanceChain(batch, blockChain)
 		for i, block := range blockChain {
 			if bc.txLookupLimit == 0 || ancientLimit <= bc.txLookupLimit || block.NumberU64() >= ancientLimit-bc.txLookupLimit {
 				rawdb.WriteTxLookupEntriesByBlock(batch, block)
@@ -1238,6 +1283,8 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 			}
 			stats.processed++
 		}
+		bc.writeIssuanceChain(batch, blockChain)
+
 		// Write everything belongs to the blocks into the database. So that
 		// we can ensure all components of body is completed(body, receipts,
 		// tx indexes)
@@ -1346,6 +1393,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	rawdb.WriteBlock(blockBatch, block)
 	rawdb.WriteReceipts(blockBatch, block.Hash(), block.NumberU64(), receipts)
 	rawdb.WritePreimages(blockBatch, state.Preimages())
+	bc.writeIssuance(blockBatch, block)
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
@@ -1354,6 +1402,10 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	if err != nil {
 		return err
 	}
+	// The path-based scheme manages the in-memory states itself
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		return nil
+	}
 	// If we're running an archive node, always flush
 	if bc.cacheConfig.TrieDirtyDisabled {
 		return bc.triedb.Commit(root, false)
@@ -1621,9 +1673,11 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals, setHead bool)
 			log.Debug("Pruned ancestor, inserting as sidechain", "number", block.Number(), "hash", block.Hash())
 			return bc.insertSideChain(block, it)
 		} else {
-			// We're post-merge and the parent is pruned, try to recover the parent state
+			// We're post-merge and the parent is pruned, try to recover the parent state.
+			// The head is not updated, so restore its state if it was rolled back.
 			log.Debug("Pruned ancestor", "number", block.Number(), "hash", block.Hash())
 			_, err := bc.recoverAncestors(block)
+			bc.recoverHead()
 			return it.index, err
 		}
 	// First block is future, shove it (and all children) to the future queue (unknown ancestor)
@@ -1952,6 +2006,15 @@ func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator) (i
 	)
 	parent := it.previous()
 	for parent != nil && !bc.HasState(parent.Root) {
+		if bc.triedb.Recoverable(parent.Root) {
+			if err := bc.triedb.Recover(parent.Root); err != nil {
+				return 0, err
+			}
+			// The rollback dropped the state of the current head, restore it
+			// unless the side chain gets imported as the new canonical one
+			defer bc.recoverHead()
+			break
+		}
 		hashes = append(hashes, parent.Hash())
 		numbers = append(numbers, parent.Number.Uint64())
 
@@ -2008,6 +2071,12 @@ func (bc *BlockChain) recoverAncestors(block *types.Block) (common.Hash, error)
 		parent  = block
 	)
 	for parent != nil && !bc.HasState(parent.Root()) {
+		if bc.triedb.Recoverable(parent.Root()) {
+			if err := bc.triedb.Recover(parent.Root()); err != nil {
+				return common.Hash{}, err
+			}
+			break
+		}
 		hashes = append(hashes, parent.Hash())
 		numbers = append(numbers, parent.NumberU64())
 		parent = bc.GetBlock(parent.ParentHash(), parent.NumberU64()-1)
@@ -2041,6 +2110,24 @@ func (bc *BlockChain) recoverAncestors(block *types.Block) (common.Hash, error)
 	return block.Hash(), nil
 }
 
+// recoverHead re-executes the canonical blocks whose state was dropped when
+// rolling the persistent state back to an ancestor, if the chain imported on
+// top of the ancestor did not become the canonical one.
+func (bc *BlockChain) recoverHead() {
+	head := bc.CurrentBlock()
+	if bc.HasState(head.Root) {
+		return
+	}
+	if _, err := bc.recoverAncestors(bc.GetBlock(head.Hash(), head.Number.Uint64())); err != nil {
+		if errors.Is(err, errInsertionInterrupted) {
+			return
+		}
+		log.Error("Failed to recover head state", "number", head.Number, "hash", head.Hash(), "err", err)
+		return
+	}
+	log.Info("Recovered head state", "number", head.Number, "hash", head.Hash())
+}
+
 // collectLogs collects the logs that were generated or removed during
 // the processing of a block. These logs are later announced as deleted or reborn.
 func (bc *BlockChain) collectLogs(b *types.Block, removed bool) []*types.Log {
@@ -2254,6 +2341,7 @@ func (bc *BlockChain) SetCanonical(head *types.Block) (common.Hash, error) {
 	// Re-execute the reorged chain in case the head state is missing.
 	if !bc.HasState(head.Root()) {
 		if latestValidHash, err := bc.recoverAncestors(head); err != nil {
+			bc.recoverHead()
 			return latestValidHash, err
 		}
 		log.Info("Recovered head state", "number", head.Number(), "hash", head.Hash())
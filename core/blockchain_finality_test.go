@@ -0,0 +1,312 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+// Tests that the finality policy of the fork choice refuses deep or late chain
+// reorgs, while still following heavier chains otherwise.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// finalityTest is a test case for a heavier side chain competing with the
+// canonical one under a finality policy.
+type finalityTest struct {
+	canonicalBlocks int    // Number of blocks to generate for the canonical chain
+	canonicalDelay  int64  // Seconds by which the first canonical block after the fork is delayed
+	forkBlock       int    // Block number of the common ancestor of the two chains
+	sidechainBlocks int    // Number of blocks to generate for the side chain after the fork
+	sidechainWeight int64  // Difficulty of the side chain blocks, relative to the canonical ones
+	maxReorgDepth   uint64 // Maximum reorg depth of the finality policy
+	mess            bool   // Whether the finality policy penalises late forks
+
+	expReorg   bool   // Whether the side chain is expected to become canonical
+	expRefused string // Reason the reorg is expected to be refused for
+}
+
+// Tests a heavier chain replacing the head when no policy is set.
+//
+// Chain:
+//
+//	G->C1->C2->C3->C4->C5->C6->C7->C8 (HEAD)
+//	└->S1->S2->S3->S4->S5->S6->S7->S8->S9->S10
+//
+// Expected: the side chain becomes canonical, dropping all 8 blocks.
+func TestFinalityNoPolicy(t *testing.T) {
+	testFinality(t, &finalityTest{
+		canonicalBlocks: 8,
+		sidechainBlocks: 10,
+		sidechainWeight: 1,
+		expReorg:        true,
+	})
+}
+
+// Tests reorgs within the maximum reorg depth.
+//
+// Chain:
+//
+//	G->C1->C2->C3->C4->C5->C6->C7->C8 (HEAD)
+//	                   └->S6->S7->S8->S9
+//
+// Policy: max reorg depth 3
+//
+// Expected: the side chain becomes canonical, dropping the 3 blocks C6-C8.
+func TestFinalityShallowReorg(t *testing.T) {
+	testFinality(t, &finalityTest{
+		canonicalBlocks: 8,
+		forkBlock:       5,
+		sidechainBlocks: 4,
+		sidechainWeight: 1,
+		maxReorgDepth:   3,
+		expReorg:        true,
+	})
+}
+
+// Tests reorgs beyond the maximum reorg depth.
+//
+// Chain:
+//
+//	G->C1->C2->C3->C4->C5->C6->C7->C8 (HEAD)
+//	               └->S5->S6->S7->S8->S9->S10
+//
+// Policy: max reorg depth 3
+//
+// Expected: the reorg dropping the 4 blocks C5-C8 is refused.
+func TestFinalityDeepReorg(t *testing.T) {
+	testFinality(t, &finalityTest{
+		canonicalBlocks: 8,
+		forkBlock:       4,
+		sidechainBlocks: 6,
+		sidechainWeight: 1,
+		maxReorgDepth:   3,
+		expRefused:      reorgRefusedDepth,
+	})
+}
+
+// Tests that MESS accepts a fork arriving soon after the canonical blocks.
+//
+// Chain:
+//
+//	G->C1->C2->C3->C4->C5->C6->C7->C8 (HEAD)
+//	               └->S5->S6->S7->S8->S9
+//
+// Policy: MESS, fork 40 seconds old
+//
+// Expected: the side chain becomes canonical.
+func TestFinalityMESSEarlyFork(t *testing.T) {
+	testFinality(t, &finalityTest{
+		canonicalBlocks: 8,
+		forkBlock:       4,
+		sidechainBlocks: 5,
+		sidechainWeight: 1,
+		mess:            true,
+		expReorg:        true,
+	})
+}
+
+// Tests that MESS refuses a late fork without overwhelming difficulty.
+//
+// Chain:
+//
+//	G->C1->C2->C3->C4->C5->C6->C7->C8 (HEAD)
+//	               └->S5->S6->S7->S8->S9
+//
+// Policy: MESS, fork 8 hours old, side chain 10 times heavier
+//
+// Expected: the reorg is refused, as the antigravity demands 31 times the TD.
+func TestFinalityMESSLateFork(t *testing.T) {
+	testFinality(t, &finalityTest{
+		canonicalBlocks: 8,
+		canonicalDelay:  8 * 3600,
+		forkBlock:       4,
+		sidechainBlocks: 5,
+		sidechainWeight: 10,
+		mess:            true,
+		expRefused:      reorgRefusedMESS,
+	})
+}
+
+// Tests that MESS accepts a late fork with overwhelming difficulty.
+//
+// Chain:
+//
+//	G->C1->C2->C3->C4->C5->C6->C7->C8 (HEAD)
+//	               └->S5->S6->S7->S8->S9
+//
+// Policy: MESS, fork 8 hours old, side chain 40 times heavier
+//
+// Expected: the side chain becomes canonical.
+func TestFinalityMESSHeavyLateFork(t *testing.T) {
+	testFinality(t, &finalityTest{
+		canonicalBlocks: 8,
+		canonicalDelay:  8 * 3600,
+		forkBlock:       4,
+		sidechainBlocks: 5,
+		sidechainWeight: 40,
+		mess:            true,
+		expReorg:        true,
+	})
+}
+
+// Tests that the reorg depth is enforced even if MESS would accept the fork.
+//
+// Chain:
+//
+//	G->C1->C2->C3->C4->C5->C6->C7->C8 (HEAD)
+//	               └->S5->S6->S7->S8->S9
+//
+// Policy: max reorg depth 3, MESS, side chain 40 times heavier
+//
+// Expected: the reorg dropping the 4 blocks C5-C8 is refused.
+func TestFinalityDeepHeavyReorg(t *testing.T) {
+	testFinality(t, &finalityTest{
+		canonicalBlocks: 8,
+		forkBlock:       4,
+		sidechainBlocks: 5,
+		sidechainWeight: 40,
+		maxReorgDepth:   3,
+		mess:            true,
+		expRefused:      reorgRefusedDepth,
+	})
+}
+
+func testFinality(t *testing.T, tt *finalityTest) {
+	t.Run("blocks", func(t *testing.T) { testFinalityImport(t, tt, true) })
+	t.Run("headers", func(t *testing.T) { testFinalityImport(t, tt, false) })
+}
+
+func testFinalityImport(t *testing.T, tt *finalityTest, full bool) {
+	var (
+		gspec = &Genesis{
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Config:  params.AllEthashProtocolChanges,
+		}
+		engine     = ethash.NewFullFaker()
+		difficulty = big.NewInt(1000000)
+	)
+	genDb, canonblocks, _ := GenerateChainWithGenesis(gspec, engine, tt.canonicalBlocks, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+		if i == tt.forkBlock && tt.canonicalDelay > 0 {
+			b.OffsetTime(tt.canonicalDelay)
+		}
+		b.SetDifficulty(difficulty)
+	})
+	forkParent := gspec.ToBlock()
+	if tt.forkBlock > 0 {
+		forkParent = canonblocks[tt.forkBlock-1]
+	}
+	sideblocks, _ := GenerateChain(gspec.Config, forkParent, engine, genDb, tt.sidechainBlocks, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x02})
+		b.SetDifficulty(new(big.Int).Mul(difficulty, big.NewInt(tt.sidechainWeight)))
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	chain.SetFinalityPolicy(FinalityConfig{MaxReorgDepth: tt.maxReorgDepth, MESS: tt.mess})
+
+	// Import the canonical chain, then the competing side chain
+	if full {
+		if _, err := chain.InsertChain(canonblocks); err != nil {
+			t.Fatalf("Failed to import canonical chain: %v", err)
+		}
+		if _, err := chain.InsertChain(sideblocks); err != nil {
+			t.Fatalf("Failed to import side chain: %v", err)
+		}
+	} else {
+		if _, err := chain.InsertHeaderChain(blockHeaders(canonblocks), 1); err != nil {
+			t.Fatalf("Failed to import canonical headers: %v", err)
+		}
+		if _, err := chain.InsertHeaderChain(blockHeaders(sideblocks), 1); err != nil {
+			t.Fatalf("Failed to import side headers: %v", err)
+		}
+	}
+	// Ensure the expected chain was chosen
+	want := canonblocks[len(canonblocks)-1]
+	if tt.expReorg {
+		want = sideblocks[len(sideblocks)-1]
+	}
+	head := chain.CurrentHeader()
+	if full {
+		head = chain.CurrentBlock()
+	}
+	if head.Hash() != want.Hash() {
+		t.Errorf("Head mismatch: have #%d [%x…], want #%d [%x…]", head.Number, head.Hash().Bytes()[:4], want.Number(), want.Hash().Bytes()[:4])
+	}
+	// Ensure the refusal was reported. Note, intermediate blocks of an accepted
+	// fork may still be refused by MESS until its TD suffices.
+	if tt.expRefused == "" {
+		return
+	}
+	policy := chain.FinalityPolicy()
+	if policy.LastRefused == nil {
+		t.Fatalf("Refused reorg not reported")
+	}
+	if policy.LastRefused.Reason != tt.expRefused {
+		t.Errorf("Refusal reason mismatch: have %s, want %s", policy.LastRefused.Reason, tt.expRefused)
+	}
+	if policy.LastRefused.Head != want.Hash() {
+		t.Errorf("Refused reorg head mismatch: have %x, want %x", policy.LastRefused.Head, want.Hash())
+	}
+	switch tt.expRefused {
+	case reorgRefusedDepth:
+		if policy.RefusedDepth == 0 || policy.RefusedMESS != 0 {
+			t.Errorf("Refusal counters mismatch: depth %d, mess %d", policy.RefusedDepth, policy.RefusedMESS)
+		}
+	case reorgRefusedMESS:
+		if policy.RefusedMESS == 0 || policy.RefusedDepth != 0 {
+			t.Errorf("Refusal counters mismatch: depth %d, mess %d", policy.RefusedDepth, policy.RefusedMESS)
+		}
+		if want := uint64(tt.forkBlock); policy.LastRefused.Ancestor != want {
+			t.Errorf("Refused reorg ancestor mismatch: have %d, want %d", policy.LastRefused.Ancestor, want)
+		}
+	}
+}
+
+// blockHeaders returns the headers of the given blocks.
+func blockHeaders(blocks []*types.Block) []*types.Header {
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	return headers
+}
+
+// Tests the MESS antigravity curve.
+func TestMESSAntigravity(t *testing.T) {
+	tests := []struct {
+		elapsed uint64
+		want    int64
+	}{
+		{0, 128},
+		{600, 134},
+		{25132 / 2, 128 + 3840/2},
+		{25132, 31 * 128},
+		{100000, 31 * 128},
+	}
+	for _, tt := range tests {
+		if have := messAntigravity(tt.elapsed); have.Int64() != tt.want {
+			t.Errorf("elapsed %d: antigravity mismatch: have %v, want %v", tt.elapsed, have, tt.want)
+		}
+	}
+}
@@ -1754,6 +1754,229 @@ func TestLargeReorgTrieGC(t *testing.T) {
 	}
 }
 
+// Tests that with the path-based scheme, deep reorgs and SetHead beyond the
+// states in memory roll the persistent state back with the state history.
+func TestPathSchemeDeepRollback(t *testing.T) {
+	// Generate the original common chain segment and the two competing forks
+	engine := ethash.NewFaker()
+	genesis := &Genesis{
+		Config:  params.TestChainConfig,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	genDb, shared, _ := GenerateChainWithGenesis(genesis, engine, 64, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{1}) })
+	original, _ := GenerateChain(genesis.Config, shared[len(shared)-1], engine, genDb, 2*TriesInMemory, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{2}) })
+	competitor, _ := GenerateChain(genesis.Config, shared[len(shared)-1], engine, genDb, 2*TriesInMemory+1, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{3}) })
+
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer db.Close()
+
+	config := &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		StateScheme:    rawdb.PathScheme,
+	}
+	chain, err := NewBlockChain(db, config, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if _, err := chain.InsertChain(shared); err != nil {
+		t.Fatalf("failed to insert shared chain: %v", err)
+	}
+	if _, err := chain.InsertChain(original); err != nil {
+		t.Fatalf("failed to insert original chain: %v", err)
+	}
+	// Ensure that the state associated with the forking point is only available
+	// through the state history
+	fork := shared[len(shared)-1]
+	if chain.HasState(fork.Root()) {
+		t.Fatalf("common-but-old ancestor state still available")
+	}
+	if !chain.triedb.Recoverable(fork.Root()) {
+		t.Fatalf("common-but-old ancestor state not recoverable")
+	}
+	// Import the competitor chain, triggering a reorg beyond the states in memory
+	if _, err := chain.InsertChain(competitor); err != nil {
+		t.Fatalf("failed to insert competitor chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != competitor[len(competitor)-1].Hash() {
+		t.Fatalf("head mismatch after reorg: have #%d %x, want #%d %x", head.Number, head.Hash(), competitor[len(competitor)-1].Number(), competitor[len(competitor)-1].Hash())
+	}
+	if !chain.HasState(competitor[len(competitor)-1].Root()) {
+		t.Fatalf("competitor head state missing")
+	}
+	// Rewind the chain beyond the states in memory
+	target := competitor[9]
+	if err := chain.SetHead(target.NumberU64()); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != target.Hash() {
+		t.Fatalf("head mismatch after rewind: have #%d %x, want #%d %x", head.Number, head.Hash(), target.Number(), target.Hash())
+	}
+	if !chain.HasState(target.Root()) {
+		t.Fatalf("rewound head state missing")
+	}
+	// Reimport the rest of the chain and ensure the head state survives a restart
+	if _, err := chain.InsertChain(competitor[10:]); err != nil {
+		t.Fatalf("failed to reimport competitor chain: %v", err)
+	}
+	chain.Stop()
+
+	chain, err = NewBlockChain(db, config, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if head := chain.CurrentBlock(); head.Hash() != competitor[len(competitor)-1].Hash() {
+		t.Fatalf("head mismatch after restart: have #%d %x, want #%d %x", head.Number, head.Hash(), competitor[len(competitor)-1].Number(), competitor[len(competitor)-1].Hash())
+	}
+	if !chain.HasState(competitor[len(competitor)-1].Root()) {
+		t.Fatalf("head state missing after restart")
+	}
+}
+
+// Tests that with the path-based scheme, the state of the canonical head is
+// not lost if a side chain fails to import after rolling the persistent state
+// back to the forking point.
+func TestPathSchemeInvalidSideChain(t *testing.T) {
+	engine := ethash.NewFaker()
+	genesis := &Genesis{
+		Config:  params.TestChainConfig,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	genDb, shared, _ := GenerateChainWithGenesis(genesis, engine, 64, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{1}) })
+	original, _ := GenerateChain(genesis.Config, shared[len(shared)-1], engine, genDb, 2*TriesInMemory, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{2}) })
+	competitor, _ := GenerateChain(genesis.Config, shared[len(shared)-1], engine, genDb, 2*TriesInMemory+1, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{3}) })
+
+	// invalidate returns the given block with a mismatching state root
+	invalidate := func(block *types.Block) *types.Block {
+		header := block.Header()
+		header.Root = common.Hash{0x01}
+		return types.NewBlockWithHeader(header).WithBody(block.Transactions(), block.Uncles())
+	}
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer db.Close()
+
+	config := &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		StateScheme:    rawdb.PathScheme,
+	}
+	chain, err := NewBlockChain(db, config, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(shared); err != nil {
+		t.Fatalf("failed to insert shared chain: %v", err)
+	}
+	if _, err := chain.InsertChain(original); err != nil {
+		t.Fatalf("failed to insert original chain: %v", err)
+	}
+	head := original[len(original)-1]
+
+	// Import an invalid block on top of the forking point without setting the
+	// head, as done post-merge
+	if err := chain.InsertBlockWithoutSetHead(invalidate(competitor[0])); err == nil {
+		t.Fatalf("invalid side block imported")
+	}
+	if current := chain.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Fatalf("head mismatch: have #%d %x, want #%d %x", current.Number, current.Hash(), head.Number(), head.Hash())
+	}
+	if !chain.HasState(head.Root()) {
+		t.Fatalf("head state missing after invalid side block")
+	}
+	// Import a side chain only failing on the block triggering the reorg
+	invalid := append(types.Blocks{}, competitor[:len(competitor)-1]...)
+	invalid = append(invalid, invalidate(competitor[len(competitor)-1]))
+	if _, err := chain.InsertChain(invalid); err == nil {
+		t.Fatalf("invalid side chain imported")
+	}
+	if current := chain.CurrentBlock(); !chain.HasState(current.Root) {
+		t.Fatalf("head state missing after invalid side chain")
+	}
+}
+
+// Tests that with the path-based scheme, the state of the canonical head is
+// not lost if a valid side chain is imported without becoming canonical.
+func TestPathSchemeRefusedSideChain(t *testing.T) {
+	engine := ethash.NewFaker()
+	genesis := &Genesis{
+		Config:  params.TestChainConfig,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	genDb, shared, _ := GenerateChainWithGenesis(genesis, engine, 64, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{1}) })
+	original, _ := GenerateChain(genesis.Config, shared[len(shared)-1], engine, genDb, 2*TriesInMemory, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{2}) })
+	competitor, _ := GenerateChain(genesis.Config, shared[len(shared)-1], engine, genDb, 2*TriesInMemory+1, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{3}) })
+
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer db.Close()
+
+	config := &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		StateScheme:    rawdb.PathScheme,
+	}
+	chain, err := NewBlockChain(db, config, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(shared); err != nil {
+		t.Fatalf("failed to insert shared chain: %v", err)
+	}
+	if _, err := chain.InsertChain(original); err != nil {
+		t.Fatalf("failed to insert original chain: %v", err)
+	}
+	head := original[len(original)-1]
+
+	// checkHead ensures the original head is still canonical, with its state
+	checkHead := func(stage string) {
+		t.Helper()
+
+		if current := chain.CurrentBlock(); current.Hash() != head.Hash() {
+			t.Fatalf("%s: head mismatch: have #%d %x, want #%d %x", stage, current.Number, current.Hash(), head.Number(), head.Hash())
+		}
+		statedb, err := chain.State()
+		if err != nil {
+			t.Fatalf("%s: failed to open head state: %v", stage, err)
+		}
+		if statedb.GetBalance(common.Address{2}).Sign() == 0 {
+			t.Fatalf("%s: head state mismatch", stage)
+		}
+	}
+	// Import a valid block on top of the forking point without setting the
+	// head, as done post-merge
+	if err := chain.InsertBlockWithoutSetHead(competitor[0]); err != nil {
+		t.Fatalf("failed to insert side block: %v", err)
+	}
+	checkHead("side block")
+
+	// Import a heavier side chain, refused by the finality policy
+	chain.SetFinalityPolicy(FinalityConfig{MaxReorgDepth: 3})
+	if _, err := chain.InsertChain(competitor); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	if chain.FinalityPolicy().RefusedDepth == 0 {
+		t.Fatalf("side chain reorg not refused")
+	}
+	checkHead("refused side chain")
+}
+
 func TestBlockchainRecovery(t *testing.T) {
 	// Configure and generate a sample block chain
 	var (
@@ -3649,6 +3872,165 @@ func TestEIP1559Transition(t *testing.T) {
 	}
 }
 
+// Tests that the base fee is burned, paid to the coinbase or paid to the treasury
+// as configured once London is activated on an existing proof-of-work chain.
+func TestBaseFeeDestination(t *testing.T) {
+	treasury := common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+
+	testBaseFeeDestination(t, nil, nil)
+	testBaseFeeDestination(t, &params.BaseFeeConfig{Recipient: params.BaseFeeBurn}, nil)
+	testBaseFeeDestination(t, &params.BaseFeeConfig{Recipient: params.BaseFeeCoinbase}, &common.Address{2})
+	testBaseFeeDestination(t, &params.BaseFeeConfig{Recipient: params.BaseFeeTreasury, Treasury: &treasury}, &treasury)
+}
+
+func testBaseFeeDestination(t *testing.T, dest *params.BaseFeeConfig, recipient *common.Address) {
+	var (
+		aa     = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		engine = ethash.NewFaker()
+
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		funds  = big.NewInt(params.Ether)
+		config = *params.AllEthashProtocolChanges
+		gspec  = &Genesis{
+			Config: &config,
+			Alloc:  GenesisAlloc{addr: {Balance: funds}},
+		}
+	)
+	// Schedule London on the second block
+	config.LondonBlock = big.NewInt(2)
+	config.ArrowGlacierBlock = big.NewInt(2)
+	config.GrayGlacierBlock = big.NewInt(2)
+	config.BaseFeeDestination = dest
+	signer := types.LatestSigner(gspec.Config)
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{byte(i + 1)})
+
+		var txdata types.TxData = &types.LegacyTx{
+			Nonce:    b.TxNonce(addr),
+			To:       &aa,
+			Gas:      params.TxGas,
+			GasPrice: newGwei(5),
+		}
+		if i == 1 {
+			txdata = &types.DynamicFeeTx{
+				ChainID:   gspec.Config.ChainID,
+				Nonce:     b.TxNonce(addr),
+				To:        &aa,
+				Gas:       params.TxGas,
+				GasFeeCap: newGwei(5),
+				GasTipCap: big.NewInt(2),
+			}
+		}
+		b.AddTx(types.MustSignNewTx(key, signer, txdata))
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	state, _ := chain.State()
+	prelondon, block := chain.GetBlockByNumber(1), chain.GetBlockByNumber(2)
+	if prelondon.BaseFee() != nil || block.BaseFee() == nil {
+		t.Fatalf("base fee activation mismatch: pre-London %v, London %v", prelondon.BaseFee(), block.BaseFee())
+	}
+	var (
+		tip     = new(big.Int).SetUint64(block.GasUsed() * block.Transactions()[0].GasTipCap().Uint64())
+		baseFee = new(big.Int).SetUint64(block.GasUsed() * block.BaseFee().Uint64())
+	)
+	// Ensure the sender paid for the tip and the base fee in any case
+	spent := new(big.Int).Mul(new(big.Int).SetUint64(prelondon.GasUsed()), newGwei(5))
+	spent.Add(spent, new(big.Int).Add(tip, baseFee))
+	if actual := new(big.Int).Sub(funds, state.GetBalance(addr)); actual.Cmp(spent) != 0 {
+		t.Fatalf("%v: sender balance incorrect: expected %d, got %d", dest, spent, actual)
+	}
+	// Ensure the base fee went to its recipient, if any, and nowhere else
+	expected := new(big.Int).Add(tip, ethash.ConstantinopleBlockReward)
+	if recipient != nil && *recipient == block.Coinbase() {
+		expected.Add(expected, baseFee)
+	}
+	if actual := state.GetBalance(block.Coinbase()); actual.Cmp(expected) != 0 {
+		t.Fatalf("%v: miner balance incorrect: expected %d, got %d", dest, expected, actual)
+	}
+	if recipient != nil && *recipient != block.Coinbase() {
+		if actual := state.GetBalance(*recipient); actual.Cmp(baseFee) != 0 {
+			t.Fatalf("%v: treasury balance incorrect: expected %d, got %d", dest, baseFee, actual)
+		}
+	}
+}
+
+// Tests that the EVM upgrade fork activates the new opcodes on a proof-of-work
+// chain by block number, without withdrawals.
+func TestEVMUpgradeTransition(t *testing.T) {
+	var (
+		aa     = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		engine = ethash.NewFaker()
+
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = *params.AllEthashProtocolChanges
+		gspec  = &Genesis{
+			Config: &config,
+			Alloc: GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				// The address 0xAAAA stores PUSH0 + 1 in slot 1
+				aa: {
+					Code: []byte{
+						byte(vm.PUSH0),
+						byte(vm.PUSH1), 0x01,
+						byte(vm.ADD),
+						byte(vm.PUSH1), 0x01,
+						byte(vm.SSTORE),
+					},
+					Balance: big.NewInt(0),
+				},
+			},
+		}
+	)
+	config.EVMUpgradeBlock = big.NewInt(2)
+	signer := types.LatestSigner(gspec.Config)
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2, func(i int, b *BlockGen) {
+		tx := types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   gspec.Config.ChainID,
+			Nonce:     b.TxNonce(addr),
+			To:        &aa,
+			Gas:       50000,
+			GasFeeCap: newGwei(5),
+			GasTipCap: big.NewInt(2),
+		})
+		b.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	for _, number := range []uint64{1, 2} {
+		block := chain.GetBlockByNumber(number)
+		if block.Header().WithdrawalsHash != nil || block.Withdrawals() != nil {
+			t.Errorf("block %d: unexpected withdrawals", number)
+		}
+		receipts := chain.GetReceiptsByHash(block.Hash())
+		if want := number >= 2; (receipts[0].Status == types.ReceiptStatusSuccessful) != want {
+			t.Errorf("block %d: PUSH0 execution mismatch: have status %d, want success %v", number, receipts[0].Status, want)
+		}
+	}
+	state, _ := chain.State()
+	if have := state.GetState(aa, common.BigToHash(common.Big1)); have != common.BigToHash(common.Big1) {
+		t.Errorf("storage mismatch: have %x, want 1", have)
+	}
+}
+
 // Tests the scenario the chain is requested to another point with the missing state.
 // It expects the state is recovered and all relevant chain markers are set correctly.
 func TestSetCanonical(t *testing.T) {
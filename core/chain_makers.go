@@ -43,8 +43,9 @@ type BlockGen struct {
 	uncles      []*types.Header
 	withdrawals []*types.Withdrawal
 
-	config *params.ChainConfig
-	engine consensus.Engine
+	config      *params.ChainConfig
+	engine      consensus.Engine
+	chainreader *fakeChainReader
 }
 
 // SetCoinbase sets the coinbase of the generated block.
@@ -192,8 +193,7 @@ func (b *BlockGen) AddUncle(h *types.Header) {
 			break
 		}
 	}
-	chainreader := &fakeChainReader{config: b.config}
-	h.Difficulty = b.engine.CalcDifficulty(chainreader, b.header.Time, parent)
+	h.Difficulty = b.engine.CalcDifficulty(b.chainreader, b.header.Time, parent)
 
 	// The gas limit and price should be derived from the parent
 	h.GasLimit = parent.GasLimit
@@ -256,8 +256,7 @@ func (b *BlockGen) OffsetTime(seconds int64) {
 	if b.header.Time <= b.parent.Header().Time {
 		panic("block time out of range")
 	}
-	chainreader := &fakeChainReader{config: b.config}
-	b.header.Difficulty = b.engine.CalcDifficulty(chainreader, b.header.Time, b.parent.Header())
+	b.header.Difficulty = b.engine.CalcDifficulty(b.chainreader, b.header.Time, b.parent.Header())
 }
 
 // GenerateChain creates a chain of n blocks. The first block's
@@ -278,8 +277,12 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 	}
 	blocks, receipts := make(types.Blocks, n), make([]types.Receipts, n)
 	chainreader := &fakeChainReader{config: config}
+	if config.Ethash != nil {
+		// Expose the generated ancestors to windowed difficulty algorithms
+		chainreader.chain = append(types.Blocks{parent}, blocks...)
+	}
 	genblock := func(i int, parent *types.Block, statedb *state.StateDB) (*types.Block, types.Receipts) {
-		b := &BlockGen{i: i, chain: blocks, parent: parent, statedb: statedb, config: config, engine: engine}
+		b := &BlockGen{i: i, chain: blocks, parent: parent, statedb: statedb, config: config, engine: engine, chainreader: chainreader}
 		b.header = makeHeader(chainreader, parent, statedb, b.engine)
 
 		// Set the difficulty for clique block. The chain maker doesn't have access
@@ -335,6 +338,9 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		}
 		block, receipt := genblock(i, parent, statedb)
 		blocks[i] = block
+		if chainreader.chain != nil {
+			chainreader.chain[i+1] = block
+		}
 		receipts[i] = receipt
 		parent = block
 	}
@@ -366,6 +372,7 @@ func makeHeader(chain consensus.ChainReader, parent *types.Block, state *state.S
 		ParentHash: parent.Hash(),
 		Coinbase:   parent.Coinbase(),
 		Difficulty: engine.CalcDifficulty(chain, time, &types.Header{
+			ParentHash: parent.ParentHash(),
 			Number:     parent.Number(),
 			Time:       time - 10,
 			Difficulty: parent.Difficulty(),
@@ -423,6 +430,7 @@ func makeBlockChainWithGenesis(genesis *Genesis, n int, engine consensus.Engine,
 
 type fakeChainReader struct {
 	config *params.ChainConfig
+	chain  types.Blocks // Generated blocks preceded by their parent, if exposed
 }
 
 // Config returns the chain configuration.
@@ -430,9 +438,41 @@ func (cr *fakeChainReader) Config() *params.ChainConfig {
 	return cr.config
 }
 
-func (cr *fakeChainReader) CurrentHeader() *types.Header                            { return nil }
-func (cr *fakeChainReader) GetHeaderByNumber(number uint64) *types.Header           { return nil }
-func (cr *fakeChainReader) GetHeaderByHash(hash common.Hash) *types.Header          { return nil }
-func (cr *fakeChainReader) GetHeader(hash common.Hash, number uint64) *types.Header { return nil }
-func (cr *fakeChainReader) GetBlock(hash common.Hash, number uint64) *types.Block   { return nil }
-func (cr *fakeChainReader) GetTd(hash common.Hash, number uint64) *big.Int          { return nil }
+func (cr *fakeChainReader) CurrentHeader() *types.Header                   { return nil }
+func (cr *fakeChainReader) GetHeaderByHash(hash common.Hash) *types.Header { return nil }
+func (cr *fakeChainReader) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
+
+// GetHeaderByNumber retrieves an already generated header by number.
+func (cr *fakeChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	if block := cr.block(number); block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+// GetHeader retrieves an already generated header by hash and number.
+func (cr *fakeChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if block := cr.GetBlock(hash, number); block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+// GetBlock retrieves an already generated block by hash and number.
+func (cr *fakeChainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block := cr.block(number); block != nil && block.Hash() == hash {
+		return block
+	}
+	return nil
+}
+
+// block returns the exposed block with the given number, if any.
+func (cr *fakeChainReader) block(number uint64) *types.Block {
+	if len(cr.chain) == 0 || number < cr.chain[0].NumberU64() {
+		return nil
+	}
+	if index := number - cr.chain[0].NumberU64(); index < uint64(len(cr.chain)) {
+		return cr.chain[index]
+	}
+	return nil
+}
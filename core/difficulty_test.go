@@ -0,0 +1,67 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that chains switching difficulty algorithms are generated and verified
+// consistently, and are rejected by nodes running the legacy rules.
+func TestDifficultyAlgorithmFork(t *testing.T) {
+	var (
+		lwmaBlock  = uint64(8)
+		asertBlock = uint64(16)
+	)
+	config := *params.AllEthashProtocolChanges
+	config.Ethash = &params.EthashConfig{Difficulty: []*params.DifficultyConfig{
+		{Block: new(big.Int).SetUint64(lwmaBlock), Algorithm: params.DifficultyLWMA1, TargetTime: 12, Window: 4},
+		{Block: new(big.Int).SetUint64(asertBlock), Algorithm: params.DifficultyASERT, TargetTime: 12, HalfLife: 60},
+	}}
+	gspec := &Genesis{Config: &config, Difficulty: big.NewInt(1 << 20), BaseFee: big.NewInt(params.InitialBaseFee)}
+	legacyGspec := &Genesis{Config: params.AllEthashProtocolChanges, Difficulty: big.NewInt(1 << 20), BaseFee: big.NewInt(params.InitialBaseFee)}
+
+	// Generate a chain with irregular block times across both switches
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), int(asertBlock)+8, func(i int, gen *BlockGen) {
+		gen.OffsetTime(int64(i%5) * 3)
+	})
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i].NumberU64() >= lwmaBlock && blocks[i].Difficulty().Cmp(blocks[i-1].Difficulty()) == 0 {
+			t.Errorf("block %d: difficulty not adjusted: %v", blocks[i].NumberU64(), blocks[i].Difficulty())
+		}
+	}
+	// A node following the switches must accept the chain
+	bc, _ := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer bc.Stop()
+
+	if _, err := bc.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	// A legacy node must accept the shared prefix, but reject the first LWMA block
+	legacyBc, _ := NewBlockChain(rawdb.NewMemoryDatabase(), nil, legacyGspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer legacyBc.Stop()
+
+	n, err := legacyBc.InsertChain(blocks)
+	if err == nil {
+		t.Fatalf("legacy node accepted the retargeted chain")
+	}
+	if blocks[n].NumberU64() != lwmaBlock {
+		t.Errorf("fork block mismatch: have %d, want %d", blocks[n].NumberU64(), lwmaBlock)
+	}
+}
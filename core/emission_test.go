@@ -0,0 +1,76 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that nodes running different block reward schedules agree on the chain
+// up to the block where the schedules diverge, and fork from there on.
+func TestEmissionScheduleFork(t *testing.T) {
+	var (
+		forkBlock = uint64(8)
+		coinbase  = common.HexToAddress("0xc0ffee")
+	)
+	// Create two configs, one halving the rewards at the fork block
+	halvingConf := *params.AllEthashProtocolChanges
+	halvingConf.Ethash = &params.EthashConfig{Emission: &params.EmissionConfig{
+		InitialReward:   big.NewInt(2 * params.Ether),
+		HalvingInterval: forkBlock,
+	}}
+	constantConf := *params.AllEthashProtocolChanges
+	constantConf.Ethash = &params.EthashConfig{Emission: &params.EmissionConfig{
+		InitialReward: big.NewInt(2 * params.Ether),
+	}}
+	halvingGspec := &Genesis{Config: &halvingConf, BaseFee: big.NewInt(params.InitialBaseFee)}
+	constantGspec := &Genesis{Config: &constantConf, BaseFee: big.NewInt(params.InitialBaseFee)}
+
+	// Generate a chain following the halving schedule
+	_, blocks, _ := GenerateChainWithGenesis(halvingGspec, ethash.NewFaker(), int(forkBlock)+2, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(coinbase)
+	})
+	// The halving node must accept the chain and pay the halved rewards
+	halvingBc, _ := NewBlockChain(rawdb.NewMemoryDatabase(), nil, halvingGspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer halvingBc.Stop()
+
+	if _, err := halvingBc.InsertChain(blocks); err != nil {
+		t.Fatalf("halving node failed to import its own chain: %v", err)
+	}
+	state, _ := halvingBc.State()
+	want := new(big.Int).Mul(big.NewInt(int64(forkBlock-1)*2+3), big.NewInt(params.Ether))
+	if have := state.GetBalance(coinbase); have.Cmp(want) != 0 {
+		t.Errorf("coinbase balance mismatch: have %v, want %v", have, want)
+	}
+	// The constant node must accept the shared prefix, but reject the fork block
+	constantBc, _ := NewBlockChain(rawdb.NewMemoryDatabase(), nil, constantGspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer constantBc.Stop()
+
+	n, err := constantBc.InsertChain(blocks)
+	if err == nil {
+		t.Fatalf("constant node accepted the halved chain")
+	}
+	if blocks[n].NumberU64() != forkBlock {
+		t.Errorf("fork block mismatch: have %d, want %d", blocks[n].NumberU64(), forkBlock)
+	}
+	if head := constantBc.CurrentBlock().Number.Uint64(); head != forkBlock-1 {
+		t.Errorf("constant node head mismatch: have %d, want %d", head, forkBlock-1)
+	}
+}
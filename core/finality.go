@@ -0,0 +1,220 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Reasons for the finality policy to refuse a chain reorganisation.
+const (
+	reorgRefusedDepth = "depth" // The reorg drops more canonical blocks than allowed
+	reorgRefusedMESS  = "mess"  // The reorg is not heavy enough for how late it arrived
+)
+
+// Parameters of the MESS antigravity curve, requiring late-arriving forks to
+// carry up to 31 times the work of the local segment they replace. These are
+// the values used by Ethereum Classic (ECBP-1100).
+var (
+	messDenominator = big.NewInt(128)   // Fixed point scale of the curve
+	messXCap        = big.NewInt(25132) // Seconds after which the curve plateaus (~7 hours)
+	messAmplitude   = big.NewInt(15)    // Half of the maximum extra TD ratio required
+	messHeight      = new(big.Int).Mul(new(big.Int).Mul(messDenominator, messAmplitude), big.NewInt(2))
+)
+
+// FinalityConfig is the finality policy of the proof-of-work fork choice,
+// protecting the canonical chain from deep reorganisations by chains with a
+// higher total difficulty, such as those of majority hashrate attacks.
+//
+// Note, a node following the policy may end up on a different chain than the
+// rest of the network. Such splits must be resolved by the operator, e.g. by
+// disabling the policy or rewinding the chain with debug_setHead.
+type FinalityConfig struct {
+	MaxReorgDepth uint64 // Maximum number of canonical blocks a reorg may drop (0 = unlimited)
+	MESS          bool   // Whether to penalise late-arriving forks by their age (modified exponential subjective scoring)
+}
+
+// RefusedReorg describes a chain reorganisation refused by the finality policy.
+type RefusedReorg struct {
+	Reason   string      `json:"reason"`   // Reason of refusal: depth or mess
+	Head     common.Hash `json:"head"`     // Hash of the local head at the time
+	Number   uint64      `json:"number"`   // Number of the local head at the time
+	Extern   common.Hash `json:"extern"`   // Hash of the refused chain's head
+	Ancestor uint64      `json:"ancestor"` // Number of the common ancestor (0 if not resolved)
+	Depth    uint64      `json:"depth"`    // Number of canonical blocks the reorg would have dropped, at least
+}
+
+// FinalityStatus is the finality policy of the fork choice, along with the
+// statistics of the reorgs it refused.
+type FinalityStatus struct {
+	MaxReorgDepth uint64        `json:"maxReorgDepth"`
+	MESS          bool          `json:"mess"`
+	RefusedDepth  uint64        `json:"refusedDepth"` // Number of reorgs refused for their depth
+	RefusedMESS   uint64        `json:"refusedMESS"`  // Number of reorgs refused for arriving late
+	LastRefused   *RefusedReorg `json:"lastRefused,omitempty"`
+}
+
+// SetFinalityPolicy updates the finality policy of the fork choice.
+func (f *ForkChoice) SetFinalityPolicy(config FinalityConfig) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.finality.MaxReorgDepth = config.MaxReorgDepth
+	f.finality.MESS = config.MESS
+}
+
+// FinalityPolicy returns the finality policy of the fork choice and the
+// statistics of the reorgs it refused.
+func (f *ForkChoice) FinalityPolicy() FinalityStatus {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	status := f.finality
+	if status.LastRefused != nil {
+		last := *status.LastRefused
+		status.LastRefused = &last
+	}
+	return status
+}
+
+// reorgAllowed checks whether a reorg to a heavier chain complies with the
+// finality policy.
+func (f *ForkChoice) reorgAllowed(current *types.Header, extern *types.Header, localTD, externTD *big.Int) bool {
+	f.lock.RLock()
+	maxDepth, mess := f.finality.MaxReorgDepth, f.finality.MESS
+	f.lock.RUnlock()
+
+	// Short circuit if there's no policy, or the head is simply extended
+	if maxDepth == 0 && !mess {
+		return true
+	}
+	if extern.ParentHash == current.Hash() {
+		return true
+	}
+	// Find the common ancestor, bailing out early if only the depth matters
+	limit := maxDepth
+	if limit == 0 || mess {
+		limit = current.Number.Uint64()
+	}
+	ancestor, depth := f.commonAncestor(current, extern, limit)
+	if ancestor == nil && depth == 0 {
+		return true // Unknown ancestry, leave it to the chain to reject
+	}
+	if maxDepth > 0 && depth > maxDepth {
+		f.refuse(reorgRefusedDepth, current, extern, ancestor, depth)
+		return false
+	}
+	if !mess || depth == 0 {
+		return true
+	}
+	// Require the TD gained by the fork over the common ancestor to outweigh
+	// the local one by the antigravity of the time passed since the fork
+	ancestorTD := f.chain.GetTd(ancestor.Hash(), ancestor.Number.Uint64())
+	if ancestorTD == nil {
+		return true
+	}
+	var (
+		gained = new(big.Int).Sub(externTD, ancestorTD)
+		local  = new(big.Int).Sub(localTD, ancestorTD)
+	)
+	gained.Mul(gained, messDenominator)
+	local.Mul(local, messAntigravity(current.Time-ancestor.Time))
+	if gained.Cmp(local) < 0 {
+		f.refuse(reorgRefusedMESS, current, extern, ancestor, depth)
+		return false
+	}
+	return true
+}
+
+// commonAncestor finds the common ancestor of the local head and an external
+// header, returning it along with the number of local blocks a reorg drops. If
+// more than limit blocks would be dropped, the search is aborted and a depth of
+// limit+1 returned. A nil ancestor with a zero depth means unknown ancestry.
+func (f *ForkChoice) commonAncestor(current *types.Header, extern *types.Header, limit uint64) (*types.Header, uint64) {
+	// Rewind the external chain to the height of the local head
+	for extern != nil && extern.Number.Cmp(current.Number) > 0 {
+		extern = f.chain.GetHeader(extern.ParentHash, extern.Number.Uint64()-1)
+	}
+	if extern == nil {
+		return nil, 0
+	}
+	// Rewind both chains until they meet
+	head := current.Number.Uint64()
+	for current.Number.Cmp(extern.Number) > 0 || current.Hash() != extern.Hash() {
+		if head-current.Number.Uint64() >= limit {
+			return nil, limit + 1
+		}
+		if current.Number.Cmp(extern.Number) == 0 {
+			extern = f.chain.GetHeader(extern.ParentHash, extern.Number.Uint64()-1)
+		}
+		current = f.chain.GetHeader(current.ParentHash, current.Number.Uint64()-1)
+		if current == nil || extern == nil {
+			return nil, 0
+		}
+	}
+	return current, head - current.Number.Uint64()
+}
+
+// refuse records and reports a refused reorg.
+func (f *ForkChoice) refuse(reason string, current *types.Header, extern *types.Header, ancestor *types.Header, depth uint64) {
+	refused := &RefusedReorg{
+		Reason: reason,
+		Head:   current.Hash(),
+		Number: current.Number.Uint64(),
+		Extern: extern.Hash(),
+		Depth:  depth,
+	}
+	if ancestor != nil {
+		refused.Ancestor = ancestor.Number.Uint64()
+	}
+	f.lock.Lock()
+	switch reason {
+	case reorgRefusedDepth:
+		f.finality.RefusedDepth++
+		reorgRefusedDepthMeter.Mark(1)
+	case reorgRefusedMESS:
+		f.finality.RefusedMESS++
+		reorgRefusedMESSMeter.Mark(1)
+	}
+	f.finality.LastRefused = refused
+	f.lock.Unlock()
+
+	log.Warn("Refused deep chain reorg", "reason", reason, "number", refused.Number, "hash", refused.Head,
+		"extern", extern.Number, "exthash", refused.Extern, "ancestor", refused.Ancestor, "depth", depth)
+}
+
+// messAntigravity returns the ratio, scaled by messDenominator, by which the TD
+// of a fork must exceed the local one, given the seconds since the fork:
+//
+//	f(x) = 1 + 2 * amplitude * (3x^2/xcap^2 - 2x^3/xcap^3), for x capped at xcap
+func messAntigravity(elapsed uint64) *big.Int {
+	x := new(big.Int).SetUint64(elapsed)
+	if x.Cmp(messXCap) > 0 {
+		x.Set(messXCap)
+	}
+	// (3 * x^2 - 2 * x^3 / xcap) * height / xcap^2 + denominator
+	square := new(big.Int).Mul(x, x)
+	cube := new(big.Int).Mul(square, x)
+	cube.Lsh(cube, 1)
+	cube.Div(cube, messXCap)
+
+	out := new(big.Int).Mul(square, big.NewInt(3))
+	out.Sub(out, cube)
+	out.Mul(out, messHeight)
+	out.Div(out, new(big.Int).Mul(messXCap, messXCap))
+	return out.Add(out, messDenominator)
+}
@@ -17,14 +17,21 @@ import (
 	"errors"
 	"math/big"
 	mrand "math/rand"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 )
 
+var (
+	reorgRefusedDepthMeter = metrics.NewRegisteredMeter("chain/reorg/refused/depth", nil)
+	reorgRefusedMESSMeter  = metrics.NewRegisteredMeter("chain/reorg/refused/mess", nil)
+)
+
 // ChainReader defines a small collection of methods needed to access the local
 // blockchain during header verification. It's implemented by both blockchain
 // and lightchain.
@@ -34,6 +41,9 @@ type ChainReader interface {
 
 	// GetTd returns the total difficulty of a local block.
 	GetTd(common.Hash, uint64) *big.Int
+
+	// GetHeader retrieves a block header from the database by hash and number.
+	GetHeader(common.Hash, uint64) *types.Header
 }
 
 // ForkChoice is the fork chooser based on the highest total difficulty of the
@@ -50,6 +60,9 @@ type ForkChoice struct {
 	// local td is equal to the extern one. It can be nil for light
 	// client
 	preserve func(header *types.Header) bool
+
+	finality FinalityStatus // Finality policy guarding against deep reorgs
+	lock     sync.RWMutex   // Protects the finality policy
 }
 
 func NewForkChoice(chainReader ChainReader, preserve func(header *types.Header) bool) *ForkChoice {
@@ -94,7 +107,7 @@ func (f *ForkChoice) ReorgNeeded(current *types.Header, extern *types.Header) (b
 
 	// If the total difficulty is higher than our known, add it to the canonical chain
 	if diff := externTd.Cmp(localTD); diff > 0 {
-		return true, nil
+		return f.reorgAllowed(current, extern, localTD, externTd), nil
 	} else if diff < 0 {
 		return false, nil
 	}
@@ -112,5 +125,8 @@ func (f *ForkChoice) ReorgNeeded(current *types.Header, extern *types.Header) (b
 		}
 		reorg = !currentPreserve && (externPreserve || f.rand.Float64() < 0.5)
 	}
+	if reorg {
+		reorg = f.reorgAllowed(current, extern, localTD, externTd)
+	}
 	return reorg, nil
 }
@@ -0,0 +1,152 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// supply returns the total balance allocated by the genesis specification.
+func (ga *GenesisAlloc) supply() *big.Int {
+	supply := new(big.Int)
+	for _, account := range *ga {
+		if account.Balance != nil {
+			supply.Add(supply, account.Balance)
+		}
+	}
+	return supply
+}
+
+// genesisIssuance returns the issuance record of the genesis block, minting
+// the allocated balances.
+func genesisIssuance(alloc GenesisAlloc) *types.Issuance {
+	supply := alloc.supply()
+	return &types.Issuance{
+		Reward:      new(big.Int),
+		UncleReward: new(big.Int),
+		Withdrawals: new(big.Int),
+		Burn:        new(big.Int),
+		Supply:      supply,
+	}
+}
+
+// blockIssuance computes the issuance record of a block on top of the record of
+// its parent: the rewards minted by the consensus engine and the withdrawals
+// credited by the consensus layer, minus the base fee burned by the
+// transactions.
+func blockIssuance(config *params.ChainConfig, engine consensus.Engine, chain consensus.ChainHeaderReader, block *types.Block, parent *types.Issuance) *types.Issuance {
+	issuance := &types.Issuance{
+		Reward:      new(big.Int),
+		UncleReward: new(big.Int),
+		Withdrawals: new(big.Int),
+		Burn:        new(big.Int),
+	}
+	if issuer, ok := engine.(consensus.Issuer); ok {
+		issuance.Reward, issuance.UncleReward = issuer.BlockRewards(chain, block.Header(), block.Uncles())
+	}
+	for _, w := range block.Withdrawals() {
+		amount := new(big.Int).SetUint64(w.Amount)
+		issuance.Withdrawals.Add(issuance.Withdrawals, amount.Mul(amount, big.NewInt(params.GWei)))
+	}
+	if config.IsLondon(block.Number()) && config.BaseFeeRecipient(block.Coinbase()) == nil {
+		issuance.Burn.Mul(block.BaseFee(), new(big.Int).SetUint64(block.GasUsed()))
+	}
+	issuance.Supply = new(big.Int).Add(parent.Supply, issuance.Issued())
+	return issuance
+}
+
+// writeIssuance stores the issuance record of a block whose parent is tracked.
+// The records are keyed by block hash, so the ones of blocks reorged out are
+// simply left unreferenced by the canonical chain.
+func (bc *BlockChain) writeIssuance(db ethdb.KeyValueWriter, block *types.Block) {
+	parent := rawdb.ReadIssuance(bc.db, block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return // Tracking not enabled, the issuance needs a backfill
+	}
+	rawdb.WriteIssuance(db, block.Hash(), block.NumberU64(), blockIssuance(bc.chainConfig, bc.engine, bc, block, parent))
+}
+
+// writeIssuanceChain stores the issuance records of a contiguous chain of blocks
+// whose first parent is tracked. The records are chained in memory, as the ones
+// written to a batch can't be read back before it's flushed.
+func (bc *BlockChain) writeIssuanceChain(db ethdb.KeyValueWriter, blocks types.Blocks) {
+	parent := rawdb.ReadIssuance(bc.db, blocks[0].ParentHash(), blocks[0].NumberU64()-1)
+	if parent == nil {
+		return // Tracking not enabled, the issuance needs a backfill
+	}
+	for _, block := range blocks {
+		parent = blockIssuance(bc.chainConfig, bc.engine, bc, block, parent)
+		rawdb.WriteIssuance(db, block.Hash(), block.NumberU64(), parent)
+	}
+}
+
+// GetIssuance retrieves the issuance record of a block from the database, or
+// nil if it is not tracked.
+func (bc *BlockChain) GetIssuance(hash common.Hash, number uint64) *types.Issuance {
+	return rawdb.ReadIssuance(bc.db, hash, number)
+}
+
+// BackfillIssuance computes the issuance records of the canonical chain from
+// the genesis block to the current head, enabling the supply tracking of chains
+// synced before it was introduced. The genesis allocation is read from the
+// database.
+func (bc *BlockChain) BackfillIssuance() error {
+	genesis, err := ReadGenesis(bc.db)
+	if err != nil {
+		return fmt.Errorf("failed to load genesis allocation: %w", err)
+	}
+	var (
+		head   = bc.CurrentBlock().Number.Uint64()
+		parent = genesisIssuance(genesis.Alloc)
+		batch  = bc.db.NewBatch()
+
+		start  = time.Now()
+		logged = time.Now()
+	)
+	rawdb.WriteIssuance(batch, bc.genesisBlock.Hash(), 0, parent)
+
+	for number := uint64(1); number <= head; number++ {
+		block := bc.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("missing canonical block #%d", number)
+		}
+		parent = blockIssuance(bc.chainConfig, bc.engine, bc, block, parent)
+		rawdb.WriteIssuance(batch, block.Hash(), number, parent)
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Backfilling issuance records", "number", number, "head", head, "supply", parent.Supply, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Backfilled issuance records", "blocks", head+1, "supply", parent.Supply, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the issuance records track the supply of the canonical chain
+// across reorgs, rewinds and backfills.
+func TestIssuance(t *testing.T) {
+	var (
+		aa     = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		engine = ethash.NewFaker()
+
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}, aa: {Balance: big.NewInt(1)}},
+		}
+		signer   = types.LatestSigner(gspec.Config)
+		accounts = []common.Address{addr, aa}
+	)
+	// Generate a canonical chain and a longer fork, both burning base fees
+	generate := func(n int, seed byte) []*types.Block {
+		_, blocks, _ := GenerateChainWithGenesis(gspec, engine, n, func(i int, b *BlockGen) {
+			coinbase := common.Address{seed, byte(i + 1)}
+			b.SetCoinbase(coinbase)
+			accounts = append(accounts, coinbase)
+
+			b.AddTx(types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+				ChainID:   gspec.Config.ChainID,
+				Nonce:     b.TxNonce(addr),
+				To:        &aa,
+				Value:     big.NewInt(int64(seed)),
+				Gas:       params.TxGas,
+				GasFeeCap: newGwei(5),
+				GasTipCap: big.NewInt(2),
+			}))
+		})
+		return blocks
+	}
+	canon, fork := generate(4, 1), generate(6, 2)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// checkSupply verifies that the records of the canonical chain match the
+	// balances of its state.
+	checkSupply := func(head uint64) []*types.Issuance {
+		var records []*types.Issuance
+		for number := uint64(0); number <= head; number++ {
+			block := chain.GetBlockByNumber(number)
+			issuance := chain.GetIssuance(block.Hash(), number)
+			if issuance == nil {
+				t.Fatalf("block %d: missing issuance record", number)
+			}
+			state, err := chain.StateAt(block.Root())
+			if err != nil {
+				t.Fatalf("block %d: missing state: %v", number, err)
+			}
+			balances := new(big.Int)
+			for _, account := range accounts {
+				balances.Add(balances, state.GetBalance(account))
+			}
+			if issuance.Supply.Cmp(balances) != 0 {
+				t.Errorf("block %d: supply mismatch: have %v, want %v", number, issuance.Supply, balances)
+			}
+			if number > 0 {
+				burn := new(big.Int).Mul(block.BaseFee(), new(big.Int).SetUint64(block.GasUsed()))
+				if issuance.Burn.Cmp(burn) != 0 {
+					t.Errorf("block %d: burn mismatch: have %v, want %v", number, issuance.Burn, burn)
+				}
+				if issuance.Reward.Cmp(ethash.ConstantinopleBlockReward) != 0 {
+					t.Errorf("block %d: reward mismatch: have %v, want %v", number, issuance.Reward, ethash.ConstantinopleBlockReward)
+				}
+			}
+			records = append(records, issuance)
+		}
+		return records
+	}
+	if n, err := chain.InsertChain(canon); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	checkSupply(4)
+
+	// Reorg to the longer fork and check the records follow
+	if n, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("block %d: failed to insert fork: %v", n, err)
+	}
+	if head := chain.CurrentBlock().Hash(); head != fork[len(fork)-1].Hash() {
+		t.Fatalf("chain not reorged to the fork")
+	}
+	records := checkSupply(6)
+
+	// Drop the records and backfill them
+	for number := uint64(0); number <= 6; number++ {
+		rawdb.DeleteIssuance(chain.db, chain.GetCanonicalHash(number), number)
+	}
+	if err := chain.BackfillIssuance(); err != nil {
+		t.Fatalf("failed to backfill issuance: %v", err)
+	}
+	for i, record := range checkSupply(6) {
+		if record.Supply.Cmp(records[i].Supply) != 0 || record.Issued().Cmp(records[i].Issued()) != 0 {
+			t.Errorf("block %d: backfilled record mismatch: have %v, want %v", i, record, records[i])
+		}
+	}
+	// Rewind the chain and check the records of the dropped blocks are deleted
+	if err := chain.SetHead(3); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	for _, block := range fork[3:] {
+		if rawdb.ReadIssuance(chain.db, block.Hash(), block.NumberU64()) != nil {
+			t.Errorf("block %d: issuance record not deleted", block.NumberU64())
+		}
+	}
+	checkSupply(3)
+}
+
+// Tests that the issuance records are tracked for the blocks imported along
+// with their receipts, both into the ancient store and the live database.
+func TestIssuanceReceiptChain(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, engine, 8, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{byte(i + 1)})
+		b.AddTx(types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   gspec.Config.ChainID,
+			Nonce:     b.TxNonce(addr),
+			To:        &common.Address{0xaa},
+			Gas:       params.TxGas,
+			GasFeeCap: newGwei(5),
+			GasTipCap: big.NewInt(2),
+		}))
+	})
+	archive, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create archive chain: %v", err)
+	}
+	defer archive.Stop()
+	if n, err := archive.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create freezer database: %v", err)
+	}
+	defer db.Close()
+	chain, err := NewBlockChain(db, nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("header %d: failed to insert: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts, uint64(len(blocks)/2)); err != nil {
+		t.Fatalf("block %d: failed to insert receipts: %v", n, err)
+	}
+	for _, block := range blocks {
+		want := archive.GetIssuance(block.Hash(), block.NumberU64())
+		have := chain.GetIssuance(block.Hash(), block.NumberU64())
+		if have == nil {
+			t.Fatalf("block %d: missing issuance record", block.NumberU64())
+		}
+		if have.Supply.Cmp(want.Supply) != 0 || have.Burn.Cmp(want.Burn) != 0 {
+			t.Errorf("block %d: issuance mismatch: have %v, want %v", block.NumberU64(), have, want)
+		}
+	}
+}
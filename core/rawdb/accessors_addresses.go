@@ -0,0 +1,124 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The roles an address can take in a transaction, combined in the index.
+const (
+	AddressRoleSender    uint8 = 1 << iota // Address signed the transaction
+	AddressRoleRecipient                   // Address is the recipient of the transaction
+	AddressRoleCreation                    // Address is the contract created by the transaction
+	AddressRoleInternal                    // Address was called during the execution of the transaction
+)
+
+// AddressIndexEntry is an entry of the address index, recording the roles an
+// address took in a transaction of a block.
+type AddressIndexEntry struct {
+	Address common.Address
+	Index   uint32
+	Roles   uint8
+}
+
+// ReadAddressIndexEntries retrieves the address index entries of a block, or
+// nil if the block is not indexed.
+func ReadAddressIndexEntries(db ethdb.KeyValueReader, number uint64) []AddressIndexEntry {
+	data, _ := db.Get(addressBlockKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	var entries []AddressIndexEntry
+	if err := rlp.Decode(bytes.NewReader(data), &entries); err != nil {
+		log.Error("Invalid address index entries RLP", "number", number, "err", err)
+		return nil
+	}
+	return entries
+}
+
+// WriteAddressIndexEntries stores the address index entries of a block into
+// the database, indexing the transactions of each address.
+func WriteAddressIndexEntries(db ethdb.KeyValueWriter, number uint64, entries []AddressIndexEntry) {
+	for _, entry := range entries {
+		if err := db.Put(addressTxKey(entry.Address, number, entry.Index), []byte{entry.Roles}); err != nil {
+			log.Crit("Failed to store address transaction", "err", err)
+		}
+	}
+	data, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		log.Crit("Failed to RLP encode address index entries", "err", err)
+	}
+	if err := db.Put(addressBlockKey(number), data); err != nil {
+		log.Crit("Failed to store address index entries", "err", err)
+	}
+}
+
+// DeleteAddressIndexEntries removes the given address index entries of a block.
+func DeleteAddressIndexEntries(db ethdb.KeyValueWriter, number uint64, entries []AddressIndexEntry) {
+	for _, entry := range entries {
+		if err := db.Delete(addressTxKey(entry.Address, number, entry.Index)); err != nil {
+			log.Crit("Failed to delete address transaction", "err", err)
+		}
+	}
+	if err := db.Delete(addressBlockKey(number)); err != nil {
+		log.Crit("Failed to delete address index entries", "err", err)
+	}
+}
+
+// IterateAddressTxs iterates over the indexed transactions of an address from
+// the newest to the oldest, starting at the given block number and transaction
+// index, until the callback returns false.
+func IterateAddressTxs(db ethdb.Iteratee, address common.Address, number uint64, index uint32, fn func(number uint64, index uint32, roles uint8) bool) {
+	prefix := append(append([]byte{}, addressTxPrefix...), address.Bytes()...)
+
+	it := db.NewIterator(prefix, addressTxPosition(number, index))
+	defer it.Release()
+
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		if len(key) != len(prefix)+12 || len(value) != 1 {
+			continue
+		}
+		number := ^binary.BigEndian.Uint64(key[len(prefix):])
+		index := ^binary.BigEndian.Uint32(key[len(prefix)+8:])
+		if !fn(number, index, value[0]) {
+			return
+		}
+	}
+}
+
+// ReadAddressIndexTail retrieves the number of the oldest block whose addresses
+// are indexed, or nil if no block was unindexed yet.
+func ReadAddressIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(addressIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteAddressIndexTail stores the number of the oldest block whose addresses
+// are indexed into the database.
+func WriteAddressIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(addressIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the address index tail", "err", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package rawdb
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadIssuance retrieves the issuance record of a block, or nil if it was not
+// tracked.
+func ReadIssuance(db ethdb.KeyValueReader, hash common.Hash, number uint64) *types.Issuance {
+	data, _ := db.Get(blockIssuanceKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	issuance := new(types.Issuance)
+	if err := rlp.Decode(bytes.NewReader(data), issuance); err != nil {
+		log.Error("Invalid block issuance RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return issuance
+}
+
+// WriteIssuance stores the issuance record of a block into the database.
+func WriteIssuance(db ethdb.KeyValueWriter, hash common.Hash, number uint64, issuance *types.Issuance) {
+	data, err := rlp.EncodeToBytes(issuance)
+	if err != nil {
+		log.Crit("Failed to RLP encode block issuance", "err", err)
+	}
+	if err := db.Put(blockIssuanceKey(number, hash), data); err != nil {
+		log.Crit("Failed to store block issuance", "err", err)
+	}
+}
+
+// DeleteIssuance removes the issuance record of a block.
+func DeleteIssuance(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockIssuanceKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block issuance", "err", err)
+	}
+}
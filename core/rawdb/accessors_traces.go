@@ -0,0 +1,184 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/golang/snappy"
+)
+
+// ReadBlockTraces retrieves the stored traces of the transactions of a block,
+// or nil if the block was not traced.
+func ReadBlockTraces(db ethdb.KeyValueReader, number uint64, hash common.Hash) []byte {
+	data, _ := db.Get(blockTracesKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	traces, err := snappy.Decode(nil, data)
+	if err != nil {
+		log.Error("Invalid block traces", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	return traces
+}
+
+// WriteBlockTraces compresses and stores the traces of the transactions of a
+// block into the database.
+func WriteBlockTraces(db ethdb.KeyValueWriter, number uint64, hash common.Hash, traces []byte) {
+	if err := db.Put(blockTracesKey(number, hash), snappy.Encode(nil, traces)); err != nil {
+		log.Crit("Failed to store block traces", "err", err)
+	}
+}
+
+// DeleteBlockTraces removes the stored traces of a block.
+func DeleteBlockTraces(db ethdb.KeyValueWriter, number uint64, hash common.Hash) {
+	if err := db.Delete(blockTracesKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block traces", "err", err)
+	}
+}
+
+// ReadBlockTracesHashes retrieves the hashes of all the blocks with the given
+// number whose traces are stored, both canonical and reorged forks included.
+func ReadBlockTracesHashes(db ethdb.Iteratee, number uint64) []common.Hash {
+	prefix := append(append([]byte{}, blockTracesPrefix...), encodeBlockNumber(number)...)
+
+	var hashes []common.Hash
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if key := it.Key(); len(key) == len(prefix)+common.HashLength {
+			hashes = append(hashes, common.BytesToHash(key[len(prefix):]))
+		}
+	}
+	return hashes
+}
+
+// ReadBlockTracesHashesInRange retrieves the numbers and hashes of all the blocks
+// in the given range whose traces are stored, both canonical and reorged forks
+// included. This method considers both limits to be _inclusive_.
+func ReadBlockTracesHashesInRange(db ethdb.Iteratee, first, last uint64) []*NumberHash {
+	var (
+		keyLength = len(blockTracesPrefix) + 8 + common.HashLength
+		hashes    []*NumberHash
+		it        = db.NewIterator(blockTracesPrefix, encodeBlockNumber(first))
+	)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != keyLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(blockTracesPrefix):])
+		if number > last {
+			break
+		}
+		hashes = append(hashes, &NumberHash{number, common.BytesToHash(key[len(key)-common.HashLength:])})
+	}
+	return hashes
+}
+
+// DeleteTraceStore removes all the stored block traces, along with the progress
+// of the indexer producing them and the metadata of the store.
+func DeleteTraceStore(db ethdb.KeyValueStore) {
+	batch := db.NewBatch()
+	drop := func(prefix []byte, length int) {
+		it := db.NewIterator(prefix, nil)
+		defer it.Release()
+
+		for it.Next() {
+			// Other metadata keys share the prefix of the traces
+			if length > 0 && len(it.Key()) != length {
+				continue
+			}
+			if err := batch.Delete(it.Key()); err != nil {
+				log.Crit("Failed to delete block traces", "err", err)
+			}
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					log.Crit("Failed to delete block traces", "err", err)
+				}
+				batch.Reset()
+			}
+		}
+	}
+	drop(blockTracesPrefix, len(blockTracesPrefix)+8+common.HashLength)
+	drop(TraceIndexPrefix, 0)
+
+	for _, key := range [][]byte{traceStoreConfigKey, traceStoreTailKey, traceFreezerOffsetKey} {
+		if err := batch.Delete(key); err != nil {
+			log.Crit("Failed to delete trace store metadata", "err", err)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete block traces", "err", err)
+	}
+}
+
+// ReadTraceStoreConfig retrieves the encoded tracer and configuration of the
+// stored block traces.
+func ReadTraceStoreConfig(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(traceStoreConfigKey)
+	return data
+}
+
+// WriteTraceStoreConfig stores the encoded tracer and configuration of the
+// stored block traces into the database.
+func WriteTraceStoreConfig(db ethdb.KeyValueWriter, config []byte) {
+	if err := db.Put(traceStoreConfigKey, config); err != nil {
+		log.Crit("Failed to store the trace store config", "err", err)
+	}
+}
+
+// ReadTraceStoreTail retrieves the number of the oldest block whose traces are
+// stored, or nil if no block was dropped from the store yet.
+func ReadTraceStoreTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(traceStoreTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTraceStoreTail stores the number of the oldest block whose traces are
+// stored into the database.
+func WriteTraceStoreTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(traceStoreTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the trace store tail", "err", err)
+	}
+}
+
+// ReadTraceFreezerOffset retrieves the number of the block the first item of
+// the trace freezer belongs to, or nil if nothing was frozen yet.
+func ReadTraceFreezerOffset(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(traceFreezerOffsetKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTraceFreezerOffset stores the number of the block the first item of the
+// trace freezer belongs to into the database.
+func WriteTraceFreezerOffset(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(traceFreezerOffsetKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the trace freezer offset", "err", err)
+	}
+}
@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"encoding/binary"
 	"fmt"
 	"sync"
 
@@ -261,3 +262,96 @@ func DeleteTrieNode(db ethdb.KeyValueWriter, owner common.Hash, path []byte, has
 		panic(fmt.Sprintf("Unknown scheme %v", scheme))
 	}
 }
+
+// ReadStateScheme reads the state scheme of the persistent state, or returns
+// the empty string if no state has been stored yet. The path-based scheme is
+// detected by the presence of the persistent state id, the hash-based one by
+// the state of the head block.
+func ReadStateScheme(db ethdb.Reader) string {
+	if ReadPersistentStateID(db) != 0 || len(ReadAccountTrieNodeBlob(db, nil)) != 0 {
+		return PathScheme
+	}
+	if hash := ReadHeadBlockHash(db); hash != (common.Hash{}) {
+		if number := ReadHeaderNumber(db, hash); number != nil {
+			if header := ReadHeader(db, hash, *number); header != nil && HasLegacyTrieNode(db, header.Root) {
+				return HashScheme
+			}
+		}
+	}
+	return ""
+}
+
+// ReadAccountTrieNodeBlob retrieves the account trie node with the specified
+// node path, without hashing it.
+func ReadAccountTrieNodeBlob(db ethdb.KeyValueReader, path []byte) []byte {
+	data, _ := db.Get(accountTrieNodeKey(path))
+	return data
+}
+
+// ReadStorageTrieNodeBlob retrieves the storage trie node with the specified
+// node path, without hashing it.
+func ReadStorageTrieNodeBlob(db ethdb.KeyValueReader, accountHash common.Hash, path []byte) []byte {
+	data, _ := db.Get(storageTrieNodeKey(accountHash, path))
+	return data
+}
+
+// ReadPersistentStateID retrieves the id of the persistent state of the
+// path-based scheme, zero if no state transition has been flushed yet.
+func ReadPersistentStateID(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(persistentStateIDKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WritePersistentStateID stores the id of the persistent state of the
+// path-based scheme.
+func WritePersistentStateID(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Put(persistentStateIDKey, encodeBlockNumber(id)); err != nil {
+		log.Crit("Failed to store the persistent state id", "err", err)
+	}
+}
+
+// ReadStateID retrieves the id of the state with the given root, or nil if the
+// state is not tracked.
+func ReadStateID(db ethdb.KeyValueReader, root common.Hash) *uint64 {
+	data, err := db.Get(stateIDKey(root))
+	if err != nil || len(data) != 8 {
+		return nil
+	}
+	id := binary.BigEndian.Uint64(data)
+	return &id
+}
+
+// WriteStateID stores the id of the state with the given root.
+func WriteStateID(db ethdb.KeyValueWriter, root common.Hash, id uint64) {
+	if err := db.Put(stateIDKey(root), encodeBlockNumber(id)); err != nil {
+		log.Crit("Failed to store state id", "err", err)
+	}
+}
+
+// DeleteStateID removes the id of the state with the given root.
+func DeleteStateID(db ethdb.KeyValueWriter, root common.Hash) {
+	if err := db.Delete(stateIDKey(root)); err != nil {
+		log.Crit("Failed to delete state id", "err", err)
+	}
+}
+
+// ReadStateHistory retrieves the reverse diff turning the state with the given
+// id into its parent. The first state transition has the id 1.
+func ReadStateHistory(db ethdb.AncientReaderOp, id uint64) []byte {
+	blob, err := db.Ancient(StateFreezerHistoryTable, id-1)
+	if err != nil {
+		return nil
+	}
+	return blob
+}
+
+// WriteStateHistory stores the reverse diff of the state with the given id.
+func WriteStateHistory(db ethdb.AncientWriter, id uint64, blob []byte) error {
+	_, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		return op.AppendRaw(StateFreezerHistoryTable, id-1, blob)
+	})
+	return err
+}
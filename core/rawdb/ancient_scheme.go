@@ -40,10 +40,31 @@ var chainFreezerNoSnappy = map[string]bool{
 	ChainFreezerDifficultyTable: true,
 }
 
+// TraceFreezerTable indicates the name of the freezer block traces table.
+const TraceFreezerTable = "traces"
+
+// traceFreezerNoSnappy configures whether compression is disabled for the trace
+// ancient-tables. Traces are JSON and compress well.
+var traceFreezerNoSnappy = map[string]bool{
+	TraceFreezerTable: false,
+}
+
+// StateFreezerHistoryTable indicates the name of the freezer table of the
+// reverse state diffs of the path-based scheme.
+const StateFreezerHistoryTable = "history"
+
+// stateFreezerNoSnappy configures whether compression is disabled for the state
+// ancient-tables.
+var stateFreezerNoSnappy = map[string]bool{
+	StateFreezerHistoryTable: false,
+}
+
 // The list of identifiers of ancient stores.
 var (
-	chainFreezerName = "chain" // the folder name of chain segment ancient store.
+	chainFreezerName = "chain"  // the folder name of chain segment ancient store.
+	traceFreezerName = "traces" // the folder name of block traces ancient store.
+	stateFreezerName = "state"  // the folder name of state history ancient store.
 )
 
 // freezers the collections of all builtin freezers.
-var freezers = []string{chainFreezerName}
+var freezers = []string{chainFreezerName, traceFreezerName, stateFreezerName}
@@ -14,6 +14,7 @@ package rawdb
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -78,6 +79,40 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 			info.tail = tail
 			infos = append(infos, info)
 
+		case traceFreezerName:
+			// The trace store is optional, only inspect it if it was created.
+			ancient, err := db.AncientDatadir()
+			if err != nil || !common.FileExist(filepath.Join(ancient, traceFreezerName)) {
+				continue
+			}
+			f, err := NewTraceFreezer(ancient, true)
+			if err != nil {
+				return nil, err
+			}
+			info, err := inspectFreezer(freezer, traceFreezerNoSnappy, f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+
+		case stateFreezerName:
+			// The state history only exists with the path-based scheme.
+			ancient, err := db.AncientDatadir()
+			if err != nil || !common.FileExist(filepath.Join(ancient, stateFreezerName)) {
+				continue
+			}
+			f, err := NewStateFreezer(ancient, true)
+			if err != nil {
+				return nil, err
+			}
+			info, err := inspectFreezer(freezer, stateFreezerNoSnappy, f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+
 		default:
 			return nil, fmt.Errorf("unknown freezer, supported ones: %v", freezers)
 		}
@@ -85,6 +120,30 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 	return infos, nil
 }
 
+// inspectFreezer collects the basic information of a standalone freezer.
+func inspectFreezer(name string, tables map[string]bool, f ethdb.AncientReader) (freezerInfo, error) {
+	info := freezerInfo{name: name}
+	for table := range tables {
+		size, err := f.AncientSize(table)
+		if err != nil {
+			return info, err
+		}
+		info.sizes = append(info.sizes, tableSize{name: table, size: common.StorageSize(size)})
+	}
+	ancients, err := f.Ancients()
+	if err != nil {
+		return info, err
+	}
+	info.head = ancients - 1
+
+	tail, err := f.Tail()
+	if err != nil {
+		return info, err
+	}
+	info.tail = tail
+	return info, nil
+}
+
 // InspectFreezerTable dumps out the index of a specific freezer table. The passed
 // ancient indicates the path of root ancient directory where the chain freezer can
 // be opened. Start and end specify the range for dumping out indexes.
@@ -97,6 +156,10 @@ func InspectFreezerTable(ancient string, freezerName string, tableName string, s
 	switch freezerName {
 	case chainFreezerName:
 		path, tables = resolveChainFreezerDir(ancient), chainFreezerNoSnappy
+	case traceFreezerName:
+		path, tables = filepath.Join(ancient, traceFreezerName), traceFreezerNoSnappy
+	case stateFreezerName:
+		path, tables = filepath.Join(ancient, stateFreezerName), stateFreezerNoSnappy
 	default:
 		return fmt.Errorf("unknown freezer, supported ones: %v", freezers)
 	}
@@ -78,6 +78,18 @@ func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer,
 	return NewFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy)
 }
 
+// NewTraceFreezer is a small utility method around NewResettableFreezer that
+// opens the store of the frozen block traces in the given root ancient directory.
+func NewTraceFreezer(ancient string, readonly bool) (*ResettableFreezer, error) {
+	return NewResettableFreezer(filepath.Join(ancient, traceFreezerName), "eth/db/traces/", readonly, freezerTableSize, traceFreezerNoSnappy)
+}
+
+// NewStateFreezer is a small utility method around NewResettableFreezer that
+// opens the store of the state history in the given root ancient directory.
+func NewStateFreezer(ancient string, readonly bool) (*ResettableFreezer, error) {
+	return NewResettableFreezer(filepath.Join(ancient, stateFreezerName), "eth/db/state/", readonly, freezerTableSize, stateFreezerNoSnappy)
+}
+
 // NewFreezer creates a freezer instance for maintaining immutable ordered
 // data according to the given parameters.
 //
@@ -68,6 +68,22 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// addressIndexTailKey tracks the oldest block whose addresses have been indexed.
+	addressIndexTailKey = []byte("AddressIndexTail")
+
+	// traceStoreConfigKey tracks the tracer and configuration of the stored block traces.
+	traceStoreConfigKey = []byte("TraceStoreConfig")
+
+	// traceStoreTailKey tracks the oldest block whose traces have been stored.
+	traceStoreTailKey = []byte("TraceStoreTail")
+
+	// traceFreezerOffsetKey tracks the block number of the first item of the trace freezer.
+	traceFreezerOffsetKey = []byte("TraceFreezerOffset")
+
+	// persistentStateIDKey tracks the id of the latest state flushed into disk
+	// by the path-based scheme.
+	persistentStateIDKey = []byte("LastStateID")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
@@ -88,13 +104,18 @@ var (
 
 	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
+	blockIssuancePrefix = []byte("I") // blockIssuancePrefix + num (uint64 big endian) + hash -> block issuance
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	addressTxPrefix       = []byte("X") // addressTxPrefix + address + ^num (uint64 big endian) + ^index (uint32 big endian) -> address roles
+	addressBlockPrefix    = []byte("x") // addressBlockPrefix + num (uint64 big endian) -> block address index entries
+	blockTracesPrefix     = []byte("T") // blockTracesPrefix + num (uint64 big endian) + hash -> compressed block traces
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
 	skeletonHeaderPrefix  = []byte("S") // skeletonHeaderPrefix + num (uint64 big endian) -> header
+	stateIDPrefix         = []byte("L") // stateIDPrefix + state root -> state id
 
 	// Path-based trie node scheme.
 	trieNodeAccountPrefix = []byte("A") // trieNodeAccountPrefix + hexPath -> trie node
@@ -107,6 +128,12 @@ var (
 	// BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 	BloomBitsIndexPrefix = []byte("iB")
 
+	// AddressIndexPrefix is the data table of a chain indexer to track its progress
+	AddressIndexPrefix = []byte("iX")
+
+	// TraceIndexPrefix is the data table of a chain indexer to track its progress
+	TraceIndexPrefix = []byte("iT")
+
 	ChtPrefix           = []byte("chtRootV2-") // ChtPrefix + chtNum (uint64 big endian) -> trie root hash
 	ChtTablePrefix      = []byte("cht-")
 	ChtIndexTablePrefix = []byte("chtIndexV2-")
@@ -171,6 +198,11 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// blockIssuanceKey = blockIssuancePrefix + num (uint64 big endian) + hash
+func blockIssuanceKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockIssuancePrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -201,11 +233,44 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// addressTxKey = addressTxPrefix + address + ^num (uint64 big endian) + ^index (uint32 big endian)
+func addressTxKey(address common.Address, number uint64, index uint32) []byte {
+	key := make([]byte, len(addressTxPrefix)+common.AddressLength+12)
+	copy(key, addressTxPrefix)
+	copy(key[len(addressTxPrefix):], address.Bytes())
+	copy(key[len(addressTxPrefix)+common.AddressLength:], addressTxPosition(number, index))
+	return key
+}
+
+// addressTxPosition = ^num (uint64 big endian) + ^index (uint32 big endian),
+// ordering the transactions of an address from the newest to the oldest.
+func addressTxPosition(number uint64, index uint32) []byte {
+	pos := make([]byte, 12)
+	binary.BigEndian.PutUint64(pos, ^number)
+	binary.BigEndian.PutUint32(pos[8:], ^index)
+	return pos
+}
+
+// addressBlockKey = addressBlockPrefix + num (uint64 big endian)
+func addressBlockKey(number uint64) []byte {
+	return append(addressBlockPrefix, encodeBlockNumber(number)...)
+}
+
+// blockTracesKey = blockTracesPrefix + num (uint64 big endian) + hash
+func blockTracesKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockTracesPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // skeletonHeaderKey = skeletonHeaderPrefix + num (uint64 big endian)
 func skeletonHeaderKey(number uint64) []byte {
 	return append(skeletonHeaderPrefix, encodeBlockNumber(number)...)
 }
 
+// stateIDKey = stateIDPrefix + root (32 bytes)
+func stateIDKey(root common.Hash) []byte {
+	return append(stateIDPrefix, root.Bytes()...)
+}
+
 // preimageKey = PreimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(PreimagePrefix, hash.Bytes()...)
@@ -377,6 +377,20 @@ func (s *StateDB) StorageTrie(addr common.Address) (Trie, error) {
 	return cpy.getTrie(s.db)
 }
 
+// GetStorageRoot returns the root of the storage trie of an account, including
+// any pending changes. It is the empty root for non-existent accounts.
+func (s *StateDB) GetStorageRoot(addr common.Address) common.Hash {
+	trie, err := s.StorageTrie(addr)
+	if err != nil {
+		s.setError(err)
+		return common.Hash{}
+	}
+	if trie == nil {
+		return types.EmptyRootHash
+	}
+	return trie.Hash()
+}
+
 func (s *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
@@ -1067,7 +1081,7 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 	}
 	if root != origin {
 		start := time.Now()
-		if err := s.db.TrieDB().Update(nodes); err != nil {
+		if err := s.db.TrieDB().UpdateState(root, origin, nodes); err != nil {
 			return common.Hash{}, err
 		}
 		s.originalRoot = root
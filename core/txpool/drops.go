@@ -0,0 +1,192 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package txpool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// DropReason is the reason a transaction was dropped from the pool.
+type DropReason string
+
+const (
+	// DropReplaced is the reason of transactions replaced by another one of the
+	// same sender and nonce, or losing to an already pooled one.
+	DropReplaced DropReason = "replaced"
+
+	// DropUnderpriced is the reason of transactions evicted by better priced
+	// ones from a full pool, or priced below a raised minimum.
+	DropUnderpriced DropReason = "underpriced"
+
+	// DropNonceTooLow is the reason of transactions whose nonce was used up,
+	// usually by their own inclusion in a block.
+	DropNonceTooLow DropReason = "nonce too low"
+
+	// DropUnpayable is the reason of transactions whose sender can no longer
+	// afford them, or exceeding the block gas limit.
+	DropUnpayable DropReason = "unpayable"
+
+	// DropOverflow is the reason of transactions evicted to keep the pool or
+	// their account within the configured limits.
+	DropOverflow DropReason = "overflow"
+
+	// DropExpired is the reason of queued transactions older than the lifetime
+	// of the pool.
+	DropExpired DropReason = "expired"
+
+	// DropConditions is the reason of conditional transactions whose conditions
+	// failed.
+	DropConditions DropReason = "conditions failed"
+)
+
+// DroppedTx describes a transaction dropped from the pool.
+type DroppedTx struct {
+	Hash        common.Hash
+	Sender      common.Address
+	Nonce       uint64
+	Reason      DropReason
+	Replacement *common.Hash // Transaction replacing the dropped one, if known
+	Time        time.Time
+}
+
+// DropTxsEvent is posted when transactions are dropped from the pool.
+type DropTxsEvent struct{ Txs []*DroppedTx }
+
+// dropMeters counts the dropped transactions by reason.
+var dropMeters = make(map[DropReason]metrics.Meter)
+
+func init() {
+	for _, reason := range []DropReason{DropReplaced, DropUnderpriced, DropNonceTooLow, DropUnpayable, DropOverflow, DropExpired, DropConditions} {
+		dropMeters[reason] = metrics.NewRegisteredMeter("txpool/drop/"+string(reason), nil)
+	}
+}
+
+// dropHistory is a bounded ring buffer of the last dropped transactions,
+// indexed by hash.
+type dropHistory struct {
+	lock  sync.RWMutex
+	items []*DroppedTx
+	next  int // Position of the next item, and of the oldest once full
+	index map[common.Hash]*DroppedTx
+}
+
+// newDropHistory creates a history retaining the given number of drops.
+func newDropHistory(limit uint64) *dropHistory {
+	return &dropHistory{
+		items: make([]*DroppedTx, 0, limit),
+		index: make(map[common.Hash]*DroppedTx),
+	}
+}
+
+// add appends drops to the history, evicting the oldest ones beyond its limit.
+func (h *dropHistory) add(drops []*DroppedTx) {
+	if cap(h.items) == 0 {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, drop := range drops {
+		if len(h.items) < cap(h.items) {
+			h.items = append(h.items, drop)
+		} else {
+			if old := h.items[h.next]; h.index[old.Hash] == old {
+				delete(h.index, old.Hash)
+			}
+			h.items[h.next] = drop
+			h.next = (h.next + 1) % len(h.items)
+		}
+		h.index[drop.Hash] = drop
+	}
+}
+
+// get retrieves the last drop of the given transaction, if it is remembered.
+func (h *dropHistory) get(hash common.Hash) *DroppedTx {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.index[hash]
+}
+
+// list returns the remembered drops, oldest first.
+func (h *dropHistory) list() []*DroppedTx {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	drops := make([]*DroppedTx, 0, len(h.items))
+	drops = append(drops, h.items[h.next:]...)
+	return append(drops, h.items[:h.next]...)
+}
+
+// recordDrop notes that a transaction was dropped from the pool, to be announced
+// after the pool lock is released.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordDrop(tx *types.Transaction, reason DropReason, replacement *types.Transaction) {
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	drop := &DroppedTx{
+		Hash:   tx.Hash(),
+		Sender: from,
+		Nonce:  tx.Nonce(),
+		Reason: reason,
+		Time:   time.Now(),
+	}
+	if replacement != nil {
+		hash := replacement.Hash()
+		drop.Replacement = &hash
+	}
+	pool.drops = append(pool.drops, drop)
+	dropMeters[reason].Mark(1)
+}
+
+// takeDrops returns the drops recorded since the last call.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) takeDrops() []*DroppedTx {
+	drops := pool.drops
+	pool.drops = nil
+	return drops
+}
+
+// announceDrops adds drops to the history and sends them to the subscribers.
+// It must be called without holding the pool lock.
+func (pool *TxPool) announceDrops(drops []*DroppedTx) {
+	if len(drops) == 0 {
+		return
+	}
+	pool.dropHistory.add(drops)
+	pool.dropFeed.Send(DropTxsEvent{Txs: drops})
+}
+
+// SubscribeDropTxsEvent registers a subscription of DropTxsEvent and starts
+// sending event to the given channel.
+func (pool *TxPool) SubscribeDropTxsEvent(ch chan<- DropTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// Dropped returns the remembered history of dropped transactions, oldest first.
+func (pool *TxPool) Dropped() []*DroppedTx {
+	return pool.dropHistory.list()
+}
+
+// LastDrop returns the last drop of the given transaction, or nil if it was
+// not dropped recently.
+func (pool *TxPool) LastDrop(hash common.Hash) *DroppedTx {
+	return pool.dropHistory.get(hash)
+}
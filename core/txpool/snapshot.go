@@ -0,0 +1,166 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package txpool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// snapshot is an on-disk dump of all the transactions of the pool, written
+// periodically and on shutdown to refill the pool after a node restart. Unlike
+// the journal it is not appended to, but regenerated whole. Local transactions
+// are only included if they are not journaled.
+type snapshot struct {
+	path  string // Filesystem path to store the transactions at
+	limit uint64 // Maximum size of the snapshot in bytes
+}
+
+// newTxSnapshot creates a pool snapshot stored at the given path.
+func newTxSnapshot(path string, limit uint64) *snapshot {
+	return &snapshot{
+		path:  path,
+		limit: limit,
+	}
+}
+
+// load parses the pool snapshot from disk, injecting its transactions into the
+// pool through the given method, which is expected to re-validate them.
+func (snap *snapshot) load(add func([]*types.Transaction) []error) error {
+	input, err := os.Open(snap.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Skip the parsing if the snapshot file doesn't exist at all
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// Refuse snapshots larger than the limit, they were not written by us
+	if info, err := input.Stat(); err != nil {
+		return err
+	} else if snap.limit > 0 && uint64(info.Size()) > snap.limit {
+		return fmt.Errorf("snapshot too large: %d bytes > %d", info.Size(), snap.limit)
+	}
+	stream := rlp.NewStream(bufio.NewReader(input), 0)
+	total, known, dropped := 0, 0, 0
+
+	loadBatch := func(txs types.Transactions) {
+		for _, err := range add(txs) {
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrAlreadyKnown):
+				known++ // Restored from the journal already
+			default:
+				log.Trace("Failed to add snapshot transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	var (
+		failure error
+		batch   types.Transactions
+	)
+	for {
+		tx := new(types.Transaction)
+		if err = stream.Decode(tx); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			if batch.Len() > 0 {
+				loadBatch(batch)
+			}
+			break
+		}
+		total++
+
+		if batch = append(batch, tx); batch.Len() > 1024 {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	snapshotLoadMeter.Mark(int64(total - known - dropped))
+	snapshotDropMeter.Mark(int64(dropped))
+	log.Info("Loaded transaction pool snapshot", "transactions", total, "known", known, "dropped", dropped)
+
+	return failure
+}
+
+// save regenerates the pool snapshot from the given nonce ordered transaction
+// lists. Transactions exceeding the size limit are left out, along with the later
+// ones of the same list which would be gapped.
+func (snap *snapshot) save(txs []types.Transactions) error {
+	start := time.Now()
+
+	output, err := os.OpenFile(snap.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	var (
+		writer         = bufio.NewWriter(output)
+		size           uint64
+		saved, skipped int
+	)
+	for _, list := range txs {
+		for i, tx := range list {
+			// Conditions are not persisted, skip transactions that would
+			// otherwise be reloaded unconditionally
+			if tx.Conditions() != nil {
+				skipped += len(list) - i
+				break
+			}
+			blob, err := rlp.EncodeToBytes(tx)
+			if err != nil {
+				output.Close()
+				return err
+			}
+			if snap.limit > 0 && size+uint64(len(blob)) > snap.limit {
+				skipped += len(list) - i
+				break
+			}
+			if _, err := writer.Write(blob); err != nil {
+				output.Close()
+				return err
+			}
+			size += uint64(len(blob))
+			saved++
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		output.Close()
+		return err
+	}
+	if err := output.Close(); err != nil {
+		return err
+	}
+	// Replace the previous snapshot with the newly generated one
+	if err := os.Rename(snap.path+".new", snap.path); err != nil {
+		return err
+	}
+	snapshotTxsGauge.Update(int64(saved))
+	snapshotSizeGauge.Update(int64(size))
+	snapshotTimer.UpdateSince(start)
+
+	log.Debug("Regenerated transaction pool snapshot", "transactions", saved, "skipped", skipped, "size", size, "elapsed", time.Since(start))
+	return nil
+}
@@ -91,6 +91,10 @@ var (
 	// ErrOverdraft is returned if a transaction would cause the senders balance to go negative
 	// thus invalidating a potential large number of transactions.
 	ErrOverdraft = errors.New("transaction would cause overdraft")
+
+	// ErrConditionsFailed is returned if the inclusion conditions attached to a
+	// transaction do not hold, or can no longer hold, at the current head.
+	ErrConditionsFailed = errors.New("transaction conditions failed")
 )
 
 var (
@@ -119,6 +123,17 @@ var (
 	underpricedTxMeter = metrics.NewRegisteredMeter("txpool/underpriced", nil)
 	overflowedTxMeter  = metrics.NewRegisteredMeter("txpool/overflowed", nil)
 
+	// snapshot metrics
+	snapshotLoadMeter = metrics.NewRegisteredMeter("txpool/snapshot/load", nil) // Reloaded from the snapshot
+	snapshotDropMeter = metrics.NewRegisteredMeter("txpool/snapshot/drop", nil) // Dropped when reloading the snapshot
+	snapshotTxsGauge  = metrics.NewRegisteredGauge("txpool/snapshot/txs", nil)
+	snapshotSizeGauge = metrics.NewRegisteredGauge("txpool/snapshot/size", nil)
+	snapshotTimer     = metrics.NewRegisteredTimer("txpool/snapshot/time", nil)
+
+	// conditionalDropMeter counts how many conditional transactions are dropped
+	// as their conditions failed on a new head.
+	conditionalDropMeter = metrics.NewRegisteredMeter("txpool/conditional/drop", nil)
+
 	// throttleTxMeter counts how many transactions are rejected due to too-many-changes between
 	// txpool reorgs.
 	throttleTxMeter = metrics.NewRegisteredMeter("txpool/throttle", nil)
@@ -163,6 +178,10 @@ type Config struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	Snapshot         string        // Snapshot of all pooled transactions to survive node restarts (empty = disabled)
+	SnapshotInterval time.Duration // Time interval to regenerate the pool snapshot
+	SnapshotLimit    uint64        // Maximum size of the pool snapshot in bytes (0 = unlimited)
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -172,6 +191,8 @@ type Config struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	DropHistory uint64 // Number of dropped transactions remembered for inspection
 }
 
 // DefaultConfig contains the default configurations for the transaction
@@ -180,6 +201,9 @@ var DefaultConfig = Config{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	SnapshotInterval: 5 * time.Minute,
+	SnapshotLimit:    128 * 1024 * 1024,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -189,6 +213,8 @@ var DefaultConfig = Config{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	DropHistory: 4096,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -199,6 +225,10 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.Snapshot != "" && conf.SnapshotInterval < time.Second {
+		log.Warn("Sanitizing invalid txpool snapshot interval", "provided", conf.SnapshotInterval, "updated", time.Second)
+		conf.SnapshotInterval = time.Second
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultConfig.PriceLimit)
 		conf.PriceLimit = DefaultConfig.PriceLimit
@@ -243,6 +273,7 @@ type TxPool struct {
 	chain       blockChain
 	gasPrice    *big.Int
 	txFeed      event.Feed
+	dropFeed    event.Feed
 	scope       event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
@@ -252,12 +283,19 @@ type TxPool struct {
 	eip1559  atomic.Bool // Fork indicator whether we are using EIP-1559 type transactions.
 	shanghai atomic.Bool // Fork indicator whether we are in the Shanghai stage.
 
+	currentHead   *types.Header  // Current head of the blockchain
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *noncer        // Pending state tracking virtual nonces
 	currentMaxGas atomic.Uint64  // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *journal    // Journal of local transaction to back up to disk
+	locals   *accountSet // Set of local transaction to exempt from eviction rules
+	journal  *journal    // Journal of local transaction to back up to disk
+	snapshot *snapshot   // Snapshot of all transactions to back up to disk
+
+	drops       []*DroppedTx             // Drops recorded but not yet announced
+	dropHistory *dropHistory             // History of the last dropped transactions
+	included    map[common.Hash]struct{} // Transactions included by the chain since the last reset, not dropped
+	deepReset   bool                     // Whether the last reset skipped a deep reorg, leaving the included transactions unknown
 
 	pending map[common.Address]*list     // All currently processable transactions
 	queue   map[common.Address]*list     // Queued but non-processable transactions
@@ -306,6 +344,7 @@ func NewTxPool(config Config, chainconfig *params.ChainConfig, chain blockChain)
 		reorgShutdownCh: make(chan struct{}),
 		initDoneCh:      make(chan struct{}),
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
+		dropHistory:     newDropHistory(config.DropHistory),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
@@ -330,6 +369,15 @@ func NewTxPool(config Config, chainconfig *params.ChainConfig, chain blockChain)
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If the pool snapshot is enabled, reload the remaining transactions from
+	// disk, re-validating them against the current head
+	if config.Snapshot != "" {
+		pool.snapshot = newTxSnapshot(config.Snapshot, config.SnapshotLimit)
+
+		if err := pool.snapshot.load(pool.addSnapshotTxs); err != nil {
+			log.Warn("Failed to load transaction pool snapshot", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -358,6 +406,14 @@ func (pool *TxPool) loop() {
 	defer evict.Stop()
 	defer journal.Stop()
 
+	// Start the snapshot ticker only if snapshots are enabled
+	var snapshot <-chan time.Time
+	if pool.snapshot != nil {
+		ticker := time.NewTicker(pool.config.SnapshotInterval)
+		defer ticker.Stop()
+		snapshot = ticker.C
+	}
+
 	// Notify tests that the init phase is done
 	close(pool.initDoneCh)
 	for {
@@ -399,11 +455,14 @@ func (pool *TxPool) loop() {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
 						pool.removeTx(tx.Hash(), true)
+						pool.recordDrop(tx, DropExpired, nil)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
+			drops := pool.takeDrops()
 			pool.mu.Unlock()
+			pool.announceDrops(drops)
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -414,6 +473,10 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+
+		// Handle pool snapshot regeneration
+		case <-snapshot:
+			pool.saveSnapshot()
 		}
 	}
 }
@@ -430,9 +493,66 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.snapshot != nil {
+		pool.saveSnapshot()
+	}
 	log.Info("Transaction pool stopped")
 }
 
+// saveSnapshot writes all the transactions of the pool to the disk snapshot,
+// executable ones first. The transactions of local accounts are left out if
+// journaled, as the journal restores them as local ones.
+func (pool *TxPool) saveSnapshot() {
+	pool.mu.RLock()
+	txs := make([]types.Transactions, 0, len(pool.pending)+len(pool.queue))
+	for addr, list := range pool.pending {
+		if pool.journal == nil || !pool.locals.contains(addr) {
+			txs = append(txs, list.Flatten())
+		}
+	}
+	for addr, list := range pool.queue {
+		if pool.journal == nil || !pool.locals.contains(addr) {
+			txs = append(txs, list.Flatten())
+		}
+	}
+	pool.mu.RUnlock()
+
+	if err := pool.snapshot.save(txs); err != nil {
+		log.Warn("Failed to save transaction pool snapshot", "err", err)
+	}
+}
+
+// addSnapshotTxs enqueues the transactions reloaded from the pool snapshot,
+// keeping the ones of local accounts local.
+func (pool *TxPool) addSnapshotTxs(txs []*types.Transaction) []error {
+	var locals, remotes []*types.Transaction
+
+	pool.mu.RLock()
+	for _, tx := range txs {
+		if pool.locals.containsTx(tx) {
+			locals = append(locals, tx)
+		} else {
+			remotes = append(remotes, tx)
+		}
+	}
+	pool.mu.RUnlock()
+
+	// Return the errors in the order of the transactions
+	var (
+		errs       = make([]error, 0, len(txs))
+		localErrs  = pool.AddLocals(locals)
+		remoteErrs = pool.AddRemotes(remotes)
+	)
+	for _, tx := range txs {
+		if len(locals) > 0 && locals[0] == tx {
+			errs, locals, localErrs = append(errs, localErrs[0]), locals[1:], localErrs[1:]
+		} else {
+			errs, remoteErrs = append(errs, remoteErrs[0]), remoteErrs[1:]
+		}
+	}
+	return errs
+}
+
 // SubscribeNewTxsEvent registers a subscription of NewTxsEvent and
 // starts sending event to the given channel.
 func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
@@ -451,7 +571,11 @@ func (pool *TxPool) GasPrice() *big.Int {
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
+	defer func() {
+		drops := pool.takeDrops()
+		pool.mu.Unlock()
+		pool.announceDrops(drops)
+	}()
 
 	old := pool.gasPrice
 	pool.gasPrice = price
@@ -461,6 +585,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 		drop := pool.all.RemotesBelowTip(price)
 		for _, tx := range drop {
 			pool.removeTx(tx.Hash(), false)
+			pool.recordDrop(tx, DropUnderpriced, nil)
 		}
 		pool.priced.Removed(len(drop))
 	}
@@ -664,6 +789,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if balance.Cmp(tx.Cost()) < 0 {
 		return core.ErrInsufficientFunds
 	}
+	// Ensure the conditions of the transaction can still be met
+	if err := pool.checkConditions(tx); err != nil {
+		return err
+	}
 
 	// Verify that replacing transactions will not result in overdraft
 	list := pool.pending[from]
@@ -761,11 +890,12 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		}
 
 		// Kick out the underpriced remote transactions.
-		for _, tx := range drop {
-			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
+		for _, dropTx := range drop {
+			log.Trace("Discarding freshly underpriced transaction", "hash", dropTx.Hash(), "gasTipCap", dropTx.GasTipCap(), "gasFeeCap", dropTx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
-			dropped := pool.removeTx(tx.Hash(), false)
+			dropped := pool.removeTx(dropTx.Hash(), false)
 			pool.changesSinceReorg += dropped
+			pool.recordDrop(dropTx, DropUnderpriced, tx)
 		}
 	}
 
@@ -782,6 +912,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.recordDrop(old, DropReplaced, tx)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
@@ -855,6 +986,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.recordDrop(old, DropReplaced, tx)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -878,8 +1010,9 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
-	// Only journal if it's enabled and the transaction is local
-	if pool.journal == nil || !pool.locals.contains(from) {
+	// Only journal if it's enabled and the transaction is local. Conditional
+	// transactions are not journaled, as their conditions are not persisted.
+	if pool.journal == nil || !pool.locals.contains(from) || tx.Conditions() != nil {
 		return
 	}
 	if err := pool.journal.insert(tx); err != nil {
@@ -904,6 +1037,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.recordDrop(tx, DropReplaced, list.txs.Get(tx.Nonce()))
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -911,6 +1045,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.recordDrop(old, DropReplaced, tx)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
@@ -1274,6 +1409,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 			nonces[addr] = highestPending.Nonce() + 1
 		}
 		pool.pendingNonces.setAll(nonces)
+		pool.included, pool.deepReset = nil, false
 	}
 	// Ensure pool.queue and pool.pending sizes stay within the configured limits.
 	pool.truncatePending()
@@ -1281,8 +1417,12 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
+	drops := pool.takeDrops()
 	pool.mu.Unlock()
 
+	// Notify subsystems for dropped transactions
+	pool.announceDrops(drops)
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1304,7 +1444,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 // of the transaction pool is valid with regard to the chain state.
 func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
-	var reinject types.Transactions
+	var reinject, included types.Transactions
 
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
@@ -1313,9 +1453,13 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 
 		if depth := uint64(math.Abs(float64(oldNum) - float64(newNum))); depth > 64 {
 			log.Debug("Skipping deep transaction reorg", "depth", depth)
+
+			// The transactions included by the new chain are not collected, so
+			// the ones whose nonce got used up can't be reported as dropped
+			pool.deepReset = true
 		} else {
 			// Reorg seems shallow enough to pull in all transactions into memory
-			var discarded, included types.Transactions
+			var discarded types.Transactions
 			var (
 				rem = pool.chain.GetBlock(oldHead.Hash(), oldHead.Number.Uint64())
 				add = pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64())
@@ -1365,6 +1509,16 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 				reinject = types.TxDifference(discarded, included)
 			}
 		}
+	} else if newHead != nil {
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			included = block.Transactions()
+		}
+	}
+	// Remember the transactions included by the new chain, to tell them apart
+	// from the ones whose nonce was used up by others
+	pool.included = make(map[common.Hash]struct{}, len(included))
+	for _, tx := range included {
+		pool.included[tx.Hash()] = struct{}{}
 	}
 	// Initialize the internal state to the current head
 	if newHead == nil {
@@ -1375,10 +1529,14 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 		log.Error("Failed to reset txpool state", "err", err)
 		return
 	}
+	pool.currentHead = newHead
 	pool.currentState = statedb
 	pool.pendingNonces = newNoncer(statedb)
 	pool.currentMaxGas.Store(newHead.GasLimit)
 
+	// Drop the transactions whose conditions no longer hold at the new head
+	pool.dropFailedConditions()
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	core.SenderCacher.Recover(pool.signer, reinject)
@@ -1389,7 +1547,44 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.istanbul.Store(pool.chainconfig.IsIstanbul(next))
 	pool.eip2718.Store(pool.chainconfig.IsBerlin(next))
 	pool.eip1559.Store(pool.chainconfig.IsLondon(next))
-	pool.shanghai.Store(pool.chainconfig.IsShanghai(uint64(time.Now().Unix())))
+	pool.shanghai.Store(pool.chainconfig.IsShanghai(uint64(time.Now().Unix())) || pool.chainconfig.IsEVMUpgrade(next))
+}
+
+// checkConditions returns an error if the conditions attached to a transaction
+// fail in the current state, or cannot be met by any future block.
+func (pool *TxPool) checkConditions(tx *types.Transaction) error {
+	conds := tx.Conditions()
+	if conds == nil {
+		return nil
+	}
+	// The head time is a lower bound for the timestamp of the next block
+	next := new(big.Int).Add(pool.currentHead.Number, common.Big1)
+	if err := conds.Expired(next, pool.currentHead.Time+1); err != nil {
+		return fmt.Errorf("%w: %v", ErrConditionsFailed, err)
+	}
+	if err := conds.CheckState(pool.currentState); err != nil {
+		return fmt.Errorf("%w: %v", ErrConditionsFailed, err)
+	}
+	return nil
+}
+
+// dropFailedConditions removes all transactions whose conditions failed on the
+// current head from the pool.
+func (pool *TxPool) dropFailedConditions() {
+	var drops []*types.Transaction
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		if err := pool.checkConditions(tx); err != nil {
+			log.Trace("Dropping conditional transaction", "hash", hash, "err", err)
+			drops = append(drops, tx)
+		}
+		return true
+	}, true, true)
+
+	for _, tx := range drops {
+		pool.removeTx(tx.Hash(), true)
+		pool.recordDrop(tx, DropConditions, nil)
+	}
+	conditionalDropMeter.Mark(int64(len(drops)))
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1410,6 +1605,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			if _, ok := pool.included[hash]; !ok && !pool.deepReset {
+				pool.recordDrop(tx, DropNonceTooLow, nil)
+			}
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1417,6 +1615,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.recordDrop(tx, DropUnpayable, nil)
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1439,6 +1638,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				pool.recordDrop(tx, DropOverflow, nil)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1501,6 +1701,7 @@ func (pool *TxPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						pool.recordDrop(tx, DropOverflow, nil)
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1528,6 +1729,7 @@ func (pool *TxPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					pool.recordDrop(tx, DropOverflow, nil)
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1575,6 +1777,7 @@ func (pool *TxPool) truncateQueue() {
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
 				pool.removeTx(tx.Hash(), true)
+				pool.recordDrop(tx, DropOverflow, nil)
 			}
 			drop -= size
 			queuedRateLimitMeter.Mark(int64(size))
@@ -1584,6 +1787,7 @@ func (pool *TxPool) truncateQueue() {
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 			pool.removeTx(txs[i].Hash(), true)
+			pool.recordDrop(txs[i], DropOverflow, nil)
 			drop--
 			queuedRateLimitMeter.Mark(1)
 		}
@@ -1607,6 +1811,9 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			if _, ok := pool.included[hash]; !ok && !pool.deepReset {
+				pool.recordDrop(tx, DropNonceTooLow, nil)
+			}
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1615,6 +1822,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			pool.recordDrop(tx, DropUnpayable, nil)
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
@@ -20,6 +20,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -32,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -2362,6 +2364,261 @@ func testJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that the pool snapshot restores both local and remote transactions on
+// restart, re-validating them against the current state.
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	snapshot := filepath.Join(t.TempDir(), "txpool.rlp")
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Snapshot = snapshot
+	config.SnapshotInterval = time.Hour
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.AddLocal(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	txs := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), remote),
+		pricedTransaction(1, 100000, big.NewInt(1), remote),
+		pricedTransaction(3, 100000, big.NewInt(1), remote),
+	}
+	for i, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	// Conditional transactions are not part of the snapshot
+	conditional := pricedTransaction(1, 100000, big.NewInt(1), local)
+	conditional.SetConditions(&types.TransactionConditions{})
+	if err := pool.AddLocal(conditional); err != nil {
+		t.Fatalf("failed to add conditional transaction: %v", err)
+	}
+	// Restart the pool with the first remote transaction included
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(remote.PublicKey), 1)
+	blockchain = newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+	<-pool.requestReset(nil, nil)
+
+	pending, queued := pool.Stats()
+	if pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if pool.Get(conditional.Hash()) != nil {
+		t.Fatalf("conditional transaction restored")
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the pool snapshot leaves the journaled local transactions to the
+// journal, so they are restored as local ones.
+func TestSnapshotJournal(t *testing.T) {
+	t.Parallel()
+
+	var (
+		snapshot = filepath.Join(t.TempDir(), "txpool.rlp")
+		journal  = filepath.Join(t.TempDir(), "transactions.rlp")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Journal = journal
+	config.Snapshot = snapshot
+	config.SnapshotInterval = time.Hour
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	localTx := pricedTransaction(0, 100000, big.NewInt(1), local)
+	if err := pool.AddLocal(localTx); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	remoteTx := pricedTransaction(0, 100000, big.NewInt(1), remote)
+	if err := pool.addRemoteSync(remoteTx); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	pool.Stop()
+
+	// Ensure only the remote transaction made it into the snapshot
+	var saved []*types.Transaction
+	err := newTxSnapshot(snapshot, 0).load(func(txs []*types.Transaction) []error {
+		saved = append(saved, txs...)
+		return make([]error, len(txs))
+	})
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if len(saved) != 1 || saved[0].Hash() != remoteTx.Hash() {
+		t.Fatalf("snapshot transactions mismatch: have %d, want the remote one", len(saved))
+	}
+	// Restart the pool and ensure the local transaction is still local
+	blockchain = newTestBlockChain(1000000, statedb, new(event.Feed))
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+	<-pool.requestReset(nil, nil)
+
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if locals := pool.Locals(); len(locals) != 1 || locals[0] != crypto.PubkeyToAddress(local.PublicKey) {
+		t.Fatalf("local accounts mismatch: have %v", locals)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the pool snapshot respects its size limit, leaving out gapped
+// transactions.
+func TestSnapshotLimit(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	txs := types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(1), key),
+		pricedTransaction(1, 100000, big.NewInt(1), key),
+		pricedTransaction(2, 100000, big.NewInt(1), key),
+	}
+	blob, _ := rlp.EncodeToBytes(txs[0])
+
+	snap := newTxSnapshot(filepath.Join(t.TempDir(), "txpool.rlp"), uint64(2*len(blob)+1))
+	if err := snap.save([]types.Transactions{txs}); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+	var loaded []*types.Transaction
+	err := snap.load(func(txs []*types.Transaction) []error {
+		loaded = append(loaded, txs...)
+		return make([]error, len(txs))
+	})
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].Hash() != txs[0].Hash() || loaded[1].Hash() != txs[1].Hash() {
+		t.Fatalf("loaded transactions mismatch: have %d, want 2", len(loaded))
+	}
+	// Snapshots over the limit are rejected
+	snap.limit = uint64(len(blob))
+	if err := snap.load(func(txs []*types.Transaction) []error { return nil }); err == nil {
+		t.Fatalf("oversized snapshot loaded")
+	}
+}
+
+// Tests that dropped transactions are announced with their reasons and kept in
+// the drop history.
+func TestDropEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	events := make(chan DropTxsEvent, 16)
+	sub := pool.SubscribeDropTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	var (
+		first  = pricedTransaction(0, 100000, big.NewInt(1), key)
+		second = pricedTransaction(0, 100000, big.NewInt(2), key)
+		third  = pricedTransaction(1, 100000, big.NewInt(1), key)
+	)
+	if err := pool.addRemoteSync(first); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(second); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(third); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Include the first nonce, dropping the replacement
+	testSetNonce(pool, from, 1)
+	<-pool.requestReset(nil, nil)
+
+	want := []*DroppedTx{
+		{Hash: first.Hash(), Sender: from, Nonce: 0, Reason: DropReplaced},
+		{Hash: second.Hash(), Sender: from, Nonce: 0, Reason: DropNonceTooLow},
+	}
+	var have []*DroppedTx
+	for len(have) < len(want) {
+		select {
+		case ev := <-events:
+			have = append(have, ev.Txs...)
+		case <-time.After(time.Second):
+			t.Fatalf("drop event timeout: have %d drops, want %d", len(have), len(want))
+		}
+	}
+	for i, drop := range have {
+		if drop.Hash != want[i].Hash || drop.Sender != want[i].Sender || drop.Nonce != want[i].Nonce || drop.Reason != want[i].Reason {
+			t.Errorf("drop %d mismatch: have %+v, want %+v", i, drop, want[i])
+		}
+	}
+	if replacement := have[0].Replacement; replacement == nil || *replacement != second.Hash() {
+		t.Errorf("replacement mismatch: have %v, want %v", replacement, second.Hash())
+	}
+	if history := pool.Dropped(); len(history) != 2 || history[0].Hash != first.Hash() {
+		t.Errorf("drop history mismatch: have %d drops", len(history))
+	}
+	if drop := pool.LastDrop(second.Hash()); drop == nil || drop.Reason != DropNonceTooLow {
+		t.Errorf("drop status mismatch: have %+v", drop)
+	}
+	if drop := pool.LastDrop(third.Hash()); drop != nil {
+		t.Errorf("pooled transaction reported as dropped: %+v", drop)
+	}
+}
+
+// Tests that the drop history retains only the configured number of drops.
+func TestDropHistory(t *testing.T) {
+	history := newDropHistory(3)
+
+	var drops []*DroppedTx
+	for i := 0; i < 5; i++ {
+		drops = append(drops, &DroppedTx{Hash: common.Hash{byte(i)}, Nonce: uint64(i)})
+	}
+	history.add(drops[:2])
+	history.add(drops[2:])
+
+	list := history.list()
+	if len(list) != 3 {
+		t.Fatalf("history length mismatch: have %d, want 3", len(list))
+	}
+	for i, drop := range list {
+		if drop != drops[i+2] {
+			t.Errorf("drop %d mismatch: have nonce %d, want %d", i, drop.Nonce, i+2)
+		}
+	}
+	if history.get(drops[1].Hash) != nil {
+		t.Errorf("evicted drop still indexed")
+	}
+	if history.get(drops[4].Hash) != drops[4] {
+		t.Errorf("retained drop not indexed")
+	}
+}
+
 // TestStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestStatusCheck(t *testing.T) {
@@ -2436,6 +2693,67 @@ func TestSlotCount(t *testing.T) {
 	}
 }
 
+// Tests that conditional transactions are only accepted while their conditions
+// hold, and are dropped from the pool once they fail on a new head.
+func TestConditionalTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	var (
+		from     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		slot     = common.HexToHash("0x01")
+		one      = common.HexToHash("0x01")
+		two      = common.HexToHash("0x02")
+	)
+	testAddBalance(pool, from, big.NewInt(1000000))
+	pool.mu.Lock()
+	pool.currentState.SetState(contract, slot, one)
+	root := pool.currentState.GetStorageRoot(contract)
+	pool.mu.Unlock()
+
+	conditional := func(nonce uint64, conds *types.TransactionConditions) *types.Transaction {
+		tx := transaction(nonce, 100000, key)
+		tx.SetConditions(conds)
+		return tx
+	}
+	tests := []struct {
+		nonce uint64
+		conds *types.TransactionConditions
+		err   error
+	}{
+		{0, &types.TransactionConditions{KnownAccounts: map[common.Address]types.KnownAccount{contract: {StorageSlots: map[common.Hash]common.Hash{slot: two}}}}, ErrConditionsFailed},
+		{0, &types.TransactionConditions{KnownAccounts: map[common.Address]types.KnownAccount{contract: {StorageRoot: &common.Hash{}}}}, ErrConditionsFailed},
+		{0, &types.TransactionConditions{BlockNumberMax: big.NewInt(0)}, ErrConditionsFailed},
+		{0, &types.TransactionConditions{BlockNumberMin: big.NewInt(5)}, nil},
+		{1, &types.TransactionConditions{KnownAccounts: map[common.Address]types.KnownAccount{contract: {StorageRoot: &root}}}, nil},
+		{2, &types.TransactionConditions{KnownAccounts: map[common.Address]types.KnownAccount{contract: {StorageSlots: map[common.Hash]common.Hash{slot: one}}}}, nil},
+	}
+	for i, tt := range tests {
+		if err := pool.AddLocal(conditional(tt.nonce, tt.conds)); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	// Change the slot and ensure the transactions conditioned on the storage are
+	// dropped on the next reset
+	pool.mu.Lock()
+	pool.currentState.SetState(contract, slot, two)
+	pool.mu.Unlock()
+
+	<-pool.requestReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("transactions mismatched: have %d pending and %d queued, want 1 and 0", pending, queued)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Benchmarks the speed of validating the contents of the pending queue of the
 // transaction pool.
 func BenchmarkPendingDemotion100(b *testing.B)   { benchmarkPendingDemotion(b, 100) }
@@ -2576,3 +2894,85 @@ func BenchmarkMultiAccountBatchInsert(b *testing.B) {
 		pool.AddRemotesSync([]*types.Transaction{tx})
 	}
 }
+
+// includingBlockChain is a test chain whose blocks contain the given
+// transactions.
+type includingBlockChain struct {
+	*testBlockChain
+	txs types.Transactions
+}
+
+func (bc *includingBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return types.NewBlock(bc.CurrentBlock(), bc.txs, nil, nil, trie.NewStackTrie(nil))
+}
+
+// Tests that the transactions included by the chain are not reported as
+// dropped, unlike the ones whose nonce was used up by others.
+func TestDropEventsIncluded(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &includingBlockChain{testBlockChain: newTestBlockChain(10000000, statedb, new(event.Feed))}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	<-pool.initDoneCh
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	events := make(chan DropTxsEvent, 16)
+	sub := pool.SubscribeDropTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	var (
+		included = pricedTransaction(0, 100000, big.NewInt(1), key)
+		pooled   = pricedTransaction(1, 100000, big.NewInt(1), key)
+		rival    = pricedTransaction(1, 100000, big.NewInt(2), key)
+	)
+	for _, tx := range []*types.Transaction{included, pooled} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	parent := blockchain.CurrentBlock()
+	head := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), GasLimit: parent.GasLimit, BaseFee: new(big.Int)}
+
+	// Include the first transaction, which must not be reported
+	blockchain.txs = types.Transactions{included}
+	testSetNonce(pool, from, 1)
+	<-pool.requestReset(parent, head)
+
+	// Include a rival of the second transaction, which must be reported
+	blockchain.txs = types.Transactions{rival}
+	testSetNonce(pool, from, 2)
+	<-pool.requestReset(head, &types.Header{ParentHash: head.Hash(), Number: big.NewInt(2), GasLimit: head.GasLimit, BaseFee: new(big.Int)})
+
+	select {
+	case ev := <-events:
+		if len(ev.Txs) != 1 || ev.Txs[0].Hash != pooled.Hash() || ev.Txs[0].Reason != DropNonceTooLow {
+			t.Fatalf("unexpected drops: %+v", ev.Txs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drop event timeout")
+	}
+	if drop := pool.LastDrop(included.Hash()); drop != nil {
+		t.Errorf("included transaction reported as dropped: %+v", drop)
+	}
+	// Reset deep into an unrelated chain, whose transactions are not collected,
+	// and ensure the used up nonces are not reported
+	deep := pricedTransaction(2, 100000, big.NewInt(1), key)
+	if err := pool.addRemoteSync(deep); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	testSetNonce(pool, from, 3)
+	<-pool.requestReset(head, &types.Header{ParentHash: common.Hash{0x01}, Number: big.NewInt(100), GasLimit: head.GasLimit, BaseFee: new(big.Int)})
+
+	if drop := pool.LastDrop(deep.Hash()); drop != nil {
+		t.Errorf("transaction reported as dropped after deep reset: %+v", drop)
+	}
+	if pool.Has(deep.Hash()) {
+		t.Errorf("transaction with used up nonce not removed after deep reset")
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package types
+
+import "math/big"
+
+// Issuance is the record of the coins created and destroyed by a block, along
+// with the total supply once the block is applied.
+type Issuance struct {
+	Reward      *big.Int // Reward of the block miner, including the uncle inclusion rewards
+	UncleReward *big.Int // Total reward of the miners of the included uncles
+	Withdrawals *big.Int // Total amount withdrawn from the consensus layer
+	Burn        *big.Int // Base fee destroyed by the transactions
+	Supply      *big.Int // Total supply after the block
+}
+
+// Issued returns the net amount of coins created by the block, negative if more
+// were burned than minted.
+func (i *Issuance) Issued() *big.Int {
+	issued := new(big.Int).Add(i.Reward, i.UncleReward)
+	issued.Add(issued, i.Withdrawals)
+	return issued.Sub(issued, i.Burn)
+}
@@ -52,6 +52,8 @@ type Transaction struct {
 	hash atomic.Value
 	size atomic.Value
 	from atomic.Value
+
+	conditions atomic.Value // Local inclusion conditions, never propagated
 }
 
 // NewTx creates a new transaction.
@@ -296,6 +298,21 @@ func (tx *Transaction) Cost() *big.Int {
 	return total
 }
 
+// Conditions returns the inclusion conditions attached to the transaction when
+// it was submitted locally, or nil if it is unconditional.
+func (tx *Transaction) Conditions() *TransactionConditions {
+	if conds := tx.conditions.Load(); conds != nil {
+		return conds.(*TransactionConditions)
+	}
+	return nil
+}
+
+// SetConditions attaches inclusion conditions to the transaction. They are not
+// part of its consensus encoding.
+func (tx *Transaction) SetConditions(conds *TransactionConditions) {
+	tx.conditions.Store(conds)
+}
+
 // RawSignatureValues returns the V, R, S signature values of the transaction.
 // The return values should not be modified by the caller.
 func (tx *Transaction) RawSignatureValues() (v, r, s *big.Int) {
@@ -0,0 +1,134 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+//go:generate go run github.com/fjl/gencodec -type TransactionConditions -field-override transactionConditionsMarshaling -out gen_conditions_json.go
+
+// TransactionConditions are the preconditions a locally submitted transaction
+// may only be included under. Nil bounds are not checked.
+type TransactionConditions struct {
+	KnownAccounts  map[common.Address]KnownAccount `json:"knownAccounts,omitempty"`
+	BlockNumberMin *big.Int                        `json:"blockNumberMin,omitempty"`
+	BlockNumberMax *big.Int                        `json:"blockNumberMax,omitempty"`
+	TimestampMin   *uint64                         `json:"timestampMin,omitempty"`
+	TimestampMax   *uint64                         `json:"timestampMax,omitempty"`
+}
+
+// field type overrides for gencodec
+type transactionConditionsMarshaling struct {
+	BlockNumberMin *hexutil.Big
+	BlockNumberMax *hexutil.Big
+	TimestampMin   *hexutil.Uint64
+	TimestampMax   *hexutil.Uint64
+}
+
+// KnownAccount is the expected storage of an account, given either as the root
+// of its storage trie or as the values of individual slots.
+type KnownAccount struct {
+	StorageRoot  *common.Hash
+	StorageSlots map[common.Hash]common.Hash
+}
+
+// MarshalJSON encodes the storage root as a hash, or the slots as an object.
+func (a KnownAccount) MarshalJSON() ([]byte, error) {
+	if a.StorageRoot != nil {
+		return json.Marshal(*a.StorageRoot)
+	}
+	return json.Marshal(a.StorageSlots)
+}
+
+// UnmarshalJSON decodes either a storage root hash or an object of slots.
+func (a *KnownAccount) UnmarshalJSON(input []byte) error {
+	*a = KnownAccount{}
+	if bytes.HasPrefix(bytes.TrimSpace(input), []byte("{")) {
+		return json.Unmarshal(input, &a.StorageSlots)
+	}
+	a.StorageRoot = new(common.Hash)
+	return json.Unmarshal(input, a.StorageRoot)
+}
+
+// Slots returns the number of storage checks the expectation requires.
+func (a KnownAccount) Slots() int {
+	if a.StorageRoot != nil {
+		return 1
+	}
+	return len(a.StorageSlots)
+}
+
+// ConditionState is the state the known accounts are checked against.
+type ConditionState interface {
+	GetState(addr common.Address, key common.Hash) common.Hash
+	GetStorageRoot(addr common.Address) common.Hash
+}
+
+// CheckBlock returns an error if a block with the given number and timestamp
+// is outside the ranges of the conditions.
+func (c *TransactionConditions) CheckBlock(number *big.Int, time uint64) error {
+	if c.BlockNumberMin != nil && number.Cmp(c.BlockNumberMin) < 0 {
+		return fmt.Errorf("block number %v before minimum %v", number, c.BlockNumberMin)
+	}
+	if c.TimestampMin != nil && time < *c.TimestampMin {
+		return fmt.Errorf("timestamp %d before minimum %d", time, *c.TimestampMin)
+	}
+	return c.Expired(number, time)
+}
+
+// Expired returns an error if no block from the one with the given number and
+// timestamp onwards can satisfy the conditions.
+func (c *TransactionConditions) Expired(number *big.Int, time uint64) error {
+	if c.BlockNumberMax != nil && number.Cmp(c.BlockNumberMax) > 0 {
+		return fmt.Errorf("block number %v after maximum %v", number, c.BlockNumberMax)
+	}
+	if c.TimestampMax != nil && time > *c.TimestampMax {
+		return fmt.Errorf("timestamp %d after maximum %d", time, *c.TimestampMax)
+	}
+	return nil
+}
+
+// CheckState returns an error if the storage of a known account differs from
+// its expectation in the given state.
+func (c *TransactionConditions) CheckState(state ConditionState) error {
+	for addr, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			if root := state.GetStorageRoot(addr); root != *account.StorageRoot {
+				return fmt.Errorf("storage root of %v mismatch: have %v, want %v", addr, root, *account.StorageRoot)
+			}
+			continue
+		}
+		for key, want := range account.StorageSlots {
+			if have := state.GetState(addr, key); have != want {
+				return fmt.Errorf("storage slot %v of %v mismatch: have %v, want %v", key, addr, have, want)
+			}
+		}
+	}
+	return nil
+}
+
+// Slots returns the number of storage checks the conditions require.
+func (c *TransactionConditions) Slots() int {
+	var slots int
+	for _, account := range c.KnownAccounts {
+		slots += account.Slots()
+	}
+	return slots
+}
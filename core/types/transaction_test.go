@@ -595,3 +595,62 @@ func TestTransactionSizes(t *testing.T) {
 		}
 	}
 }
+
+// Tests that transaction conditions decode known accounts given either as a
+// storage root or as storage slots, and check block bounds.
+func TestTransactionConditionsJSON(t *testing.T) {
+	input := `{
+		"knownAccounts": {
+			"0x000000000000000000000000000000000000aaaa": "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+			"0x000000000000000000000000000000000000bbbb": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"}
+		},
+		"blockNumberMin": "0x2",
+		"timestampMax": "0x64"
+	}`
+	var conds TransactionConditions
+	if err := json.Unmarshal([]byte(input), &conds); err != nil {
+		t.Fatalf("failed to decode conditions: %v", err)
+	}
+	aa, bb := common.HexToAddress("0xaaaa"), common.HexToAddress("0xbbbb")
+	if root := conds.KnownAccounts[aa].StorageRoot; root == nil || *root != EmptyRootHash {
+		t.Errorf("storage root mismatch: have %v, want %v", root, EmptyRootHash)
+	}
+	if slots := conds.KnownAccounts[bb].StorageSlots; len(slots) != 1 || slots[common.HexToHash("0x01")] != common.HexToHash("0x02") {
+		t.Errorf("storage slots mismatch: have %v", slots)
+	}
+	if n := conds.Slots(); n != 2 {
+		t.Errorf("slot count mismatch: have %d, want 2", n)
+	}
+	// Check the conditions survive a round trip
+	enc, err := json.Marshal(&conds)
+	if err != nil {
+		t.Fatalf("failed to encode conditions: %v", err)
+	}
+	var dec TransactionConditions
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatalf("failed to decode encoded conditions: %v", err)
+	}
+	if !reflect.DeepEqual(conds, dec) {
+		t.Errorf("round trip mismatch: have %+v, want %+v", dec, conds)
+	}
+	// Check the block bounds
+	tests := []struct {
+		number  int64
+		time    uint64
+		valid   bool
+		expired bool
+	}{
+		{1, 50, false, false},
+		{2, 50, true, false},
+		{2, 100, true, false},
+		{3, 101, false, true},
+	}
+	for i, tt := range tests {
+		if err := conds.CheckBlock(big.NewInt(tt.number), tt.time); (err == nil) != tt.valid {
+			t.Errorf("test %d: block check mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+		if err := conds.Expired(big.NewInt(tt.number), tt.time); (err != nil) != tt.expired {
+			t.Errorf("test %d: expiry mismatch: have %v, want expired %v", i, err, tt.expired)
+		}
+	}
+}
@@ -52,8 +52,12 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 	// If jump table was not initialised we set the default one.
 	var table *JumpTable
 	switch {
+	case evm.chainRules.IsEVMUpgrade && (evm.chainRules.IsShanghai || evm.chainRules.IsMerge):
+		table = &mergeEVMUpgradeInstructionSet
 	case evm.chainRules.IsShanghai:
 		table = &shanghaiInstructionSet
+	case evm.chainRules.IsEVMUpgrade:
+		table = &evmUpgradeInstructionSet
 	case evm.chainRules.IsMerge:
 		table = &mergeInstructionSet
 	case evm.chainRules.IsLondon:
@@ -52,6 +52,8 @@ var (
 	londonInstructionSet           = newLondonInstructionSet()
 	mergeInstructionSet            = newMergeInstructionSet()
 	shanghaiInstructionSet         = newShanghaiInstructionSet()
+	evmUpgradeInstructionSet       = newEVMUpgradeInstructionSet()
+	mergeEVMUpgradeInstructionSet  = newMergeEVMUpgradeInstructionSet()
 )
 
 // JumpTable contains the EVM opcodes supported at a given fork.
@@ -82,6 +84,27 @@ func newShanghaiInstructionSet() JumpTable {
 	return validate(instructionSet)
 }
 
+// newEVMUpgradeInstructionSet returns the london instructions along with the
+// Shanghai and Cancun ones not tied to the beacon chain, for proof-of-work.
+func newEVMUpgradeInstructionSet() JumpTable {
+	instructionSet := newLondonInstructionSet()
+	enable3855(&instructionSet) // PUSH0 instruction
+	enable3860(&instructionSet) // Limit and meter initcode
+	enable1153(&instructionSet) // Transient storage opcodes
+	enable5656(&instructionSet) // MCOPY instruction
+	return validate(instructionSet)
+}
+
+// newMergeEVMUpgradeInstructionSet returns the shanghai instructions along with
+// the Cancun ones of the EVM upgrade, for chains running both the merge and the
+// EVM upgrade.
+func newMergeEVMUpgradeInstructionSet() JumpTable {
+	instructionSet := newShanghaiInstructionSet()
+	enable1153(&instructionSet) // Transient storage opcodes
+	enable5656(&instructionSet) // MCOPY instruction
+	return validate(instructionSet)
+}
+
 func newMergeInstructionSet() JumpTable {
 	instructionSet := newLondonInstructionSet()
 	instructionSet[PREVRANDAO] = &operation{
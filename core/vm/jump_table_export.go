@@ -26,8 +26,12 @@ func LookupInstructionSet(rules params.Rules) (JumpTable, error) {
 		return newShanghaiInstructionSet(), errors.New("prague-fork not defined yet")
 	case rules.IsCancun:
 		return newShanghaiInstructionSet(), errors.New("cancun-fork not defined yet")
+	case rules.IsEVMUpgrade && (rules.IsShanghai || rules.IsMerge):
+		return newMergeEVMUpgradeInstructionSet(), nil
 	case rules.IsShanghai:
 		return newShanghaiInstructionSet(), nil
+	case rules.IsEVMUpgrade:
+		return newEVMUpgradeInstructionSet(), nil
 	case rules.IsMerge:
 		return newMergeInstructionSet(), nil
 	case rules.IsLondon:
@@ -13,8 +13,10 @@
 package vm
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +31,43 @@ func TestJumpTableCopy(t *testing.T) {
 	require.Equal(t, uint64(100), deepCopy[SLOAD].constantGas)
 	require.Equal(t, uint64(0), tbl[SLOAD].constantGas)
 }
+
+// TestEVMUpgradeInstructionSets tests that the EVM upgrade combined with the
+// merge or Shanghai keeps the opcodes of both.
+func TestEVMUpgradeInstructionSets(t *testing.T) {
+	tests := []struct {
+		rules params.Rules
+		ops   []OpCode
+	}{
+		{params.Rules{IsLondon: true, IsEVMUpgrade: true}, []OpCode{BASEFEE, PUSH0, TLOAD, TSTORE, MCOPY}},
+		{params.Rules{IsLondon: true, IsMerge: true, IsEVMUpgrade: true}, []OpCode{BASEFEE, PREVRANDAO, PUSH0, TLOAD, TSTORE, MCOPY}},
+		{params.Rules{IsLondon: true, IsMerge: true, IsShanghai: true, IsEVMUpgrade: true}, []OpCode{BASEFEE, PREVRANDAO, PUSH0, TLOAD, TSTORE, MCOPY}},
+		{params.Rules{IsLondon: true, IsMerge: true, IsShanghai: true}, []OpCode{BASEFEE, PREVRANDAO, PUSH0}},
+	}
+	for i, tt := range tests {
+		tbl, err := LookupInstructionSet(tt.rules)
+		require.NoError(t, err)
+		for _, op := range tt.ops {
+			require.Truef(t, tbl[op].HasCost(), "test %d: %v missing from the lookup table", i, op)
+		}
+		evm := NewEVM(BlockContext{}, TxContext{}, nil, params.TestChainConfig, Config{})
+		evm.chainRules = tt.rules
+		interpreter := NewEVMInterpreter(evm)
+		for _, op := range tt.ops {
+			require.Truef(t, interpreter.table[op].HasCost(), "test %d: %v missing from the interpreter table", i, op)
+		}
+	}
+	// PREVRANDAO must replace DIFFICULTY only once merged
+	isRandom := func(tbl *JumpTable) bool {
+		return reflect.ValueOf(tbl[PREVRANDAO].execute).Pointer() == reflect.ValueOf(opRandom).Pointer()
+	}
+	for i, tt := range tests {
+		tbl, _ := LookupInstructionSet(tt.rules)
+		require.Equalf(t, tt.rules.IsMerge, isRandom(&tbl), "test %d: PREVRANDAO mismatch", i)
+	}
+	// Opcodes not enabled by the active forks must remain undefined
+	tbl, _ := LookupInstructionSet(params.Rules{IsLondon: true, IsMerge: true, IsShanghai: true})
+	for _, op := range []OpCode{TLOAD, TSTORE, MCOPY} {
+		require.Falsef(t, tbl[op].HasCost(), "%v enabled without the EVM upgrade", op)
+	}
+}
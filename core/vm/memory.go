@@ -99,3 +99,12 @@ func (m *Memory) Len() int {
 func (m *Memory) Data() []byte {
 	return m.store
 }
+
+// Copy copies size bytes from offset src to offset dst, the two ranges may
+// overlap. The store should be resized PRIOR to copying.
+func (m *Memory) Copy(dst, src, size uint64) {
+	if size == 0 {
+		return
+	}
+	copy(m.store[dst:], m.store[src:src+size])
+}
@@ -20,6 +20,14 @@ func memoryCallDataCopy(stack *Stack) (uint64, bool) {
 	return calcMemSize64(stack.Back(0), stack.Back(2))
 }
 
+func memoryMcopy(stack *Stack) (uint64, bool) {
+	offset := stack.Back(0) // destination
+	if stack.Back(1).Gt(offset) {
+		offset = stack.Back(1) // source
+	}
+	return calcMemSize64(offset, stack.Back(2))
+}
+
 func memoryReturnDataCopy(stack *Stack) (uint64, bool) {
 	return calcMemSize64(stack.Back(0), stack.Back(2))
 }
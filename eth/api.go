@@ -190,6 +190,27 @@ func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	return true
 }
 
+// FinalityPolicy returns the finality policy guarding the chain against deep
+// reorgs, along with the statistics of the reorgs it refused.
+func (api *AdminAPI) FinalityPolicy() core.FinalityStatus {
+	return api.eth.BlockChain().FinalityPolicy()
+}
+
+// SetMaxReorgDepth sets the maximum number of canonical blocks a chain reorg
+// may drop, or disables the limit if zero.
+func (api *AdminAPI) SetMaxReorgDepth(depth uint64) bool {
+	policy := api.eth.BlockChain().FinalityPolicy()
+	api.eth.BlockChain().SetFinalityPolicy(core.FinalityConfig{MaxReorgDepth: depth, MESS: policy.MESS})
+	return true
+}
+
+// SetMESS enables or disables the penalization of late-arriving chain forks.
+func (api *AdminAPI) SetMESS(enabled bool) bool {
+	policy := api.eth.BlockChain().FinalityPolicy()
+	api.eth.BlockChain().SetFinalityPolicy(core.FinalityConfig{MaxReorgDepth: policy.MaxReorgDepth, MESS: enabled})
+	return true
+}
+
 // ImportChain imports a blockchain from a local file.
 func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	// Make sure the can access the file to import
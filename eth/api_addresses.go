@@ -0,0 +1,240 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// addressIndexReexec is the number of blocks the address indexer re-executes
+	// to regenerate a missing state when indexing internal calls.
+	addressIndexReexec = 128
+
+	// maxAddressTxs is the maximum number of transactions returned in a single
+	// page of eth_getTransactionsByAddress.
+	maxAddressTxs = 1000
+)
+
+var (
+	errAddressIndexDisabled = errors.New("address index is disabled")
+	errAddressIndexNotReady = errors.New("address index not ready")
+)
+
+// AddressAPI provides an API to look up the transactions addresses took part
+// in, using the address index.
+type AddressAPI struct {
+	e *Ethereum
+}
+
+// NewAddressAPI creates a new address API instance.
+func NewAddressAPI(e *Ethereum) *AddressAPI {
+	return &AddressAPI{e}
+}
+
+// AddressTxsArgs are the arguments of eth_getTransactionsByAddress.
+type AddressTxsArgs struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"` // Oldest block of the range, genesis if nil
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`   // Newest block of the range, latest if nil
+	Limit     hexutil.Uint     `json:"limit"`     // Maximum number of transactions in the page, capped by the node
+	Cursor    hexutil.Bytes    `json:"cursor"`    // Cursor of the previous page to continue from
+}
+
+// AddressTx is a transaction an address took part in.
+type AddressTx struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	TransactionIndex hexutil.Uint   `json:"transactionIndex"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	Roles            []string       `json:"roles"`
+}
+
+// AddressTxsPage is a page of the results of eth_getTransactionsByAddress. The
+// cursor is nil on the last page of the query.
+type AddressTxsPage struct {
+	Transactions []*AddressTx   `json:"transactions"`
+	Cursor       *hexutil.Bytes `json:"cursor"`
+}
+
+// addressRoleNames are the names of the address roles, by bit.
+var addressRoleNames = []string{"sender", "recipient", "creation", "internal"}
+
+// GetTransactionsByAddress returns the transactions the address sent, received,
+// created a contract in or, if indexed, was called internally by, from the newest
+// to the oldest. The results are paginated, passing the cursor of a page back
+// with the same arguments returns the next page. Blocks older than the tail of
+// the index are not covered, nor are the internal calls of the recent blocks the
+// index did not process yet. Queries reaching further behind the chain head than
+// the index normally lags fail until it catches up.
+func (api *AddressAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, args *AddressTxsArgs) (*AddressTxsPage, error) {
+	indexer := api.e.addressIndexer
+	if indexer == nil {
+		return nil, errAddressIndexDisabled
+	}
+	if args == nil {
+		args = new(AddressTxsArgs)
+	}
+	var (
+		chain = api.e.blockchain
+		db    = api.e.chainDb
+		head  = chain.CurrentBlock().Number.Uint64()
+		from  = uint64(0)
+		to    = head
+		limit = int(args.Limit)
+	)
+	if args.FromBlock != nil && *args.FromBlock >= 0 {
+		from = uint64(*args.FromBlock)
+	}
+	if args.ToBlock != nil && *args.ToBlock >= 0 && uint64(*args.ToBlock) < to {
+		to = uint64(*args.ToBlock)
+	}
+	if limit == 0 || limit > maxAddressTxs {
+		limit = maxAddressTxs
+	}
+	digest := args.digest(address)
+
+	// Start at the newest transaction of the range, or where the last page ended
+	number, index := to, uint32(math.MaxUint32)
+	if len(args.Cursor) > 0 {
+		if len(args.Cursor) != 20 || !bytes.Equal(args.Cursor[12:], digest) {
+			return nil, errors.New("invalid cursor")
+		}
+		number, index = binary.BigEndian.Uint64(args.Cursor), binary.BigEndian.Uint32(args.Cursor[8:])
+		if number > to || number < from {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+	page := &AddressTxsPage{Transactions: []*AddressTx{}}
+	if from > to {
+		return page, nil
+	}
+	var (
+		txs   []*AddressTx
+		block *types.Block
+	)
+	add := func(number uint64, index uint32, roles uint8) error {
+		if block == nil || block.NumberU64() != number {
+			if block = chain.GetBlockByNumber(number); block == nil {
+				return errors.New("block not found")
+			}
+		}
+		if int(index) >= len(block.Transactions()) {
+			return errors.New("transaction not found")
+		}
+		tx := &AddressTx{
+			BlockNumber:      hexutil.Uint64(number),
+			BlockHash:        block.Hash(),
+			TransactionIndex: hexutil.Uint(index),
+			TransactionHash:  block.Transactions()[index].Hash(),
+			Roles:            []string{},
+		}
+		for bit, name := range addressRoleNames {
+			if roles&(1<<bit) != 0 {
+				tx.Roles = append(tx.Roles, name)
+			}
+		}
+		txs = append(txs, tx)
+		return nil
+	}
+	// Scan the recent blocks the index did not process yet directly
+	var (
+		sections, _, _ = indexer.Sections()
+		indexed        = sections * params.AddressIndexBlocks
+		lowest         = indexed
+		done           bool
+	)
+	if from > lowest {
+		lowest = from
+	}
+	if number >= lowest && number-lowest >= params.AddressIndexBlocks+params.AddressIndexConfirms {
+		return nil, errAddressIndexNotReady
+	}
+	for len(txs) <= limit && number >= indexed {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if block = chain.GetBlockByNumber(number); block == nil {
+			return nil, errors.New("block not found")
+		}
+		entries, err := core.AddressIndexEntries(db, chain.Config(), block, nil)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(entries) - 1; i >= 0 && len(txs) <= limit; i-- {
+			if entries[i].Address == address && entries[i].Index <= index {
+				if err := add(number, entries[i].Index, entries[i].Roles); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if number == from {
+			done = true
+			break
+		}
+		number, index = number-1, math.MaxUint32
+	}
+	// Iterate over the indexed transactions of the address for the rest
+	if !done && len(txs) <= limit {
+		var err error
+		rawdb.IterateAddressTxs(db, address, number, index, func(number uint64, index uint32, roles uint8) bool {
+			if number < from {
+				return false
+			}
+			if err = add(number, index, roles); err != nil {
+				return false
+			}
+			return len(txs) <= limit
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Return the page, with a cursor pointing at the first transaction of the next
+	if len(txs) > limit {
+		next := txs[limit]
+		cursor := make(hexutil.Bytes, 12, 20)
+		binary.BigEndian.PutUint64(cursor, uint64(next.BlockNumber))
+		binary.BigEndian.PutUint32(cursor[8:], uint32(next.TransactionIndex))
+		cursor = append(cursor, digest...)
+		page.Cursor, txs = &cursor, txs[:limit]
+	}
+	page.Transactions = append(page.Transactions, txs...)
+	return page, nil
+}
+
+// digest returns a short hash of the arguments and the address, binding the
+// cursors of a paginated query to them.
+func (args *AddressTxsArgs) digest(address common.Address) []byte {
+	blob := append([]byte{}, address[:]...)
+	for _, number := range []*rpc.BlockNumber{args.FromBlock, args.ToBlock} {
+		if number != nil {
+			blob = binary.BigEndian.AppendUint64(blob, uint64(*number))
+		} else {
+			blob = append(blob, 0)
+		}
+	}
+	return crypto.Keccak256(blob)[:8]
+}
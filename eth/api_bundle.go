@@ -0,0 +1,246 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BundleAPI provides an API to submit and simulate private bundles of
+// transactions, included atomically at the top of the block they target.
+type BundleAPI struct {
+	e *Ethereum
+}
+
+// NewBundleAPI creates a new bundle API instance.
+func NewBundleAPI(e *Ethereum) *BundleAPI {
+	return &BundleAPI{e}
+}
+
+// SendBundleArgs are the arguments of eth_sendBundle.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp      *hexutil.Uint64 `json:"minTimestamp"`
+	MaxTimestamp      *hexutil.Uint64 `json:"maxTimestamp"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// decodeTxs decodes the binary encoded transactions of a bundle.
+func decodeTxs(encoded []hexutil.Bytes) (types.Transactions, error) {
+	if len(encoded) == 0 {
+		return nil, errors.New("bundle missing txs")
+	}
+	txs := make(types.Transactions, 0, len(encoded))
+	for i, enc := range encoded {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(enc); err != nil {
+			return nil, fmt.Errorf("invalid transaction %d: %w", i, err)
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// SendBundle schedules an ordered bundle of signed transactions for inclusion
+// at the top of the given block. The bundle is included only as a whole, and
+// only if none of its transactions revert, except the ones explicitly allowed
+// to. Competing bundles are ordered by the value they bring to the miner.
+func (api *BundleAPI) SendBundle(ctx context.Context, args SendBundleArgs) (map[string]interface{}, error) {
+	txs, err := decodeTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &miner.Bundle{
+		Txs:               txs,
+		BlockNumber:       uint64(args.BlockNumber),
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = uint64(*args.MinTimestamp)
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*args.MaxTimestamp)
+	}
+	if err := api.e.Miner().AddBundle(bundle); err != nil {
+		return nil, err
+	}
+	hash := bundle.Hash()
+	log.Debug("Submitted bundle", "hash", hash, "number", bundle.BlockNumber, "txs", len(txs))
+	return map[string]interface{}{"bundleHash": hash}, nil
+}
+
+// CallBundleArgs are the arguments of eth_callBundle.
+type CallBundleArgs struct {
+	Txs              []hexutil.Bytes       `json:"txs"`
+	BlockNumber      hexutil.Uint64        `json:"blockNumber"`
+	StateBlockNumber rpc.BlockNumberOrHash `json:"stateBlockNumber"`
+	Coinbase         *common.Address       `json:"coinbase"`
+	Timestamp        *hexutil.Uint64       `json:"timestamp"`
+	GasLimit         *hexutil.Uint64       `json:"gasLimit"`
+	Difficulty       *hexutil.Big          `json:"difficulty"`
+	BaseFee          *hexutil.Big          `json:"baseFee"`
+}
+
+// CallBundle simulates a bundle of signed transactions on top of the state of
+// the given block, as if it were at the top of the given block number, and
+// returns the outcome of each transaction along with the value the bundle
+// brings to the coinbase.
+func (api *BundleAPI) CallBundle(ctx context.Context, args CallBundleArgs) (map[string]interface{}, error) {
+	txs, err := decodeTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	b := api.e.APIBackend
+	state, parent, err := b.StateAndHeaderByNumberOrHash(ctx, args.StateBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Setup context so it may be cancelled the call has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	timeout := b.RPCEVMTimeout()
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// Assemble the block the bundle is simulated in, defaulting to a child of
+	// the state block.
+	config := b.ChainConfig()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).SetUint64(uint64(args.BlockNumber)),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+		Difficulty: parent.Difficulty,
+		Coinbase:   parent.Coinbase,
+	}
+	if args.Coinbase != nil {
+		header.Coinbase = *args.Coinbase
+	}
+	if args.Timestamp != nil {
+		header.Time = uint64(*args.Timestamp)
+	}
+	if args.GasLimit != nil {
+		header.GasLimit = uint64(*args.GasLimit)
+	}
+	if args.Difficulty != nil {
+		header.Difficulty = args.Difficulty.ToInt()
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(config, parent)
+		if args.BaseFee != nil {
+			header.BaseFee = args.BaseFee.ToInt()
+		}
+	}
+	var (
+		signer     = types.MakeSigner(config, header.Number)
+		blockCtx   = core.NewEVMBlockContext(header, api.e.BlockChain(), nil)
+		rules      = config.Rules(header.Number, blockCtx.Random != nil, header.Time)
+		gp         = new(core.GasPool).AddGas(header.GasLimit)
+		feeToMiner = config.BaseFeeRecipient(header.Coinbase)
+
+		results      = make([]map[string]interface{}, 0, len(txs))
+		bundleBefore = state.GetBalance(header.Coinbase)
+		gasFees      = new(big.Int)
+		totalGasUsed uint64
+	)
+	for i, tx := range txs {
+		msg, err := core.TransactionToMessage(tx, signer, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		state.SetTxContext(tx.Hash(), i)
+
+		evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), state, config, vm.Config{})
+		go func() {
+			<-ctx.Done()
+			evm.Cancel()
+		}()
+		before := state.GetBalance(header.Coinbase)
+		result, err := core.ApplyMessage(evm, msg, gp)
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %v: %w", tx.Hash(), err)
+		}
+		state.Finalise(rules.IsEIP158)
+
+		// Split the coinbase increase between the transaction fees and the direct
+		// payments made by the transaction.
+		price := new(big.Int).Set(msg.GasPrice)
+		if header.BaseFee != nil && (feeToMiner == nil || *feeToMiner != header.Coinbase) {
+			price.Sub(price, header.BaseFee)
+		}
+		fees := new(big.Int).Mul(price, new(big.Int).SetUint64(result.UsedGas))
+		diff := new(big.Int).Sub(state.GetBalance(header.Coinbase), before)
+
+		res := map[string]interface{}{
+			"txHash":            tx.Hash(),
+			"gasUsed":           hexutil.Uint64(result.UsedGas),
+			"gasPrice":          (*hexutil.Big)(msg.GasPrice),
+			"gasFees":           (*hexutil.Big)(fees),
+			"fromAddress":       msg.From,
+			"toAddress":         tx.To(),
+			"coinbaseDiff":      (*hexutil.Big)(diff),
+			"ethSentToCoinbase": (*hexutil.Big)(new(big.Int).Sub(diff, fees)),
+		}
+		if result.Err != nil {
+			res["error"] = result.Err.Error()
+			if revert := result.Revert(); len(revert) > 0 {
+				res["revert"] = hexutil.Bytes(revert)
+			}
+		} else {
+			res["value"] = hexutil.Bytes(result.Return())
+		}
+		results = append(results, res)
+
+		gasFees.Add(gasFees, fees)
+		totalGasUsed += result.UsedGas
+	}
+	var (
+		coinbaseDiff = new(big.Int).Sub(state.GetBalance(header.Coinbase), bundleBefore)
+		bundle       = &miner.Bundle{Txs: txs}
+		price        = new(big.Int)
+	)
+	if totalGasUsed > 0 {
+		price.Div(coinbaseDiff, new(big.Int).SetUint64(totalGasUsed))
+	}
+	return map[string]interface{}{
+		"results":           results,
+		"bundleHash":        bundle.Hash(),
+		"coinbaseDiff":      (*hexutil.Big)(coinbaseDiff),
+		"gasFees":           (*hexutil.Big)(gasFees),
+		"ethSentToCoinbase": (*hexutil.Big)(new(big.Int).Sub(coinbaseDiff, gasFees)),
+		"totalGasUsed":      hexutil.Uint64(totalGasUsed),
+		"bundleGasPrice":    (*hexutil.Big)(price),
+		"stateBlockNumber":  hexutil.Uint64(parent.Number.Uint64()),
+	}, nil
+}
@@ -14,6 +14,8 @@
 package eth
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -30,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -39,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -81,6 +85,11 @@ type Ethereum struct {
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
 
+	addressIndexer *core.ChainIndexer // Address indexer operating during block imports, nil if disabled
+
+	traceStore   *tracers.TraceStore // Store of the block traces, nil if disabled
+	traceIndexer *core.ChainIndexer  // Trace indexer operating during block imports, nil if disabled
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -127,17 +136,34 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	// The state scheme can't be switched once some state is stored, default
+	// to the stored one if none is configured
+	scheme, stored := config.StateScheme, rawdb.ReadStateScheme(chainDb)
+	if scheme == "" {
+		scheme = stored
+	}
+	if scheme == "" {
+		scheme = rawdb.HashScheme
+	}
+	if stored != "" && stored != scheme {
+		return nil, fmt.Errorf("incompatible state scheme, stored: %s, configured: %s", stored, scheme)
+	}
+	if scheme == rawdb.PathScheme && config.NoPruning {
+		return nil, errors.New("archive mode is not supported by the path-based state scheme")
+	}
 	if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb, stack.ResolvePath(config.TrieCleanCacheJournal)); err != nil {
 		log.Error("Failed to recover state", "error", err)
 	}
 	// Transfer mining-related config to the ethash config.
 	ethashConfig := config.Ethash
 	ethashConfig.NotifyFull = config.Miner.NotifyFull
-	cliqueConfig, err := core.LoadCliqueConfig(chainDb, config.Genesis)
+	ethashConfig.StratumAddr = config.Miner.Stratum
+	ethashConfig.StratumDifficulty = config.Miner.StratumDifficulty
+	chainConfig, err := core.LoadChainConfig(chainDb, config.Genesis)
 	if err != nil {
 		return nil, err
 	}
-	engine := ethconfig.CreateConsensusEngine(stack, &ethashConfig, cliqueConfig, config.Miner.Notify, config.Miner.Noverify, chainDb)
+	engine := ethconfig.CreateConsensusEngine(stack, &ethashConfig, chainConfig, config.Miner.Notify, config.Miner.Noverify, chainDb)
 
 	eth := &Ethereum{
 		config:            config,
@@ -187,10 +213,15 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			StateScheme:         scheme,
+			StateHistory:        config.StateHistory,
 		}
 	)
 	// Override the chain config with provided settings.
 	var overrides core.ChainOverrides
+	if config.OverrideLondon != nil {
+		overrides.OverrideLondon = config.OverrideLondon
+	}
 	if config.OverrideShanghai != nil {
 		overrides.OverrideShanghai = config.OverrideShanghai
 	}
@@ -198,11 +229,30 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	if config.Finality.MaxReorgDepth > 0 || config.Finality.MESS {
+		log.Info("Enabling chain finality policy", "maxreorgdepth", config.Finality.MaxReorgDepth, "mess", config.Finality.MESS)
+	}
+	eth.blockchain.SetFinalityPolicy(config.Finality)
 	eth.bloomIndexer.Start(eth.blockchain)
 
+	if config.AddressIndex {
+		var tracer core.AddressTracer
+		if config.AddressIndexInternal {
+			tracer = core.NewInternalCallTracer(eth.blockchain, func(ctx context.Context, parent *types.Block) (*state.StateDB, func(), error) {
+				return eth.StateAtBlock(ctx, parent, addressIndexReexec, nil, true, false)
+			})
+		}
+		log.Info("Enabling address index", "internal", config.AddressIndexInternal, "tail", config.AddressIndexTail)
+		eth.addressIndexer = core.NewAddressIndexer(chainDb, eth.blockchain.Config(), params.AddressIndexBlocks, params.AddressIndexConfirms, config.AddressIndexTail, tracer)
+		eth.addressIndexer.Start(eth.blockchain)
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = stack.ResolvePath(config.TxPool.Snapshot)
+	}
 	eth.txPool = txpool.NewTxPool(config.TxPool, eth.blockchain.Config(), eth.blockchain)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
@@ -239,6 +289,16 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	eth.APIBackend.gpo = gasprice.NewOracle(eth.APIBackend, gpoParams)
 
+	if config.TraceStore != "" {
+		eth.traceStore, err = tracers.NewTraceStore(chainDb, config.TraceStore, json.RawMessage(config.TraceStoreConfig), config.TraceStoreTail)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace store: %v", err)
+		}
+		log.Info("Enabling trace store", "tracer", config.TraceStore, "tail", config.TraceStoreTail)
+		eth.traceIndexer = tracers.NewTraceIndexer(eth.APIBackend, eth.traceStore, params.TraceStoreBlocks, params.TraceStoreConfirms)
+		eth.traceIndexer.Start(eth.blockchain)
+	}
+
 	// Setup DNS discovery iterators.
 	dnsclient := dnsdisc.NewClient(dnsdisc.Config{})
 	eth.ethDialCandidates, err = dnsclient.NewIterator(eth.config.EthDiscoveryURLs...)
@@ -297,6 +357,12 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "miner",
 			Service:   NewMinerAPI(s),
+		}, {
+			Namespace: "eth",
+			Service:   NewBundleAPI(s),
+		}, {
+			Namespace: "eth",
+			Service:   NewAddressAPI(s),
 		}, {
 			Namespace: "eth",
 			Service:   downloader.NewDownloaderAPI(s.handler.downloader, s.eventMux),
@@ -474,6 +540,8 @@ func (s *Ethereum) Synced() bool                       { return atomic.LoadUint3
 func (s *Ethereum) SetSynced()                         { atomic.StoreUint32(&s.handler.acceptTxs, 1) }
 func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
 func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
+func (s *Ethereum) AddressIndexer() *core.ChainIndexer { return s.addressIndexer }
+func (s *Ethereum) TraceStore() *tracers.TraceStore    { return s.traceStore }
 func (s *Ethereum) Merger() *consensus.Merger          { return s.merger }
 func (s *Ethereum) SyncMode() downloader.SyncMode {
 	mode, _ := s.handler.chainSync.modeAndLocalHead()
@@ -524,6 +592,13 @@ func (s *Ethereum) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.addressIndexer != nil {
+		s.addressIndexer.Close()
+	}
+	if s.traceIndexer != nil {
+		s.traceIndexer.Close()
+		s.traceStore.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
 	s.miner.Close()
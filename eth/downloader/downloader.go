@@ -404,6 +404,11 @@ func (d *Downloader) synchronise(id string, hash common.Hash, td, ttd *big.Int,
 		if snapshots := d.blockchain.Snapshots(); snapshots != nil { // Only nil in tests
 			snapshots.Disable()
 		}
+		// The path-based trie database keeps a single persistent state, which
+		// gets rewritten by state sync. Drop the states in memory meanwhile.
+		if err := d.blockchain.TrieDB().Disable(); err != nil {
+			return err
+		}
 	}
 	// Reset the queue, peer set and wake channels to clean any internal leftover state
 	d.queue.Reset(blockCacheMaxItems, blockCacheInitialItems)
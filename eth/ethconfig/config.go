@@ -79,7 +79,9 @@ var Defaults = Config{
 	TrieDirtyCache:          256,
 	TrieTimeout:             60 * time.Minute,
 	SnapshotCache:           102,
+	StateHistory:            params.FullImmutabilityThreshold,
 	FilterLogCacheSize:      32,
+	TraceFilterRangeLimit:   100,
 	Miner:                   miner.DefaultConfig,
 	TxPool:                  txpool.DefaultConfig,
 	RPCGasCap:               50000000,
@@ -131,6 +133,16 @@ type Config struct {
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
+	// Address index options
+	AddressIndex         bool   `toml:",omitempty"` // Whether to index the transactions by the addresses taking part in them
+	AddressIndexInternal bool   `toml:",omitempty"` // Whether to also index the addresses called internally (requires the historical states)
+	AddressIndexTail     uint64 `toml:",omitempty"` // The number of recent blocks to keep indexed (0 = entire chain)
+
+	// Trace store options
+	TraceStore       string `toml:",omitempty"` // Name of the native tracer to store the block traces of (empty = disabled)
+	TraceStoreConfig string `toml:",omitempty"` // JSON configuration of the trace store tracer
+	TraceStoreTail   uint64 `toml:",omitempty"` // The number of recent blocks to keep the traces of (0 = entire chain)
+
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
 	// presence of these blocks for every new peer connection.
@@ -163,10 +175,25 @@ type Config struct {
 	TrieTimeout             time.Duration
 	SnapshotCache           int
 	Preimages               bool
+	StateScheme             string `toml:",omitempty"` // Scheme used to store the state trie nodes (rawdb.HashScheme or rawdb.PathScheme), the stored one if empty
+	StateHistory            uint64 `toml:",omitempty"` // Number of recent blocks whose state can be rolled back to with the path-based scheme, 0 for all
 
 	// This is the number of blocks for which logs will be cached in the filter system.
 	FilterLogCacheSize int
 
+	// FilterRangeLimit and FilterLogLimit cap the number of blocks a log query
+	// may span and the number of logs it may return (0 = unlimited).
+	FilterRangeLimit uint64 `toml:",omitempty"`
+	FilterLogLimit   int    `toml:",omitempty"`
+
+	// TraceFilterRangeLimit caps the number of blocks a trace_filter query may
+	// span (0 = unlimited).
+	TraceFilterRangeLimit uint64 `toml:",omitempty"`
+
+	// Finality is the policy guarding the proof-of-work fork choice against deep
+	// reorgs, such as those of majority hashrate attacks.
+	Finality core.FinalityConfig
+
 	// Mining options
 	Miner miner.Config
 
@@ -201,16 +228,20 @@ type Config struct {
 	// CheckpointOracle is the configuration for checkpoint oracle.
 	CheckpointOracle *params.CheckpointOracleConfig `toml:",omitempty"`
 
+	// OverrideLondon schedules the London fork, enabling EIP-1559 on a chain
+	// which doesn't bundle it yet.
+	OverrideLondon *uint64 `toml:",omitempty"`
+
 	// OverrideShanghai (TODO: remove after the fork)
 	OverrideShanghai *uint64 `toml:",omitempty"`
 }
 
 // CreateConsensusEngine creates a consensus engine for the given chain configuration.
-func CreateConsensusEngine(stack *node.Node, ethashConfig *ethash.Config, cliqueConfig *params.CliqueConfig, notify []string, noverify bool, db ethdb.Database) consensus.Engine {
+func CreateConsensusEngine(stack *node.Node, ethashConfig *ethash.Config, chainConfig *params.ChainConfig, notify []string, noverify bool, db ethdb.Database) consensus.Engine {
 	// If proof-of-authority is requested, set it up
 	var engine consensus.Engine
-	if cliqueConfig != nil {
-		engine = clique.New(cliqueConfig, db)
+	if chainConfig.Clique != nil {
+		engine = clique.New(chainConfig.Clique, db)
 	} else {
 		switch ethashConfig.PowMode {
 		case ethash.ModeFake:
@@ -221,16 +252,19 @@ func CreateConsensusEngine(stack *node.Node, ethashConfig *ethash.Config, clique
 			log.Warn("Ethash used in shared mode")
 		}
 		engine = ethash.New(ethash.Config{
-			PowMode:          ethashConfig.PowMode,
-			CacheDir:         stack.ResolvePath(ethashConfig.CacheDir),
-			CachesInMem:      ethashConfig.CachesInMem,
-			CachesOnDisk:     ethashConfig.CachesOnDisk,
-			CachesLockMmap:   ethashConfig.CachesLockMmap,
-			DatasetDir:       ethashConfig.DatasetDir,
-			DatasetsInMem:    ethashConfig.DatasetsInMem,
-			DatasetsOnDisk:   ethashConfig.DatasetsOnDisk,
-			DatasetsLockMmap: ethashConfig.DatasetsLockMmap,
-			NotifyFull:       ethashConfig.NotifyFull,
+			PowMode:           ethashConfig.PowMode,
+			CacheDir:          stack.ResolvePath(ethashConfig.CacheDir),
+			CachesInMem:       ethashConfig.CachesInMem,
+			CachesOnDisk:      ethashConfig.CachesOnDisk,
+			CachesLockMmap:    ethashConfig.CachesLockMmap,
+			DatasetDir:        ethashConfig.DatasetDir,
+			DatasetsInMem:     ethashConfig.DatasetsInMem,
+			DatasetsOnDisk:    ethashConfig.DatasetsOnDisk,
+			DatasetsLockMmap:  ethashConfig.DatasetsLockMmap,
+			NotifyFull:        ethashConfig.NotifyFull,
+			StratumAddr:       ethashConfig.StratumAddr,
+			StratumDifficulty: ethashConfig.StratumDifficulty,
+			ECIP1099Block:     chainConfig.ECIP1099Block(),
 		}, notify, noverify)
 		engine.(*ethash.Ethash).SetThreads(-1) // Disable CPU mining
 	}
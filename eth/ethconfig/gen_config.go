@@ -26,6 +26,12 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NoPruning               bool
 		NoPrefetch              bool
 		TxLookupLimit           uint64                 `toml:",omitempty"`
+		AddressIndex            bool                   `toml:",omitempty"`
+		AddressIndexInternal    bool                   `toml:",omitempty"`
+		AddressIndexTail        uint64                 `toml:",omitempty"`
+		TraceStore              string                 `toml:",omitempty"`
+		TraceStoreConfig        string                 `toml:",omitempty"`
+		TraceStoreTail          uint64                 `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
@@ -48,7 +54,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TrieTimeout             time.Duration
 		SnapshotCache           int
 		Preimages               bool
+		StateScheme             string `toml:",omitempty"`
+		StateHistory            uint64 `toml:",omitempty"`
 		FilterLogCacheSize      int
+		FilterRangeLimit        uint64 `toml:",omitempty"`
+		FilterLogLimit          int    `toml:",omitempty"`
+		TraceFilterRangeLimit   uint64 `toml:",omitempty"`
+		Finality                core.FinalityConfig
 		Miner                   miner.Config
 		Ethash                  ethash.Config
 		TxPool                  txpool.Config
@@ -60,6 +72,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		RPCTxFeeCap             float64
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
+		OverrideLondon          *uint64                        `toml:",omitempty"`
 		OverrideShanghai        *uint64                        `toml:",omitempty"`
 	}
 	var enc Config
@@ -71,6 +84,12 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
 	enc.TxLookupLimit = c.TxLookupLimit
+	enc.AddressIndex = c.AddressIndex
+	enc.AddressIndexInternal = c.AddressIndexInternal
+	enc.AddressIndexTail = c.AddressIndexTail
+	enc.TraceStore = c.TraceStore
+	enc.TraceStoreConfig = c.TraceStoreConfig
+	enc.TraceStoreTail = c.TraceStoreTail
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -93,7 +112,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TrieTimeout = c.TrieTimeout
 	enc.SnapshotCache = c.SnapshotCache
 	enc.Preimages = c.Preimages
+	enc.StateScheme = c.StateScheme
+	enc.StateHistory = c.StateHistory
 	enc.FilterLogCacheSize = c.FilterLogCacheSize
+	enc.FilterRangeLimit = c.FilterRangeLimit
+	enc.FilterLogLimit = c.FilterLogLimit
+	enc.TraceFilterRangeLimit = c.TraceFilterRangeLimit
+	enc.Finality = c.Finality
 	enc.Miner = c.Miner
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
@@ -105,6 +130,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
+	enc.OverrideLondon = c.OverrideLondon
 	enc.OverrideShanghai = c.OverrideShanghai
 	return &enc, nil
 }
@@ -120,6 +146,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NoPruning               *bool
 		NoPrefetch              *bool
 		TxLookupLimit           *uint64                `toml:",omitempty"`
+		AddressIndex            *bool                  `toml:",omitempty"`
+		AddressIndexInternal    *bool                  `toml:",omitempty"`
+		AddressIndexTail        *uint64                `toml:",omitempty"`
+		TraceStore              *string                `toml:",omitempty"`
+		TraceStoreConfig        *string                `toml:",omitempty"`
+		TraceStoreTail          *uint64                `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
@@ -142,7 +174,13 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TrieTimeout             *time.Duration
 		SnapshotCache           *int
 		Preimages               *bool
+		StateScheme             *string `toml:",omitempty"`
+		StateHistory            *uint64 `toml:",omitempty"`
 		FilterLogCacheSize      *int
+		FilterRangeLimit        *uint64 `toml:",omitempty"`
+		FilterLogLimit          *int    `toml:",omitempty"`
+		TraceFilterRangeLimit   *uint64 `toml:",omitempty"`
+		Finality                *core.FinalityConfig
 		Miner                   *miner.Config
 		Ethash                  *ethash.Config
 		TxPool                  *txpool.Config
@@ -154,6 +192,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		RPCTxFeeCap             *float64
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
+		OverrideLondon          *uint64                        `toml:",omitempty"`
 		OverrideShanghai        *uint64                        `toml:",omitempty"`
 	}
 	var dec Config
@@ -184,6 +223,24 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
+	if dec.AddressIndex != nil {
+		c.AddressIndex = *dec.AddressIndex
+	}
+	if dec.AddressIndexInternal != nil {
+		c.AddressIndexInternal = *dec.AddressIndexInternal
+	}
+	if dec.AddressIndexTail != nil {
+		c.AddressIndexTail = *dec.AddressIndexTail
+	}
+	if dec.TraceStore != nil {
+		c.TraceStore = *dec.TraceStore
+	}
+	if dec.TraceStoreConfig != nil {
+		c.TraceStoreConfig = *dec.TraceStoreConfig
+	}
+	if dec.TraceStoreTail != nil {
+		c.TraceStoreTail = *dec.TraceStoreTail
+	}
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
@@ -250,9 +307,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Preimages != nil {
 		c.Preimages = *dec.Preimages
 	}
+	if dec.StateScheme != nil {
+		c.StateScheme = *dec.StateScheme
+	}
+	if dec.StateHistory != nil {
+		c.StateHistory = *dec.StateHistory
+	}
 	if dec.FilterLogCacheSize != nil {
 		c.FilterLogCacheSize = *dec.FilterLogCacheSize
 	}
+	if dec.FilterRangeLimit != nil {
+		c.FilterRangeLimit = *dec.FilterRangeLimit
+	}
+	if dec.FilterLogLimit != nil {
+		c.FilterLogLimit = *dec.FilterLogLimit
+	}
+	if dec.TraceFilterRangeLimit != nil {
+		c.TraceFilterRangeLimit = *dec.TraceFilterRangeLimit
+	}
+	if dec.Finality != nil {
+		c.Finality = *dec.Finality
+	}
 	if dec.Miner != nil {
 		c.Miner = *dec.Miner
 	}
@@ -286,6 +361,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.CheckpointOracle != nil {
 		c.CheckpointOracle = dec.CheckpointOracle
 	}
+	if dec.OverrideLondon != nil {
+		c.OverrideLondon = dec.OverrideLondon
+	}
 	if dec.OverrideShanghai != nil {
 		c.OverrideShanghai = dec.OverrideShanghai
 	}
@@ -13,7 +13,9 @@
 package filters
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -322,8 +325,27 @@ func (api *FilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 	return logsSub.ID, nil
 }
 
+// LogPageArgs opts eth_getLogs into pagination, requesting a page of its
+// results.
+type LogPageArgs struct {
+	Limit  hexutil.Uint  `json:"limit"`  // Maximum number of logs in the page, capped by the node
+	Cursor hexutil.Bytes `json:"cursor"` // Cursor of the previous page to continue from
+}
+
+// LogPage is a page of the results of eth_getLogs. The cursor is nil on the last
+// page of the query.
+type LogPage struct {
+	Logs   []*types.Log   `json:"logs"`
+	Cursor *hexutil.Bytes `json:"cursor"`
+}
+
 // GetLogs returns logs matching the given argument that are stored within the state.
-func (api *FilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+//
+// If page is given, the query is paginated: instead of failing when exceeding the
+// block range or log limits of the node, a page of the results is returned along
+// with a cursor to continue the query from. Passing the cursor back with the same
+// criteria returns the next page.
+func (api *FilterAPI) GetLogs(ctx context.Context, crit FilterCriteria, page *LogPageArgs) (interface{}, error) {
 	var filter *Filter
 	if crit.BlockHash != nil {
 		// Block filter requested, construct a single-shot filter
@@ -341,12 +363,84 @@ func (api *FilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*type
 		// Construct the range filter
 		filter = api.sys.NewRangeFilter(begin, end, crit.Addresses, crit.Topics)
 	}
+	if page != nil {
+		var cursor *logCursor
+		if len(page.Cursor) > 0 {
+			var err error
+			if cursor, err = decodeLogCursor(page.Cursor, crit.digest()); err != nil {
+				return nil, err
+			}
+		}
+		filter.paginateFrom(cursor, int(page.Limit))
+	}
 	// Run the filter and return all the logs
 	logs, err := filter.Logs(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return returnLogs(logs), err
+	if page == nil {
+		return returnLogs(logs), nil
+	}
+	result := &LogPage{Logs: returnLogs(logs)}
+	if filter.next != nil {
+		cursor := filter.next.encode(crit.digest())
+		result.Cursor = &cursor
+	}
+	return result, nil
+}
+
+// digest returns a short hash of the criteria, binding the cursors of a
+// paginated query to it.
+func (crit *FilterCriteria) digest() []byte {
+	var blob []byte
+	if crit.BlockHash != nil {
+		blob = append(blob, crit.BlockHash[:]...)
+	}
+	for _, number := range []*big.Int{crit.FromBlock, crit.ToBlock} {
+		if number != nil {
+			blob = binary.BigEndian.AppendUint64(blob, uint64(number.Int64()))
+		} else {
+			blob = append(blob, 0)
+		}
+	}
+	for _, addr := range crit.Addresses {
+		blob = append(blob, addr[:]...)
+	}
+	for _, topics := range crit.Topics {
+		blob = append(blob, byte(len(topics)))
+		for _, topic := range topics {
+			blob = append(blob, topic[:]...)
+		}
+	}
+	return crypto.Keccak256(blob)[:8]
+}
+
+// encode packs the cursor into an opaque token bound to the query with the
+// given criteria digest.
+func (c *logCursor) encode(digest []byte) hexutil.Bytes {
+	enc := make([]byte, 32)
+	binary.BigEndian.PutUint64(enc[0:], c.Block)
+	binary.BigEndian.PutUint64(enc[8:], c.Index)
+	binary.BigEndian.PutUint64(enc[16:], c.End)
+	copy(enc[24:], digest)
+	return enc
+}
+
+// decodeLogCursor unpacks a cursor token, checking that it belongs to the query
+// with the given criteria digest.
+func decodeLogCursor(enc []byte, digest []byte) (*logCursor, error) {
+	if len(enc) != 32 || !bytes.Equal(enc[24:], digest) {
+		return nil, errors.New("invalid cursor")
+	}
+	cursor := &logCursor{
+		Block: binary.BigEndian.Uint64(enc[0:]),
+		Index: binary.BigEndian.Uint64(enc[8:]),
+		End:   binary.BigEndian.Uint64(enc[16:]),
+	}
+	if cursor.Block > cursor.End {
+		return nil, errors.New("invalid cursor")
+	}
+	return cursor, nil
 }
 
 // UninstallFilter removes the filter with the given filter id.
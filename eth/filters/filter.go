@@ -15,6 +15,7 @@ package filters
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -23,6 +24,20 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+var (
+	errExceedRangeLimit = errors.New("block range too large")
+	errExceedLogLimit   = errors.New("query returned too many results")
+	errPendingPage      = errors.New("pending logs cannot be paginated")
+)
+
+// logCursor is the position within the chain a paginated log query resumes
+// from.
+type logCursor struct {
+	Block uint64 // Number of the block to resume from
+	Index uint64 // Number of matching logs of the block already returned
+	End   uint64 // Last block of the query range
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	sys *FilterSystem
@@ -32,8 +47,14 @@ type Filter struct {
 
 	block      *common.Hash // Block hash if filtering a single block
 	begin, end int64        // Range interval if filtering multiple blocks
+	last       int64        // Last block of the range, before capping it to the range limit
 
 	matcher *bloombits.Matcher
+
+	paginate bool       // Whether to cut the results short on the limits instead of failing
+	limit    int        // Maximum number of logs to return, zero if unlimited
+	resume   *logCursor // Position the query resumes from, if continuing a previous page
+	next     *logCursor // Position to continue from, if the results were cut short
 }
 
 // NewRangeFilter creates a new filter which uses a bloom filter on blocks to
@@ -85,6 +106,21 @@ func newFilter(sys *FilterSystem, addresses []common.Address, topics [][]common.
 		sys:       sys,
 		addresses: addresses,
 		topics:    topics,
+		limit:     sys.cfg.LogLimit,
+	}
+}
+
+// paginateFrom turns the filter into a paginated one, returning at most limit
+// logs, and resuming from the given cursor if continuing a previous page. The
+// limit is capped by the configured log limit.
+func (f *Filter) paginateFrom(cursor *logCursor, limit int) {
+	f.paginate = true
+	if limit > 0 && (f.limit == 0 || limit < f.limit) {
+		f.limit = limit
+	}
+	if cursor != nil {
+		f.resume = cursor
+		f.begin, f.end = int64(cursor.Block), int64(cursor.End)
 	}
 }
 
@@ -100,13 +136,26 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		if header == nil {
 			return nil, errors.New("unknown block")
 		}
-		return f.blockLogs(ctx, header)
+		number := header.Number.Uint64()
+		if f.resume != nil && f.resume.Block != number {
+			return nil, errors.New("cursor does not match block")
+		}
+		f.last = int64(number)
+
+		found, err := f.blockLogs(ctx, header)
+		if err != nil {
+			return nil, err
+		}
+		return f.collect(nil, found, number)
 	}
 	// Short-cut if all we care about is pending logs
 	if f.begin == rpc.PendingBlockNumber.Int64() {
 		if f.end != rpc.PendingBlockNumber.Int64() {
 			return nil, errors.New("invalid block range")
 		}
+		if f.paginate {
+			return nil, errPendingPage
+		}
 		return f.pendingLogs()
 	}
 	// Figure out the limits of the filter range
@@ -149,6 +198,22 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end, err = resolveSpecial(f.end); err != nil {
 		return nil, err
 	}
+	if pending && f.paginate {
+		return nil, errPendingPage
+	}
+	// Cap the range to the configured limit, or fail if the caller can't resume
+	// from the end of it
+	var capped *logCursor
+
+	f.last = f.end
+	if limit := f.sys.cfg.RangeLimit; limit > 0 && f.end >= f.begin && uint64(f.end-f.begin) >= limit {
+		if !f.paginate {
+			return nil, fmt.Errorf("%w (%d>%d)", errExceedRangeLimit, f.end-f.begin+1, limit)
+		}
+		f.end = f.begin + int64(limit) - 1
+		capped = &logCursor{Block: uint64(f.end) + 1, End: uint64(f.last)}
+		pending = false
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs           []*types.Log
@@ -165,8 +230,15 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 			return logs, err
 		}
 	}
-	rest, err := f.unindexedLogs(ctx, end)
-	logs = append(logs, rest...)
+	if f.next == nil {
+		logs, err = f.unindexedLogs(ctx, logs, end)
+		if err != nil {
+			return logs, err
+		}
+	}
+	if f.next == nil {
+		f.next = capped
+	}
 	if pending {
 		pendingLogs, err := f.pendingLogs()
 		if err != nil {
@@ -216,7 +288,9 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 			if err != nil {
 				return logs, err
 			}
-			logs = append(logs, found...)
+			if logs, err = f.collect(logs, found, number); err != nil || f.next != nil {
+				return logs, err
+			}
 
 		case <-ctx.Done():
 			return logs, ctx.Err()
@@ -224,11 +298,9 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 }
 
-// unindexedLogs returns the logs matching the filter criteria based on raw block
+// unindexedLogs appends the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
-	var logs []*types.Log
-
+func (f *Filter) unindexedLogs(ctx context.Context, logs []*types.Log, end uint64) ([]*types.Log, error) {
 	for ; f.begin <= int64(end); f.begin++ {
 		if f.begin%10 == 0 && ctx.Err() != nil {
 			return logs, ctx.Err()
@@ -241,11 +313,37 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 		if err != nil {
 			return logs, err
 		}
-		logs = append(logs, found...)
+		if logs, err = f.collect(logs, found, uint64(f.begin)); err != nil || f.next != nil {
+			return logs, err
+		}
 	}
 	return logs, nil
 }
 
+// collect appends the matching logs of a block to the results, dropping the
+// ones already returned by the previous page. If the log limit is reached, the
+// results are cut short and the position to continue from is recorded, unless
+// the filter is not paginated, in which case an error is returned.
+func (f *Filter) collect(logs []*types.Log, found []*types.Log, number uint64) ([]*types.Log, error) {
+	var skipped uint64
+	if f.resume != nil && f.resume.Block == number {
+		skipped = f.resume.Index
+		if skipped > uint64(len(found)) {
+			skipped = uint64(len(found))
+		}
+		found = found[skipped:]
+	}
+	if f.limit == 0 || len(logs)+len(found) <= f.limit {
+		return append(logs, found...), nil
+	}
+	if !f.paginate {
+		return nil, fmt.Errorf("%w (>%d)", errExceedLogLimit, f.limit)
+	}
+	n := f.limit - len(logs)
+	f.next = &logCursor{Block: number, Index: skipped + uint64(n), End: uint64(f.last)}
+	return append(logs, found[:n]...), nil
+}
+
 // blockLogs returns the logs matching the filter criteria within a single block.
 func (f *Filter) blockLogs(ctx context.Context, header *types.Header) ([]*types.Log, error) {
 	if bloomFilter(header.Bloom, f.addresses, f.topics) {
@@ -14,13 +14,17 @@ package filters
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/bitutil"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -235,3 +239,128 @@ func TestFilters(t *testing.T) {
 		}
 	}
 }
+
+// Tests that paginated log queries return all the logs of the range across
+// pages, through both the bloombits index and the unindexed tail, and that the
+// limits are enforced on queries that are not paginated.
+func TestLogsPagination(t *testing.T) {
+	var (
+		db, _   = rawdb.NewLevelDBDatabase(t.TempDir(), 0, 0, "", false)
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key1.PublicKey)
+		topic   = common.BytesToHash([]byte("topic"))
+		other   = common.BytesToHash([]byte("other"))
+
+		gspec = &core.Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   core.GenesisAlloc{addr: {Balance: big.NewInt(1000000)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		sectionSize = params.BloomBitsBlocks
+	)
+	defer db.Close()
+
+	// Generate a chain spanning a full bloombits section, with batches of logs
+	// around the section boundary
+	matching := map[int]bool{1: true, 2: true, 500: true, 4093: true, 4094: true, 4095: true, 4096: true, 4100: true}
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), int(sectionSize)+8, func(i int, gen *core.BlockGen) {
+		if !matching[i] {
+			return
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{
+			{Address: addr, Topics: []common.Hash{topic}},
+			{Address: addr, Topics: []common.Hash{other}},
+			{Address: addr, Topics: []common.Hash{topic}},
+		}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+	})
+	gspec.MustCommit(db)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Index the first section of the chain
+	gen, err := bloombits.NewGenerator(uint(sectionSize))
+	if err != nil {
+		t.Fatalf("failed to create bloombits generator: %v", err)
+	}
+	for i := uint64(0); i < sectionSize; i++ {
+		gen.AddBloom(uint(i), rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, i), i).Bloom)
+	}
+	for i := 0; i < types.BloomBitLength; i++ {
+		bits, _ := gen.Bitset(uint(i))
+		rawdb.WriteBloomBits(db, uint(i), 0, rawdb.ReadCanonicalHash(db, sectionSize-1), bitutil.CompressBytes(bits))
+	}
+	// paginate runs the query with the given page size until exhausted
+	crit := FilterCriteria{FromBlock: big.NewInt(0), Addresses: []common.Address{addr}, Topics: [][]common.Hash{{topic}}}
+	paginate := func(api *FilterAPI, crit FilterCriteria, limit uint) ([]*types.Log, int) {
+		var (
+			logs   []*types.Log
+			cursor hexutil.Bytes
+			pages  int
+		)
+		for {
+			res, err := api.GetLogs(context.Background(), crit, &LogPageArgs{Limit: hexutil.Uint(limit), Cursor: cursor})
+			if err != nil {
+				t.Fatalf("page %d: failed to retrieve logs: %v", pages, err)
+			}
+			page := res.(*LogPage)
+			logs = append(logs, page.Logs...)
+			pages++
+			if page.Cursor == nil {
+				return logs, pages
+			}
+			cursor = *page.Cursor
+		}
+	}
+	checkLogs := func(name string, have []*types.Log) {
+		if len(have) != 2*len(matching) {
+			t.Fatalf("%s: log count mismatch: have %d, want %d", name, len(have), 2*len(matching))
+		}
+		for i := 1; i < len(have); i++ {
+			prev, log := have[i-1], have[i]
+			if log.BlockNumber < prev.BlockNumber || (log.BlockNumber == prev.BlockNumber && log.Index <= prev.Index) {
+				t.Fatalf("%s: log %d out of order or duplicated: block %d index %d after block %d index %d", name, i, log.BlockNumber, log.Index, prev.BlockNumber, prev.Index)
+			}
+		}
+	}
+	for _, backend := range []*testBackend{{db: db}, {db: db, sections: 1}} {
+		var (
+			unlimited = NewFilterAPI(NewFilterSystem(backend, Config{}), false)
+			limited   = NewFilterAPI(NewFilterSystem(backend, Config{RangeLimit: 1000, LogLimit: 5}), false)
+		)
+		res, err := unlimited.GetLogs(context.Background(), crit, nil)
+		if err != nil {
+			t.Fatalf("sections %d: failed to retrieve logs: %v", backend.sections, err)
+		}
+		checkLogs("unpaginated", res.([]*types.Log))
+
+		// Pages cut short by the requested limit, mid-block too
+		logs, pages := paginate(unlimited, crit, 3)
+		checkLogs("page size 3", logs)
+		if pages != 6 {
+			t.Errorf("sections %d: page count mismatch: have %d, want 6", backend.sections, pages)
+		}
+		// Pages cut short by the node limits
+		logs, _ = paginate(limited, crit, 0)
+		checkLogs("node limits", logs)
+
+		// Queries not opting into pagination fail on the limits
+		if _, err := limited.GetLogs(context.Background(), crit, nil); !errors.Is(err, errExceedRangeLimit) {
+			t.Errorf("sections %d: range limit error mismatch: have %v, want %v", backend.sections, err, errExceedRangeLimit)
+		}
+		narrow := FilterCriteria{FromBlock: big.NewInt(4000), Addresses: crit.Addresses, Topics: crit.Topics}
+		if _, err := limited.GetLogs(context.Background(), narrow, nil); !errors.Is(err, errExceedLogLimit) {
+			t.Errorf("sections %d: log limit error mismatch: have %v, want %v", backend.sections, err, errExceedLogLimit)
+		}
+		// Cursors are bound to the criteria of their query
+		res, _ = unlimited.GetLogs(context.Background(), crit, &LogPageArgs{Limit: 1})
+		if _, err := unlimited.GetLogs(context.Background(), narrow, &LogPageArgs{Cursor: *res.(*LogPage).Cursor}); err == nil {
+			t.Errorf("sections %d: cursor of another query accepted", backend.sections)
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -57,7 +58,7 @@ func TestFeeHistory(t *testing.T) {
 		backend := newTestBackend(t, big.NewInt(16), c.pending)
 		oracle := NewOracle(backend, config)
 
-		first, reward, baseFee, ratio, err := oracle.FeeHistory(context.Background(), c.count, c.last, c.percent)
+		first, reward, baseFee, ratio, burnt, err := oracle.FeeHistory(context.Background(), c.count, c.last, c.percent)
 		backend.teardown()
 		expReward := c.expCount
 		if len(c.percent) == 0 {
@@ -80,8 +81,42 @@ func TestFeeHistory(t *testing.T) {
 		if len(ratio) != c.expCount {
 			t.Fatalf("Test case %d: gasUsedRatio array length mismatch, want %d, got %d", i, c.expCount, len(ratio))
 		}
+		if len(burnt) != c.expCount {
+			t.Fatalf("Test case %d: burntFees array length mismatch, want %d, got %d", i, c.expCount, len(burnt))
+		}
 		if err != c.expErr && !errors.Is(err, c.expErr) {
 			t.Fatalf("Test case %d: error mismatch, want %v, got %v", i, c.expErr, err)
 		}
 	}
 }
+
+// Tests that the fee history reports the base fees burned, and none if the
+// chain pays them out.
+func TestFeeHistoryBurntFees(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(16), false)
+	defer backend.teardown()
+
+	first, _, baseFee, _, burnt, err := NewOracle(backend, Config{MaxHeaderHistory: 1000}).FeeHistory(context.Background(), 10, 30, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	for i, fee := range burnt {
+		header := backend.chain.GetHeaderByNumber(first.Uint64() + uint64(i))
+		want := new(big.Int).Mul(baseFee[i], new(big.Int).SetUint64(header.GasUsed))
+		if fee.Cmp(want) != 0 || (header.BaseFee != nil && fee.Sign() == 0) {
+			t.Errorf("block %d: burnt fees mismatch: have %v, want %v", header.Number, fee, want)
+		}
+	}
+	// Pay the base fee to the miners, nothing should be burned anymore
+	backend.chain.Config().BaseFeeDestination = &params.BaseFeeConfig{Recipient: params.BaseFeeCoinbase}
+
+	_, _, _, _, burnt, err = NewOracle(backend, Config{MaxHeaderHistory: 1000}).FeeHistory(context.Background(), 10, 30, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	for i, fee := range burnt {
+		if fee.Sign() != 0 {
+			t.Errorf("block %d: burnt fees mismatch: have %v, want 0", first.Uint64()+uint64(i), fee)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -60,6 +61,9 @@ func (eth *Ethereum) StateAtBlock(ctx context.Context, block *types.Block, reexe
 		report   = true
 		origin   = block.NumberU64()
 	)
+	if eth.blockchain.TrieDB().Scheme() == rawdb.PathScheme {
+		return eth.pathStateAtBlock(block)
+	}
 	// The state is only for reading purposes, check the state presence in
 	// live database.
 	if readOnly {
@@ -184,6 +188,17 @@ func (eth *Ethereum) StateAtBlock(ctx context.Context, block *types.Block, reexe
 	return statedb, func() { database.TrieDB().Dereference(block.Root()) }, nil
 }
 
+// pathStateAtBlock retrieves the state of the given block with the path-based
+// scheme. Only the recent states kept by the live database are available, the
+// historical ones can't be regenerated on top of the single persistent state.
+func (eth *Ethereum) pathStateAtBlock(block *types.Block) (*state.StateDB, tracers.StateReleaseFunc, error) {
+	statedb, err := eth.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, nil, fmt.Errorf("historical state unavailable with the path-based scheme: %v", err)
+	}
+	return statedb, noopReleaser, nil
+}
+
 // stateAtTransaction returns the execution environment of a certain transaction.
 func (eth *Ethereum) stateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (*core.Message, vm.BlockContext, *state.StateDB, tracers.StateReleaseFunc, error) {
 	// Short circuit if it's genesis block.
@@ -86,14 +86,25 @@ type Backend interface {
 	StateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (*core.Message, vm.BlockContext, *state.StateDB, StateReleaseFunc, error)
 }
 
+// traceStoreBackend is implemented by the backends persisting the traces of the
+// canonical blocks.
+type traceStoreBackend interface {
+	TraceStore() *TraceStore
+}
+
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
 type API struct {
 	backend Backend
+	store   *TraceStore // Store of the block traces, nil if the backend has none
 }
 
 // NewAPI creates a new API definition for the tracing methods of the Ethereum service.
 func NewAPI(backend Backend) *API {
-	return &API{backend: backend}
+	api := &API{backend: backend}
+	if b, ok := backend.(traceStoreBackend); ok {
+		api.store = b.TraceStore()
+	}
+	return api
 }
 
 type chainContext struct {
@@ -590,6 +601,16 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	if block.NumberU64() == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
+	// Serve the traces from the store if they were produced alike
+	if api.store.matches(config) {
+		if traces := api.store.BlockTraces(block.NumberU64(), block.Hash()); traces != nil && len(traces) == block.Transactions().Len() {
+			results := make([]*txTraceResult, len(traces))
+			for i, trace := range traces {
+				results[i] = &txTraceResult{Result: trace}
+			}
+			return results, nil
+		}
+	}
 	// Prepare base state
 	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
 	if err != nil {
@@ -857,6 +878,12 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 	if blockNumber == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
+	// Serve the trace from the store if it was produced alike
+	if api.store.matches(config) {
+		if traces := api.store.BlockTraces(blockNumber, blockHash); index < uint64(len(traces)) {
+			return traces[index], nil
+		}
+	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
@@ -994,6 +1021,12 @@ func APIs(backend Backend) []rpc.API {
 		{
 			Namespace: "debug",
 			Service:   NewAPI(backend),
+		}, {
+			Namespace: "trace",
+			Service:   NewTraceAPI(backend),
+		}, {
+			Namespace: "eth",
+			Service:   NewTransferAPI(backend),
 		},
 	}
 }
@@ -61,10 +61,16 @@ type testBackend struct {
 // testBackend creates a new test backend. OBS: After test is done, teardown must be
 // invoked in order to release associated resources.
 func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) *testBackend {
+	return newTestBackendWithDatabase(t, rawdb.NewMemoryDatabase(), n, gspec, generator)
+}
+
+// newTestBackendWithDatabase creates a new test backend importing the generated
+// chain into the given database.
+func newTestBackendWithDatabase(t *testing.T, db ethdb.Database, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) *testBackend {
 	backend := &testBackend{
 		chainConfig: gspec.Config,
 		engine:      ethash.NewFaker(),
-		chaindb:     rawdb.NewMemoryDatabase(),
+		chaindb:     db,
 	}
 	// Generate blocks for testing
 	_, blocks, _ := core.GenerateChainWithGenesis(gspec, backend.engine, n, generator)
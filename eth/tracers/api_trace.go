@@ -0,0 +1,547 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TraceAPI is the collection of OpenEthereum compatible tracing APIs exposed
+// over the trace namespace. The call traces, state diffs and VM traces are
+// produced by the native flatCallTracer, prestateTracer and vmTracer.
+//
+// Unlike OpenEthereum, block and uncle rewards are not reported.
+type TraceAPI struct {
+	api        *API
+	rangeLimit uint64 // Maximum number of blocks spanned by trace_filter, 0 if unlimited
+}
+
+// traceFilterBackend is implemented by the backends limiting the block range
+// of trace_filter.
+type traceFilterBackend interface {
+	TraceFilterRangeLimit() uint64
+}
+
+// NewTraceAPI creates a new API definition for the trace namespace.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	api := &TraceAPI{api: NewAPI(backend)}
+	if b, ok := backend.(traceFilterBackend); ok {
+		api.rangeLimit = b.TraceFilterRangeLimit()
+	}
+	return api
+}
+
+// TraceFilterArgs are the arguments of trace_filter.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`   // Oldest block of the range, genesis if nil
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`     // Newest block of the range, latest if nil
+	FromAddress []common.Address `json:"fromAddress"` // Senders of the calls to return, any if empty
+	ToAddress   []common.Address `json:"toAddress"`   // Recipients of the calls to return, any if empty
+	After       *uint64          `json:"after"`       // Number of matching traces to skip
+	Count       *uint64          `json:"count"`       // Maximum number of traces to return
+}
+
+// flatTrace is a call frame of a transaction, in the format of OpenEthereum.
+// The location of the transaction is left out of replayed transactions.
+type flatTrace struct {
+	Action              json.RawMessage `json:"action"`
+	BlockHash           *common.Hash    `json:"blockHash,omitempty"`
+	BlockNumber         *uint64         `json:"blockNumber,omitempty"`
+	Error               string          `json:"error,omitempty"`
+	Result              json.RawMessage `json:"result"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     *common.Hash    `json:"transactionHash,omitempty"`
+	TransactionPosition *uint64         `json:"transactionPosition,omitempty"`
+	Type                string          `json:"type"`
+}
+
+// parties returns the sender and the recipient of the call frame, the created
+// contract being the recipient of creations, and the beneficiary the one of
+// self-destructs.
+func (t *flatTrace) parties() (from, to *common.Address) {
+	var action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+	}
+	var result struct {
+		Address *common.Address `json:"address"`
+	}
+	json.Unmarshal(t.Action, &action)
+	if len(t.Result) > 0 {
+		json.Unmarshal(t.Result, &result)
+	}
+	switch t.Type {
+	case "create":
+		return action.From, result.Address
+	case "suicide":
+		return action.Address, action.RefundAddress
+	default:
+		return action.From, action.To
+	}
+}
+
+// traceResults are the outputs of a replayed transaction or call. The outputs
+// which were not requested are nil.
+type traceResults struct {
+	Output          hexutil.Bytes                   `json:"output"`
+	StateDiff       map[common.Address]*accountDiff `json:"stateDiff"`
+	Trace           []*flatTrace                    `json:"trace"`
+	VMTrace         json.RawMessage                 `json:"vmTrace"`
+	TransactionHash *common.Hash                    `json:"transactionHash,omitempty"`
+}
+
+// accountDiff is the change of an account, in the format of OpenEthereum: each
+// field is either "=" if unchanged, or an object keyed by "+" if the account was
+// created, "-" if it was destroyed, or "*" if the value changed.
+type accountDiff struct {
+	Balance interface{}                 `json:"balance"`
+	Code    interface{}                 `json:"code"`
+	Nonce   interface{}                 `json:"nonce"`
+	Storage map[common.Hash]interface{} `json:"storage"`
+}
+
+// flatTraceConfig is the configuration tracing the call frames of transactions.
+func flatTraceConfig() *TraceConfig {
+	tracer := "flatCallTracer"
+	return &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"convertParityErrors":true}`)}
+}
+
+// replayConfig is the configuration producing the requested outputs of a
+// replayed transaction. The call frames are always traced, the output of the
+// transaction being read from them.
+func replayConfig(traceTypes []string) (*TraceConfig, error) {
+	config := map[string]json.RawMessage{
+		"flatCallTracer": json.RawMessage(`{"convertParityErrors":true}`),
+	}
+	for _, typ := range traceTypes {
+		switch typ {
+		case "trace":
+		case "stateDiff":
+			config["prestateTracer"] = json.RawMessage(`{"diffMode":true}`)
+		case "vmTrace":
+			config["vmTracer"] = json.RawMessage(`{}`)
+		default:
+			return nil, fmt.Errorf("invalid trace type %q", typ)
+		}
+	}
+	blob, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	tracer := "muxTracer"
+	return &TraceConfig{Tracer: &tracer, TracerConfig: blob}, nil
+}
+
+// decodeFlatTraces decodes the result of the flatCallTracer.
+func decodeFlatTraces(res interface{}) ([]*flatTrace, error) {
+	blob, ok := res.(json.RawMessage)
+	if !ok {
+		return nil, errors.New("unexpected tracer result")
+	}
+	var traces []*flatTrace
+	if err := json.Unmarshal(blob, &traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// decodeReplay decodes the result of the tracers of a replayed transaction,
+// keeping the requested outputs.
+func decodeReplay(res interface{}, traceTypes []string) (*traceResults, error) {
+	blob, ok := res.(json.RawMessage)
+	if !ok {
+		return nil, errors.New("unexpected tracer result")
+	}
+	var outputs map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &outputs); err != nil {
+		return nil, err
+	}
+	traces, err := decodeFlatTraces(outputs["flatCallTracer"])
+	if err != nil {
+		return nil, err
+	}
+	results := &traceResults{Output: hexutil.Bytes{}}
+	if len(traces) > 0 && len(traces[0].Result) > 0 {
+		var result struct {
+			Code   hexutil.Bytes `json:"code"`
+			Output hexutil.Bytes `json:"output"`
+		}
+		if err := json.Unmarshal(traces[0].Result, &result); err == nil {
+			if traces[0].Type == "create" {
+				results.Output = result.Code
+			} else {
+				results.Output = result.Output
+			}
+		}
+	}
+	for _, typ := range traceTypes {
+		switch typ {
+		case "trace":
+			for _, trace := range traces {
+				trace.BlockHash, trace.BlockNumber, trace.TransactionHash, trace.TransactionPosition = nil, nil, nil, nil
+			}
+			results.Trace = traces
+		case "stateDiff":
+			if results.StateDiff, err = decodeStateDiff(outputs["prestateTracer"]); err != nil {
+				return nil, err
+			}
+		case "vmTrace":
+			results.VMTrace = outputs["vmTracer"]
+		}
+	}
+	return results, nil
+}
+
+// decodeStateDiff converts the result of the prestateTracer in diff mode into
+// the state diff of OpenEthereum.
+func decodeStateDiff(blob json.RawMessage) (map[common.Address]*accountDiff, error) {
+	type account struct {
+		Balance *hexutil.Big                `json:"balance"`
+		Code    *hexutil.Bytes              `json:"code"`
+		Nonce   *uint64                     `json:"nonce"`
+		Storage map[common.Hash]common.Hash `json:"storage"`
+	}
+	var diff struct {
+		Pre  map[common.Address]*account `json:"pre"`
+		Post map[common.Address]*account `json:"post"`
+	}
+	if err := json.Unmarshal(blob, &diff); err != nil {
+		return nil, err
+	}
+	// Fields left out by the tracer are zero, or unchanged in the post state
+	fields := func(a *account) (*hexutil.Big, hexutil.Bytes, hexutil.Uint64) {
+		var (
+			balance = (*hexutil.Big)(new(big.Int))
+			code    = hexutil.Bytes{}
+			nonce   hexutil.Uint64
+		)
+		if a.Balance != nil {
+			balance = a.Balance
+		}
+		if a.Code != nil {
+			code = *a.Code
+		}
+		if a.Nonce != nil {
+			nonce = hexutil.Uint64(*a.Nonce)
+		}
+		return balance, code, nonce
+	}
+	// Accounts touched while empty are reported as created
+	exists := func(a *account) bool {
+		return a != nil && ((a.Balance != nil && a.Balance.ToInt().Sign() != 0) || (a.Code != nil && len(*a.Code) > 0) || (a.Nonce != nil && *a.Nonce > 0) || len(a.Storage) > 0)
+	}
+	stateDiff := make(map[common.Address]*accountDiff)
+	for addr, pre := range diff.Pre {
+		if !exists(pre) {
+			continue
+		}
+		from, code, nonce := fields(pre)
+		if diff.Post[addr] == nil {
+			storage := make(map[common.Hash]interface{})
+			for key, val := range pre.Storage {
+				storage[key] = map[string]interface{}{"-": val}
+			}
+			stateDiff[addr] = &accountDiff{
+				Balance: map[string]interface{}{"-": from},
+				Code:    map[string]interface{}{"-": code},
+				Nonce:   map[string]interface{}{"-": nonce},
+				Storage: storage,
+			}
+			continue
+		}
+		var (
+			post = diff.Post[addr]
+			ad   = &accountDiff{Balance: "=", Code: "=", Nonce: "=", Storage: make(map[common.Hash]interface{})}
+		)
+		toBalance, toCode, toNonce := fields(post)
+		if post.Balance != nil {
+			ad.Balance = changed(from, toBalance)
+		}
+		if post.Code != nil {
+			ad.Code = changed(code, toCode)
+		}
+		if post.Nonce != nil {
+			ad.Nonce = changed(nonce, toNonce)
+		}
+		for key, val := range pre.Storage {
+			ad.Storage[key] = changed(val, post.Storage[key])
+		}
+		for key, val := range post.Storage {
+			if _, ok := pre.Storage[key]; !ok {
+				ad.Storage[key] = changed(common.Hash{}, val)
+			}
+		}
+		stateDiff[addr] = ad
+	}
+	for addr, post := range diff.Post {
+		if exists(diff.Pre[addr]) {
+			continue
+		}
+		balance, code, nonce := fields(post)
+		storage := make(map[common.Hash]interface{})
+		for key, val := range post.Storage {
+			storage[key] = map[string]interface{}{"+": val}
+		}
+		stateDiff[addr] = &accountDiff{
+			Balance: map[string]interface{}{"+": balance},
+			Code:    map[string]interface{}{"+": code},
+			Nonce:   map[string]interface{}{"+": nonce},
+			Storage: storage,
+		}
+	}
+	return stateDiff, nil
+}
+
+// changed returns the diff of a changed value.
+func changed(from, to interface{}) interface{} {
+	return map[string]interface{}{"*": map[string]interface{}{"from": from, "to": to}}
+}
+
+// blockTraces returns the call traces of the transactions of a block.
+func (api *TraceAPI) blockTraces(ctx context.Context, block *types.Block) ([]*flatTrace, error) {
+	if block.NumberU64() == 0 {
+		return []*flatTrace{}, nil
+	}
+	results, err := api.api.traceBlock(ctx, block, flatTraceConfig())
+	if err != nil {
+		return nil, err
+	}
+	traces := []*flatTrace{}
+	for _, res := range results {
+		if res.Error != "" {
+			return nil, errors.New(res.Error)
+		}
+		txTraces, err := decodeFlatTraces(res.Result)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, txTraces...)
+	}
+	return traces, nil
+}
+
+// block retrieves the block of the given number or hash.
+func (api *TraceAPI) block(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return api.api.blockByHash(ctx, hash)
+	}
+	if number, ok := blockNrOrHash.Number(); ok {
+		return api.api.blockByNumber(ctx, number)
+	}
+	return nil, errors.New("invalid arguments; neither block nor hash specified")
+}
+
+// Block returns the call traces of all the transactions of a block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]*flatTrace, error) {
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return api.blockTraces(ctx, block)
+}
+
+// Transaction returns the call traces of a transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*flatTrace, error) {
+	res, err := api.api.TraceTransaction(ctx, hash, flatTraceConfig())
+	if err != nil {
+		return nil, err
+	}
+	return decodeFlatTraces(res)
+}
+
+// Get returns the call trace of a transaction at the given trace address, or
+// nil if the transaction has no such call.
+func (api *TraceAPI) Get(ctx context.Context, hash common.Hash, indices []hexutil.Uint64) (*flatTrace, error) {
+	traces, err := api.Transaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	for _, trace := range traces {
+		if len(trace.TraceAddress) != len(indices) {
+			continue
+		}
+		match := true
+		for i, index := range indices {
+			if uint64(trace.TraceAddress[i]) != uint64(index) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return trace, nil
+		}
+	}
+	return nil, nil
+}
+
+// Call executes a call on top of a block, the latest one by default, returning
+// the requested trace types among trace, stateDiff and vmTrace.
+func (api *TraceAPI) Call(ctx context.Context, args ethapi.TransactionArgs, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash) (*traceResults, error) {
+	config, err := replayConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	res, err := api.api.TraceCall(ctx, args, *blockNrOrHash, &TraceCallConfig{TraceConfig: *config})
+	if err != nil {
+		return nil, err
+	}
+	return decodeReplay(res, traceTypes)
+}
+
+// ReplayTransaction replays a transaction, returning the requested trace types
+// among trace, stateDiff and vmTrace.
+func (api *TraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string) (*traceResults, error) {
+	config, err := replayConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.api.TraceTransaction(ctx, hash, config)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReplay(res, traceTypes)
+}
+
+// ReplayBlockTransactions replays all the transactions of a block, returning
+// the requested trace types among trace, stateDiff and vmTrace.
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, traceTypes []string) ([]*traceResults, error) {
+	config, err := replayConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.block(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return []*traceResults{}, nil
+	}
+	results, err := api.api.traceBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	replays := make([]*traceResults, len(results))
+	for i, res := range results {
+		if res.Error != "" {
+			return nil, errors.New(res.Error)
+		}
+		if replays[i], err = decodeReplay(res.Result, traceTypes); err != nil {
+			return nil, err
+		}
+		hash := block.Transactions()[i].Hash()
+		replays[i].TransactionHash = &hash
+	}
+	return replays, nil
+}
+
+// Filter returns the call traces of the transactions of a block range, sent
+// from and to the given addresses.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]*flatTrace, error) {
+	var (
+		fromNumber = rpc.EarliestBlockNumber
+		toNumber   = rpc.LatestBlockNumber
+	)
+	if args.FromBlock != nil {
+		fromNumber = *args.FromBlock
+	}
+	if args.ToBlock != nil {
+		toNumber = *args.ToBlock
+	}
+	from, err := api.api.backend.HeaderByNumber(ctx, fromNumber)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.api.backend.HeaderByNumber(ctx, toNumber)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil || to == nil {
+		return nil, errors.New("block not found")
+	}
+	if from.Number.Cmp(to.Number) > 0 {
+		return nil, errors.New("invalid block range")
+	}
+	// Every block of the range is re-executed, reject too large ones
+	if span := to.Number.Uint64() - from.Number.Uint64() + 1; api.rangeLimit != 0 && span > api.rangeLimit {
+		return nil, fmt.Errorf("block range too large: %d blocks, limit is %d", span, api.rangeLimit)
+	}
+	var (
+		fromAddrs = make(map[common.Address]bool)
+		toAddrs   = make(map[common.Address]bool)
+		after     uint64
+		traces    = []*flatTrace{}
+	)
+	for _, addr := range args.FromAddress {
+		fromAddrs[addr] = true
+	}
+	for _, addr := range args.ToAddress {
+		toAddrs[addr] = true
+	}
+	if args.After != nil {
+		after = *args.After
+	}
+	matches := func(trace *flatTrace) bool {
+		sender, recipient := trace.parties()
+		if len(fromAddrs) > 0 && (sender == nil || !fromAddrs[*sender]) {
+			return false
+		}
+		if len(toAddrs) > 0 && (recipient == nil || !toAddrs[*recipient]) {
+			return false
+		}
+		return true
+	}
+	for number := from.Number.Uint64(); number <= to.Number.Uint64(); number++ {
+		if args.Count != nil && uint64(len(traces)) >= *args.Count {
+			break
+		}
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		blockTraces, err := api.blockTraces(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range blockTraces {
+			if !matches(trace) {
+				continue
+			}
+			if after > 0 {
+				after--
+				continue
+			}
+			if args.Count != nil && uint64(len(traces)) >= *args.Count {
+				break
+			}
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
+}
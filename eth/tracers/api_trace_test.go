@@ -0,0 +1,320 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package tracers_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests the OpenEthereum compatible trace namespace.
+func TestTraceAPI(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		aa       = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		bb       = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		contract = common.HexToAddress("0x000000000000000000000000000000000000cccc")
+		signer   = types.HomesteadSigner{}
+		txs      []common.Hash
+	)
+	// The contract stores 1 at slot 0, then sends 1 wei to aa
+	code := []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE)}
+	code = append(code, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 1, byte(vm.PUSH20))
+	code = append(code, aa.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			addr:     {Balance: big.NewInt(params.Ether)},
+			contract: {Balance: big.NewInt(10), Code: code},
+		},
+	}
+	backend := tracers.NewTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		to := contract
+		if i == 1 {
+			to = bb
+		}
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), to, big.NewInt(5), 100000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+		txs = append(txs, tx.Hash())
+	})
+	var (
+		api = tracers.NewTraceAPI(backend)
+		ctx = context.Background()
+	)
+	// decode converts a result into its generic JSON representation.
+	decode := func(res interface{}) interface{} {
+		t.Helper()
+		blob, err := json.Marshal(res)
+		if err != nil {
+			t.Fatalf("failed to encode result: %v", err)
+		}
+		var v interface{}
+		if err := json.Unmarshal(blob, &v); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		return v
+	}
+	// addresses returns the trace addresses of a list of traces.
+	addresses := func(traces interface{}) [][]interface{} {
+		var addrs [][]interface{}
+		for _, trace := range traces.([]interface{}) {
+			addrs = append(addrs, trace.(map[string]interface{})["traceAddress"].([]interface{}))
+		}
+		return addrs
+	}
+	// Block and transaction traces
+	block, err := api.Block(ctx, 1)
+	if err != nil {
+		t.Fatalf("trace_block failed: %v", err)
+	}
+	traces := decode(block).([]interface{})
+	if len(traces) != 2 {
+		t.Fatalf("trace_block: have %d traces, want 2", len(traces))
+	}
+	top, sub := traces[0].(map[string]interface{}), traces[1].(map[string]interface{})
+	if top["subtraces"] != 1.0 || top["blockNumber"] != 1.0 || top["transactionHash"] != txs[0].Hex() {
+		t.Errorf("trace_block: unexpected top trace %v", top)
+	}
+	if action := sub["action"].(map[string]interface{}); action["to"] != aa.Hex() && action["to"] != hexutil.Encode(aa[:]) || action["value"] != "0x1" || action["callType"] != "call" {
+		t.Errorf("trace_block: unexpected sub trace %v", sub)
+	}
+	tx, err := api.Transaction(ctx, txs[0])
+	if err != nil {
+		t.Fatalf("trace_transaction failed: %v", err)
+	}
+	if !reflect.DeepEqual(decode(tx), traces) {
+		t.Errorf("trace_transaction: traces mismatch with trace_block")
+	}
+	get, err := api.Get(ctx, txs[0], []hexutil.Uint64{0})
+	if err != nil {
+		t.Fatalf("trace_get failed: %v", err)
+	}
+	if !reflect.DeepEqual(decode(get), traces[1]) {
+		t.Errorf("trace_get: have %v, want %v", decode(get), traces[1])
+	}
+	if get, _ := api.Get(ctx, txs[0], []hexutil.Uint64{1}); get != nil {
+		t.Errorf("trace_get: unexpected trace %v", decode(get))
+	}
+	// Replays, with all the trace types
+	replay, err := api.ReplayTransaction(ctx, txs[0], []string{"trace", "stateDiff", "vmTrace"})
+	if err != nil {
+		t.Fatalf("trace_replayTransaction failed: %v", err)
+	}
+	res := decode(replay).(map[string]interface{})
+	if trace := res["trace"].([]interface{})[0].(map[string]interface{}); trace["blockHash"] != nil || trace["transactionHash"] != nil {
+		t.Errorf("trace_replayTransaction: unexpected transaction location in %v", trace)
+	}
+	diff := res["stateDiff"].(map[string]interface{})
+	if have := decode(diff[hexutil.Encode(aa[:])]); !reflect.DeepEqual(have, decode(map[string]interface{}{
+		"balance": map[string]interface{}{"+": "0x1"},
+		"code":    map[string]interface{}{"+": "0x"},
+		"nonce":   map[string]interface{}{"+": "0x0"},
+		"storage": map[string]interface{}{},
+	})) {
+		t.Errorf("trace_replayTransaction: unexpected diff of the recipient %v", have)
+	}
+	slot := diff[hexutil.Encode(contract[:])].(map[string]interface{})["storage"].(map[string]interface{})[common.Hash{}.Hex()]
+	if !reflect.DeepEqual(slot, decode(map[string]interface{}{"*": map[string]interface{}{"from": common.Hash{}, "to": common.BigToHash(common.Big1)}})) {
+		t.Errorf("trace_replayTransaction: unexpected storage diff %v", slot)
+	}
+	vmTrace := res["vmTrace"].(map[string]interface{})
+	if vmTrace["code"] != hexutil.Encode(code) {
+		t.Errorf("trace_replayTransaction: unexpected code %v", vmTrace["code"])
+	}
+	ops := vmTrace["ops"].([]interface{})
+	if len(ops) != 12 {
+		t.Fatalf("trace_replayTransaction: have %d ops, want 12", len(ops))
+	}
+	if ex := ops[0].(map[string]interface{})["ex"].(map[string]interface{}); !reflect.DeepEqual(ex["push"], []interface{}{"0x1"}) {
+		t.Errorf("trace_replayTransaction: unexpected push %v", ex["push"])
+	}
+	if ex := ops[2].(map[string]interface{})["ex"].(map[string]interface{}); !reflect.DeepEqual(ex["store"], map[string]interface{}{"key": "0x0", "val": "0x1"}) {
+		t.Errorf("trace_replayTransaction: unexpected store %v", ex["store"])
+	}
+	if call := ops[10].(map[string]interface{}); call["sub"] == nil || !reflect.DeepEqual(call["ex"].(map[string]interface{})["push"], []interface{}{"0x1"}) {
+		t.Errorf("trace_replayTransaction: unexpected call %v", call)
+	}
+	replays, err := api.ReplayBlockTransactions(ctx, rpc.BlockNumberOrHashWithNumber(2), []string{"trace"})
+	if err != nil {
+		t.Fatalf("trace_replayBlockTransactions failed: %v", err)
+	}
+	if len(replays) != 1 || replays[0].TransactionHash == nil || *replays[0].TransactionHash != txs[1] {
+		t.Errorf("trace_replayBlockTransactions: unexpected results %v", decode(replays))
+	}
+	if res := decode(replays[0]).(map[string]interface{}); res["stateDiff"] != nil || res["vmTrace"] != nil {
+		t.Errorf("trace_replayBlockTransactions: unexpected outputs %v", res)
+	}
+	if _, err := api.ReplayTransaction(ctx, txs[0], []string{"bogus"}); err == nil {
+		t.Errorf("trace_replayTransaction: expected error for invalid trace type")
+	}
+	// Calls
+	call, err := api.Call(ctx, ethapi.TransactionArgs{From: &addr, To: &contract}, []string{"stateDiff"}, nil)
+	if err != nil {
+		t.Fatalf("trace_call failed: %v", err)
+	}
+	if res := decode(call).(map[string]interface{}); res["trace"] != nil || res["output"] != "0x" || res["stateDiff"] == nil {
+		t.Errorf("trace_call: unexpected result %v", res)
+	}
+	// Filters
+	one, two := rpc.BlockNumber(1), rpc.BlockNumber(2)
+	count, after := uint64(1), uint64(1)
+	for i, tt := range []struct {
+		args tracers.TraceFilterArgs
+		want [][]interface{}
+		txs  []common.Hash
+	}{
+		{tracers.TraceFilterArgs{FromBlock: &one, ToBlock: &two}, [][]interface{}{{}, {0.0}, {}}, []common.Hash{txs[0], txs[0], txs[1]}},
+		{tracers.TraceFilterArgs{ToAddress: []common.Address{aa}}, [][]interface{}{{0.0}}, []common.Hash{txs[0]}},
+		{tracers.TraceFilterArgs{FromAddress: []common.Address{addr}}, [][]interface{}{{}, {}}, []common.Hash{txs[0], txs[1]}},
+		{tracers.TraceFilterArgs{FromAddress: []common.Address{addr}, After: &after, Count: &count}, [][]interface{}{{}}, []common.Hash{txs[1]}},
+		{tracers.TraceFilterArgs{FromAddress: []common.Address{contract}, ToAddress: []common.Address{bb}}, nil, nil},
+	} {
+		res, err := api.Filter(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("test %d: trace_filter failed: %v", i, err)
+		}
+		traces := decode(res)
+		if have := addresses(traces); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: trace addresses mismatch: have %v, want %v", i, have, tt.want)
+		}
+		for j, trace := range traces.([]interface{}) {
+			if hash := trace.(map[string]interface{})["transactionHash"]; hash != tt.txs[j].Hex() {
+				t.Errorf("test %d, trace %d: transaction mismatch: have %v, want %v", i, j, hash, tt.txs[j])
+			}
+		}
+	}
+	// Filters exceeding the block range limit, or with an inverted range
+	limited := tracers.NewLimitedTraceAPI(backend, 1)
+	if _, err := limited.Filter(ctx, tracers.TraceFilterArgs{}); err == nil {
+		t.Errorf("trace_filter: expected error for a range over the limit")
+	}
+	if _, err := limited.Filter(ctx, tracers.TraceFilterArgs{FromBlock: &two, ToBlock: &two}); err != nil {
+		t.Errorf("trace_filter: range within the limit rejected: %v", err)
+	}
+	if _, err := api.Filter(ctx, tracers.TraceFilterArgs{FromBlock: &two, ToBlock: &one}); err == nil {
+		t.Errorf("trace_filter: expected error for an inverted range")
+	}
+}
+
+// Tests that the vmTrace of a transaction pairs the scopes correctly when a
+// nested call selfdestructs.
+func TestTraceAPISelfdestruct(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		aa     = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		outer  = common.HexToAddress("0x000000000000000000000000000000000000cccc")
+		inner  = common.HexToAddress("0x000000000000000000000000000000000000dddd")
+		doomed = common.HexToAddress("0x000000000000000000000000000000000000eeee")
+		signer = types.HomesteadSigner{}
+		hash   common.Hash
+	)
+	// call returns the code calling the given address without value.
+	call := func(to common.Address) []byte {
+		code := []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH20)}
+		code = append(code, to.Bytes()...)
+		return append(code, byte(vm.GAS), byte(vm.CALL))
+	}
+	// The outer contract calls the inner one, which calls a contract destructing
+	// itself and then stores 1 at slot 0
+	outerCode := append(call(inner), byte(vm.STOP))
+	innerCode := append(call(doomed), byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP))
+	doomedCode := append(append([]byte{byte(vm.PUSH20)}, aa.Bytes()...), byte(vm.SELFDESTRUCT))
+
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			addr:   {Balance: big.NewInt(params.Ether)},
+			outer:  {Balance: big.NewInt(0), Code: outerCode},
+			inner:  {Balance: big.NewInt(0), Code: innerCode},
+			doomed: {Balance: big.NewInt(10), Code: doomedCode},
+		},
+	}
+	backend := tracers.NewTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), outer, big.NewInt(0), 200000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+		hash = tx.Hash()
+	})
+	replay, err := tracers.NewTraceAPI(backend).ReplayTransaction(context.Background(), hash, []string{"vmTrace"})
+	if err != nil {
+		t.Fatalf("trace_replayTransaction failed: %v", err)
+	}
+	blob, err := json.Marshal(replay)
+	if err != nil {
+		t.Fatalf("failed to encode result: %v", err)
+	}
+	var res struct {
+		VMTrace *struct {
+			Code string `json:"code"`
+			Ops  []struct {
+				Sub *json.RawMessage `json:"sub"`
+			} `json:"ops"`
+		} `json:"vmTrace"`
+	}
+	if err := json.Unmarshal(blob, &res); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	// Check the ops of each scope, and descend into the call
+	scope := res.VMTrace
+	for depth, want := range []struct {
+		code []byte
+		ops  int
+	}{
+		{outerCode, 9},
+		{innerCode, 12},
+		{doomedCode, 2},
+	} {
+		if scope == nil {
+			t.Fatalf("depth %d: missing trace", depth)
+		}
+		if scope.Code != hexutil.Encode(want.code) {
+			t.Fatalf("depth %d: code mismatch: have %s, want %x", depth, scope.Code, want.code)
+		}
+		if len(scope.Ops) != want.ops {
+			t.Fatalf("depth %d: have %d ops, want %d", depth, len(scope.Ops), want.ops)
+		}
+		if depth == 2 {
+			break
+		}
+		sub := scope.Ops[7].Sub
+		if sub == nil {
+			t.Fatalf("depth %d: call has no sub trace", depth)
+		}
+		scope = nil
+		if err := json.Unmarshal(*sub, &scope); err != nil {
+			t.Fatalf("depth %d: failed to decode sub trace: %v", depth, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TransferAPI exposes the value movements of blocks over the eth namespace, as
+// collected by the native transferTracer.
+type TransferAPI struct {
+	api *API
+}
+
+// NewTransferAPI creates a new API definition for the block transfers.
+func NewTransferAPI(backend Backend) *TransferAPI {
+	return &TransferAPI{api: NewAPI(backend)}
+}
+
+// blockTransfer is a native currency or token transfer made by a transaction
+// of a block.
+type blockTransfer struct {
+	Type             string          `json:"type"`
+	Asset            *common.Address `json:"asset,omitempty"`
+	From             common.Address  `json:"from"`
+	To               common.Address  `json:"to"`
+	Amount           *hexutil.Big    `json:"amount"`
+	TokenID          *hexutil.Big    `json:"tokenId,omitempty"`
+	CallPath         []int           `json:"callPath"`
+	BlockHash        common.Hash     `json:"blockHash"`
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	TransactionHash  common.Hash     `json:"transactionHash"`
+	TransactionIndex hexutil.Uint    `json:"transactionIndex"`
+}
+
+// transferTraceConfig is the configuration collecting the transfers of
+// transactions.
+func transferTraceConfig() *TraceConfig {
+	tracer := "transferTracer"
+	return &TraceConfig{Tracer: &tracer}
+}
+
+// GetBlockTransfers returns all the native currency and token transfers made
+// by the transactions of a block, ordered by transaction and by execution
+// within each transaction. Transfers of reverted calls are left out.
+func (api *TransferAPI) GetBlockTransfers(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*blockTransfer, error) {
+	var (
+		block *types.Block
+		err   error
+	)
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block, err = api.api.blockByHash(ctx, hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		block, err = api.api.blockByNumber(ctx, number)
+	} else {
+		return nil, errors.New("invalid arguments; neither block nor hash specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+	transfers := []*blockTransfer{}
+	if block.NumberU64() == 0 {
+		return transfers, nil
+	}
+	results, err := api.api.traceBlock(ctx, block, transferTraceConfig())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	for i, res := range results {
+		if res.Error != "" {
+			return nil, errors.New(res.Error)
+		}
+		blob, ok := res.Result.(json.RawMessage)
+		if !ok {
+			return nil, errors.New("unexpected tracer result")
+		}
+		var txTransfers []*blockTransfer
+		if err := json.Unmarshal(blob, &txTransfers); err != nil {
+			return nil, err
+		}
+		for _, transfer := range txTransfers {
+			transfer.BlockHash = block.Hash()
+			transfer.BlockNumber = hexutil.Uint64(block.NumberU64())
+			transfer.TransactionHash = txs[i].Hash()
+			transfer.TransactionIndex = hexutil.Uint(i)
+		}
+		transfers = append(transfers, txTransfers...)
+	}
+	return transfers, nil
+}
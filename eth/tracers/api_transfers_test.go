@@ -0,0 +1,103 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package tracers_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that eth_getBlockTransfers returns the transfers of all the
+// transactions of a block, in order.
+func TestGetBlockTransfers(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fixture  = tracers.NewTransferFixture(big.NewInt(0))
+		addr     = fixture.Addr
+		aa       = fixture.Aa
+		bb       = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		contract = fixture.Contract
+		signer   = types.HomesteadSigner{}
+		txs      []common.Hash
+	)
+	backend := tracers.NewTestBackend(t, 1, fixture.Genesis, func(i int, b *core.BlockGen) {
+		for nonce, to := range []common.Address{contract, bb} {
+			tx, _ := types.SignTx(types.NewTransaction(uint64(nonce), to, big.NewInt(5), 100000, b.BaseFee(), nil), signer, fixture.Key)
+			b.AddTx(tx)
+			txs = append(txs, tx.Hash())
+		}
+	})
+	var (
+		api = tracers.NewTransferAPI(backend)
+		ctx = context.Background()
+	)
+	transfers, err := api.GetBlockTransfers(ctx, rpc.BlockNumberOrHashWithNumber(1))
+	if err != nil {
+		t.Fatalf("eth_getBlockTransfers failed: %v", err)
+	}
+	blob, _ := json.Marshal(transfers)
+
+	var have []map[string]interface{}
+	if err := json.Unmarshal(blob, &have); err != nil {
+		t.Fatalf("failed to decode transfers: %v", err)
+	}
+	if len(have) != 3 {
+		t.Fatalf("transfer count mismatch: have %d, want 3", len(have))
+	}
+	hash := have[0]["blockHash"].(string)
+	for i, want := range []struct {
+		from, to common.Address
+		amount   string
+		tx       int
+		path     []interface{}
+	}{
+		{addr, contract, "0x5", 0, []interface{}{}},
+		{contract, aa, "0x1", 0, []interface{}{0.0}},
+		{addr, bb, "0x5", 1, []interface{}{}},
+	} {
+		transfer := have[i]
+		if transfer["type"] != "native" || transfer["from"] != hexutil.Encode(want.from[:]) || transfer["to"] != hexutil.Encode(want.to[:]) || transfer["amount"] != want.amount {
+			t.Errorf("transfer %d: unexpected value movement %v", i, transfer)
+		}
+		if !reflect.DeepEqual(transfer["callPath"], want.path) {
+			t.Errorf("transfer %d: call path mismatch: have %v, want %v", i, transfer["callPath"], want.path)
+		}
+		if transfer["blockHash"] != hash || transfer["blockNumber"] != "0x1" || transfer["transactionHash"] != txs[want.tx].Hex() || transfer["transactionIndex"] != hexutil.EncodeUint64(uint64(want.tx)) {
+			t.Errorf("transfer %d: unexpected location %v", i, transfer)
+		}
+	}
+	// The block can also be referenced by hash, and the genesis has no transfers
+	byHash, err := api.GetBlockTransfers(ctx, rpc.BlockNumberOrHashWithHash(common.HexToHash(hash), false))
+	if err != nil {
+		t.Fatalf("eth_getBlockTransfers by hash failed: %v", err)
+	}
+	if blob2, _ := json.Marshal(byHash); string(blob2) != string(blob) {
+		t.Errorf("transfers by hash mismatch: have %s, want %s", blob2, blob)
+	}
+	genesisTransfers, err := api.GetBlockTransfers(ctx, rpc.BlockNumberOrHashWithNumber(0))
+	if err != nil || len(genesisTransfers) != 0 {
+		t.Errorf("genesis transfers: have %v, err %v", genesisTransfers, err)
+	}
+}
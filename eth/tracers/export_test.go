@@ -0,0 +1,137 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package tracers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TransferFixture is a chain shared by the external tests of the package: a
+// funded account and a contract sending 1 wei to aa whenever it's called.
+type TransferFixture struct {
+	Key      *ecdsa.PrivateKey
+	Addr     common.Address
+	Aa       common.Address
+	Contract common.Address
+	Genesis  *core.Genesis
+}
+
+// NewTransferFixture creates the genesis of the transfer test chain, with the
+// given balance of the contract.
+func NewTransferFixture(balance *big.Int) *TransferFixture {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		aa       = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		contract = common.HexToAddress("0x000000000000000000000000000000000000cccc")
+	)
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 1, byte(vm.PUSH20)}
+	code = append(code, aa.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	return &TransferFixture{
+		Key:      key,
+		Addr:     addr,
+		Aa:       aa,
+		Contract: contract,
+		Genesis: &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				addr:     {Balance: big.NewInt(params.Ether)},
+				contract: {Balance: balance, Code: code},
+			},
+		},
+	}
+}
+
+// NewTestBackend exposes the test backend to the external tests of the package,
+// which can import the native tracers.
+func NewTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) Backend {
+	backend := newTestBackend(t, n, gspec, generator)
+	t.Cleanup(backend.teardown)
+	return backend
+}
+
+// NewTestFreezerBackend is like NewTestBackend, importing the chain into a
+// database with an ancient store.
+func NewTestFreezerBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) Backend {
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	backend := newTestBackendWithDatabase(t, db, n, gspec, generator)
+	t.Cleanup(func() {
+		backend.teardown()
+		db.Close()
+	})
+	return backend
+}
+
+// NewLimitedTraceAPI creates a trace namespace API limiting the block range of
+// trace_filter queries.
+func NewLimitedTraceAPI(backend Backend, rangeLimit uint64) *TraceAPI {
+	api := NewTraceAPI(backend)
+	api.rangeLimit = rangeLimit
+	return api
+}
+
+// NewTestTraceStore opens a trace store freezing the traces of the blocks older
+// than the given number of blocks.
+func NewTestTraceStore(db ethdb.Database, tracer string, config json.RawMessage, tail, immutable uint64) (*TraceStore, error) {
+	store, err := NewTraceStore(db, tracer, config, tail)
+	if err != nil {
+		return nil, err
+	}
+	store.immutable = immutable
+	return store, nil
+}
+
+// IndexTraces traces the given sections of the canonical chain into the store,
+// as the chain indexer would.
+func IndexTraces(backend Backend, store *TraceStore, size, first, sections uint64) error {
+	var (
+		ctx     = context.Background()
+		indexer = &traceIndexer{store: store, api: &API{backend: backend}, size: size}
+	)
+	for section := first; section < first+sections; section++ {
+		if err := indexer.Reset(ctx, section, common.Hash{}); err != nil {
+			return err
+		}
+		for number := section * size; number < (section+1)*size; number++ {
+			header, err := backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if err != nil {
+				return err
+			}
+			if err := indexer.Process(ctx, header); err != nil {
+				return err
+			}
+		}
+		if err := indexer.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
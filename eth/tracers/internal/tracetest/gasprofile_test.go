@@ -0,0 +1,200 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package tracetest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// gasProfile is the subset of the gas profiler result checked by the tests.
+type gasProfile struct {
+	GasUsed      uint64 `json:"gasUsed"`
+	IntrinsicGas uint64 `json:"intrinsicGas"`
+	Refund       uint64 `json:"refund"`
+	Contracts    []struct {
+		Address common.Address `json:"address"`
+		Gas     uint64         `json:"gas"`
+	} `json:"contracts"`
+	Frames []struct {
+		To      common.Address `json:"to"`
+		GasUsed uint64         `json:"gasUsed"`
+		SelfGas uint64         `json:"selfGas"`
+	} `json:"frames"`
+	Pcs []struct {
+		Address common.Address `json:"address"`
+		Pc      uint64         `json:"pc"`
+		Op      string         `json:"op"`
+		Source  string         `json:"source"`
+		Gas     uint64         `json:"gas"`
+	} `json:"pcs"`
+	Sources []struct {
+		Address common.Address `json:"address"`
+		Source  string         `json:"source"`
+		Gas     uint64         `json:"gas"`
+	} `json:"sources"`
+	Folded string        `json:"folded"`
+	Pprof  hexutil.Bytes `json:"pprof"`
+}
+
+// Tests that the gas profiler attributes the gas of a transaction to the call
+// frames and instructions using it, in all its output formats.
+func TestGasProfiler(t *testing.T) {
+	var (
+		caller = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		callee = common.HexToAddress("0x00000000000000000000000000000000000cafe0")
+		origin = common.HexToAddress("0x00000000000000000000000000000000feed")
+	)
+	// The caller calls the callee, which stores 1 at slot 0
+	code := []byte{byte(vm.PUSH1), 0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.PUSH20)}
+	code = append(code, callee.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), core.GenesisAlloc{
+		caller: {Code: code},
+		callee: {Code: []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}},
+		origin: {Balance: big.NewInt(500000000000000)},
+	}, false)
+
+	// Map the SSTORE of the callee to the source range 10:5:0
+	cfg, _ := json.Marshal(map[string]interface{}{
+		"sourceMaps": map[common.Address]string{callee: "0:1:0;;10:5:0;"},
+	})
+	tracer, err := tracers.DefaultDirectory.New("gasProfiler", nil, cfg)
+	if err != nil {
+		t.Fatalf("failed to create gas profiler: %v", err)
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: new(big.Int).SetUint64(8000000),
+		Time:        5,
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+	evm := vm.NewEVM(context, vm.TxContext{Origin: origin, GasPrice: big.NewInt(1)}, statedb, params.MainnetChainConfig, vm.Config{Tracer: tracer})
+	msg := &core.Message{
+		To:        &caller,
+		From:      origin,
+		Value:     big.NewInt(0),
+		GasLimit:  100000,
+		GasPrice:  big.NewInt(0),
+		GasFeeCap: big.NewInt(0),
+		GasTipCap: big.NewInt(0),
+	}
+	result, err := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit)).TransitionDb()
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var profile gasProfile
+	if err := json.Unmarshal(res, &profile); err != nil {
+		t.Fatalf("failed to decode gas profile: %v", err)
+	}
+	// Check the totals and the attribution to the call frames
+	if profile.GasUsed != result.UsedGas {
+		t.Errorf("gas used mismatch: have %d, want %d", profile.GasUsed, result.UsedGas)
+	}
+	if profile.IntrinsicGas != params.TxGas {
+		t.Errorf("intrinsic gas mismatch: have %d, want %d", profile.IntrinsicGas, params.TxGas)
+	}
+	if len(profile.Frames) != 2 {
+		t.Fatalf("frame count mismatch: have %d, want 2", len(profile.Frames))
+	}
+	outer, inner := profile.Frames[0], profile.Frames[1]
+	if outer.To != caller || inner.To != callee {
+		t.Errorf("frames mismatch: have %x and %x, want %x and %x", outer.To, inner.To, caller, callee)
+	}
+	if outer.GasUsed != profile.GasUsed+profile.Refund-profile.IntrinsicGas {
+		t.Errorf("outer frame gas mismatch: have %d, want %d", outer.GasUsed, profile.GasUsed+profile.Refund-profile.IntrinsicGas)
+	}
+	if outer.SelfGas+inner.GasUsed != outer.GasUsed || inner.SelfGas != inner.GasUsed {
+		t.Errorf("self gas mismatch: outer %d/%d, inner %d/%d", outer.SelfGas, outer.GasUsed, inner.SelfGas, inner.GasUsed)
+	}
+	// Check the attribution to the contracts, instructions and source ranges
+	gas := make(map[common.Address]uint64)
+	for _, contract := range profile.Contracts {
+		gas[contract.Address] = contract.Gas
+	}
+	if gas[caller] != outer.SelfGas || gas[callee] != inner.SelfGas {
+		t.Errorf("contract gas mismatch: have %v", gas)
+	}
+	var total uint64
+	for _, pc := range profile.Pcs {
+		total += pc.Gas
+		if pc.Address == callee && pc.Op == "SSTORE" {
+			if pc.Pc != 4 || pc.Gas != params.SstoreSetGas+params.ColdSloadCostEIP2929 || pc.Source != "10:5:0" {
+				t.Errorf("callee SSTORE mismatch: have pc %d, gas %d, source %q", pc.Pc, pc.Gas, pc.Source)
+			}
+		}
+	}
+	if total != outer.GasUsed {
+		t.Errorf("instruction gas mismatch: have %d, want %d", total, outer.GasUsed)
+	}
+	sources := make(map[string]uint64)
+	for _, source := range profile.Sources {
+		sources[source.Source] += source.Gas
+	}
+	if len(sources) != 2 || sources["0:1:0"] != 2*vm.GasFastestStep || sources["0:1:0"]+sources["10:5:0"] != inner.GasUsed {
+		t.Errorf("source ranges mismatch: have %+v", profile.Sources)
+	}
+	// Check the folded stacks add up to the gas used
+	var folded uint64
+	for _, line := range strings.Split(strings.TrimSpace(profile.Folded), "\n") {
+		idx := strings.LastIndexByte(line, ' ')
+		value, err := strconv.ParseUint(line[idx+1:], 10, 64)
+		if err != nil {
+			t.Fatalf("invalid folded stack %q: %v", line, err)
+		}
+		folded += value
+	}
+	if folded != profile.GasUsed+profile.Refund {
+		t.Errorf("folded stacks gas mismatch: have %d, want %d", folded, profile.GasUsed+profile.Refund)
+	}
+	want := caller.Hex() + ";" + callee.Hex() + ";SSTORE@10:5:0 " + strconv.FormatUint(params.SstoreSetGas+params.ColdSloadCostEIP2929, 10)
+	if !strings.Contains(profile.Folded, want+"\n") || !strings.Contains(profile.Folded, "[intrinsic] 21000\n") {
+		t.Errorf("folded stacks mismatch: have\n%s", profile.Folded)
+	}
+	// Check the pprof profile is gzipped and references the contracts
+	gz, err := gzip.NewReader(bytes.NewReader(profile.Pprof))
+	if err != nil {
+		t.Fatalf("invalid pprof profile: %v", err)
+	}
+	blob, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("invalid pprof profile: %v", err)
+	}
+	for _, s := range []string{"gas", "nanoseconds", "[intrinsic]", caller.Hex(), callee.Hex()} {
+		if !bytes.Contains(blob, []byte(s)) {
+			t.Errorf("pprof profile misses %q", s)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// transferCode assembles the bytecode of the transfer tracer tests.
+type transferCode []byte
+
+// push pushes a word onto the stack.
+func (c transferCode) push(word []byte) transferCode {
+	return append(append(c, byte(vm.PUSH32)), common.LeftPadBytes(word, 32)...)
+}
+
+// pushInt pushes a small integer onto the stack.
+func (c transferCode) pushInt(n uint64) transferCode {
+	return c.push(new(big.Int).SetUint64(n).Bytes())
+}
+
+// log emits an event with the given topics and the memory range as data.
+func (c transferCode) log(offset, size uint64, topics ...[]byte) transferCode {
+	for i := len(topics) - 1; i >= 0; i-- {
+		c = c.push(topics[i])
+	}
+	return append(c.pushInt(size).pushInt(offset), byte(vm.LOG0)+byte(len(topics)))
+}
+
+// call calls the given address with value, without input nor output.
+func (c transferCode) call(to common.Address, value uint64) transferCode {
+	c = c.pushInt(0).pushInt(0).pushInt(0).pushInt(0).pushInt(value).push(to.Bytes())
+	return append(c, byte(vm.GAS), byte(vm.CALL), byte(vm.POP))
+}
+
+// Tests that the transfer tracer collects the native currency transfers and
+// the standard token transfer events in execution order, dropping the ones of
+// reverted calls.
+func TestTransferTracer(t *testing.T) {
+	var (
+		origin   = common.HexToAddress("0x00000000000000000000000000000000feed")
+		token    = common.HexToAddress("0x00000000000000000000000000000000000cafe0")
+		reverter = common.HexToAddress("0x00000000000000000000000000000000000dead0")
+		account  = common.HexToAddress("0x00000000000000000000000000000000000beef0")
+		alice    = common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+		bob      = common.HexToAddress("0x00000000000000000000000000000000000000b0")
+
+		transfer       = crypto.Keccak256([]byte("Transfer(address,address,uint256)"))
+		transferSingle = crypto.Keccak256([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+		transferBatch  = crypto.Keccak256([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+		approval       = crypto.Keccak256([]byte("Approval(address,address,uint256)"))
+	)
+	// The token emits ERC-20, ERC-721 and ERC-1155 transfers and an unrelated
+	// event, calls the reverter and sends 2 wei to an account.
+	var code transferCode
+	for i, word := range []uint64{64, 160, 2, 1, 2, 2, 10, 20} {
+		code = append(code.pushInt(word).pushInt(uint64(32*i)), byte(vm.MSTORE))
+	}
+	code = code.log(224, 32, transfer, alice.Bytes(), bob.Bytes())
+	code = code.log(224, 32, approval, alice.Bytes(), bob.Bytes())
+	code = code.log(0, 0, transfer, alice.Bytes(), bob.Bytes(), []byte{42})
+	code = code.log(96, 64, transferSingle, token.Bytes(), alice.Bytes(), bob.Bytes())
+	code = code.log(0, 256, transferBatch, token.Bytes(), bob.Bytes(), alice.Bytes())
+	code = code.call(reverter, 3).call(account, 2)
+
+	// The reverter emits an ERC-20 transfer and reverts
+	var revert transferCode
+	revert = append(revert.pushInt(1).pushInt(0), byte(vm.MSTORE))
+	revert = revert.log(0, 32, transfer, alice.Bytes(), bob.Bytes())
+	revert = append(revert.pushInt(0).pushInt(0), byte(vm.REVERT))
+
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), core.GenesisAlloc{
+		token:    {Code: code},
+		reverter: {Code: revert},
+		origin:   {Balance: big.NewInt(500000000000000)},
+	}, false)
+
+	tracer, err := tracers.DefaultDirectory.New("transferTracer", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create transfer tracer: %v", err)
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: new(big.Int).SetUint64(8000000),
+		Time:        5,
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+	evm := vm.NewEVM(context, vm.TxContext{Origin: origin, GasPrice: big.NewInt(1)}, statedb, params.MainnetChainConfig, vm.Config{Tracer: tracer})
+	msg := &core.Message{
+		To:        &token,
+		From:      origin,
+		Value:     big.NewInt(5),
+		GasLimit:  500000,
+		GasPrice:  big.NewInt(0),
+		GasFeeCap: big.NewInt(0),
+		GasTipCap: big.NewInt(0),
+	}
+	result, err := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit)).TransitionDb()
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("transaction failed: %v", result.Err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var have []map[string]interface{}
+	if err := json.Unmarshal(res, &have); err != nil {
+		t.Fatalf("failed to decode transfers: %v", err)
+	}
+	hex := func(addr common.Address) string { return hexutil.Encode(addr[:]) }
+
+	var want []map[string]interface{}
+	if err := json.Unmarshal([]byte(`[
+		{"type": "native", "from": "`+hex(origin)+`", "to": "`+hex(token)+`", "amount": "0x5", "callPath": []},
+		{"type": "erc20", "asset": "`+hex(token)+`", "from": "`+hex(alice)+`", "to": "`+hex(bob)+`", "amount": "0x14", "callPath": []},
+		{"type": "erc721", "asset": "`+hex(token)+`", "from": "`+hex(alice)+`", "to": "`+hex(bob)+`", "amount": "0x1", "tokenId": "0x2a", "callPath": []},
+		{"type": "erc1155", "asset": "`+hex(token)+`", "from": "`+hex(alice)+`", "to": "`+hex(bob)+`", "amount": "0x2", "tokenId": "0x1", "callPath": []},
+		{"type": "erc1155", "asset": "`+hex(token)+`", "from": "`+hex(bob)+`", "to": "`+hex(alice)+`", "amount": "0xa", "tokenId": "0x1", "callPath": []},
+		{"type": "erc1155", "asset": "`+hex(token)+`", "from": "`+hex(bob)+`", "to": "`+hex(alice)+`", "amount": "0x14", "tokenId": "0x2", "callPath": []},
+		{"type": "native", "from": "`+hex(token)+`", "to": "`+hex(account)+`", "amount": "0x2", "callPath": [1]}
+	]`), &want); err != nil {
+		t.Fatalf("failed to decode expected transfers: %v", err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("transfers mismatch:\nhave %s\nwant %v", res, want)
+	}
+}
@@ -0,0 +1,588 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package native
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("gasProfiler", newGasProfilerTracer, false)
+}
+
+// noPC is the program counter of the location standing for a call frame which
+// executed no code, such as a precompile or a plain value transfer.
+const noPC = ^uint64(0)
+
+// gasProfilerConfig is the configuration of the gas profiler.
+type gasProfilerConfig struct {
+	SourceMaps map[common.Address]string `json:"sourceMaps"` // Solidity source maps of the runtime code of contracts
+}
+
+// profileLocation is a point of execution: an instruction of the code run by a
+// call frame, or the call frame itself if it executed no code. The intrinsic
+// gas of transactions is charged to a location of its own.
+type profileLocation struct {
+	addr      common.Address
+	create    bool // Whether the code is the init code of a contract creation
+	pc        uint64
+	intrinsic bool
+}
+
+// profileSample aggregates the gas and time spent at a location, reached
+// through the given stack of call sites.
+type profileSample struct {
+	stack []profileLocation // Leaf location first, followed by the call sites
+	gas   uint64
+	time  time.Duration
+	count uint64
+}
+
+// profileFrame is a call frame being executed.
+type profileFrame struct {
+	stats   *frameGas
+	addr    common.Address
+	create  bool
+	callers []profileLocation // Call sites leading to the frame, innermost first
+	start   time.Time
+
+	pending     bool          // Whether an instruction awaits the next one to be charged
+	pc          uint64        // Program counter of the pending instruction
+	op          vm.OpCode     // Opcode of the pending instruction
+	gas         uint64        // Gas available before the pending instruction
+	opStart     time.Time     // Time the pending instruction started executing at
+	opChildGas  uint64        // Gas used by the frames called by the pending instruction
+	opChildTime time.Duration // Time spent by the frames called by the pending instruction
+
+	charged   uint64        // Gas charged to the instructions of the frame, children included
+	childGas  uint64        // Gas used by all the frames called
+	childTime time.Duration // Time spent by all the frames called
+}
+
+// contractGas is the gas and time spent running the code of a contract.
+type contractGas struct {
+	Address common.Address `json:"address"`
+	Gas     uint64         `json:"gas"`
+	Time    uint64         `json:"time"` // Nanoseconds
+	Ops     uint64         `json:"ops"`
+}
+
+// frameGas is the gas and time spent by a call frame, excluding the ones of
+// the frames it called in the self figures.
+type frameGas struct {
+	Type     string         `json:"type"`
+	From     common.Address `json:"from"`
+	To       common.Address `json:"to"`
+	Depth    int            `json:"depth"`
+	GasUsed  uint64         `json:"gasUsed"`
+	SelfGas  uint64         `json:"selfGas"`
+	Time     uint64         `json:"time"`     // Nanoseconds
+	SelfTime uint64         `json:"selfTime"` // Nanoseconds
+	Error    string         `json:"error,omitempty"`
+}
+
+// pcGas is the gas and time spent executing an instruction of a contract.
+type pcGas struct {
+	Address common.Address `json:"address"`
+	Pc      uint64         `json:"pc"`
+	Op      string         `json:"op"`
+	Source  string         `json:"source,omitempty"` // Source range of the instruction, as s:l:f
+	Gas     uint64         `json:"gas"`
+	Time    uint64         `json:"time"` // Nanoseconds
+	Count   uint64         `json:"count"`
+}
+
+// sourceGas is the gas and time spent executing the instructions of a source
+// range of a contract.
+type sourceGas struct {
+	Address common.Address `json:"address"`
+	Source  string         `json:"source"` // Source range, as s:l:f
+	Gas     uint64         `json:"gas"`
+	Time    uint64         `json:"time"` // Nanoseconds
+	Count   uint64         `json:"count"`
+}
+
+// gasProfile is the result of the gas profiler.
+type gasProfile struct {
+	GasUsed      uint64         `json:"gasUsed"`
+	IntrinsicGas uint64         `json:"intrinsicGas"`
+	Refund       uint64         `json:"refund"`
+	Contracts    []*contractGas `json:"contracts"`
+	Frames       []*frameGas    `json:"frames"`
+	Pcs          []*pcGas       `json:"pcs"`
+	Sources      []*sourceGas   `json:"sources,omitempty"`
+	Folded       string         `json:"folded"`
+	Pprof        hexutil.Bytes  `json:"pprof"`
+}
+
+type pcKey struct {
+	addr common.Address
+	pc   uint64
+}
+
+type sourceKey struct {
+	addr   common.Address
+	source string
+}
+
+// GasProfiler aggregates the gas used and the time spent by the executed code
+// per contract, per call frame and per instruction, or per source range of the
+// contracts with a source map. The profile is available as JSON, as a pprof
+// profile and as folded stacks for flamegraphs.
+type GasProfiler struct {
+	noopTracer
+	env       *vm.EVM
+	srcmaps   map[common.Address]string    // Source maps of the contracts, as configured
+	sources   map[common.Address]sourceMap // Source ranges of the instructions of the contracts, parsed on demand
+	gasLimit  uint64
+	intrinsic uint64
+	execGas   uint64
+	gasUsed   uint64
+	frames    []*profileFrame
+	calls     []*frameGas
+	samples   map[string]*profileSample
+	contracts map[common.Address]*contractGas
+	pcs       map[pcKey]*pcGas
+	sourceGas map[sourceKey]*sourceGas
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newGasProfilerTracer returns a new gas profiler, registered as a tracer.
+func newGasProfilerTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	return NewGasProfiler(cfg)
+}
+
+// NewGasProfiler returns a new gas profiler. The configuration may provide the
+// Solidity source maps of the runtime code of contracts, to aggregate the gas
+// per source range.
+func NewGasProfiler(cfg json.RawMessage) (*GasProfiler, error) {
+	var config gasProfilerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	return &GasProfiler{
+		srcmaps:   config.SourceMaps,
+		sources:   make(map[common.Address]sourceMap),
+		samples:   make(map[string]*profileSample),
+		contracts: make(map[common.Address]*contractGas),
+		pcs:       make(map[pcKey]*pcGas),
+		sourceGas: make(map[sourceKey]*sourceGas),
+	}, nil
+}
+
+// CaptureTxStart implements the EVMLogger interface to initialize the tracing of
+// a transaction.
+func (t *GasProfiler) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureTxEnd implements the EVMLogger interface to finalize the tracing of a
+// transaction.
+func (t *GasProfiler) CaptureTxEnd(restGas uint64) {
+	t.gasUsed = t.gasLimit - restGas
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *GasProfiler) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	if t.gasLimit > gas {
+		t.intrinsic = t.gasLimit - gas
+		t.charge(profileLocation{intrinsic: true}, nil, t.intrinsic, 0)
+	}
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.enter(typ, from, to, create)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *GasProfiler) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.execGas += gasUsed
+	t.exit(gasUsed, err)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *GasProfiler) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	var (
+		frame = t.frames[len(t.frames)-1]
+		now   = time.Now()
+	)
+	// Charge the previous instruction of the frame with the gas it consumed,
+	// less the gas its children used
+	if frame.pending {
+		t.chargeOp(frame, frame.gas-gas, now)
+	}
+	frame.pending, frame.pc, frame.op, frame.gas, frame.opStart = true, pc, op, gas, now
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *GasProfiler) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	t.enter(typ, from, to, typ == vm.CREATE || typ == vm.CREATE2)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *GasProfiler) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.interrupt.Load() || len(t.frames) < 2 {
+		return
+	}
+	t.exit(gasUsed, err)
+}
+
+// enter starts tracking a new call frame, called by the pending instruction of
+// the current one, if any.
+func (t *GasProfiler) enter(typ vm.OpCode, from, to common.Address, create bool) {
+	frame := &profileFrame{
+		stats:  &frameGas{Type: typ.String(), From: from, To: to, Depth: len(t.frames)},
+		addr:   to,
+		create: create,
+		start:  time.Now(),
+	}
+	if len(t.frames) > 0 {
+		parent := t.frames[len(t.frames)-1]
+		site := profileLocation{addr: parent.addr, create: parent.create, pc: parent.pc}
+		frame.callers = append([]profileLocation{site}, parent.callers...)
+	}
+	t.frames = append(t.frames, frame)
+	t.calls = append(t.calls, frame.stats)
+}
+
+// exit finishes the current call frame, charging its last instruction with the
+// gas the frame used and was not charged yet.
+func (t *GasProfiler) exit(gasUsed uint64, err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	var (
+		frame = t.frames[len(t.frames)-1]
+		now   = time.Now()
+	)
+	t.frames = t.frames[:len(t.frames)-1]
+
+	switch {
+	case frame.pending:
+		var used uint64
+		if gasUsed > frame.charged {
+			used = gasUsed - frame.charged
+		}
+		t.chargeOp(frame, used, now)
+	case frame.charged == 0 && gasUsed > 0:
+		// The frame executed no code, charge it as a whole
+		loc := profileLocation{addr: frame.addr, create: frame.create, pc: noPC}
+		t.charge(loc, frame.callers, gasUsed, now.Sub(frame.start))
+	}
+	elapsed := now.Sub(frame.start)
+
+	frame.stats.GasUsed = gasUsed
+	frame.stats.Time = uint64(elapsed)
+	if gasUsed > frame.childGas {
+		frame.stats.SelfGas = gasUsed - frame.childGas
+	}
+	if elapsed > frame.childTime {
+		frame.stats.SelfTime = uint64(elapsed - frame.childTime)
+	}
+	if err != nil {
+		frame.stats.Error = err.Error()
+	}
+	if len(t.frames) > 0 {
+		parent := t.frames[len(t.frames)-1]
+		parent.opChildGas += gasUsed
+		parent.opChildTime += elapsed
+		parent.childGas += gasUsed
+		parent.childTime += elapsed
+	}
+}
+
+// chargeOp charges the pending instruction of a frame with the gas it used,
+// less the gas and time used by the frames it called.
+func (t *GasProfiler) chargeOp(frame *profileFrame, used uint64, now time.Time) {
+	frame.charged += used
+
+	self := uint64(0)
+	if used > frame.opChildGas {
+		self = used - frame.opChildGas
+	}
+	elapsed := now.Sub(frame.opStart) - frame.opChildTime
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	frame.pending, frame.opChildGas, frame.opChildTime = false, 0, 0
+
+	loc := profileLocation{addr: frame.addr, create: frame.create, pc: frame.pc}
+	t.charge(loc, frame.callers, self, elapsed)
+
+	// Aggregate the instruction per contract, program counter and source range
+	contract := t.contracts[frame.addr]
+	if contract == nil {
+		contract = &contractGas{Address: frame.addr}
+		t.contracts[frame.addr] = contract
+	}
+	contract.Gas += self
+	contract.Time += uint64(elapsed)
+	contract.Ops++
+
+	key := pcKey{frame.addr, frame.pc}
+	stat := t.pcs[key]
+	if stat == nil {
+		stat = &pcGas{Address: frame.addr, Pc: frame.pc, Op: frame.op.String()}
+		if !frame.create {
+			stat.Source = t.source(frame.addr, frame.pc)
+		}
+		t.pcs[key] = stat
+	}
+	stat.Gas += self
+	stat.Time += uint64(elapsed)
+	stat.Count++
+
+	if stat.Source != "" {
+		key := sourceKey{frame.addr, stat.Source}
+		src := t.sourceGas[key]
+		if src == nil {
+			src = &sourceGas{Address: frame.addr, Source: stat.Source}
+			t.sourceGas[key] = src
+		}
+		src.Gas += self
+		src.Time += uint64(elapsed)
+		src.Count++
+	}
+}
+
+// charge aggregates the gas and time spent at a location into the sample of
+// its stack.
+func (t *GasProfiler) charge(loc profileLocation, callers []profileLocation, gas uint64, elapsed time.Duration) {
+	stack := append([]profileLocation{loc}, callers...)
+
+	key := make([]byte, 0, len(stack)*(common.AddressLength+10))
+	for _, l := range stack {
+		key = append(key, l.addr[:]...)
+		key = append(key, profileLocationFlags(l))
+		key = binary.BigEndian.AppendUint64(key, l.pc)
+	}
+	sample := t.samples[string(key)]
+	if sample == nil {
+		sample = &profileSample{stack: stack}
+		t.samples[string(key)] = sample
+	}
+	sample.gas += gas
+	sample.time += elapsed
+	sample.count++
+}
+
+// source returns the source range of an instruction of a contract, or an empty
+// string if the contract has no source map.
+func (t *GasProfiler) source(addr common.Address, pc uint64) string {
+	srcmap, ok := t.srcmaps[addr]
+	if !ok || t.env == nil {
+		return ""
+	}
+	sources, ok := t.sources[addr]
+	if !ok {
+		sources = parseSourceMap(t.env.StateDB.GetCode(addr), srcmap)
+		t.sources[addr] = sources
+	}
+	return sources[pc]
+}
+
+// GetResult returns the json-encoded gas profile, and any error arising from
+// the encoding or forceful termination (via `Stop`).
+func (t *GasProfiler) GetResult() (json.RawMessage, error) {
+	profile := &gasProfile{
+		GasUsed:      t.gasUsed,
+		IntrinsicGas: t.intrinsic,
+		Frames:       t.calls,
+	}
+	if profile.GasUsed == 0 {
+		profile.GasUsed = t.intrinsic + t.execGas
+	}
+	if t.intrinsic+t.execGas > profile.GasUsed {
+		profile.Refund = t.intrinsic + t.execGas - profile.GasUsed
+	}
+	for _, contract := range t.contracts {
+		profile.Contracts = append(profile.Contracts, contract)
+	}
+	sort.Slice(profile.Contracts, func(i, j int) bool {
+		a, b := profile.Contracts[i], profile.Contracts[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		return bytes.Compare(a.Address[:], b.Address[:]) < 0
+	})
+	for _, pc := range t.pcs {
+		profile.Pcs = append(profile.Pcs, pc)
+	}
+	sort.Slice(profile.Pcs, func(i, j int) bool {
+		a, b := profile.Pcs[i], profile.Pcs[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		if a.Address != b.Address {
+			return bytes.Compare(a.Address[:], b.Address[:]) < 0
+		}
+		return a.Pc < b.Pc
+	})
+	for _, src := range t.sourceGas {
+		profile.Sources = append(profile.Sources, src)
+	}
+	sort.Slice(profile.Sources, func(i, j int) bool {
+		a, b := profile.Sources[i], profile.Sources[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		if a.Address != b.Address {
+			return bytes.Compare(a.Address[:], b.Address[:]) < 0
+		}
+		return a.Source < b.Source
+	})
+	var folded strings.Builder
+	if err := t.WriteFolded(&folded); err != nil {
+		return nil, err
+	}
+	profile.Folded = folded.String()
+
+	var pprof bytes.Buffer
+	if err := t.WriteProfile(&pprof); err != nil {
+		return nil, err
+	}
+	profile.Pprof = pprof.Bytes()
+
+	res, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *GasProfiler) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// WriteFolded writes the gas profile as folded stacks, one line per stack of
+// call frames and instruction, as consumed by flamegraph tools.
+func (t *GasProfiler) WriteFolded(w io.Writer) error {
+	stacks := make(map[string]uint64)
+	for _, sample := range t.samples {
+		if sample.gas == 0 {
+			continue
+		}
+		frames := make([]string, 0, len(sample.stack)+1)
+		for i := len(sample.stack) - 1; i >= 0; i-- {
+			frames = append(frames, t.frameName(sample.stack[i]))
+		}
+		if leaf := sample.stack[0]; !leaf.intrinsic && leaf.pc != noPC {
+			frames = append(frames, t.opName(leaf))
+		}
+		stacks[strings.Join(frames, ";")] += sample.gas
+	}
+	lines := make([]string, 0, len(stacks))
+	for stack, gas := range stacks {
+		lines = append(lines, fmt.Sprintf("%s %d\n", stack, gas))
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frameName returns the name of the call frame of a location in the profiles.
+func (t *GasProfiler) frameName(loc profileLocation) string {
+	switch {
+	case loc.intrinsic:
+		return "[intrinsic]"
+	case loc.create:
+		return loc.addr.Hex() + " (create)"
+	default:
+		return loc.addr.Hex()
+	}
+}
+
+// profileLocationFlags encodes the kind of a location into a byte.
+func profileLocationFlags(loc profileLocation) byte {
+	var flags byte
+	if loc.create {
+		flags |= 1
+	}
+	if loc.intrinsic {
+		flags |= 2
+	}
+	return flags
+}
+
+// opName returns the name of the instruction of a location in the profiles.
+func (t *GasProfiler) opName(loc profileLocation) string {
+	if stat := t.pcs[pcKey{loc.addr, loc.pc}]; stat != nil {
+		if stat.Source != "" {
+			return fmt.Sprintf("%s@%s", stat.Op, stat.Source)
+		}
+		return fmt.Sprintf("%s@%#x", stat.Op, loc.pc)
+	}
+	return fmt.Sprintf("%#x", loc.pc)
+}
+
+// sourceMap maps the program counters of the instructions of a contract code to
+// their source ranges.
+type sourceMap map[uint64]string
+
+// parseSourceMap decodes a Solidity source map of the given code. The entries
+// of the map, separated by semicolons, hold the start, length and file index of
+// the source range of each instruction, omitted if unchanged.
+func parseSourceMap(code []byte, srcmap string) sourceMap {
+	var (
+		entries = strings.Split(srcmap, ";")
+		fields  [3]string
+		sources = make(sourceMap)
+	)
+	for i, pc := 0, uint64(0); pc < uint64(len(code)) && i < len(entries); i++ {
+		for j, field := range strings.Split(entries[i], ":") {
+			if j < len(fields) && field != "" {
+				fields[j] = field
+			}
+		}
+		if fields[0] != "" {
+			sources[pc] = strings.Join(fields[:], ":")
+		}
+		if op := vm.OpCode(code[pc]); op.IsPush() {
+			pc += uint64(op-vm.PUSH1) + 1
+		}
+		pc++
+	}
+	return sources
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package native
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// Field numbers of the messages of the pprof profile.proto format.
+const (
+	pprofProfileSampleType        = 1
+	pprofProfileSample            = 2
+	pprofProfileLocation          = 4
+	pprofProfileFunction          = 5
+	pprofProfileStringTable       = 6
+	pprofProfilePeriodType        = 11
+	pprofProfilePeriod            = 12
+	pprofProfileDefaultSampleType = 14
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocation = 1
+	pprofSampleValue    = 2
+
+	pprofLocationID      = 1
+	pprofLocationAddress = 3
+	pprofLocationLine    = 4
+
+	pprofLineFunctionID = 1
+	pprofLineLine       = 2
+
+	pprofFunctionID         = 1
+	pprofFunctionName       = 2
+	pprofFunctionSystemName = 3
+)
+
+// protobuf is a minimal protocol buffer encoder, sufficient to write pprof
+// profiles without depending on a protobuf library.
+type protobuf struct {
+	data []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protobuf) uint64(field int, x uint64) {
+	b.varint(uint64(field) << 3)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *protobuf) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *protobuf) message(field int, msg *protobuf) {
+	b.bytes(field, msg.data)
+}
+
+func (b *protobuf) packed(field int, xs []uint64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	b.bytes(field, packed.data)
+}
+
+// pprofBuilder assembles a pprof profile, interning its strings, functions and
+// locations.
+type pprofBuilder struct {
+	profile   protobuf
+	strings   map[string]int64
+	functions map[string]uint64
+	locations map[profileLocation]uint64
+}
+
+func newPprofBuilder() *pprofBuilder {
+	b := &pprofBuilder{
+		strings:   make(map[string]int64),
+		functions: make(map[string]uint64),
+		locations: make(map[profileLocation]uint64),
+	}
+	b.string("") // The string table starts with the empty string
+	return b
+}
+
+// string returns the index of a string in the string table, adding it if new.
+func (b *pprofBuilder) string(s string) int64 {
+	if index, ok := b.strings[s]; ok {
+		return index
+	}
+	index := int64(len(b.strings))
+	b.strings[s] = index
+	b.profile.string(pprofProfileStringTable, s)
+	return index
+}
+
+// valueType encodes a type of the values of the profile.
+func (b *pprofBuilder) valueType(field int, typ, unit string) {
+	var msg protobuf
+	msg.int64(pprofValueTypeType, b.string(typ))
+	msg.int64(pprofValueTypeUnit, b.string(unit))
+	b.profile.message(field, &msg)
+}
+
+// function returns the id of the function of the given name, adding it if new.
+func (b *pprofBuilder) function(name string) uint64 {
+	if id, ok := b.functions[name]; ok {
+		return id
+	}
+	id := uint64(len(b.functions) + 1)
+	b.functions[name] = id
+
+	var msg protobuf
+	msg.uint64(pprofFunctionID, id)
+	msg.int64(pprofFunctionName, b.string(name))
+	msg.int64(pprofFunctionSystemName, b.string(name))
+	b.profile.message(pprofProfileFunction, &msg)
+	return id
+}
+
+// location returns the id of a location, adding it if new. Locations are lines
+// of the functions standing for the contract codes, numbered by program counter.
+func (b *pprofBuilder) location(t *GasProfiler, loc profileLocation) uint64 {
+	if id, ok := b.locations[loc]; ok {
+		return id
+	}
+	id := uint64(len(b.locations) + 1)
+	b.locations[loc] = id
+
+	var pc uint64
+	if !loc.intrinsic && loc.pc != noPC {
+		pc = loc.pc
+	}
+	var line protobuf
+	line.uint64(pprofLineFunctionID, b.function(t.frameName(loc)))
+	line.int64(pprofLineLine, int64(pc))
+
+	var msg protobuf
+	msg.uint64(pprofLocationID, id)
+	msg.uint64(pprofLocationAddress, pc)
+	msg.message(pprofLocationLine, &line)
+	b.profile.message(pprofProfileLocation, &msg)
+	return id
+}
+
+// WriteProfile writes the gas profile as a gzipped pprof profile, sampling the
+// gas used, the time spent in nanoseconds and the number of instructions of
+// every stack of call sites and instruction.
+func (t *GasProfiler) WriteProfile(w io.Writer) error {
+	b := newPprofBuilder()
+	b.valueType(pprofProfileSampleType, "gas", "gas")
+	b.valueType(pprofProfileSampleType, "time", "nanoseconds")
+	b.valueType(pprofProfileSampleType, "ops", "count")
+	b.valueType(pprofProfilePeriodType, "gas", "gas")
+	b.profile.int64(pprofProfilePeriod, 1)
+	b.profile.int64(pprofProfileDefaultSampleType, b.string("gas"))
+
+	// Encode the samples in a deterministic order
+	keys := make([]string, 0, len(t.samples))
+	for key := range t.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		sample := t.samples[key]
+		ids := make([]uint64, len(sample.stack))
+		for i, loc := range sample.stack {
+			ids[i] = b.location(t, loc)
+		}
+		var msg protobuf
+		msg.packed(pprofSampleLocation, ids)
+		msg.packed(pprofSampleValue, []uint64{sample.gas, uint64(sample.time), sample.count})
+		b.profile.message(pprofProfileSample, &msg)
+	}
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(b.profile.data); err != nil {
+		return err
+	}
+	return gz.Close()
+}
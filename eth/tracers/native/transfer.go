@@ -0,0 +1,268 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("transferTracer", newTransferTracer, false)
+}
+
+var (
+	// transferTopic is the topic of the ERC-20 and ERC-721 Transfer events.
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+	// transferSingleTopic and transferBatchTopic are the topics of the ERC-1155
+	// TransferSingle and TransferBatch events.
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	transferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// Kinds of the assets moved by transfers.
+const (
+	transferNative  = "native"
+	transferERC20   = "erc20"
+	transferERC721  = "erc721"
+	transferERC1155 = "erc1155"
+)
+
+// transfer is a movement of value, either of the native currency through the
+// value of a call, or of a token through a standard transfer event.
+type transfer struct {
+	Type     string          `json:"type"`
+	Asset    *common.Address `json:"asset,omitempty"` // Token contract, nil for the native currency
+	From     common.Address  `json:"from"`
+	To       common.Address  `json:"to"`
+	Amount   *hexutil.Big    `json:"amount"`
+	TokenID  *hexutil.Big    `json:"tokenId,omitempty"` // Token of ERC-721 and ERC-1155 transfers
+	CallPath []int           `json:"callPath"`          // Position of the call frame moving the value
+}
+
+// transferFrame is a call frame being executed, holding the transfers made
+// by it and by its successful subcalls.
+type transferFrame struct {
+	path      []int
+	calls     int
+	transfers []*transfer
+}
+
+// transferTracer is a native tracer collecting all the value movements of a
+// transaction in execution order: native currency transfers done by the calls,
+// creations and self-destructs, and the token transfers announced by ERC-20,
+// ERC-721 and ERC-1155 events. Transfers of reverted call frames are dropped.
+// Gas fees are not reported.
+type transferTracer struct {
+	noopTracer
+	frames    []*transferFrame
+	transfers []*transfer
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newTransferTracer returns a native go tracer which collects the native
+// currency and token transfers of a transaction, and implements vm.EVMLogger.
+func newTransferTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &transferTracer{transfers: []*transfer{}}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *transferTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.frames = []*transferFrame{{path: []int{}}}
+	t.transferValue(from, to, value)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *transferTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	if err == nil {
+		t.transfers = append(t.transfers, t.frames[0].transfers...)
+	}
+	t.frames = nil
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *transferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || (op != vm.LOG3 && op != vm.LOG4) || len(t.frames) == 0 {
+		return
+	}
+	// Skip if tracing was interrupted
+	if t.interrupt.Load() {
+		return
+	}
+	stack := scope.Stack.Data()
+	mStart, mSize := stack[len(stack)-1], stack[len(stack)-2]
+	topics := make([]common.Hash, op-vm.LOG0)
+	for i := range topics {
+		topics[i] = common.Hash(stack[len(stack)-3-i].Bytes32())
+	}
+	data, err := tracers.GetMemoryCopyPadded(scope.Memory, int64(mStart.Uint64()), int64(mSize.Uint64()))
+	if err != nil {
+		// mSize was unrealistically large
+		return
+	}
+	t.transferTokens(scope.Contract.Address(), topics, data)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *transferTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	parent := t.frames[len(t.frames)-1]
+	path := make([]int, len(parent.path), len(parent.path)+1)
+	copy(path, parent.path)
+
+	t.frames = append(t.frames, &transferFrame{path: append(path, parent.calls)})
+	parent.calls++
+
+	// Delegated calls execute with the value of their parent, and the value of
+	// code calls is sent by the contract to itself.
+	switch typ {
+	case vm.CALL, vm.CREATE, vm.CREATE2, vm.SELFDESTRUCT:
+		t.transferValue(from, to, value)
+	}
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *transferTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if len(t.frames) <= 1 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	if err == nil {
+		parent := t.frames[len(t.frames)-1]
+		parent.transfers = append(parent.transfers, frame.transfers...)
+	}
+}
+
+// transferValue records the native currency sent by the current call frame.
+func (t *transferTracer) transferValue(from, to common.Address, value *big.Int) {
+	if value == nil || value.Sign() == 0 {
+		return
+	}
+	t.record(&transfer{
+		Type:   transferNative,
+		From:   from,
+		To:     to,
+		Amount: (*hexutil.Big)(new(big.Int).Set(value)),
+	})
+}
+
+// transferTokens records the token transfers announced by an event, ignoring
+// the events not matching the standard transfer events.
+func (t *transferTracer) transferTokens(token common.Address, topics []common.Hash, data []byte) {
+	switch {
+	case topics[0] == transferTopic && len(topics) == 3 && len(data) == 32:
+		t.record(&transfer{
+			Type:   transferERC20,
+			Asset:  &token,
+			From:   common.BytesToAddress(topics[1][:]),
+			To:     common.BytesToAddress(topics[2][:]),
+			Amount: (*hexutil.Big)(new(big.Int).SetBytes(data)),
+		})
+
+	case topics[0] == transferTopic && len(topics) == 4 && len(data) == 0:
+		t.record(&transfer{
+			Type:    transferERC721,
+			Asset:   &token,
+			From:    common.BytesToAddress(topics[1][:]),
+			To:      common.BytesToAddress(topics[2][:]),
+			Amount:  (*hexutil.Big)(big.NewInt(1)),
+			TokenID: (*hexutil.Big)(new(big.Int).SetBytes(topics[3][:])),
+		})
+
+	case topics[0] == transferSingleTopic && len(topics) == 4 && len(data) == 64:
+		t.record(&transfer{
+			Type:    transferERC1155,
+			Asset:   &token,
+			From:    common.BytesToAddress(topics[2][:]),
+			To:      common.BytesToAddress(topics[3][:]),
+			Amount:  (*hexutil.Big)(new(big.Int).SetBytes(data[32:])),
+			TokenID: (*hexutil.Big)(new(big.Int).SetBytes(data[:32])),
+		})
+
+	case topics[0] == transferBatchTopic && len(topics) == 4:
+		ids, amounts := decodeUint256Array(data, 0), decodeUint256Array(data, 32)
+		if ids == nil || len(ids) != len(amounts) {
+			return
+		}
+		for i := range ids {
+			t.record(&transfer{
+				Type:    transferERC1155,
+				Asset:   &token,
+				From:    common.BytesToAddress(topics[2][:]),
+				To:      common.BytesToAddress(topics[3][:]),
+				Amount:  (*hexutil.Big)(amounts[i]),
+				TokenID: (*hexutil.Big)(ids[i]),
+			})
+		}
+	}
+}
+
+// record appends a transfer to the current call frame.
+func (t *transferTracer) record(tr *transfer) {
+	frame := t.frames[len(t.frames)-1]
+	tr.CallPath = frame.path
+	frame.transfers = append(frame.transfers, tr)
+}
+
+// decodeUint256Array decodes the ABI encoded uint256[] argument whose offset
+// is stored at the given position of the data, returning nil if malformed.
+func decodeUint256Array(data []byte, pos int) []*big.Int {
+	if len(data) < pos+32 {
+		return nil
+	}
+	offset := new(big.Int).SetBytes(data[pos : pos+32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return nil
+	}
+	start := int(offset.Uint64())
+	size := new(big.Int).SetBytes(data[start : start+32])
+	if !size.IsUint64() || size.Uint64() > uint64(len(data)-start-32)/32 {
+		return nil
+	}
+	values := make([]*big.Int, size.Uint64())
+	for i := range values {
+		at := start + 32 + 32*i
+		values[i] = new(big.Int).SetBytes(data[at : at+32])
+	}
+	return values
+}
+
+// GetResult returns the json-encoded list of transfers, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *transferTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.transfers)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *transferTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
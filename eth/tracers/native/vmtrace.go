@@ -0,0 +1,255 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/holiman/uint256"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("vmTracer", newVMTracer, false)
+}
+
+// vmTrace is the trace of the code executed by a call frame, in the vmTrace
+// format of OpenEthereum.
+type vmTrace struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []*vmTraceOp  `json:"ops"`
+}
+
+// vmTraceOp is an executed instruction, along with the trace of the call frame
+// it entered, if any.
+type vmTraceOp struct {
+	Cost uint64     `json:"cost"`
+	Ex   *vmTraceEx `json:"ex"` // Nil if the instruction failed
+	Pc   uint64     `json:"pc"`
+	Sub  *vmTrace   `json:"sub"`
+}
+
+// vmTraceEx holds the effects of an executed instruction: the remaining gas,
+// the items it pushed onto the stack, and the memory and storage it wrote.
+type vmTraceEx struct {
+	Mem   *vmTraceMem    `json:"mem"`
+	Push  []*hexutil.Big `json:"push"`
+	Store *vmTraceStore  `json:"store"`
+	Used  uint64         `json:"used"`
+}
+
+type vmTraceMem struct {
+	Data hexutil.Bytes `json:"data"`
+	Off  uint64        `json:"off"`
+}
+
+type vmTraceStore struct {
+	Key *hexutil.Big `json:"key"`
+	Val *hexutil.Big `json:"val"`
+}
+
+// vmTraceFrame tracks the instruction of a call frame whose effects are only
+// known once the next one of the frame is reached.
+type vmTraceFrame struct {
+	trace   *vmTrace
+	pending *vmTraceOp
+	op      vm.OpCode
+	gas     uint64
+	memOff  uint64
+	memSize uint64
+	store   *vmTraceStore
+}
+
+// vmTracer records the instructions executed by a transaction, as well as
+// their effects, nested by call frame.
+type vmTracer struct {
+	noopTracer
+	env       *vm.EVM
+	root      *vmTrace
+	frames    []*vmTraceFrame
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newVMTracer returns a new vmTracer.
+func newVMTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	return &vmTracer{}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *vmTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.root = t.newTrace(to, create, input)
+	t.frames = []*vmTraceFrame{{trace: t.root}}
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *vmTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if len(t.frames) > 0 {
+		t.frames[0].finish(nil)
+	}
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *vmTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	frame.gas = gas
+	frame.finish(scope)
+
+	// Record the instruction, and the memory and storage it is about to write
+	frame.pending = &vmTraceOp{Cost: cost, Pc: pc}
+	frame.trace.Ops = append(frame.trace.Ops, frame.pending)
+	frame.op, frame.gas = op, gas-cost
+	frame.memOff, frame.memSize, frame.store = 0, 0, nil
+
+	stack := scope.Stack.Data()
+	back := func(n int) *uint256.Int {
+		return &stack[len(stack)-1-n]
+	}
+	switch {
+	case op == vm.SSTORE && len(stack) >= 2:
+		frame.store = &vmTraceStore{Key: (*hexutil.Big)(back(0).ToBig()), Val: (*hexutil.Big)(back(1).ToBig())}
+	case op == vm.MSTORE && len(stack) >= 1:
+		frame.setMem(back(0), uint256.NewInt(32))
+	case op == vm.MSTORE8 && len(stack) >= 1:
+		frame.setMem(back(0), uint256.NewInt(1))
+	case (op == vm.CALLDATACOPY || op == vm.CODECOPY || op == vm.RETURNDATACOPY) && len(stack) >= 3:
+		frame.setMem(back(0), back(2))
+	case op == vm.EXTCODECOPY && len(stack) >= 4:
+		frame.setMem(back(1), back(3))
+	case (op == vm.CALL || op == vm.CALLCODE) && len(stack) >= 7:
+		frame.setMem(back(5), back(6))
+	case (op == vm.DELEGATECALL || op == vm.STATICCALL) && len(stack) >= 6:
+		frame.setMem(back(4), back(5))
+	}
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *vmTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if len(t.frames) > 0 {
+		t.frames[len(t.frames)-1].pending = nil
+	}
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *vmTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	// Selfdestructs execute no code, track them only to pair their exits
+	if typ == vm.SELFDESTRUCT {
+		t.frames = append(t.frames, &vmTraceFrame{})
+		return
+	}
+	sub := t.newTrace(to, typ == vm.CREATE || typ == vm.CREATE2, input)
+	if parent := t.frames[len(t.frames)-1]; parent.pending != nil {
+		parent.pending.Sub = sub
+	}
+	t.frames = append(t.frames, &vmTraceFrame{trace: sub})
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *vmTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.interrupt.Load() || len(t.frames) < 2 {
+		return
+	}
+	t.frames[len(t.frames)-1].finish(nil)
+	t.frames = t.frames[:len(t.frames)-1]
+}
+
+// GetResult returns the json-encoded trace of the executed code, and any
+// error arising from the encoding or forceful termination (via `Stop`).
+func (t *vmTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.root)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *vmTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// newTrace creates the trace of a call frame, running either the code of the
+// callee or the init code of a contract creation.
+func (t *vmTracer) newTrace(to common.Address, create bool, input []byte) *vmTrace {
+	code := input
+	if !create {
+		code = t.env.StateDB.GetCode(to)
+	}
+	return &vmTrace{Code: common.CopyBytes(code), Ops: []*vmTraceOp{}}
+}
+
+// setMem records the memory region the pending instruction writes to.
+func (f *vmTraceFrame) setMem(off, size *uint256.Int) {
+	if off.IsUint64() && size.IsUint64() {
+		f.memOff, f.memSize = off.Uint64(), size.Uint64()
+	}
+}
+
+// finish records the effects of the pending instruction of the frame, given
+// the scope at the next instruction, or nil if the frame returned.
+func (f *vmTraceFrame) finish(scope *vm.ScopeContext) {
+	if f.pending == nil {
+		return
+	}
+	ex := &vmTraceEx{Push: []*hexutil.Big{}, Store: f.store, Used: f.gas}
+	if scope != nil {
+		stack := scope.Stack.Data()
+		for i := vmTracePushes(f.op); i > 0; i-- {
+			if i <= len(stack) {
+				ex.Push = append(ex.Push, (*hexutil.Big)(stack[len(stack)-i].ToBig()))
+			}
+		}
+		if f.memSize > 0 && f.memOff+f.memSize >= f.memOff && f.memOff+f.memSize <= uint64(scope.Memory.Len()) {
+			ex.Mem = &vmTraceMem{Off: f.memOff, Data: scope.Memory.GetCopy(int64(f.memOff), int64(f.memSize))}
+		}
+	}
+	f.pending.Ex, f.pending = ex, nil
+}
+
+// vmTracePushes returns the number of stack items an instruction leaves on top
+// of the stack, as reported in the trace. Duplications and swaps report all the
+// items they touched.
+func vmTracePushes(op vm.OpCode) int {
+	switch {
+	case op >= vm.PUSH0 && op <= vm.PUSH32:
+		return 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	}
+	switch op {
+	case vm.ADD, vm.MUL, vm.SUB, vm.DIV, vm.SDIV, vm.MOD, vm.SMOD, vm.ADDMOD, vm.MULMOD, vm.EXP, vm.SIGNEXTEND,
+		vm.LT, vm.GT, vm.SLT, vm.SGT, vm.EQ, vm.ISZERO, vm.AND, vm.OR, vm.XOR, vm.NOT, vm.BYTE, vm.SHL, vm.SHR, vm.SAR,
+		vm.KECCAK256, vm.ADDRESS, vm.BALANCE, vm.ORIGIN, vm.CALLER, vm.CALLVALUE, vm.CALLDATALOAD, vm.CALLDATASIZE,
+		vm.CODESIZE, vm.GASPRICE, vm.EXTCODESIZE, vm.RETURNDATASIZE, vm.EXTCODEHASH, vm.BLOCKHASH, vm.COINBASE,
+		vm.TIMESTAMP, vm.NUMBER, vm.DIFFICULTY, vm.GASLIMIT, vm.CHAINID, vm.SELFBALANCE, vm.BASEFEE, vm.MLOAD,
+		vm.SLOAD, vm.PC, vm.MSIZE, vm.GAS, vm.CREATE, vm.CREATE2, vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,406 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// traceThrottling is the time to wait between processing two consecutive
+	// trace store sections. It's useful during chain upgrades to prevent disk
+	// overload.
+	traceThrottling = 10 * time.Millisecond
+)
+
+// storeConfig is the tracer and configuration the stored traces were produced
+// with, persisted to detect configuration changes across restarts.
+type storeConfig struct {
+	Tracer string
+	Config []byte
+}
+
+// frozenTraces is an item of the trace freezer, recording the traces of the
+// canonical block of a height, if any were produced.
+type frozenTraces struct {
+	Hash   common.Hash
+	Traces []byte
+}
+
+// TraceStore persists the results of a native tracer for the blocks of the
+// canonical chain, so that historical traces can be served without re-executing
+// the blocks leading up to them. Recent traces are kept in the key-value store,
+// keyed by block number and hash, while the traces of immutable blocks are moved
+// into a freezer.
+type TraceStore struct {
+	db        ethdb.Database
+	freezer   *rawdb.ResettableFreezer // Store of the frozen traces, nil if the database has no ancient store
+	tracer    string                   // Name of the tracer producing the traces
+	config    json.RawMessage          // Compacted configuration of the tracer
+	tail      uint64                   // Number of recent blocks to keep the traces of, zero meaning all
+	immutable uint64                   // Number of blocks after which the traces are frozen
+
+	lock sync.RWMutex // Lock protecting the freezer offset against concurrent modifications
+}
+
+// NewTraceStore opens the store of the traces produced by the given native tracer
+// and configuration. The traces produced by a different tracer or configuration
+// are dropped. Blocks older than the tail are dropped from the store, unless it
+// is zero.
+func NewTraceStore(db ethdb.Database, tracer string, config json.RawMessage, tail uint64) (*TraceStore, error) {
+	if DefaultDirectory.IsJS(tracer) {
+		return nil, fmt.Errorf("tracer %q is not a native tracer", tracer)
+	}
+	config, err := compactConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracer config: %v", err)
+	}
+	if _, err := DefaultDirectory.New(tracer, new(Context), config); err != nil {
+		return nil, fmt.Errorf("invalid tracer config: %v", err)
+	}
+	store := &TraceStore{
+		db:        db,
+		tracer:    tracer,
+		config:    config,
+		tail:      tail,
+		immutable: params.FullImmutabilityThreshold,
+	}
+	if ancient, err := db.AncientDatadir(); err == nil && ancient != "" {
+		if store.freezer, err = rawdb.NewTraceFreezer(ancient, false); err != nil {
+			return nil, err
+		}
+	}
+	// Drop the traces of any previous tracer or configuration
+	enc, err := rlp.EncodeToBytes(&storeConfig{Tracer: tracer, Config: config})
+	if err != nil {
+		return nil, err
+	}
+	if stored := rawdb.ReadTraceStoreConfig(db); !bytes.Equal(stored, enc) {
+		if stored != nil {
+			log.Info("Dropping traces of previous tracer configuration")
+		}
+		rawdb.DeleteTraceStore(db)
+		if store.freezer != nil {
+			if err := store.freezer.Reset(); err != nil {
+				store.freezer.Close()
+				return nil, err
+			}
+		}
+		rawdb.WriteTraceStoreConfig(db, enc)
+	}
+	return store, nil
+}
+
+// Close releases the resources held by the store.
+func (s *TraceStore) Close() error {
+	if s.freezer != nil {
+		return s.freezer.Close()
+	}
+	return nil
+}
+
+// compactConfig returns the canonical form of a tracer configuration, the empty
+// configurations being normalised to nil.
+func compactConfig(config json.RawMessage) (json.RawMessage, error) {
+	if len(config) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, config); err != nil {
+		return nil, err
+	}
+	if compact := buf.String(); compact == "null" || compact == "{}" {
+		return nil, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// traceConfig returns the configuration to trace the blocks of the store with.
+func (s *TraceStore) traceConfig() *TraceConfig {
+	tracer := s.tracer
+	return &TraceConfig{Tracer: &tracer, TracerConfig: s.config}
+}
+
+// matches reports whether the traces of the store were produced by the tracer
+// and configuration requested.
+func (s *TraceStore) matches(config *TraceConfig) bool {
+	if s == nil || config == nil || config.Tracer == nil || *config.Tracer != s.tracer {
+		return false
+	}
+	compact, err := compactConfig(config.TracerConfig)
+	return err == nil && bytes.Equal(compact, s.config)
+}
+
+// BlockTraces returns the stored traces of the transactions of a block, or nil
+// if the block was not traced.
+func (s *TraceStore) BlockTraces(number uint64, hash common.Hash) []json.RawMessage {
+	blob := rawdb.ReadBlockTraces(s.db, number, hash)
+	if blob == nil {
+		blob = s.frozenTraces(number, hash)
+	}
+	if blob == nil {
+		return nil
+	}
+	var traces []json.RawMessage
+	if err := json.Unmarshal(blob, &traces); err != nil {
+		log.Error("Invalid stored block traces", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	if traces == nil {
+		traces = []json.RawMessage{}
+	}
+	return traces
+}
+
+// frozenTraces retrieves the traces of a block from the freezer, or nil if they
+// were not frozen.
+func (s *TraceStore) frozenTraces(number uint64, hash common.Hash) []byte {
+	if s.freezer == nil {
+		return nil
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	offset := rawdb.ReadTraceFreezerOffset(s.db)
+	if offset == nil || number < *offset {
+		return nil
+	}
+	blob, err := s.freezer.Ancient(rawdb.TraceFreezerTable, number-*offset)
+	if err != nil {
+		return nil
+	}
+	var item frozenTraces
+	if err := rlp.DecodeBytes(blob, &item); err != nil {
+		log.Error("Invalid frozen block traces", "number", number, "err", err)
+		return nil
+	}
+	if item.Hash != hash || len(item.Traces) == 0 {
+		return nil
+	}
+	return item.Traces
+}
+
+// truncate drops the traces of the blocks which fell below the tail of a chain
+// with the given head, and moves the traces of its immutable blocks into the
+// freezer.
+func (s *TraceStore) truncate(head uint64) error {
+	var (
+		batch = s.db.NewBatch()
+		tail  uint64
+	)
+	if stored := rawdb.ReadTraceStoreTail(s.db); stored != nil {
+		tail = *stored
+	}
+	if s.tail > 0 && head > s.tail && head-s.tail > tail {
+		limit := head - s.tail
+		for _, block := range rawdb.ReadBlockTracesHashesInRange(s.db, tail, limit-1) {
+			rawdb.DeleteBlockTraces(batch, block.Number, block.Hash)
+		}
+		rawdb.WriteTraceStoreTail(batch, limit)
+		tail = limit
+	}
+	if s.freezer != nil {
+		var limit uint64
+		if head > s.immutable {
+			limit = head - s.immutable
+		}
+		if err := s.freeze(batch, tail, limit); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// freeze moves the traces of the canonical blocks below the limit into the
+// freezer, dropping the traces of the reorged ones. Frozen traces below the
+// tail are dropped from the freezer.
+func (s *TraceStore) freeze(batch ethdb.Batch, tail, limit uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	items, err := s.freezer.Ancients()
+	if err != nil {
+		return err
+	}
+	hidden, err := s.freezer.Tail()
+	if err != nil {
+		return err
+	}
+	var offset uint64
+	if stored := rawdb.ReadTraceFreezerOffset(s.db); stored != nil {
+		offset = *stored
+	}
+	// Restart the freezer at the tail if none of its traces is retained anymore,
+	// otherwise hide the traces below the tail
+	if offset+items <= tail {
+		if items > 0 {
+			if err := s.freezer.Reset(); err != nil {
+				return err
+			}
+		}
+		offset, items, hidden = tail, 0, 0
+		rawdb.WriteTraceFreezerOffset(s.db, offset)
+	} else if tail > offset+hidden {
+		if err := s.freezer.TruncateTail(tail - offset); err != nil {
+			return err
+		}
+	}
+	if offset+items >= limit {
+		return nil
+	}
+	from := offset + items
+	_, err = s.freezer.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for number := from; number < limit; number++ {
+			item := frozenTraces{Hash: rawdb.ReadCanonicalHash(s.db, number)}
+			if number >= tail {
+				item.Traces = rawdb.ReadBlockTraces(s.db, number, item.Hash)
+			}
+			if err := op.Append(rawdb.TraceFreezerTable, number-offset, &item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.freezer.Sync(); err != nil {
+		return err
+	}
+	for _, block := range rawdb.ReadBlockTracesHashesInRange(s.db, from, limit-1) {
+		rawdb.DeleteBlockTraces(batch, block.Number, block.Hash)
+	}
+	return nil
+}
+
+// traceIndexer implements a core.ChainIndexerBackend, tracing the blocks of the
+// canonical chain into a trace store.
+type traceIndexer struct {
+	store *TraceStore // Store to write the traces into
+	api   *API        // API tracing the blocks, bypassing the store
+	size  uint64      // Section size to trace the blocks of
+
+	section uint64      // Section is the section number being processed currently
+	skip    bool        // Whether the section is below the tail and left untraced
+	missed  int         // Number of blocks of the section which failed to be traced
+	batch   ethdb.Batch // Batch collecting the traces of the section
+}
+
+// NewTraceIndexer returns a chain indexer that traces the blocks of the canonical
+// chain with the tracer of the given store, writing the results into the store.
+func NewTraceIndexer(backend Backend, store *TraceStore, size, confirms uint64) *core.ChainIndexer {
+	indexer := &traceIndexer{
+		store: store,
+		api:   &API{backend: backend},
+		size:  size,
+	}
+	table := rawdb.NewTable(store.db, string(rawdb.TraceIndexPrefix))
+
+	return core.NewChainIndexer(store.db, table, indexer, size, confirms, traceThrottling, "traces")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new trace store section.
+// Sections entirely below the tail of the current chain are skipped.
+func (b *traceIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.section, b.skip, b.missed = section, false, 0
+	b.batch = b.store.db.NewBatch()
+
+	if b.store.tail > 0 {
+		if head := rawdb.ReadHeaderNumber(b.store.db, rawdb.ReadHeadHeaderHash(b.store.db)); head != nil {
+			b.skip = (section+1)*b.size+b.store.tail <= *head
+		}
+	}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, tracing the block of the header
+// and dropping the traces of the blocks it replaced.
+func (b *traceIndexer) Process(ctx context.Context, header *types.Header) error {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+	)
+	// Drop the traces of the blocks replaced by a reorg, if any
+	for _, stale := range rawdb.ReadBlockTracesHashes(b.store.db, number) {
+		if stale != hash {
+			rawdb.DeleteBlockTraces(b.batch, number, stale)
+		}
+	}
+	if b.skip || number == 0 {
+		return nil
+	}
+	block, err := b.api.backend.BlockByHash(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return fmt.Errorf("block #%d [%x..] not found", number, hash[:4])
+	}
+	traces := make([]interface{}, 0, len(block.Transactions()))
+	if len(block.Transactions()) > 0 {
+		results, err := b.api.traceBlock(ctx, block, b.store.traceConfig())
+		if err != nil {
+			log.Debug("Failed to trace block", "number", number, "hash", hash, "err", err)
+			b.missed++
+			return nil
+		}
+		for _, result := range results {
+			// Only the results are stored, leave the failed traces to be
+			// reproduced along with their errors
+			if result.Error != "" {
+				log.Debug("Failed to trace transaction", "number", number, "hash", hash, "err", result.Error)
+				b.missed++
+				return nil
+			}
+			traces = append(traces, result.Result)
+		}
+	}
+	blob, err := json.Marshal(traces)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteBlockTraces(b.batch, number, hash, blob)
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the traces of the
+// section, then dropping the ones which fell below the tail and freezing the
+// ones of the blocks which became immutable.
+func (b *traceIndexer) Commit() error {
+	if b.missed > 0 {
+		log.Warn("Failed to trace blocks of section", "section", b.section, "blocks", b.missed)
+	}
+	if err := b.batch.Write(); err != nil {
+		return err
+	}
+	return b.store.truncate((b.section + 1) * b.size)
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *traceIndexer) Prune(threshold uint64) error {
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package tracers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// storeBackend is a tracing backend serving the traces of a trace store.
+type storeBackend struct {
+	tracers.Backend
+	store *tracers.TraceStore
+}
+
+func (b *storeBackend) TraceStore() *tracers.TraceStore { return b.store }
+
+// failingTracer is a call tracer failing to produce any result.
+type failingTracer struct {
+	tracers.Tracer
+}
+
+func (t *failingTracer) GetResult() (json.RawMessage, error) {
+	return nil, errors.New("tracer failure")
+}
+
+func init() {
+	tracers.DefaultDirectory.Register("failingTracer", func(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+		tracer, err := tracers.DefaultDirectory.New("callTracer", ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &failingTracer{tracer}, nil
+	}, false)
+}
+
+// Tests that the traces of the canonical blocks are stored, frozen and dropped
+// below the tail, and served in place of re-executing the blocks.
+func TestTraceStore(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fixture = tracers.NewTransferFixture(big.NewInt(100))
+		signer  = types.HomesteadSigner{}
+	)
+	backend := tracers.NewTestFreezerBackend(t, 24, fixture.Genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), fixture.Contract, big.NewInt(5), 100000, b.BaseFee(), nil), signer, fixture.Key)
+		b.AddTx(tx)
+	})
+	var (
+		ctx    = context.Background()
+		db     = backend.ChainDb()
+		tracer = "callTracer"
+		config = &tracers.TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"onlyTopCall":false}`)}
+		plain  = tracers.NewAPI(backend)
+	)
+	block := func(number uint64) *types.Block {
+		t.Helper()
+		block, err := backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil || block == nil {
+			t.Fatalf("block %d not found: %v", number, err)
+		}
+		return block
+	}
+	encode := func(res interface{}) []byte {
+		t.Helper()
+		blob, err := json.Marshal(res)
+		if err != nil {
+			t.Fatalf("failed to encode trace: %v", err)
+		}
+		return blob
+	}
+	// Store the traces of the first 24 blocks, freezing the ones older than 8 blocks
+	store, err := tracers.NewTestTraceStore(db, tracer, json.RawMessage(`{ "onlyTopCall": false }`), 0, 8)
+	if err != nil {
+		t.Fatalf("failed to open trace store: %v", err)
+	}
+	if err := tracers.IndexTraces(backend, store, 4, 0, 6); err != nil {
+		t.Fatalf("failed to index traces: %v", err)
+	}
+	api := tracers.NewAPI(&storeBackend{backend, store})
+	for number := uint64(1); number <= 24; number++ {
+		b := block(number)
+		stored := store.BlockTraces(number, b.Hash())
+		switch {
+		case number == 24:
+			if stored != nil {
+				t.Fatalf("block %d: unindexed block has traces", number)
+			}
+		case number < 16:
+			if rawdb.ReadBlockTraces(db, number, b.Hash()) != nil || stored == nil {
+				t.Fatalf("block %d: traces not frozen", number)
+			}
+		default:
+			if rawdb.ReadBlockTraces(db, number, b.Hash()) == nil {
+				t.Fatalf("block %d: traces not stored", number)
+			}
+		}
+		for i, tx := range b.Transactions() {
+			have, err := api.TraceTransaction(ctx, tx.Hash(), config)
+			if err != nil {
+				t.Fatalf("block %d: failed to trace transaction: %v", number, err)
+			}
+			want, err := plain.TraceTransaction(ctx, tx.Hash(), config)
+			if err != nil {
+				t.Fatalf("block %d: failed to trace transaction: %v", number, err)
+			}
+			if !bytes.Equal(encode(have), encode(want)) {
+				t.Fatalf("block %d: trace mismatch: have %s, want %s", number, encode(have), encode(want))
+			}
+			if stored != nil && !bytes.Equal(stored[i], encode(want)) {
+				t.Fatalf("block %d: stored trace mismatch: have %s, want %s", number, stored[i], encode(want))
+			}
+		}
+	}
+	// Ensure the traces are served from the store, unless the config differs
+	b := block(20)
+	rawdb.WriteBlockTraces(db, 20, b.Hash(), []byte(`[{"stored":true}]`))
+
+	res, err := api.TraceBlockByNumber(ctx, 20, config)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if have := string(encode(res)); have != `[{"result":{"stored":true}}]` {
+		t.Fatalf("block traces not served from the store: %s", have)
+	}
+	other := &tracers.TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"onlyTopCall":true}`)}
+	have, err := api.TraceTransaction(ctx, b.Transactions()[0].Hash(), other)
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	want, _ := plain.TraceTransaction(ctx, b.Transactions()[0].Hash(), other)
+	if !bytes.Equal(encode(have), encode(want)) {
+		t.Fatalf("trace of different config served from the store: %s", encode(have))
+	}
+	// Ensure the traces of reorged blocks are dropped and never served
+	stale := common.HexToHash("0xdeadbeef")
+	rawdb.WriteBlockTraces(db, 21, stale, []byte(`[]`))
+	if store.BlockTraces(21, stale) == nil {
+		t.Fatalf("stale traces not stored")
+	}
+	if store.BlockTraces(21, b.Hash()) != nil {
+		t.Fatalf("traces served for mismatching block")
+	}
+	if err := tracers.IndexTraces(backend, store, 4, 5, 1); err != nil {
+		t.Fatalf("failed to index traces: %v", err)
+	}
+	if hashes := rawdb.ReadBlockTracesHashes(db, 21); len(hashes) != 1 || hashes[0] != block(21).Hash() {
+		t.Fatalf("stale traces not dropped: %v", hashes)
+	}
+	store.Close()
+
+	// Reopen the store with a tail of 6 blocks, dropping the frozen traces
+	store, err = tracers.NewTestTraceStore(db, tracer, config.TracerConfig, 6, 8)
+	if err != nil {
+		t.Fatalf("failed to open trace store: %v", err)
+	}
+	if err := tracers.IndexTraces(backend, store, 4, 5, 1); err != nil {
+		t.Fatalf("failed to index traces: %v", err)
+	}
+	for number := uint64(1); number < 24; number++ {
+		if stored := store.BlockTraces(number, block(number).Hash()); (stored != nil) != (number >= 18) {
+			t.Fatalf("block %d: traces stored %v, want %v", number, stored != nil, number >= 18)
+		}
+	}
+	store.Close()
+
+	// Reopen the store with another tracer, dropping all the traces
+	tracer = "flatCallTracer"
+	store, err = tracers.NewTestTraceStore(db, tracer, nil, 6, 8)
+	if err != nil {
+		t.Fatalf("failed to open trace store: %v", err)
+	}
+	defer store.Close()
+
+	if stored := store.BlockTraces(20, block(20).Hash()); stored != nil {
+		t.Fatalf("traces of previous tracer not dropped")
+	}
+}
+
+// Tests that the blocks whose traces failed are not stored, so that the errors
+// are reproduced instead of being served as empty results.
+func TestTraceStoreFailures(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fixture = tracers.NewTransferFixture(big.NewInt(100))
+		signer  = types.HomesteadSigner{}
+	)
+	backend := tracers.NewTestFreezerBackend(t, 4, fixture.Genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), fixture.Contract, big.NewInt(5), 100000, b.BaseFee(), nil), signer, fixture.Key)
+		b.AddTx(tx)
+	})
+	tracer := "failingTracer"
+	store, err := tracers.NewTestTraceStore(backend.ChainDb(), tracer, nil, 0, 8)
+	if err != nil {
+		t.Fatalf("failed to open trace store: %v", err)
+	}
+	defer store.Close()
+
+	if err := tracers.IndexTraces(backend, store, 2, 0, 2); err != nil {
+		t.Fatalf("failed to index traces: %v", err)
+	}
+	block, err := backend.BlockByNumber(context.Background(), 1)
+	if err != nil || block == nil {
+		t.Fatalf("block 1 not found: %v", err)
+	}
+	if stored := store.BlockTraces(1, block.Hash()); stored != nil {
+		t.Fatalf("failed traces stored: %s", stored)
+	}
+	api := tracers.NewAPI(&storeBackend{backend, store})
+	if _, err := api.TraceBlockByNumber(context.Background(), 1, &tracers.TraceConfig{Tracer: &tracer}); err == nil || err.Error() != "tracer failure" {
+		t.Fatalf("trace failure mismatch: have %v, want tracer failure", err)
+	}
+}
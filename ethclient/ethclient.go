@@ -294,6 +294,17 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// BlockReceipts returns the receipts of all the transactions of a block, by
+// block number or hash.
+func (ec *Client) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	var r []*types.Receipt
+	err := ec.c.CallContext(ctx, &r, "eth_getBlockReceipts", blockNrOrHash)
+	if err == nil && r == nil {
+		return nil, ethereum.NotFound
+	}
+	return r, err
+}
+
 // SyncProgress retrieves the current progress of the sync algorithm. If there's
 // no sync currently running, it returns nil.
 func (ec *Client) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
@@ -386,6 +397,72 @@ func (ec *Client) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]typ
 	return result, err
 }
 
+// LogIterator pages through the results of a filter query, fetching the pages
+// on demand with paginated eth_getLogs calls.
+type LogIterator struct {
+	ec    *Client
+	ctx   context.Context
+	arg   interface{}
+	limit uint64
+
+	logs   []types.Log   // Logs of the current page not yet iterated over
+	log    types.Log     // Log the iterator is positioned at
+	cursor hexutil.Bytes // Cursor to fetch the next page with
+	done   bool          // Whether the last page was fetched
+	err    error         // Any error that occurred while fetching a page
+}
+
+// FilterLogsIterator returns an iterator over the results of a filter query,
+// which are fetched in pages of at most pageSize logs. The node may cap the
+// pages further. A zero pageSize leaves the page size up to the node.
+func (ec *Client) FilterLogsIterator(ctx context.Context, q ethereum.FilterQuery, pageSize uint64) (*LogIterator, error) {
+	arg, err := toFilterArg(q)
+	if err != nil {
+		return nil, err
+	}
+	return &LogIterator{ec: ec, ctx: ctx, arg: arg, limit: pageSize}, nil
+}
+
+// Next advances the iterator to the next log, fetching the next page of the
+// query if needed. It returns false when the results are exhausted or an error
+// occurred.
+func (it *LogIterator) Next() bool {
+	for len(it.logs) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		page := map[string]interface{}{"limit": hexutil.Uint64(it.limit)}
+		if it.cursor != nil {
+			page["cursor"] = it.cursor
+		}
+		var result struct {
+			Logs   []types.Log    `json:"logs"`
+			Cursor *hexutil.Bytes `json:"cursor"`
+		}
+		if it.err = it.ec.c.CallContext(it.ctx, &result, "eth_getLogs", it.arg, page); it.err != nil {
+			return false
+		}
+		it.logs = result.Logs
+		if result.Cursor == nil {
+			it.done = true
+		} else {
+			it.cursor = *result.Cursor
+		}
+	}
+	it.log, it.logs = it.logs[0], it.logs[1:]
+	return true
+}
+
+// Log returns the log the iterator is positioned at.
+func (it *LogIterator) Log() types.Log {
+	return it.log
+}
+
+// Error returns any error that occurred while fetching the results.
+func (it *LogIterator) Error() error {
+	return it.err
+}
+
 // SubscribeFilterLogs subscribes to the results of a streaming filter query.
 func (ec *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
 	arg, err := toFilterArg(q)
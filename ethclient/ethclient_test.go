@@ -285,6 +285,9 @@ func TestEthClient(t *testing.T) {
 		"TransactionSender": {
 			func(t *testing.T) { testTransactionSender(t, client) },
 		},
+		"BlockReceipts": {
+			func(t *testing.T) { testBlockReceipts(t, chain, client) },
+		},
 	}
 
 	t.Parallel()
@@ -643,6 +646,35 @@ func testAtFunctions(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func testBlockReceipts(t *testing.T, chain []*types.Block, client *rpc.Client) {
+	ec := NewClient(client)
+
+	for _, blockNrOrHash := range []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithNumber(2),
+		rpc.BlockNumberOrHashWithHash(chain[2].Hash(), false),
+	} {
+		receipts, err := ec.BlockReceipts(context.Background(), blockNrOrHash)
+		if err != nil {
+			t.Fatalf("BlockReceipts(%v) error: %v", blockNrOrHash.String(), err)
+		}
+		if len(receipts) != 2 {
+			t.Fatalf("BlockReceipts(%v): receipt count mismatch: have %d, want 2", blockNrOrHash.String(), len(receipts))
+		}
+		for i, tx := range []*types.Transaction{testTx1, testTx2} {
+			receipt := receipts[i]
+			if receipt.TxHash != tx.Hash() || receipt.BlockHash != chain[2].Hash() || receipt.TransactionIndex != uint(i) {
+				t.Errorf("BlockReceipts(%v): receipt %d mismatch: tx %x, block %x, index %d", blockNrOrHash.String(), i, receipt.TxHash, receipt.BlockHash, receipt.TransactionIndex)
+			}
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				t.Errorf("BlockReceipts(%v): receipt %d failed", blockNrOrHash.String(), i)
+			}
+		}
+	}
+	if _, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(1000)); err != ethereum.NotFound {
+		t.Errorf("BlockReceipts(1000) error mismatch: have %v, want %v", err, ethereum.NotFound)
+	}
+}
+
 func testTransactionSender(t *testing.T, client *rpc.Client) {
 	ec := NewClient(client)
 	ctx := context.Background()
@@ -14,11 +14,15 @@ package graphql
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -35,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,7 +54,7 @@ func TestBuildSchema(t *testing.T) {
 	}
 	defer stack.Close()
 	// Make sure the schema can be parsed and matched up to the object model.
-	if _, err := newHandler(stack, nil, nil, []string{}, []string{}); err != nil {
+	if _, err := newHandler(stack, nil, nil, []string{}, []string{}, Config{}); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
@@ -63,7 +68,7 @@ func TestGraphQLBlockSerialization(t *testing.T) {
 		GasLimit:   11500000,
 		Difficulty: big.NewInt(1048576),
 	}
-	newGQLService(t, stack, genesis, 10, func(i int, gen *core.BlockGen) {})
+	newGQLService(t, stack, genesis, 10, func(i int, gen *core.BlockGen) {}, Config{})
 	// start node
 	if err := stack.Start(); err != nil {
 		t.Fatalf("could not start node: %v", err)
@@ -165,6 +170,104 @@ func TestGraphQLBlockSerialization(t *testing.T) {
 	}
 }
 
+// Tests that the depth and cost limits of queries are enforced, and that the
+// queries can be referenced by their hash.
+func TestGraphQLLimits(t *testing.T) {
+	var (
+		stack   = createNode(t)
+		genesis = &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+		}
+		persisted = `{block{number}}`
+		automatic = `{block{gasLimit}}`
+		hash      = func(query string) string {
+			hash := sha256.Sum256([]byte(query))
+			return hex.EncodeToString(hash[:])
+		}
+		file = filepath.Join(t.TempDir(), "queries.json")
+	)
+	defer stack.Close()
+
+	if err := os.WriteFile(file, []byte(`{"0x`+hash(persisted)+`":"`+persisted+`"}`), 0600); err != nil {
+		t.Fatalf("could not write persisted queries: %v", err)
+	}
+	newGQLService(t, stack, genesis, 10, func(i int, gen *core.BlockGen) {}, Config{
+		MaxDepth:         3,
+		MaxCost:          1500,
+		PersistedQueries: file,
+		AutoPersist:      16,
+	})
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	for i, tt := range []struct {
+		body string
+		want string
+		code int
+	}{
+		// Eleven blocks cost 1100 units, their transactions as much again
+		{
+			body: `{"query": "{blocks(from:0){number}}"}`,
+			want: `{"data":{"blocks":[{"number":0},{"number":1},{"number":2},{"number":3},{"number":4},{"number":5},{"number":6},{"number":7},{"number":8},{"number":9},{"number":10}]}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{blocks(from:0){number transactions{hash}}}"}`,
+			want: `{"errors":[{"message":"query cost limit exceeded (max 1500)"}]}`,
+			code: 400,
+		},
+		{
+			body: `{"query": "{block{parent{parent{number}}}}"}`,
+			want: `{"errors":[{"message":"Field \"number\" has depth 4 that exceeds max depth 3","locations":[{"line":1,"column":22}]}]}`,
+			code: 400,
+		},
+		// Persisted queries are resolved by hash
+		{
+			body: `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + hash(persisted) + `"}}}`,
+			want: `{"data":{"block":{"number":10}}}`,
+			code: 200,
+		},
+		{
+			body: `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + hash(automatic) + `"}}}`,
+			want: `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`,
+			code: 400,
+		},
+		{
+			body: `{"query":"` + automatic + `","extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + hash(persisted) + `"}}}`,
+			want: `{"errors":[{"message":"provided sha does not match query","extensions":{"code":"PERSISTED_QUERY_HASH_MISMATCH"}}]}`,
+			code: 400,
+		},
+		{
+			body: `{"query":"` + automatic + `","extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + hash(automatic) + `"}}}`,
+			want: `{"data":{"block":{"gasLimit":11500000}}}`,
+			code: 200,
+		},
+		{
+			body: `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + hash(automatic) + `"}}}`,
+			want: `{"data":{"block":{"gasLimit":11500000}}}`,
+			code: 200,
+		},
+	} {
+		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("could not post: %v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("could not read from response body: %v", err)
+		}
+		if have := string(bodyBytes); have != tt.want {
+			t.Errorf("testcase %d %s,\nhave:\n%v\nwant:\n%v", i, tt.body, have, tt.want)
+		}
+		if tt.code != resp.StatusCode {
+			t.Errorf("testcase %d %s,\nwrong statuscode, have: %v, want: %v", i, tt.body, resp.StatusCode, tt.code)
+		}
+	}
+}
+
 func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 	// Account for signing txes
 	var (
@@ -214,7 +317,7 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 			}},
 		})
 		gen.AddTx(tx)
-	})
+	}, Config{})
 	// start node
 	if err := stack.Start(); err != nil {
 		t.Fatalf("could not start node: %v", err)
@@ -230,6 +333,11 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 			want: `{"data":{"block":{"number":1,"transactions":[{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x64","hash":"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b","type":0,"accessList":[],"index":0},{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x32","hash":"0x19b35f8187b4e15fb59a9af469dca5dfa3cd363c11d372058c12f6482477b474","type":1,"accessList":[{"address":"0x0000000000000000000000000000000000000dad","storageKeys":["0x0000000000000000000000000000000000000000000000000000000000000000"]}],"index":1}]}}}`,
 			code: 200,
 		},
+		{
+			body: `{"query": "{block {receipts { transaction { hash index } status gasUsed cumulativeGasUsed createdContract { address } logs { index }}}}"}`,
+			want: `{"data":{"block":{"receipts":[{"transaction":{"hash":"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b","index":0},"status":1,"gasUsed":25204,"cumulativeGasUsed":25204,"createdContract":null,"logs":[]},{"transaction":{"hash":"0x19b35f8187b4e15fb59a9af469dca5dfa3cd363c11d372058c12f6482477b474","index":1},"status":1,"gasUsed":27504,"cumulativeGasUsed":52708,"createdContract":null,"logs":[]}]}}}`,
+			code: 200,
+		},
 	} {
 		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
 		if err != nil {
@@ -299,7 +407,7 @@ func TestGraphQLConcurrentResolvers(t *testing.T) {
 		gen.AddTx(tx)
 		tx, _ = types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Nonce: 2, Gas: 100000, GasPrice: big.NewInt(params.InitialBaseFee)})
 		gen.AddTx(tx)
-	})
+	}, Config{})
 	// start node
 	if err := stack.Start(); err != nil {
 		t.Fatalf("could not start node: %v", err)
@@ -374,7 +482,7 @@ func createNode(t *testing.T) *node.Node {
 	return stack
 }
 
-func newGQLService(t *testing.T, stack *node.Node, gspec *core.Genesis, genBlocks int, genfunc func(i int, gen *core.BlockGen)) (*handler, []*types.Block) {
+func newGQLService(t *testing.T, stack *node.Node, gspec *core.Genesis, genBlocks int, genfunc func(i int, gen *core.BlockGen), config Config) (*handler, []*types.Block) {
 	ethConf := &ethconfig.Config{
 		Genesis: gspec,
 		Ethash: ethash.Config{
@@ -401,9 +509,139 @@ func newGQLService(t *testing.T, stack *node.Node, gspec *core.Genesis, genBlock
 	}
 	// Set up handler
 	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
-	handler, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{})
+	handler, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{}, config)
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
 	return handler, chain
 }
+
+func TestGraphQLSubscriptions(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		dad     = common.HexToAddress("0x0000000000000000000000000000000000000dad")
+		genesis = &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc: core.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				dad: {
+					// LOG0(0, 0), RETURN(0, 0)
+					Code:    common.Hex2Bytes("60006000a060006000f3"),
+					Balance: big.NewInt(0),
+				},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		stack  = createNode(t)
+	)
+	defer stack.Close()
+
+	ethBackend, err := eth.New(stack, &ethconfig.Config{
+		Genesis:        genesis,
+		Ethash:         ethash.Config{PowMode: ethash.ModeFake},
+		NetworkId:      1337,
+		TrieCleanCache: 5,
+		TrieDirtyCache: 5,
+		TrieTimeout:    60 * time.Minute,
+		SnapshotCache:  5,
+	})
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
+	if _, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{}, Config{}); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	// Connect to the endpoint and subscribe to blocks and logs
+	url := "ws" + strings.TrimPrefix(stack.HTTPEndpoint(), "http") + "/graphql"
+	dialer := websocket.Dialer{Subprotocols: []string{protocolTransportWS}}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("could not dial websocket: %v", err)
+	}
+	defer conn.Close()
+
+	messages := []string{
+		`{"type":"connection_init"}`,
+		`{"id":"1","type":"subscribe","payload":{"query":"subscription { newBlocks { number } }"}}`,
+		`{"id":"2","type":"subscribe","payload":{"query":"subscription { logs(filter: {addresses: [\"` + dad.Hex() + `\"]}) { account { address } transaction { from { address } } } }"}}`,
+		`{"id":"3","type":"subscribe","payload":{"query":"query Chain { chainID }"}}`,
+	}
+	for _, msg := range messages {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("could not send message: %v", err)
+		}
+	}
+	// Import blocks calling the logging contract until the subscriptions deliver
+	chain, _ := core.GenerateChain(genesis.Config, ethBackend.BlockChain().Genesis(), ethash.NewFaker(), ethBackend.ChainDb(), 20, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Gas: 100000, GasPrice: big.NewInt(params.InitialBaseFee), Nonce: uint64(i)})
+		gen.AddTx(tx)
+	})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for _, block := range chain {
+			select {
+			case <-time.After(100 * time.Millisecond):
+				ethBackend.BlockChain().InsertChain(types.Blocks{block})
+			case <-done:
+				return
+			}
+		}
+	}()
+	want := map[string]string{
+		"1": `{"data":{"newBlocks":{"number":`,
+		"2": `{"data":{"logs":{"account":{"address":"` + strings.ToLower(dad.Hex()) + `"},"transaction":{"from":{"address":"` + strings.ToLower(addr.Hex()) + `"}}}}}`,
+		"3": `{"data":{"chainID":"0x539"}}`,
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for len(want) > 0 {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("could not read message, missing %v: %v", want, err)
+		}
+		switch msg.Type {
+		case "connection_ack", "complete":
+		case "next":
+			if prefix, ok := want[msg.ID]; ok {
+				if !strings.HasPrefix(string(msg.Payload), prefix) {
+					t.Fatalf("operation %s: payload mismatch: have %s, want %s", msg.ID, msg.Payload, prefix)
+				}
+				delete(want, msg.ID)
+			}
+		default:
+			t.Fatalf("unexpected message: %s %s", msg.Type, msg.Payload)
+		}
+	}
+}
+
+func TestOperationType(t *testing.T) {
+	tests := []struct {
+		document string
+		name     string
+		want     string
+	}{
+		{`{ block { number } }`, "", "query"},
+		{`query { block { number } }`, "", "query"},
+		{`subscription { newBlocks { number } }`, "", "subscription"},
+		{`# subscription
+		  mutation Send($data: Bytes!) { sendRawTransaction(data: $data) }`, "", "mutation"},
+		{`subscription Logs($filter: BlockFilterCriteria! = {topics: [["}"]]}) @dir { logs(filter: $filter) { index } }`, "", "subscription"},
+		{`fragment Num on Block { number } subscription { newBlocks { ...Num } }`, "", "subscription"},
+		{`query A { chainID } subscription B { newBlocks { number } }`, "B", "subscription"},
+		{`query A { chainID } subscription B { newBlocks { number } }`, "A", "query"},
+		{`query A { chainID } subscription B { newBlocks { number } }`, "", ""},
+		{`query A { chainID }`, "B", ""},
+	}
+	for i, tt := range tests {
+		if have := operationType(tt.document, tt.name); have != tt.want {
+			t.Errorf("test %d: operation type mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	gqlErrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/graph-gophers/graphql-go/trace"
+)
+
+var errCostExceeded = errors.New("query cost limit exceeded")
+
+// fieldCosts are the costs of resolving the fields which read the state, the
+// chain database or the transaction pool, or which execute EVM code, by type
+// and field name. All other fields cost a single unit.
+var fieldCosts = map[string]int{
+	"Query.block":                100,
+	"Query.blocks":               1, // Charged per block by the resolver
+	"Query.transaction":          100,
+	"Query.logs":                 1000,
+	"Query.gasPrice":             100,
+	"Query.maxPriorityFeePerGas": 100,
+
+	"Block.parent":        100,
+	"Block.ommers":        100,
+	"Block.ommerAt":       100,
+	"Block.transactions":  100,
+	"Block.transactionAt": 100,
+	"Block.receipts":      100,
+	"Block.logs":          1000,
+	"Block.call":          1000,
+	"Block.estimateGas":   10000,
+	"Block.raw":           100,
+
+	"Pending.transactionCount": 100,
+	"Pending.transactions":     1000,
+	"Pending.call":             1000,
+	"Pending.estimateGas":      10000,
+
+	"Account.balance":          100,
+	"Account.transactionCount": 100,
+	"Account.code":             100,
+	"Account.storage":          100,
+
+	"Transaction.block":             100,
+	"Transaction.status":            100,
+	"Transaction.gasUsed":           100,
+	"Transaction.cumulativeGasUsed": 100,
+	"Transaction.effectiveGasPrice": 100,
+	"Transaction.createdContract":   100,
+	"Transaction.logs":              100,
+	"Transaction.rawReceipt":        100,
+}
+
+// blockCost is the cost of each block of a block range.
+const blockCost = 100
+
+// costMeter tracks the cost of the fields resolved for a request, cancelling
+// the request once its limit is exceeded.
+type costMeter struct {
+	limit  int64
+	used   atomic.Int64
+	cancel context.CancelFunc
+}
+
+type costMeterKey struct{}
+
+// newCostMeter returns a context carrying a meter limiting the cost of the
+// request to the given number of units, zero meaning unlimited. The context is
+// cancelled when the limit is exceeded, or when the meter is stopped.
+func newCostMeter(ctx context.Context, limit int) (context.Context, *costMeter) {
+	m := &costMeter{limit: int64(limit)}
+	ctx, m.cancel = context.WithCancel(ctx)
+	return context.WithValue(ctx, costMeterKey{}, m), m
+}
+
+// exceeded reports whether the request exceeded its cost limit.
+func (m *costMeter) exceeded() bool {
+	return m.limit > 0 && m.used.Load() > m.limit
+}
+
+// stop releases the resources of the meter.
+func (m *costMeter) stop() {
+	m.cancel()
+}
+
+// chargeCost adds the given cost to the meter of the request, returning an
+// error if the request exceeded its limit.
+func chargeCost(ctx context.Context, cost int) error {
+	m, _ := ctx.Value(costMeterKey{}).(*costMeter)
+	if m == nil || m.limit == 0 {
+		return nil
+	}
+	if m.used.Add(int64(cost)) > m.limit {
+		m.cancel()
+		return errCostExceeded
+	}
+	return nil
+}
+
+// costTracer charges the cost of every field resolved for a request to its
+// meter. Resolvers of fields cancelled by an exceeded limit are not called.
+type costTracer struct{}
+
+func (costTracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, trace.TraceQueryFinishFunc) {
+	return ctx, func([]*gqlErrors.QueryError) {}
+}
+
+func (costTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	cost, ok := fieldCosts[typeName+"."+fieldName]
+	if !ok {
+		cost = 1
+	}
+	chargeCost(ctx, cost)
+	return ctx, func(*gqlErrors.QueryError) {}
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	gqlErrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// persistedQuery is the request extension referencing a query by the hex
+// encoded SHA-256 hash of its text, as defined by Apollo's automatic persisted
+// queries.
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// persistedQueries resolves the queries referenced by hash, either loaded from
+// a file on startup, or persisted automatically when clients first send them.
+type persistedQueries struct {
+	static map[string]string
+	auto   *lru.Cache[string, string] // Nil if automatic persistence is disabled
+}
+
+// newPersistedQueries creates a store of persisted queries. The queries of the
+// file, if any, are a JSON object mapping the hashes to the queries. Up to the
+// given number of queries are persisted automatically.
+func newPersistedQueries(file string, auto int) (*persistedQueries, error) {
+	p := &persistedQueries{static: make(map[string]string)}
+	if file != "" {
+		blob, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var queries map[string]string
+		if err := json.Unmarshal(blob, &queries); err != nil {
+			return nil, fmt.Errorf("invalid persisted queries: %v", err)
+		}
+		for hash, query := range queries {
+			hash = strings.TrimPrefix(strings.ToLower(hash), "0x")
+			if hash != queryHash(query) {
+				return nil, fmt.Errorf("persisted query %s: hash mismatch", hash)
+			}
+			p.static[hash] = query
+		}
+	}
+	if auto > 0 {
+		p.auto = lru.NewCache[string, string](auto)
+	}
+	return p, nil
+}
+
+// queryHash returns the hex encoded SHA-256 hash of a query.
+func queryHash(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
+
+// resolve fills in the query of a request referencing a persisted one, and
+// drops the reference. Queries sent along with their hash are persisted if
+// automatic persistence is enabled.
+func (p *persistedQueries) resolve(req *request) *gqlErrors.QueryError {
+	ext := req.Extensions.PersistedQuery
+	if ext == nil {
+		return nil
+	}
+	req.Extensions.PersistedQuery = nil
+
+	if ext.Version != 1 {
+		return &gqlErrors.QueryError{Message: fmt.Sprintf("unsupported persisted query version %d", ext.Version)}
+	}
+	hash := strings.ToLower(ext.Sha256Hash)
+	if req.Query != "" {
+		if queryHash(req.Query) != hash {
+			return &gqlErrors.QueryError{Message: "provided sha does not match query", Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_HASH_MISMATCH"}}
+		}
+		if p.auto != nil {
+			if _, ok := p.static[hash]; !ok {
+				p.auto.Add(hash, req.Query)
+			}
+		}
+		return nil
+	}
+	if query, ok := p.static[hash]; ok {
+		req.Query = query
+		return nil
+	}
+	if p.auto != nil {
+		if query, ok := p.auto.Get(hash); ok {
+			req.Query = query
+			return nil
+		}
+	}
+	return &gqlErrors.QueryError{Message: "PersistedQueryNotFound", Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_NOT_FOUND"}}
+}
@@ -12,7 +12,8 @@
 
 package graphql
 
-const schema string = `
+// schemaTypes contains the types shared by the query and subscription schemas.
+const schemaTypes string = `
     # Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
     scalar Bytes32
     # Address is a 20 byte Ethereum address, represented as 0x-prefixed hexadecimal.
@@ -27,11 +28,6 @@ const schema string = `
     # Long is a 64 bit unsigned integer.
     scalar Long
 
-    schema {
-        query: Query
-        mutation: Mutation
-    }
-
     # Account is an Ethereum account at a particular block.
     type Account {
         # Address is the address owning the account.
@@ -63,6 +59,9 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Removed is true if the log was reverted due to a chain reorganisation.
+        # It is only ever set on logs delivered by subscriptions.
+        removed: Boolean!
     }
 
     #EIP-2718
@@ -145,6 +144,36 @@ const schema string = `
         rawReceipt: Bytes!
     }
 
+    # Receipt is the outcome of the execution of a transaction included in a block.
+    type Receipt {
+        # Transaction is the transaction the receipt belongs to.
+        transaction: Transaction!
+        # Status is the return status of the transaction. This will be 1 if the
+        # transaction succeeded, or 0 if it failed. It is null for receipts of
+        # blocks preceding Byzantium, which carry the post state root instead.
+        status: Long
+        # Root is the post state root of the transaction, for receipts of blocks
+        # preceding Byzantium.
+        root: Bytes32
+        # GasUsed is the amount of gas that was used processing the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # the transaction.
+        cumulativeGasUsed: Long!
+        # EffectiveGasPrice is actual value per gas deducted from the sender's
+        # account.
+        effectiveGasPrice: BigInt
+        # CreatedContract is the account that was created by a contract creation
+        # transaction, null otherwise.
+        createdContract(block: Long): Account
+        # Logs is the list of log entries emitted by the transaction.
+        logs: [Log!]!
+        # LogsBloom is the bloom filter of the log entries of the transaction.
+        logsBloom: Bytes!
+        # Raw is the canonical encoding of the receipt.
+        raw: Bytes!
+    }
+
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
     # to a single block.
     input BlockFilterCriteria {
@@ -229,6 +258,9 @@ const schema string = `
         # transactions are unavailable for this block, or if the index is out of
         # bounds, this field will be null.
         transactionAt(index: Int!): Transaction
+        # Receipts is the list of receipts of the transactions of this block. If
+        # transactions are unavailable for this block, this field will be null.
+        receipts: [Receipt!]
         # Logs returns a filtered set of logs from this block.
         logs(filter: BlockFilterCriteria!): [Log!]!
         # Account fetches an Ethereum account at the current block's state.
@@ -324,6 +356,14 @@ const schema string = `
       # successful execution of a transaction for the pending state.
       estimateGas(data: CallData!): Long!
     }
+`
+
+// schema is the schema of the queries and mutations served over HTTP.
+const schema string = schemaTypes + `
+    schema {
+        query: Query
+        mutation: Mutation
+    }
 
     type Query {
         # Block fetches an Ethereum block by number or by hash. If neither is
@@ -355,3 +395,29 @@ const schema string = `
         sendRawTransaction(data: Bytes!): Bytes32!
     }
 `
+
+// subscriptionSchema is the schema of the subscriptions served over WebSocket.
+// GraphQL mandates a query root, but queries sent over WebSocket are executed
+// against the main schema, so the one defined here is only a stub.
+const subscriptionSchema string = schemaTypes + `
+    schema {
+        query: SubscriptionQuery
+        subscription: Subscription
+    }
+
+    type SubscriptionQuery {
+        # ChainID returns the current chain ID for transaction replay protection.
+        chainID: BigInt!
+    }
+
+    type Subscription {
+        # NewBlocks emits every block added to the canonical chain, including
+        # the blocks of the new chain after a reorganisation.
+        newBlocks: Block!
+        # Logs emits the log entries matching the provided filter as they are
+        # added to the canonical chain, or removed from it by a reorganisation.
+        logs(filter: BlockFilterCriteria!): Log!
+        # PendingTransactions emits the transactions entering the pending state.
+        pendingTransactions: Transaction!
+    }
+`
@@ -15,6 +15,7 @@ package graphql
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
@@ -28,16 +29,52 @@ import (
 	gqlErrors "github.com/graph-gophers/graphql-go/errors"
 )
 
+// Config contains the limits of the GraphQL service, and its persisted queries.
+type Config struct {
+	// MaxDepth is the maximum nesting depth of the fields of a query.
+	MaxDepth int
+
+	// MaxCost is the maximum total cost of the fields resolved for a query.
+	MaxCost int
+
+	// Timeout is the maximum execution time of a query.
+	Timeout time.Duration
+
+	// PersistedQueries is the JSON file of the queries clients may reference by
+	// their SHA-256 hash.
+	PersistedQueries string
+
+	// AutoPersist is the number of queries persisted automatically when clients
+	// send them along with their hash.
+	AutoPersist int
+}
+
+// request is a GraphQL request, as sent over HTTP or WebSocket.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    struct {
+		PersistedQuery *persistedQuery `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
 type handler struct {
-	Schema *graphql.Schema
+	Schema        *graphql.Schema
+	Subscriptions *graphql.Schema
+
+	cors      []string      // Origins allowed to open WebSocket connections
+	maxCost   int           // Maximum cost of a query, zero if unlimited
+	timeout   time.Duration // Maximum execution time of a query, zero if unlimited
+	persisted *persistedQueries
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	var params struct {
-		Query         string                 `json:"query"`
-		OperationName string                 `json:"operationName"`
-		Variables     map[string]interface{} `json:"variables"`
+	if isWebsocket(r) {
+		h.serveWebSocket(w, r)
+		return
 	}
+	var params request
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -49,7 +86,11 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		timer     *time.Timer
 		cancel    context.CancelFunc
 	)
-	ctx, cancel = context.WithCancel(ctx)
+	if h.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
 	defer cancel()
 
 	if timeout, ok := rpc.ContextRequestTimeout(ctx); ok {
@@ -83,7 +124,7 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	response := h.Schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+	response := h.execute(ctx, &params)
 	timer.Stop()
 	responded.Do(func() {
 		responseJSON, err := json.Marshal(response)
@@ -99,22 +140,60 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// execute runs a query or mutation, resolving it first if it is persisted, and
+// aborting it if it exceeds the cost limit.
+func (h handler) execute(ctx context.Context, req *request) *graphql.Response {
+	if err := h.persisted.resolve(req); err != nil {
+		return &graphql.Response{Errors: []*gqlErrors.QueryError{err}}
+	}
+	ctx, meter := newCostMeter(ctx, h.maxCost)
+	defer meter.stop()
+
+	response := h.Schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	if meter.exceeded() {
+		return &graphql.Response{
+			Errors: []*gqlErrors.QueryError{{Message: fmt.Sprintf("%v (max %d)", errCostExceeded, h.maxCost)}},
+		}
+	}
+	return response
+}
+
 // New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cors, vhosts []string) error {
-	_, err := newHandler(stack, backend, filterSystem, cors, vhosts)
+func New(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cors, vhosts []string, config Config) error {
+	_, err := newHandler(stack, backend, filterSystem, cors, vhosts, config)
 	return err
 }
 
-// newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cors, vhosts []string) (*handler, error) {
+// newHandler returns a new `http.Handler` that will answer GraphQL queries,
+// and serve subscriptions to WebSocket clients. It additionally exports an
+// interactive query browser on the / endpoint.
+func newHandler(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cors, vhosts []string, config Config) (*handler, error) {
 	q := Resolver{backend, filterSystem}
 
-	s, err := graphql.ParseSchema(schema, &q)
+	opts := []graphql.SchemaOpt{graphql.Tracer(costTracer{})}
+	if config.MaxDepth > 0 {
+		opts = append(opts, graphql.MaxDepth(config.MaxDepth))
+	}
+	s, err := graphql.ParseSchema(schema, &q, opts...)
+	if err != nil {
+		return nil, err
+	}
+	subs, err := graphql.ParseSchema(subscriptionSchema, &SubscriptionResolver{r: &q}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	persisted, err := newPersistedQueries(config.PersistedQueries, config.AutoPersist)
 	if err != nil {
 		return nil, err
 	}
-	h := handler{Schema: s}
+	h := handler{
+		Schema:        s,
+		Subscriptions: subs,
+		cors:          cors,
+		maxCost:       config.MaxCost,
+		timeout:       config.Timeout,
+		persisted:     persisted,
+	}
 	handler := node.NewHTTPHandlerStack(h, cors, vhosts, nil)
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
@@ -0,0 +1,173 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errSubscriptionsUnavailable = errors.New("subscriptions are not available")
+
+// SubscriptionResolver is the root resolver of the subscription schema. Its
+// subscriptions are fed by an event system, which is only created once the
+// first client subscribes.
+type SubscriptionResolver struct {
+	r *Resolver
+
+	once   sync.Once
+	events *filters.EventSystem
+}
+
+// eventSystem returns the event system feeding the subscriptions, or nil if
+// the resolver has no filter system to create it from.
+func (s *SubscriptionResolver) eventSystem() *filters.EventSystem {
+	s.once.Do(func() {
+		if s.r.filterSystem != nil {
+			s.events = filters.NewEventSystem(s.r.filterSystem, false)
+		}
+	})
+	return s.events
+}
+
+func (s *SubscriptionResolver) ChainID(ctx context.Context) (hexutil.Big, error) {
+	return s.r.ChainID(ctx)
+}
+
+// NewBlocks streams the blocks added to the canonical chain until the context
+// is cancelled.
+func (s *SubscriptionResolver) NewBlocks(ctx context.Context) (<-chan *Block, error) {
+	events := s.eventSystem()
+	if events == nil {
+		return nil, errSubscriptionsUnavailable
+	}
+	var (
+		headers = make(chan *types.Header)
+		sub     = events.SubscribeNewHeads(headers)
+		blocks  = make(chan *Block)
+	)
+	go func() {
+		defer close(blocks)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case header := <-headers:
+				hash := header.Hash()
+				numberOrHash := rpc.BlockNumberOrHashWithHash(hash, false)
+				block := &Block{
+					r:            s.r,
+					numberOrHash: &numberOrHash,
+					hash:         hash,
+					header:       header,
+				}
+				select {
+				case blocks <- block:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+// Logs streams the log entries matching the filter as they are added to, or
+// removed from, the canonical chain until the context is cancelled.
+func (s *SubscriptionResolver) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	events := s.eventSystem()
+	if events == nil {
+		return nil, errSubscriptionsUnavailable
+	}
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	matches := make(chan []*types.Log)
+	sub, err := events.SubscribeLogs(crit, matches)
+	if err != nil {
+		return nil, err
+	}
+	logs := make(chan *Log)
+	go func() {
+		defer close(logs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-matches:
+				for _, log := range batch {
+					l := &Log{
+						r:           s.r,
+						transaction: &Transaction{r: s.r, hash: log.TxHash},
+						log:         log,
+					}
+					select {
+					case logs <- l:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
+
+// PendingTransactions streams the transactions entering the pending state of
+// the transaction pool until the context is cancelled.
+func (s *SubscriptionResolver) PendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	events := s.eventSystem()
+	if events == nil {
+		return nil, errSubscriptionsUnavailable
+	}
+	var (
+		pending = make(chan []*types.Transaction)
+		sub     = events.SubscribePendingTxs(pending)
+		txs     = make(chan *Transaction)
+	)
+	go func() {
+		defer close(txs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-pending:
+				for _, tx := range batch {
+					select {
+					case txs <- &Transaction{r: s.r, hash: tx.Hash(), tx: tx}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return txs, nil
+}
@@ -0,0 +1,445 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	gqlErrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// WebSocket subprotocols of the GraphQL over WebSocket transports.
+const (
+	protocolTransportWS = "graphql-transport-ws" // graphql-ws
+	protocolLegacyWS    = "graphql-ws"           // subscriptions-transport-ws, deprecated
+)
+
+const (
+	wsInitTimeout   = 10 * time.Second // Time allowed for the client to initialise the connection
+	wsWriteTimeout  = 10 * time.Second // Time allowed to write a message to the client
+	wsReadLimit     = 1024 * 1024      // Maximum size of a message sent by the client
+	wsMaxOperations = 100              // Maximum number of concurrent operations of a connection
+)
+
+// Close codes defined by the graphql-transport-ws protocol.
+const (
+	closeBadRequest       = 4400
+	closeUnauthorized     = 4401
+	closeInitTimeout      = 4408
+	closeSubscriberExists = 4409
+	closeTooManyInits     = 4429
+)
+
+var (
+	errOperationExists   = errors.New("operation already exists")
+	errTooManyOperations = errors.New("too many operations")
+)
+
+// wsMessage is a message of the GraphQL over WebSocket protocols.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsOperation is an operation running on a WebSocket connection.
+type wsOperation struct {
+	cancel context.CancelFunc
+}
+
+// wsConn is a GraphQL over WebSocket connection, executing the operations the
+// client starts on it until the connection is closed.
+type wsConn struct {
+	h      handler
+	conn   *websocket.Conn
+	legacy bool // Whether the client speaks the legacy graphql-ws protocol
+	ctx    context.Context
+
+	writeLock sync.Mutex
+
+	lock sync.Mutex
+	ops  map[string]*wsOperation
+	wg   sync.WaitGroup
+}
+
+// isWebsocket checks the header of an http request for a websocket upgrade request.
+func isWebsocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// checkOrigin reports whether WebSocket connections are accepted from the origin
+// of the request. Browsers are allowed from the origins permitted by the CORS
+// settings of the endpoint, or from the endpoint itself.
+func (h handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.cors {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	log.Warn("Rejected GraphQL WebSocket connection", "origin", origin)
+	return false
+}
+
+// serveWebSocket upgrades the request to a WebSocket connection, and serves the
+// GraphQL operations sent over it.
+func (h handler) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{protocolTransportWS, protocolLegacyWS},
+		CheckOrigin:  h.checkOrigin,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL WebSocket upgrade failed", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	c := &wsConn{
+		h:      h,
+		conn:   conn,
+		legacy: conn.Subprotocol() == protocolLegacyWS,
+		ctx:    ctx,
+		ops:    make(map[string]*wsOperation),
+	}
+	if conn.Subprotocol() == "" {
+		c.close(websocket.CloseProtocolError, "Unsupported subprotocol")
+		return
+	}
+	c.run()
+	cancel()
+	c.wg.Wait()
+}
+
+// run reads and handles the messages of the client until the connection fails
+// or is closed.
+func (c *wsConn) run() {
+	c.conn.SetReadLimit(wsReadLimit)
+	c.conn.SetReadDeadline(time.Now().Add(wsInitTimeout))
+
+	initialised := false
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() && !initialised {
+				c.close(closeInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.close(closeBadRequest, "Invalid message received")
+			return
+		}
+		// Translate the legacy message types to their current equivalents
+		if c.legacy {
+			switch msg.Type {
+			case "start":
+				msg.Type = "subscribe"
+			case "stop":
+				msg.Type = "complete"
+			case "subscribe", "complete", "ping", "pong":
+				msg.Type = ""
+			}
+		}
+		switch msg.Type {
+		case "connection_init":
+			if initialised {
+				c.close(closeTooManyInits, "Too many initialisation requests")
+				return
+			}
+			initialised = true
+			c.conn.SetReadDeadline(time.Time{})
+			c.send(&wsMessage{Type: "connection_ack"})
+
+		case "connection_terminate":
+			if !c.legacy {
+				c.close(closeBadRequest, "Invalid message received")
+			}
+			return
+
+		case "ping":
+			c.send(&wsMessage{Type: "pong", Payload: msg.Payload})
+
+		case "pong":
+			// Unsolicited pongs are allowed as unidirectional heartbeats
+
+		case "subscribe":
+			if !initialised {
+				c.close(closeUnauthorized, "Unauthorized")
+				return
+			}
+			var req request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				c.close(closeBadRequest, "Invalid message received")
+				return
+			}
+			switch err := c.start(msg.ID, &req); {
+			case err == errOperationExists && !c.legacy:
+				c.close(closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+				return
+			case err != nil:
+				c.sendErrors(msg.ID, []*gqlErrors.QueryError{{Message: err.Error()}})
+			}
+
+		case "complete":
+			c.stop(msg.ID)
+
+		default:
+			c.close(closeBadRequest, "Invalid message received")
+			return
+		}
+	}
+}
+
+// start executes an operation of the client in the background.
+func (c *wsConn) start(id string, req *request) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.ops[id]; ok {
+		return errOperationExists
+	}
+	if len(c.ops) >= wsMaxOperations {
+		return errTooManyOperations
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	op := &wsOperation{cancel: cancel}
+	c.ops[id] = op
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer c.finish(id, op)
+
+		c.execute(ctx, id, req)
+	}()
+	return nil
+}
+
+// stop cancels an operation on request of the client.
+func (c *wsConn) stop(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if op, ok := c.ops[id]; ok {
+		op.cancel()
+		delete(c.ops, id)
+	}
+}
+
+// finish releases an operation that has terminated.
+func (c *wsConn) finish(id string, op *wsOperation) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	op.cancel()
+	if c.ops[id] == op {
+		delete(c.ops, id)
+	}
+}
+
+// execute runs an operation, streaming its results to the client. Subscriptions
+// run against the subscription schema, queries and mutations against the main
+// schema, within the same limits as over HTTP.
+func (c *wsConn) execute(ctx context.Context, id string, req *request) {
+	if err := c.h.persisted.resolve(req); err != nil {
+		c.sendErrors(id, []*gqlErrors.QueryError{err})
+		return
+	}
+	var responses <-chan interface{}
+	if operationType(req.Query, req.OperationName) == "subscription" {
+		var err error
+		if responses, err = c.h.Subscriptions.Subscribe(ctx, req.Query, req.OperationName, req.Variables); err != nil {
+			c.sendErrors(id, []*gqlErrors.QueryError{{Message: err.Error()}})
+			return
+		}
+	} else {
+		if c.h.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.h.timeout)
+			defer cancel()
+		}
+		response := make(chan interface{}, 1)
+		response <- c.h.execute(ctx, req)
+		close(response)
+		responses = response
+	}
+	for res := range responses {
+		response := res.(*graphql.Response)
+
+		// Responses without data failed altogether, they terminate the operation
+		if response.Data == nil && len(response.Errors) > 0 {
+			c.sendErrors(id, response.Errors)
+			return
+		}
+		payload, err := json.Marshal(response)
+		if err != nil {
+			c.sendErrors(id, []*gqlErrors.QueryError{{Message: err.Error()}})
+			return
+		}
+		typ := "next"
+		if c.legacy {
+			typ = "data"
+		}
+		if err := c.send(&wsMessage{ID: id, Type: typ, Payload: payload}); err != nil {
+			return
+		}
+	}
+	// Operations stopped by the client are not completed
+	if ctx.Err() == nil {
+		c.send(&wsMessage{ID: id, Type: "complete"})
+	}
+}
+
+// send writes a message to the client. A failed write closes the connection,
+// which terminates the operations running on it.
+func (c *wsConn) send(msg *wsMessage) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.conn.Close()
+		return err
+	}
+	return nil
+}
+
+// sendErrors terminates an operation with the given errors. The legacy protocol
+// only carries a single error per message.
+func (c *wsConn) sendErrors(id string, errs []*gqlErrors.QueryError) error {
+	var (
+		payload []byte
+		err     error
+	)
+	if c.legacy {
+		payload, err = json.Marshal(errs[0])
+	} else {
+		payload, err = json.Marshal(errs)
+	}
+	if err != nil {
+		return err
+	}
+	return c.send(&wsMessage{ID: id, Type: "error", Payload: payload})
+}
+
+// close terminates the connection with the given close code and reason.
+func (c *wsConn) close(code int, reason string) {
+	deadline := time.Now().Add(wsWriteTimeout)
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+}
+
+// operationType returns the type of the named operation of a GraphQL document,
+// or of its only operation if no name is given. The document is only scanned
+// lexically, it is validated when the operation is executed.
+func operationType(document, name string) string {
+	var (
+		types = make(map[string]string) // Types of the named operations
+		ops   int                       // Number of operations in the document
+		last  string                    // Type of the last operation
+		depth int                       // Nesting depth of brackets
+		kind  string                    // Kind of the definition being scanned
+		named bool                      // Whether the next word names the definition
+	)
+	for i := 0; i < len(document); i++ {
+		switch c := document[i]; {
+		case c == '#':
+			for i < len(document) && document[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			if strings.HasPrefix(document[i:], `"""`) {
+				end := strings.Index(document[i+3:], `"""`)
+				if end < 0 {
+					return ""
+				}
+				i += end + 5
+				continue
+			}
+			for i++; i < len(document) && document[i] != '"'; i++ {
+				if document[i] == '\\' {
+					i++
+				}
+			}
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i+1 < len(document) && isNameChar(document[i+1]) {
+				i++
+			}
+			word := document[start : i+1]
+			if depth > 0 {
+				continue
+			}
+			switch {
+			case named:
+				if kind != "fragment" {
+					types[word] = kind
+				}
+				named = false
+			case kind == "":
+				kind, named = word, true
+				if word == "query" || word == "mutation" || word == "subscription" {
+					ops, last = ops+1, kind
+				}
+			}
+		case c == '{' || c == '(' || c == '[':
+			if depth == 0 && kind == "" {
+				kind = "query"
+				ops, last = ops+1, kind
+			}
+			depth++
+			named = false
+		case c == '}' || c == ')' || c == ']':
+			depth--
+			if depth == 0 && c == '}' {
+				kind = ""
+			}
+		case c == '@' || c == '$':
+			named = false
+		}
+	}
+	if name != "" {
+		return types[name]
+	}
+	// Without a name, the document must contain a single operation
+	if ops == 1 {
+		return last
+	}
+	return ""
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -32,7 +32,9 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -81,11 +83,12 @@ type feeHistoryResult struct {
 	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
 	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
 	GasUsedRatio []float64        `json:"gasUsedRatio"`
+	BurntFees    []*hexutil.Big   `json:"burntFees,omitempty"`
 }
 
 // FeeHistory returns the fee market history.
 func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
-	oldest, reward, baseFee, gasUsed, err := s.b.FeeHistory(ctx, uint64(blockCount), lastBlock, rewardPercentiles)
+	oldest, reward, baseFee, gasUsed, burntFees, err := s.b.FeeHistory(ctx, uint64(blockCount), lastBlock, rewardPercentiles)
 	if err != nil {
 		return nil, err
 	}
@@ -108,6 +111,12 @@ func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecim
 			results.BaseFee[i] = (*hexutil.Big)(v)
 		}
 	}
+	if burntFees != nil {
+		results.BurntFees = make([]*hexutil.Big, len(burntFees))
+		for i, v := range burntFees {
+			results.BurntFees[i] = (*hexutil.Big)(v)
+		}
+	}
 	return results, nil
 }
 
@@ -205,13 +214,106 @@ func (s *TxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCT
 	return content
 }
 
-// Status returns the number of pending and queued transaction in the pool.
-func (s *TxPoolAPI) Status() map[string]hexutil.Uint {
-	pending, queue := s.b.Stats()
-	return map[string]hexutil.Uint{
-		"pending": hexutil.Uint(pending),
-		"queued":  hexutil.Uint(queue),
+// Status returns the number of pending and queued transaction in the pool, or
+// if a transaction hash is given, whether that transaction is pending, queued,
+// or was dropped from the pool and why.
+func (s *TxPoolAPI) Status(hash *common.Hash) interface{} {
+	if hash == nil {
+		pending, queue := s.b.Stats()
+		return map[string]hexutil.Uint{
+			"pending": hexutil.Uint(pending),
+			"queued":  hexutil.Uint(queue),
+		}
+	}
+	status := &RPCTxPoolStatus{Hash: *hash, Status: "unknown"}
+	if tx := s.b.GetPoolTransaction(*hash); tx != nil {
+		status.Status = "queued"
+
+		signer := types.LatestSigner(s.b.ChainConfig())
+		from, _ := types.Sender(signer, tx)
+		pending, _ := s.b.TxPoolContentFrom(from)
+		for _, ptx := range pending {
+			if ptx.Hash() == *hash {
+				status.Status = "pending"
+				break
+			}
+		}
+	} else if drop := s.b.TxPoolLastDrop(*hash); drop != nil {
+		status.Status = "dropped"
+		status.Drop = newRPCDroppedTransaction(drop)
 	}
+	return status
+}
+
+// RPCTxPoolStatus is the status of a transaction in the pool.
+type RPCTxPoolStatus struct {
+	Hash   common.Hash            `json:"hash"`
+	Status string                 `json:"status"`
+	Drop   *RPCDroppedTransaction `json:"drop,omitempty"`
+}
+
+// RPCDroppedTransaction describes a transaction dropped from the pool.
+type RPCDroppedTransaction struct {
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	Reason     string         `json:"reason"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+	Time       hexutil.Uint64 `json:"time"`
+}
+
+func newRPCDroppedTransaction(drop *txpool.DroppedTx) *RPCDroppedTransaction {
+	return &RPCDroppedTransaction{
+		Hash:       drop.Hash,
+		From:       drop.Sender,
+		Nonce:      hexutil.Uint64(drop.Nonce),
+		Reason:     string(drop.Reason),
+		ReplacedBy: drop.Replacement,
+		Time:       hexutil.Uint64(drop.Time.Unix()),
+	}
+}
+
+// Dropped returns the recently dropped transactions, oldest first, optionally
+// only those of the given sender.
+func (s *TxPoolAPI) Dropped(from *common.Address) []*RPCDroppedTransaction {
+	drops := make([]*RPCDroppedTransaction, 0)
+	for _, drop := range s.b.TxPoolDropped() {
+		if from == nil || drop.Sender == *from {
+			drops = append(drops, newRPCDroppedTransaction(drop))
+		}
+	}
+	return drops
+}
+
+// DroppedTransactions creates a subscription that is notified of each
+// transaction dropped from the pool.
+func (s *TxPoolAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var (
+		rpcSub = notifier.CreateSubscription()
+		events = make(chan txpool.DropTxsEvent, 128)
+		sub    = s.b.SubscribeDropTxsEvent(events)
+	)
+	go func() {
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				for _, drop := range ev.Txs {
+					notifier.Notify(rpcSub.ID, newRPCDroppedTransaction(drop))
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
 }
 
 // Inspect retrieves the content of the transaction pool and flattens it into an
@@ -782,6 +884,32 @@ func (s *BlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fu
 	return nil, err
 }
 
+// GetBlockReceipts returns the receipts of all the transactions of the given
+// block, in the order of the transactions.
+func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		// When the block doesn't exist, the RPC method should return JSON null
+		// as per specification.
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	signer := types.MakeSigner(s.b.ChainConfig(), block.Number())
+
+	result := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], uint64(i))
+	}
+	return result, nil
+}
+
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index.
 func (s *BlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
 	block, err := s.b.BlockByNumber(ctx, blockNr)
@@ -856,6 +984,24 @@ func (s *BlockChainAPI) GetStorageAt(ctx context.Context, address common.Address
 	return res[:], state.Error()
 }
 
+// GetSupply returns the total amount of coins in existence after the given
+// block, as tracked by the issuance records of the node.
+func (s *BlockChainAPI) GetSupply(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[string]interface{}, error) {
+	header, err := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	issuance := rawdb.ReadIssuance(s.b.ChainDb(), header.Hash(), header.Number.Uint64())
+	if issuance == nil {
+		return nil, fmt.Errorf("issuance of block #%d not tracked", header.Number)
+	}
+	return map[string]interface{}{
+		"number": (*hexutil.Big)(header.Number),
+		"hash":   header.Hash(),
+		"supply": (*hexutil.Big)(issuance.Supply),
+	}, nil
+}
+
 // OverrideAccount indicates the overriding fields of account during the execution
 // of a message call.
 // Note, state and stateDiff can't be specified at the same time. If state is
@@ -1630,13 +1776,18 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 	// Derive the sender.
 	bigblock := new(big.Int).SetUint64(blockNumber)
 	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
+	return marshalReceipt(receipt, blockHash, blockNumber, signer, tx, index), nil
+}
+
+// marshalReceipt marshals a transaction receipt into a JSON object.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, txIndex uint64) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
-		"transactionIndex":  hexutil.Uint64(index),
+		"transactionHash":   tx.Hash(),
+		"transactionIndex":  hexutil.Uint64(txIndex),
 		"from":              from,
 		"to":                tx.To(),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
@@ -1662,7 +1813,7 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1767,6 +1918,32 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// maxConditionSlots is the maximum number of storage checks the conditions of a
+// transaction may require.
+const maxConditionSlots = 1000
+
+// SendRawTransactionConditional adds the signed transaction to the transaction
+// pool, to be included only while the given conditions hold. The conditions are
+// checked against the current head before the transaction is accepted, and are
+// never shared with other nodes, so the transaction is not propagated either.
+func (s *TransactionAPI) SendRawTransactionConditional(ctx context.Context, input hexutil.Bytes, conds types.TransactionConditions) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if slots := conds.Slots(); slots > maxConditionSlots {
+		return common.Hash{}, fmt.Errorf("too many storage conditions: %d > %d", slots, maxConditionSlots)
+	}
+	if conds.BlockNumberMin != nil && conds.BlockNumberMax != nil && conds.BlockNumberMin.Cmp(conds.BlockNumberMax) > 0 {
+		return common.Hash{}, errors.New("block number range is empty")
+	}
+	if conds.TimestampMin != nil && conds.TimestampMax != nil && *conds.TimestampMin > *conds.TimestampMax {
+		return common.Hash{}, errors.New("timestamp range is empty")
+	}
+	tx.SetConditions(&conds)
+	return SubmitTransaction(ctx, s.b, tx)
+}
+
 // Sign calculates an ECDSA signature for:
 // keccak256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -2013,7 +2190,7 @@ func (api *DebugAPI) SeedHash(ctx context.Context, number uint64) (string, error
 	if block == nil {
 		return "", fmt.Errorf("block #%d not found", number)
 	}
-	return fmt.Sprintf("%#x", ethash.SeedHash(number)), nil
+	return fmt.Sprintf("%#x", ethash.SeedHash(number, api.b.ChainConfig().ECIP1099Block())), nil
 }
 
 // ChaindbProperty returns leveldb properties of the key-value database.
@@ -2044,6 +2221,53 @@ func (api *DebugAPI) SetHead(number hexutil.Uint64) {
 	api.b.SetHead(uint64(number))
 }
 
+// maxIssuanceRange is the maximum number of blocks whose issuance records can be
+// retrieved at once.
+const maxIssuanceRange = 10000
+
+// GetIssuance returns the issuance records of the canonical blocks in the given
+// range, inclusive: the coins minted as block and uncle rewards and through
+// withdrawals, the base fee burned, and the total supply after each block.
+func (api *DebugAPI) GetIssuance(ctx context.Context, from, to rpc.BlockNumber) ([]map[string]interface{}, error) {
+	start, err := api.b.HeaderByNumber(ctx, from)
+	if start == nil || err != nil {
+		return nil, fmt.Errorf("block #%d not found", from)
+	}
+	end, err := api.b.HeaderByNumber(ctx, to)
+	if end == nil || err != nil {
+		return nil, fmt.Errorf("block #%d not found", to)
+	}
+	first, last := start.Number.Uint64(), end.Number.Uint64()
+	if first > last {
+		return nil, fmt.Errorf("invalid block range #%d-#%d", first, last)
+	}
+	if last-first >= maxIssuanceRange {
+		return nil, fmt.Errorf("block range #%d-#%d exceeds the limit of %d blocks", first, last, maxIssuanceRange)
+	}
+	results := make([]map[string]interface{}, 0, last-first+1)
+	for number := first; number <= last; number++ {
+		header, err := api.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if header == nil || err != nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		issuance := rawdb.ReadIssuance(api.b.ChainDb(), header.Hash(), number)
+		if issuance == nil {
+			return nil, fmt.Errorf("issuance of block #%d not tracked", number)
+		}
+		results = append(results, map[string]interface{}{
+			"number":      hexutil.Uint64(number),
+			"hash":        header.Hash(),
+			"reward":      (*hexutil.Big)(issuance.Reward),
+			"uncleReward": (*hexutil.Big)(issuance.UncleReward),
+			"withdrawals": (*hexutil.Big)(issuance.Withdrawals),
+			"burn":        (*hexutil.Big)(issuance.Burn),
+			"issuance":    (*hexutil.Big)(issuance.Issued()),
+			"supply":      (*hexutil.Big)(issuance.Supply),
+		})
+	}
+	return results, nil
+}
+
 // NetAPI offers network related RPC methods
 type NetAPI struct {
 	net            *p2p.Server
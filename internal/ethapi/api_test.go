@@ -18,6 +18,8 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
@@ -153,3 +155,76 @@ func allTransactionTypes(addr common.Address, config *params.ChainConfig) []type
 		},
 	}
 }
+
+// dropBackend is a backend with a transaction pool holding one pending
+// transaction, and remembering one dropped transaction.
+type dropBackend struct {
+	*backendMock
+	pending *types.Transaction
+	drop    *txpool.DroppedTx
+}
+
+func (b *dropBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	if hash == b.pending.Hash() {
+		return b.pending
+	}
+	return nil
+}
+
+func (b *dropBackend) TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	return types.Transactions{b.pending}, nil
+}
+
+func (b *dropBackend) TxPoolDropped() []*txpool.DroppedTx { return []*txpool.DroppedTx{b.drop} }
+
+func (b *dropBackend) TxPoolLastDrop(hash common.Hash) *txpool.DroppedTx {
+	if hash == b.drop.Hash {
+		return b.drop
+	}
+	return nil
+}
+
+func TestTxPoolStatus(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mock   = newBackendMock()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		tx     = types.MustSignNewTx(key, types.LatestSigner(mock.config), &types.LegacyTx{Gas: 21000, GasPrice: big.NewInt(1)})
+		next   = common.Hash{0x02}
+	)
+	backend := &dropBackend{
+		backendMock: mock,
+		pending:     tx,
+		drop:        &txpool.DroppedTx{Hash: common.Hash{0x01}, Sender: from, Nonce: 1, Reason: txpool.DropReplaced, Replacement: &next},
+	}
+	api := NewTxPoolAPI(backend)
+
+	if _, ok := api.Status(nil).(map[string]hexutil.Uint); !ok {
+		t.Errorf("pool status type mismatch: have %T", api.Status(nil))
+	}
+	tests := []struct {
+		hash   common.Hash
+		status string
+	}{
+		{tx.Hash(), "pending"},
+		{backend.drop.Hash, "dropped"},
+		{common.Hash{0x03}, "unknown"},
+	}
+	for i, tt := range tests {
+		status := api.Status(&tt.hash).(*RPCTxPoolStatus)
+		if status.Status != tt.status {
+			t.Errorf("test %d: status mismatch: have %s, want %s", i, status.Status, tt.status)
+		}
+		if (status.Drop != nil) != (tt.status == "dropped") {
+			t.Errorf("test %d: drop details mismatch: have %v", i, status.Drop)
+		}
+	}
+	if drops := api.Dropped(&from); len(drops) != 1 || drops[0].Reason != "replaced" || *drops[0].ReplacedBy != next {
+		t.Errorf("sender drops mismatch: have %v", drops)
+	}
+	if drops := api.Dropped(&common.Address{}); len(drops) != 0 {
+		t.Errorf("other sender drops mismatch: have %d, want 0", len(drops))
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks a single simulation may
+	// span.
+	maxSimulateBlocks = 256
+
+	// simTimestampIncrement is the default time between simulated blocks.
+	simTimestampIncrement = 12
+
+	// errcodeSimVMError is the error code of calls failing in the EVM for other
+	// reasons than a revert.
+	errcodeSimVMError = -32015
+)
+
+var (
+	// transferAddress is the pseudo-address emitting the logs of ether transfers.
+	transferAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+	// transferTopic is the topic of the logs of ether transfers, the signature
+	// of the ERC-20 Transfer event.
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+// SimBlock is a block of calls to simulate, on top of the state left by the
+// previous blocks of the simulation.
+type SimBlock struct {
+	BlockOverrides *BlockOverrides   `json:"blockOverrides"`
+	StateOverrides *StateOverride    `json:"stateOverrides"`
+	Calls          []TransactionArgs `json:"calls"`
+}
+
+// SimOpts are the inputs of eth_simulateV1.
+type SimOpts struct {
+	BlockStateCalls []SimBlock `json:"blockStateCalls"`
+
+	// TraceTransfers adds a log for every ether transfer, emitted by
+	// 0xeee...eee with the topics of an ERC-20 Transfer event.
+	TraceTransfers bool `json:"traceTransfers"`
+
+	// Validation runs the calls as if they were transactions, enforcing the
+	// nonces, balances and base fee, without requiring any signature.
+	Validation bool `json:"validation"`
+}
+
+// simCallError is the error of a failed simulated call.
+type simCallError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// simCallResult is the outcome of a simulated call.
+type simCallResult struct {
+	ReturnValue hexutil.Bytes  `json:"returnData"`
+	Logs        []*types.Log   `json:"logs"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Status      hexutil.Uint64 `json:"status"`
+	Error       *simCallError  `json:"error,omitempty"`
+}
+
+// SimulateV1 executes a sequence of blocks of calls on top of the given block,
+// each block and call seeing the state changes of the previous ones. Every
+// block may override the header fields and the state it starts from.
+//
+// Note, the simulation doesn't make any changes in the state/blockchain.
+func (s *BlockChainAPI) SimulateV1(ctx context.Context, opts SimOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errors.New("empty simulation")
+	}
+	if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks (%d>%d)", len(opts.BlockStateCalls), maxSimulateBlocks)
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	state, parent, err := s.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Setup context so it may be cancelled the simulation has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	timeout := s.b.RPCEVMTimeout()
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var (
+		base    = parent
+		headers = make([]*types.Header, 0, len(opts.BlockStateCalls))
+		results = make([]map[string]interface{}, 0, len(opts.BlockStateCalls))
+	)
+	for i, block := range opts.BlockStateCalls {
+		header, err := s.makeSimHeader(parent, block.BlockOverrides, opts.Validation)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		if err := block.StateOverrides.Apply(state); err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		calls, err := s.simulateBlock(ctx, state, header, block.Calls, &opts, s.simHashFn(ctx, base, headers), timeout)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		results = append(results, map[string]interface{}{
+			"number":        (*hexutil.Big)(header.Number),
+			"hash":          header.Hash(),
+			"parentHash":    header.ParentHash,
+			"stateRoot":     header.Root,
+			"timestamp":     hexutil.Uint64(header.Time),
+			"gasLimit":      hexutil.Uint64(header.GasLimit),
+			"gasUsed":       hexutil.Uint64(header.GasUsed),
+			"miner":         header.Coinbase,
+			"baseFeePerGas": (*hexutil.Big)(header.BaseFee),
+			"calls":         calls,
+		})
+		headers = append(headers, header)
+		parent = header
+	}
+	return results, nil
+}
+
+// simHashFn returns the hash function of BLOCKHASH in a simulated block,
+// resolving the earlier simulated blocks first, then the ancestors of the block
+// the simulation is based on.
+func (s *BlockChainAPI) simHashFn(ctx context.Context, base *types.Header, headers []*types.Header) vm.GetHashFunc {
+	return func(number uint64) common.Hash {
+		for i := len(headers) - 1; i >= 0; i-- {
+			if headers[i].Number.Uint64() == number {
+				return headers[i].Hash()
+			}
+		}
+		header := base
+		for header != nil && header.Number.Uint64() > number {
+			header, _ = s.b.HeaderByHash(ctx, header.ParentHash)
+		}
+		if header == nil || header.Number.Uint64() != number {
+			return common.Hash{}
+		}
+		return header.Hash()
+	}
+}
+
+// makeSimHeader assembles the header of a simulated block on top of the given
+// parent, applying the block overrides.
+func (s *BlockChainAPI) makeSimHeader(parent *types.Header, overrides *BlockOverrides, validation bool) (*types.Header, error) {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		Time:       parent.Time + simTimestampIncrement,
+		GasLimit:   parent.GasLimit,
+		Coinbase:   parent.Coinbase,
+		Difficulty: parent.Difficulty,
+		MixDigest:  parent.MixDigest,
+	}
+	if overrides != nil {
+		if overrides.Number != nil {
+			if overrides.Number.ToInt().Cmp(parent.Number) <= 0 {
+				return nil, fmt.Errorf("block number %v not above parent %v", overrides.Number.ToInt(), parent.Number)
+			}
+			header.Number = new(big.Int).Set(overrides.Number.ToInt())
+		}
+		if overrides.Time != nil {
+			if uint64(*overrides.Time) <= parent.Time {
+				return nil, fmt.Errorf("block timestamp %d not above parent %d", *overrides.Time, parent.Time)
+			}
+			header.Time = uint64(*overrides.Time)
+		}
+		if overrides.GasLimit != nil {
+			header.GasLimit = uint64(*overrides.GasLimit)
+		}
+		if overrides.Coinbase != nil {
+			header.Coinbase = *overrides.Coinbase
+		}
+		if overrides.Difficulty != nil {
+			header.Difficulty = overrides.Difficulty.ToInt()
+		}
+		if overrides.Random != nil {
+			header.MixDigest = *overrides.Random
+		}
+	}
+	// Derive the base fee from the parent, unless overridden. Calls are not
+	// charged any base fee outside of validation, so it is zero by default.
+	if config := s.b.ChainConfig(); config.IsLondon(header.Number) {
+		switch {
+		case overrides != nil && overrides.BaseFee != nil:
+			header.BaseFee = overrides.BaseFee.ToInt()
+		case validation:
+			header.BaseFee = misc.CalcBaseFee(config, parent)
+		default:
+			header.BaseFee = new(big.Int)
+		}
+	}
+	return header, nil
+}
+
+// simulateBlock executes the calls of a simulated block on top of the given
+// state, filling in the gas used and state root of the header.
+func (s *BlockChainAPI) simulateBlock(ctx context.Context, state *state.StateDB, header *types.Header, calls []TransactionArgs, opts *SimOpts, getHash vm.GetHashFunc, timeout time.Duration) ([]*simCallResult, error) {
+	var (
+		config  = s.b.ChainConfig()
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		results = make([]*simCallResult, 0, len(calls))
+		logs    []*types.Log
+	)
+	for i, args := range calls {
+		if args.Gas == nil {
+			gas := hexutil.Uint64(gp.Gas())
+			args.Gas = &gas
+		}
+		msg, err := args.ToMessage(s.b.RPCGasCap(), header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		msg.Nonce = state.GetNonce(msg.From)
+		if opts.Validation {
+			if args.Nonce != nil {
+				msg.Nonce = uint64(*args.Nonce)
+			}
+			msg.SkipAccountChecks = false
+		}
+		// Derive a unique hash for the call to attribute its logs to
+		txHash := types.NewTx(&types.LegacyTx{
+			Nonce:    msg.Nonce,
+			GasPrice: msg.GasPrice,
+			Gas:      msg.GasLimit,
+			To:       msg.To,
+			Value:    msg.Value,
+			Data:     msg.Data,
+		}).Hash()
+		state.SetTxContext(txHash, i)
+
+		var tracer *transferTracer
+		vmConfig := &vm.Config{NoBaseFee: !opts.Validation}
+		if opts.TraceTransfers {
+			tracer = new(transferTracer)
+			vmConfig.Tracer = tracer
+		}
+		evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmConfig)
+		if err != nil {
+			return nil, err
+		}
+		evm.Context.GetHash = getHash
+
+		// Abort the call if the context is cancelled before it's done
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		result, err := core.ApplyMessage(evm, msg, gp)
+		close(done)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w (supplied gas %d)", i, err, msg.GasLimit)
+		}
+		state.Finalise(config.IsEIP158(header.Number))
+		header.GasUsed += result.UsedGas
+
+		// Gather the logs of the call, listing the ether transfers first
+		var callLogs []*types.Log
+		if tracer != nil {
+			for _, log := range tracer.logs {
+				log.TxHash, log.TxIndex = txHash, uint(i)
+			}
+			callLogs = append(callLogs, tracer.logs...)
+		}
+		callLogs = append(callLogs, state.GetLogs(txHash, header.Number.Uint64(), common.Hash{})...)
+		for _, log := range callLogs {
+			log.BlockNumber = header.Number.Uint64()
+			log.Index = uint(len(logs))
+			logs = append(logs, log)
+		}
+		res := &simCallResult{
+			ReturnValue: result.Return(),
+			Logs:        callLogs,
+			GasUsed:     hexutil.Uint64(result.UsedGas),
+			Status:      hexutil.Uint64(types.ReceiptStatusSuccessful),
+		}
+		if result.Failed() {
+			res.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if len(result.Revert()) > 0 {
+				revert := newRevertError(result)
+				res.Error = &simCallError{Code: revert.ErrorCode(), Message: revert.Error(), Data: revert.reason}
+			} else {
+				res.Error = &simCallError{Code: errcodeSimVMError, Message: result.Err.Error()}
+			}
+		}
+		if res.Logs == nil {
+			res.Logs = []*types.Log{}
+		}
+		results = append(results, res)
+	}
+	header.Root = state.IntermediateRoot(config.IsEIP158(header.Number))
+
+	// Now that the header is final, attribute the logs to its hash
+	hash := header.Hash()
+	for _, log := range logs {
+		log.BlockHash = hash
+	}
+	return results, nil
+}
+
+// transferTracer records the ether transfers of a call as ERC-20 style
+// Transfer logs, dropping the ones of reverted call frames.
+type transferTracer struct {
+	logs   []*types.Log
+	frames []int // Number of logs at the start of each open call frame
+}
+
+func (t *transferTracer) transfer(from, to common.Address, value *big.Int) {
+	if value == nil || value.Sign() == 0 {
+		return
+	}
+	t.logs = append(t.logs, &types.Log{
+		Address: transferAddress,
+		Topics:  []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:    common.BigToHash(value).Bytes(),
+	})
+}
+
+func (t *transferTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *transferTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *transferTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.frames = append(t.frames[:0], 0)
+	t.transfer(from, to, value)
+}
+
+func (t *transferTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if err != nil {
+		t.logs = t.logs[:0]
+	}
+	t.frames = t.frames[:0]
+}
+
+func (t *transferTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.frames = append(t.frames, len(t.logs))
+	// Delegated calls carry the value of their parent without moving it
+	if typ != vm.DELEGATECALL && typ != vm.STATICCALL {
+		t.transfer(from, to, value)
+	}
+}
+
+func (t *transferTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	start := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+	if err != nil {
+		t.logs = t.logs[:start]
+	}
+}
+
+func (t *transferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (t *transferTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
@@ -0,0 +1,215 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// simBackend is a backend serving the state of its current header, with the
+// given contracts deployed.
+type simBackend struct {
+	*backendMock
+	code map[common.Address][]byte
+}
+
+func (b *simBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	for addr, code := range b.code {
+		statedb.SetCode(addr, code)
+	}
+	return statedb, b.current, nil
+}
+
+func (b *simBackend) GetEVM(ctx context.Context, msg *core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	context := core.NewEVMBlockContext(header, nil, &header.Coinbase)
+	return vm.NewEVM(context, core.NewEVMTxContext(msg), state, b.config, *vmConfig), state.Error, nil
+}
+
+func TestSimulateV1(t *testing.T) {
+	var (
+		sender   = common.HexToAddress("0x1000")
+		counter  = common.HexToAddress("0xc0")
+		reverter = common.HexToAddress("0xee")
+		payer    = common.HexToAddress("0xfa")
+		payee    = common.HexToAddress("0xbb")
+		coinbase = common.HexToAddress("0xcb")
+	)
+	backend := &simBackend{
+		backendMock: newBackendMock(),
+		code: map[common.Address][]byte{
+			// Increments slot 0, logs it and returns it
+			counter: common.FromHex("0x60005460010180600055600052600160206000a160206000f3"),
+			// Reverts with 0x2a
+			reverter: common.FromHex("0x602a60005260206000fd"),
+			// Forwards the call value to the payee
+			payer: append(append(common.FromHex("0x60006000600060003473"), payee.Bytes()...), common.FromHex("0x5af100")...),
+		},
+	}
+	api := NewBlockChainAPI(backend)
+
+	var (
+		balance   = (*hexutil.Big)(big.NewInt(1000000))
+		value     = (*hexutil.Big)(big.NewInt(1000))
+		blockTime = hexutil.Uint64(1000)
+	)
+	opts := SimOpts{
+		TraceTransfers: true,
+		BlockStateCalls: []SimBlock{
+			{
+				BlockOverrides: &BlockOverrides{Time: &blockTime, Coinbase: &coinbase},
+				StateOverrides: &StateOverride{sender: OverrideAccount{Balance: &balance}},
+				Calls: []TransactionArgs{
+					{From: &sender, To: &counter},
+					{From: &sender, To: &counter},
+					{From: &sender, To: &payer, Value: value},
+				},
+			},
+			{
+				Calls: []TransactionArgs{
+					{From: &sender, To: &counter},
+					{From: &sender, To: &reverter},
+				},
+			},
+		},
+	}
+	blocks, err := api.SimulateV1(context.Background(), opts, nil)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("block count mismatch: have %d, want 2", len(blocks))
+	}
+	// Check the headers of the blocks follow the overrides and their parents
+	if number := blocks[0]["number"].(*hexutil.Big).ToInt(); number.Uint64() != 1101 {
+		t.Errorf("block 0: number mismatch: have %v, want 1101", number)
+	}
+	if number := blocks[1]["number"].(*hexutil.Big).ToInt(); number.Uint64() != 1102 {
+		t.Errorf("block 1: number mismatch: have %v, want 1102", number)
+	}
+	if time := blocks[1]["timestamp"].(hexutil.Uint64); time != blockTime+simTimestampIncrement {
+		t.Errorf("block 1: timestamp mismatch: have %d, want %d", time, blockTime+simTimestampIncrement)
+	}
+	if miner := blocks[1]["miner"].(common.Address); miner != coinbase {
+		t.Errorf("block 1: miner mismatch: have %x, want %x", miner, coinbase)
+	}
+	if parent := blocks[1]["parentHash"].(common.Hash); parent != blocks[0]["hash"].(common.Hash) {
+		t.Errorf("block 1: parent hash mismatch: have %x, want %x", parent, blocks[0]["hash"])
+	}
+	// Check the calls see the state changes of the previous ones
+	var gasUsed uint64
+	calls := blocks[0]["calls"].([]*simCallResult)
+	for i, call := range calls[:2] {
+		if have, want := new(big.Int).SetBytes(call.ReturnValue), big.NewInt(int64(i+1)); have.Cmp(want) != 0 {
+			t.Errorf("block 0 call %d: result mismatch: have %v, want %v", i, have, want)
+		}
+		if len(call.Logs) != 1 || call.Logs[0].Address != counter || call.Logs[0].Index != uint(i) {
+			t.Errorf("block 0 call %d: unexpected logs %v", i, call.Logs)
+		}
+		gasUsed += uint64(call.GasUsed)
+	}
+	// Check the ether transfers are logged
+	if logs := calls[2].Logs; len(logs) != 2 {
+		t.Errorf("block 0 call 2: transfer log count mismatch: have %d, want 2", len(logs))
+	} else {
+		for i, transfer := range [][2]common.Address{{sender, payer}, {payer, payee}} {
+			log := logs[i]
+			if log.Address != transferAddress || log.Topics[1] != common.BytesToHash(transfer[0].Bytes()) || log.Topics[2] != common.BytesToHash(transfer[1].Bytes()) || new(big.Int).SetBytes(log.Data).Cmp(value.ToInt()) != 0 {
+				t.Errorf("block 0 call 2: transfer log %d mismatch: %v", i, log)
+			}
+		}
+	}
+	gasUsed += uint64(calls[2].GasUsed)
+	if have := blocks[0]["gasUsed"].(hexutil.Uint64); uint64(have) != gasUsed {
+		t.Errorf("block 0: gas used mismatch: have %d, want %d", have, gasUsed)
+	}
+	calls = blocks[1]["calls"].([]*simCallResult)
+	if have := new(big.Int).SetBytes(calls[0].ReturnValue); have.Uint64() != 3 {
+		t.Errorf("block 1 call 0: result mismatch: have %v, want 3", have)
+	}
+	if calls[0].Logs[0].BlockHash != blocks[1]["hash"].(common.Hash) {
+		t.Errorf("block 1 call 0: log block hash mismatch")
+	}
+	// Check reverts are reported along with their data
+	if call := calls[1]; call.Status != 0 || call.Error == nil || call.Error.Code != 3 || call.Error.Data != hexutil.Encode(common.LeftPadBytes([]byte{0x2a}, 32)) {
+		t.Errorf("block 1 call 1: unexpected revert result %+v, error %+v", call, call.Error)
+	}
+	// Check the block overrides must move forward
+	past := hexutil.Uint64(backend.current.Time)
+	opts = SimOpts{BlockStateCalls: []SimBlock{{BlockOverrides: &BlockOverrides{Time: &past}}}}
+	if _, err := api.SimulateV1(context.Background(), opts, nil); err == nil {
+		t.Errorf("simulation with past timestamp succeeded")
+	}
+	// Check the validation enforces nonces and base fees
+	var (
+		funded   = &StateOverride{sender: OverrideAccount{Balance: &balance}}
+		gasPrice = (*hexutil.Big)(big.NewInt(20))
+		gas      = hexutil.Uint64(50000)
+		nonce    = hexutil.Uint64(1)
+	)
+	for i, tt := range []struct {
+		call  TransactionArgs
+		valid bool
+	}{
+		{TransactionArgs{From: &sender, To: &counter, Gas: &gas, GasPrice: gasPrice}, true},
+		{TransactionArgs{From: &sender, To: &counter, Gas: &gas, GasPrice: gasPrice, Nonce: &nonce}, false},
+		{TransactionArgs{From: &sender, To: &counter, Gas: &gas}, false},
+	} {
+		opts = SimOpts{Validation: true, BlockStateCalls: []SimBlock{{StateOverrides: funded, Calls: []TransactionArgs{tt.call}}}}
+		if _, err := api.SimulateV1(context.Background(), opts, nil); (err == nil) != tt.valid {
+			t.Errorf("validation %d: error mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
+
+// Tests that BLOCKHASH resolves the earlier simulated blocks, then the block the
+// simulation is based on.
+func TestSimulateV1BlockHash(t *testing.T) {
+	var (
+		sender = common.HexToAddress("0x1000")
+		hasher = common.HexToAddress("0xb0")
+	)
+	backend := &simBackend{
+		backendMock: newBackendMock(),
+		code: map[common.Address][]byte{
+			// Returns the hash of the parent block
+			hasher: common.FromHex("0x600143034060005260206000f3"),
+		},
+	}
+	api := NewBlockChainAPI(backend)
+
+	call := SimBlock{Calls: []TransactionArgs{{From: &sender, To: &hasher}}}
+	blocks, err := api.SimulateV1(context.Background(), SimOpts{BlockStateCalls: []SimBlock{call, call, call}}, nil)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	want := backend.current.Hash()
+	for i, block := range blocks {
+		calls := block["calls"].([]*simCallResult)
+		if have := common.BytesToHash(calls[0].ReturnValue); have != want {
+			t.Errorf("block %d: parent hash mismatch: have %x, want %x", i, have, want)
+		}
+		want = block["hash"].(common.Hash)
+	}
+}
@@ -314,7 +314,7 @@ func (pool *TxPool) setNewHead(head *types.Header) {
 	next := new(big.Int).Add(head.Number, big.NewInt(1))
 	pool.istanbul = pool.config.IsIstanbul(next)
 	pool.eip2718 = pool.config.IsBerlin(next)
-	pool.shanghai = pool.config.IsShanghai(uint64(time.Now().Unix()))
+	pool.shanghai = pool.config.IsShanghai(uint64(time.Now().Unix())) || pool.config.IsEVMUpgrade(next)
 }
 
 // Stop stops the light transaction pool
@@ -0,0 +1,327 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// maxBundlesPerBlock is the maximum number of bundles accepted for a block.
+	maxBundlesPerBlock = 256
+
+	// maxBundleFutureBlocks is how many blocks ahead of the chain head a bundle
+	// may target.
+	maxBundleFutureBlocks = 256
+
+	// maxBundleTxs is the maximum number of transactions in a bundle.
+	maxBundleTxs = 64
+)
+
+var (
+	errEmptyBundle           = errors.New("bundle has no transactions")
+	errBundleTooLarge        = errors.New("bundle has too many transactions")
+	errBundleGasLimit        = errors.New("bundle exceeds block gas limit")
+	errBundleTargetPast      = errors.New("bundle targets a past block")
+	errBundleTargetFuture    = errors.New("bundle targets a block too far in the future")
+	errBundleTimestamps      = errors.New("bundle minimum timestamp above maximum timestamp")
+	errBundleKnown           = errors.New("bundle already known")
+	errBundlePoolFull        = errors.New("too many bundles for block")
+	errBundleReverted        = errors.New("bundle transaction reverted")
+	errBundleNotProfitable   = errors.New("bundle does not pay the coinbase")
+	errBundleGasLimitReached = errors.New("bundle exceeds remaining block gas")
+)
+
+// Bundle is an ordered list of transactions submitted privately by a client,
+// to be included atomically at the top of a given block rather than gossiped
+// through the transaction pool.
+type Bundle struct {
+	Txs               types.Transactions // Transactions to include, in order
+	BlockNumber       uint64             // Number of the block the bundle is valid for
+	MinTimestamp      uint64             // Earliest block timestamp the bundle is valid for (0 = no limit)
+	MaxTimestamp      uint64             // Latest block timestamp the bundle is valid for (0 = no limit)
+	RevertingTxHashes []common.Hash      // Transactions allowed to revert without invalidating the bundle
+}
+
+// Hash returns the identifier of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// canRevert returns whether the transaction with the given hash is allowed to
+// revert without invalidating the bundle.
+func (b *Bundle) canRevert(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// validFor returns whether the bundle may be included in the given block.
+func (b *Bundle) validFor(header *types.Header) bool {
+	if b.BlockNumber != header.Number.Uint64() {
+		return false
+	}
+	if b.MinTimestamp != 0 && header.Time < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && header.Time > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// bundlePool holds the bundles waiting for the block they target.
+type bundlePool struct {
+	bundles map[uint64][]*Bundle // Bundles grouped by target block number
+	lock    sync.Mutex
+}
+
+// newBundlePool creates an empty bundle pool.
+func newBundlePool() *bundlePool {
+	return &bundlePool{
+		bundles: make(map[uint64][]*Bundle),
+	}
+}
+
+// validateBundle checks the transactions of a bundle against the state of the
+// chain head: their senders, nonces and intrinsic gas, and that all of them fit
+// in a block.
+func validateBundle(config *params.ChainConfig, bundle *Bundle, head *types.Header, statedb *state.StateDB) error {
+	var (
+		number = new(big.Int).SetUint64(bundle.BlockNumber)
+		signer = types.MakeSigner(config, number)
+		rules  = config.Rules(number, head.Difficulty.Sign() == 0, head.Time)
+		nonces = make(map[common.Address]uint64)
+		gas    uint64
+	)
+	for i, tx := range bundle.Txs {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return fmt.Errorf("transaction %d: invalid sender: %w", i, err)
+		}
+		next, ok := nonces[from]
+		if !ok {
+			next = statedb.GetNonce(from)
+		}
+		if tx.Nonce() < next {
+			return fmt.Errorf("transaction %d: %w: address %v, tx: %d state: %d", i, core.ErrNonceTooLow, from, tx.Nonce(), next)
+		}
+		nonces[from] = tx.Nonce() + 1
+
+		intrGas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, rules.IsHomestead, rules.IsIstanbul, rules.IsShanghai || rules.IsEVMUpgrade)
+		if err != nil {
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+		if tx.Gas() < intrGas {
+			return fmt.Errorf("transaction %d: %w: have %d, want %d", i, core.ErrIntrinsicGas, tx.Gas(), intrGas)
+		}
+		gas += tx.Gas()
+	}
+	if gas > head.GasLimit {
+		return fmt.Errorf("%w: have %d, limit %d", errBundleGasLimit, gas, head.GasLimit)
+	}
+	return nil
+}
+
+// add validates a bundle against the current chain head and stores it until the
+// block it targets gets built.
+func (pool *bundlePool) add(bundle *Bundle, head uint64) error {
+	switch {
+	case len(bundle.Txs) == 0:
+		return errEmptyBundle
+	case len(bundle.Txs) > maxBundleTxs:
+		return errBundleTooLarge
+	case bundle.BlockNumber <= head:
+		return errBundleTargetPast
+	case bundle.BlockNumber > head+maxBundleFutureBlocks:
+		return errBundleTargetFuture
+	case bundle.MaxTimestamp != 0 && bundle.MinTimestamp > bundle.MaxTimestamp:
+		return errBundleTimestamps
+	}
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.prune(head)
+
+	bundles := pool.bundles[bundle.BlockNumber]
+	hash := bundle.Hash()
+	for _, b := range bundles {
+		if b.Hash() == hash {
+			return errBundleKnown
+		}
+	}
+	if len(bundles) >= maxBundlesPerBlock {
+		return errBundlePoolFull
+	}
+	pool.bundles[bundle.BlockNumber] = append(bundles, bundle)
+	return nil
+}
+
+// pending returns the bundles which may be included in the given block, and
+// drops the ones targeting earlier blocks.
+func (pool *bundlePool) pending(header *types.Header) []*Bundle {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.prune(header.Number.Uint64() - 1)
+
+	var bundles []*Bundle
+	for _, bundle := range pool.bundles[header.Number.Uint64()] {
+		if bundle.validFor(header) {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// prune drops the bundles targeting blocks up to the given head. The lock must
+// be held.
+func (pool *bundlePool) prune(head uint64) {
+	for number := range pool.bundles {
+		if number <= head {
+			delete(pool.bundles, number)
+		}
+	}
+}
+
+// simulatedBundle is a bundle executed on top of a sealing block, along with
+// the value it brings to the coinbase.
+type simulatedBundle struct {
+	bundle  *Bundle
+	profit  *big.Int // Coinbase balance increase, fees and direct payments
+	gasUsed uint64
+}
+
+// commitBundle applies all the transactions of a bundle on top of the sealing
+// block, or none of them if any fails or reverts without being allowed to. It
+// returns the increase of the coinbase balance.
+func (w *worker) commitBundle(env *environment, bundle *Bundle) (*simulatedBundle, error) {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	// Transactions finalise the state, dropping its journal, so a copy is kept
+	// to roll back a partially applied bundle.
+	var (
+		prev     = env.state.Copy()
+		gp       = env.gasPool.Gas()
+		gasUsed  = env.header.GasUsed
+		tcount   = env.tcount
+		txs      = len(env.txs)
+		receipts = len(env.receipts)
+		balance  = env.state.GetBalance(env.coinbase)
+	)
+	rollback := func() {
+		env.state.StopPrefetcher()
+		env.state = prev
+		env.gasPool.SetGas(gp)
+		env.header.GasUsed = gasUsed
+		env.tcount = tcount
+		env.txs = env.txs[:txs]
+		env.receipts = env.receipts[:receipts]
+	}
+	for _, tx := range bundle.Txs {
+		if env.gasPool.Gas() < tx.Gas() {
+			rollback()
+			return nil, errBundleGasLimitReached
+		}
+		env.state.SetTxContext(tx.Hash(), env.tcount)
+
+		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed, *w.chain.GetVMConfig())
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("transaction %v: %w", tx.Hash(), err)
+		}
+		if receipt.Status == types.ReceiptStatusFailed && !bundle.canRevert(tx.Hash()) {
+			rollback()
+			return nil, fmt.Errorf("%w: %v", errBundleReverted, tx.Hash())
+		}
+		env.txs = append(env.txs, tx)
+		env.receipts = append(env.receipts, receipt)
+		env.tcount++
+	}
+	profit := new(big.Int).Sub(env.state.GetBalance(env.coinbase), balance)
+	if profit.Sign() <= 0 {
+		rollback()
+		return nil, errBundleNotProfitable
+	}
+	return &simulatedBundle{
+		bundle:  bundle,
+		profit:  profit,
+		gasUsed: env.header.GasUsed - gasUsed,
+	}, nil
+}
+
+// simulateBundles executes each bundle on its own on top of the sealing block,
+// and returns the valid ones, the most profitable first.
+func (w *worker) simulateBundles(env *environment, bundles []*Bundle) []*simulatedBundle {
+	var simulated []*simulatedBundle
+	for _, bundle := range bundles {
+		work := env.copy()
+		sim, err := w.commitBundle(work, bundle)
+		work.discard()
+		if err != nil {
+			log.Debug("Discarding invalid bundle", "hash", bundle.Hash(), "number", bundle.BlockNumber, "err", err)
+			continue
+		}
+		simulated = append(simulated, sim)
+	}
+	sort.SliceStable(simulated, func(i, j int) bool {
+		return simulated[i].profit.Cmp(simulated[j].profit) > 0
+	})
+	return simulated
+}
+
+// commitBundles places the most profitable bundles targeting the sealing block
+// at its top. Bundles conflicting with more profitable ones already included
+// are skipped.
+func (w *worker) commitBundles(env *environment, interrupt *atomic.Int32) error {
+	bundles := w.bundles.pending(env.header)
+	if len(bundles) == 0 {
+		return nil
+	}
+	for _, sim := range w.simulateBundles(env, bundles) {
+		// Check interruption signal and abort building if it's fired.
+		if interrupt != nil {
+			if signal := interrupt.Load(); signal != commitInterruptNone {
+				return signalToErr(signal)
+			}
+		}
+		if _, err := w.commitBundle(env, sim.bundle); err != nil {
+			log.Debug("Skipping conflicting bundle", "hash", sim.bundle.Hash(), "number", sim.bundle.BlockNumber, "err", err)
+			continue
+		}
+		env.bundled = true
+		log.Trace("Included bundle", "hash", sim.bundle.Hash(), "number", sim.bundle.BlockNumber, "txs", len(sim.bundle.Txs), "profit", sim.profit)
+	}
+	return nil
+}
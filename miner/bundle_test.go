@@ -0,0 +1,217 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newBundleTx creates a transaction of the test bank with the given nonce and
+// gas price. If revert is set, the transaction deploys a contract whose
+// constructor fails.
+func newBundleTx(nonce uint64, gasPrice int64, revert bool) *types.Transaction {
+	tx := &types.LegacyTx{
+		Nonce:    nonce,
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(gasPrice * params.GWei),
+	}
+	if revert {
+		tx.Gas, tx.Data = 100000, []byte{0xfe}
+	} else {
+		tx.To, tx.Value = &testUserAddress, big.NewInt(1000)
+	}
+	return types.MustSignNewTx(testBankKey, types.LatestSigner(params.TestChainConfig), tx)
+}
+
+func TestBundlePool(t *testing.T) {
+	pool := newBundlePool()
+	tx := newBundleTx(0, 2, false)
+
+	tests := []struct {
+		bundle *Bundle
+		err    error
+	}{
+		{&Bundle{BlockNumber: 11}, errEmptyBundle},
+		{&Bundle{Txs: make(types.Transactions, maxBundleTxs+1), BlockNumber: 11}, errBundleTooLarge},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 10}, errBundleTargetPast},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 11 + maxBundleFutureBlocks}, errBundleTargetFuture},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 11, MinTimestamp: 2, MaxTimestamp: 1}, errBundleTimestamps},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 11}, nil},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 11}, errBundleKnown},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 12, MinTimestamp: 100}, nil},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 13, MaxTimestamp: 100}, nil},
+	}
+	for i, tt := range tests {
+		if err := pool.add(tt.bundle, 10); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if n := len(pool.pending(&types.Header{Number: big.NewInt(11), Time: 50})); n != 1 {
+		t.Errorf("block 11: pending bundles mismatch: have %d, want 1", n)
+	}
+	// Bundles outside of their time window are kept but not returned
+	if n := len(pool.pending(&types.Header{Number: big.NewInt(12), Time: 50})); n != 0 {
+		t.Errorf("block 12 early: pending bundles mismatch: have %d, want 0", n)
+	}
+	if n := len(pool.pending(&types.Header{Number: big.NewInt(12), Time: 100})); n != 1 {
+		t.Errorf("block 12: pending bundles mismatch: have %d, want 1", n)
+	}
+	// Bundles targeting past blocks are dropped
+	if n := len(pool.pending(&types.Header{Number: big.NewInt(13), Time: 200})); n != 0 {
+		t.Errorf("block 13 late: pending bundles mismatch: have %d, want 0", n)
+	}
+	if _, ok := pool.bundles[11]; ok {
+		t.Error("bundles of past block not pruned")
+	}
+}
+
+func TestCommitBundles(t *testing.T) {
+	var (
+		cheap    = newBundleTx(0, 2, false)
+		rich     = newBundleTx(0, 3, false)
+		reverted = newBundleTx(0, 10, true)
+	)
+	tests := []struct {
+		bundles []*Bundle
+		want    common.Hash
+	}{
+		// The most profitable of the conflicting bundles is included
+		{
+			[]*Bundle{{Txs: types.Transactions{cheap}}, {Txs: types.Transactions{rich}}},
+			rich.Hash(),
+		},
+		// Bundles with reverting transactions are discarded
+		{
+			[]*Bundle{{Txs: types.Transactions{rich}}, {Txs: types.Transactions{reverted}}},
+			rich.Hash(),
+		},
+		// Unless the transaction was explicitly allowed to revert
+		{
+			[]*Bundle{{Txs: types.Transactions{rich}}, {Txs: types.Transactions{reverted}, RevertingTxHashes: []common.Hash{reverted.Hash()}}},
+			reverted.Hash(),
+		},
+	}
+	for i, tt := range tests {
+		engine := ethash.NewFaker()
+		w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+		w.skipSealHook = func(task *task) bool { return true }
+
+		for _, bundle := range tt.bundles {
+			bundle.BlockNumber = 1
+			if err := w.bundles.add(bundle, 0); err != nil {
+				t.Fatalf("test %d: failed to add bundle: %v", i, err)
+			}
+		}
+		block, _, err := w.getSealingBlock(b.chain.Genesis().Hash(), uint64(time.Now().Unix()), common.HexToAddress("0xdeadbeef"), common.Hash{}, nil, false)
+		if err != nil {
+			t.Fatalf("test %d: failed to generate block: %v", i, err)
+		}
+		// The pending pool transaction has the same nonce and must be dropped
+		if txs := block.Transactions(); len(txs) != 1 || txs[0].Hash() != tt.want {
+			t.Errorf("test %d: unexpected block transactions: have %d, want bundle tx %x", i, len(txs), tt.want)
+		}
+		w.close()
+		engine.Close()
+	}
+}
+
+func TestValidateBundle(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		head     = b.chain.CurrentBlock()
+		valid    = newBundleTx(0, 2, false)
+		next     = newBundleTx(1, 2, false)
+		foreign  = types.MustSignNewTx(testBankKey, types.NewEIP155Signer(big.NewInt(1337)), &types.LegacyTx{To: &testUserAddress, Gas: params.TxGas, GasPrice: big.NewInt(params.GWei)})
+		underpay = types.MustSignNewTx(testBankKey, types.LatestSigner(params.TestChainConfig), &types.LegacyTx{To: &testUserAddress, Gas: params.TxGas - 1, GasPrice: big.NewInt(params.GWei)})
+		greedy   = types.MustSignNewTx(testBankKey, types.LatestSigner(params.TestChainConfig), &types.LegacyTx{To: &testUserAddress, Gas: head.GasLimit + 1, GasPrice: big.NewInt(params.GWei)})
+	)
+	tests := []struct {
+		txs types.Transactions
+		err error
+	}{
+		{types.Transactions{valid, next}, nil},
+		{types.Transactions{foreign}, types.ErrInvalidChainId},
+		{types.Transactions{valid, valid}, core.ErrNonceTooLow},
+		{types.Transactions{underpay}, core.ErrIntrinsicGas},
+		{types.Transactions{greedy}, errBundleGasLimit},
+	}
+	for i, tt := range tests {
+		statedb, err := b.chain.StateAt(head.Root)
+		if err != nil {
+			t.Fatalf("failed to open state: %v", err)
+		}
+		bundle := &Bundle{Txs: tt.txs, BlockNumber: 1}
+		if err := validateBundle(w.chainConfig, bundle, head, statedb); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the bundles are only included in the blocks actually sealed, not
+// in the pending one.
+func TestBundlesNotPending(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+	w.setEtherbase(common.HexToAddress("0xdeadbeef"))
+
+	bundled := newBundleTx(0, 3, false)
+	if err := w.bundles.add(&Bundle{Txs: types.Transactions{bundled}, BlockNumber: 1}, 0); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	taskCh := make(chan *types.Block, 2)
+	w.newTaskHook = func(task *task) {
+		if task.block.NumberU64() == 1 && len(task.block.Transactions()) > 0 {
+			taskCh <- task.block
+		}
+	}
+	w.skipSealHook = func(task *task) bool { return true }
+	w.start()
+
+	select {
+	case block := <-taskCh:
+		if txs := block.Transactions(); len(txs) != 1 || txs[0].Hash() != bundled.Hash() {
+			t.Fatalf("sealing block misses bundle: have %d txs", len(txs))
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("new task timeout")
+	}
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		block := w.pendingBlock()
+		if block == nil || block.NumberU64() != 1 || len(block.Transactions()) == 0 {
+			continue
+		}
+		if txs := block.Transactions(); len(txs) != 1 || txs[0].Hash() != pendingTxs[0].Hash() {
+			t.Fatalf("pending block mismatch: have %d txs, want pool tx %x", len(txs), pendingTxs[0].Hash())
+		}
+		return
+	}
+	t.Fatal("pending block not updated")
+}
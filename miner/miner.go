@@ -51,6 +51,9 @@ type Config struct {
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
 
+	Stratum           string  `toml:",omitempty"` // TCP listening address of the stratum server (only useful in ethash).
+	StratumDifficulty float64 // Default and minimum share difficulty of stratum connections (only useful in ethash).
+
 	NewPayloadTimeout time.Duration // The maximum time allowance for creating a new payload
 }
 
@@ -65,6 +68,8 @@ var DefaultConfig = Config{
 	// run 3 rounds.
 	Recommit:          2 * time.Second,
 	NewPayloadTimeout: 2 * time.Second,
+
+	StratumDifficulty: 1,
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -241,6 +246,21 @@ func (miner *Miner) DisablePreseal() {
 	miner.worker.disablePreseal()
 }
 
+// AddBundle validates a bundle of transactions against the chain head and
+// schedules it for inclusion at the top of the block it targets, if profitable
+// enough.
+func (miner *Miner) AddBundle(bundle *Bundle) error {
+	head := miner.worker.chain.CurrentBlock()
+	statedb, err := miner.worker.chain.StateAt(head.Root)
+	if err != nil {
+		return err
+	}
+	if err := validateBundle(miner.worker.chainConfig, bundle, head, statedb); err != nil {
+		return err
+	}
+	return miner.worker.bundles.add(bundle, head.Number.Uint64())
+}
+
 // SubscribePendingLogs starts delivering logs from pending transactions
 // to the given channel.
 func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscription {
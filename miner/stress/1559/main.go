@@ -54,7 +54,7 @@ func main() {
 		faucets[i], _ = crypto.GenerateKey()
 	}
 	// Pre-generate the ethash mining DAG so we don't race
-	ethash.MakeDataset(1, ethconfig.Defaults.Ethash.DatasetDir)
+	ethash.MakeDataset(1, nil, ethconfig.Defaults.Ethash.DatasetDir)
 
 	// Create an Ethash network
 	genesis := makeGenesis(faucets)
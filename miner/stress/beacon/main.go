@@ -390,7 +390,7 @@ func main() {
 		faucets[i], _ = crypto.GenerateKey()
 	}
 	// Pre-generate the ethash mining DAG so we don't race
-	ethash.MakeDataset(1, filepath.Join(os.Getenv("HOME"), ".ethash"))
+	ethash.MakeDataset(1, nil, filepath.Join(os.Getenv("HOME"), ".ethash"))
 
 	// Create an Ethash network
 	genesis := makeGenesis(faucets)
@@ -95,6 +95,7 @@ type environment struct {
 	txs      []*types.Transaction
 	receipts []*types.Receipt
 	uncles   map[common.Hash]*types.Header
+	bundled  bool // Whether private bundles are included, keeping the block from being exposed as pending
 }
 
 // copy creates a deep copy of environment.
@@ -108,6 +109,7 @@ func (env *environment) copy() *environment {
 		coinbase:  env.coinbase,
 		header:    types.CopyHeader(env.header),
 		receipts:  copyReceipts(env.receipts),
+		bundled:   env.bundled,
 	}
 	if env.gasPool != nil {
 		gasPool := *env.gasPool
@@ -221,6 +223,7 @@ type worker struct {
 	localUncles  map[common.Hash]*types.Block // A set of side blocks generated locally as the possible uncle blocks.
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
+	bundles      *bundlePool                  // Privately submitted bundles waiting for the block they target.
 
 	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
 	coinbase common.Address
@@ -278,6 +281,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
 		unconfirmed:        newUnconfirmedBlocks(eth.BlockChain(), sealingLogAtDepth),
+		bundles:            newBundlePool(),
 		coinbase:           config.Etherbase,
 		extra:              config.ExtraData,
 		pendingTasks:       make(map[common.Hash]*task),
@@ -840,8 +844,12 @@ func (w *worker) commitUncle(env *environment, uncle *types.Header) error {
 	return nil
 }
 
-// updateSnapshot updates pending snapshot block, receipts and state.
+// updateSnapshot updates pending snapshot block, receipts and state. Blocks
+// including private bundles are never exposed as pending.
 func (w *worker) updateSnapshot(env *environment) {
+	if env.bundled {
+		return
+	}
 	w.snapshotMu.Lock()
 	defer w.snapshotMu.Unlock()
 
@@ -909,6 +917,20 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			txs.Pop()
 			continue
 		}
+		// Check whether the conditions of the tx hold in this block, ignoring the
+		// sender otherwise as its later transactions may depend on it.
+		if conds := tx.Conditions(); conds != nil {
+			err := conds.CheckBlock(env.header.Number, env.header.Time)
+			if err == nil {
+				err = conds.CheckState(env.state)
+			}
+			if err != nil {
+				log.Trace("Ignoring transaction with failed conditions", "hash", tx.Hash(), "err", err)
+
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.SetTxContext(tx.Hash(), env.tcount)
 
@@ -1046,9 +1068,16 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 }
 
 // fillTransactions retrieves the pending transactions from the txpool and fills them
-// into the given sealing block. The transaction selection and ordering strategy can
-// be customized with the plugin in the future.
-func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) error {
+// into the given sealing block, after the most profitable bundles targeting it if
+// requested. The transaction selection and ordering strategy can be customized with
+// the plugin in the future.
+func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment, bundles bool) error {
+	// Place the privately submitted bundles at the top of the block
+	if bundles {
+		if err := w.commitBundles(env, interrupt); err != nil {
+			return err
+		}
+	}
 	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
 	pending := w.eth.TxPool().Pending(true)
@@ -1089,7 +1118,7 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, e
 		})
 		defer timer.Stop()
 
-		err := w.fillTransactions(interrupt, work)
+		err := w.fillTransactions(interrupt, work, true)
 		if errors.Is(err, errBlockInterruptedByTimeout) {
 			log.Warn("Block building is interrupted", "allowance", common.PrettyDuration(w.newpayloadTimeout))
 		}
@@ -1098,7 +1127,7 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, e
 	if err != nil {
 		return nil, nil, err
 	}
-	return block, totalFees(block, work.receipts), nil
+	return block, totalFees(w.chainConfig, block, work.receipts), nil
 }
 
 // commitWork generates several new sealing tasks based on the parent block
@@ -1115,10 +1144,11 @@ func (w *worker) commitWork(interrupt *atomic.Int32, noempty bool, timestamp int
 			return
 		}
 	}
-	work, err := w.prepareWork(&generateParams{
+	genParams := &generateParams{
 		timestamp: uint64(timestamp),
 		coinbase:  coinbase,
-	})
+	}
+	work, err := w.prepareWork(genParams)
 	if err != nil {
 		return
 	}
@@ -1127,8 +1157,9 @@ func (w *worker) commitWork(interrupt *atomic.Int32, noempty bool, timestamp int
 	if !noempty && !w.noempty.Load() {
 		w.commit(work.copy(), nil, false, start)
 	}
-	// Fill pending transactions from the txpool into the block.
-	err = w.fillTransactions(interrupt, work)
+	// Fill pending transactions from the txpool into the block, along with the
+	// private bundles if the block is actually sealed.
+	err = w.fillTransactions(interrupt, work, w.isRunning())
 	switch {
 	case err == nil:
 		// The entire block is filled, decrease resubmit interval in case
@@ -1159,6 +1190,16 @@ func (w *worker) commitWork(interrupt *atomic.Int32, noempty bool, timestamp int
 	// Submit the generated block for consensus sealing.
 	w.commit(work.copy(), w.fullTaskHook, true, start)
 
+	// The sealing block can't be exposed as pending if it includes private
+	// bundles, build a separate one without them.
+	if work.bundled {
+		if pending, err := w.prepareWork(genParams); err == nil {
+			w.fillTransactions(interrupt, pending, false)
+			w.updateSnapshot(pending)
+			pending.discard()
+		}
+	}
+
 	// Swap out the old work with the new one, terminating any leftover
 	// prefetcher processes in the mean time and starting a new one.
 	if w.current != nil {
@@ -1190,7 +1231,7 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 			case w.taskCh <- &task{receipts: env.receipts, state: env.state, block: block, createdAt: time.Now()}:
 				w.unconfirmed.Shift(block.NumberU64() - 1)
 
-				fees := totalFees(block, env.receipts)
+				fees := totalFees(w.chainConfig, block, env.receipts)
 				feesInEther := new(big.Float).Quo(new(big.Float).SetInt(fees), big.NewFloat(params.Ether))
 				log.Info("Commit new sealing work", "number", block.Number(), "sealhash", w.engine.SealHash(block.Header()),
 					"uncles", len(env.uncles), "txs", env.tcount,
@@ -1262,13 +1303,18 @@ func (w *worker) postSideBlock(event core.ChainSideEvent) {
 	}
 }
 
-// totalFees computes total consumed miner fees in Wei. Block transactions and receipts have to have the same order.
-func totalFees(block *types.Block, receipts []*types.Receipt) *big.Int {
+// totalFees computes total consumed miner fees in Wei, including the base fee if
+// the chain pays it to the coinbase. Block transactions and receipts have to have
+// the same order.
+func totalFees(config *params.ChainConfig, block *types.Block, receipts []*types.Receipt) *big.Int {
 	feesWei := new(big.Int)
 	for i, tx := range block.Transactions() {
 		minerFee, _ := tx.EffectiveGasTip(block.BaseFee())
 		feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), minerFee))
 	}
+	if recipient := config.BaseFeeRecipient(block.Coinbase()); block.BaseFee() != nil && recipient != nil && *recipient == block.Coinbase() {
+		feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(block.GasUsed()), block.BaseFee()))
+	}
 	return feesWei
 }
 
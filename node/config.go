@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -132,6 +133,12 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// RPCQuotas limits the resources each client may consume over the HTTP and
+	// WebSocket RPC interfaces, including the authenticated ones whose clients
+	// may be told apart by their JWT subject. The limits must thus leave room
+	// for the consensus client. IPC and in-process clients are not limited.
+	RPCQuotas *rpc.QuotaConfig `toml:",omitempty"`
+
 	// AuthAddr is the listening address on which authenticated APIs are provided.
 	AuthAddr string `toml:",omitempty"`
 
@@ -185,6 +192,27 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLMaxDepth is the maximum nesting depth of the fields of a GraphQL
+	// query. Zero means unlimited.
+	GraphQLMaxDepth int `toml:",omitempty"`
+
+	// GraphQLMaxCost is the maximum total cost of the fields resolved for a
+	// GraphQL query. Fields reading the chain or the state are more expensive
+	// than plain ones. Zero means unlimited.
+	GraphQLMaxCost int `toml:",omitempty"`
+
+	// GraphQLTimeout is the maximum execution time of a GraphQL query. Zero
+	// means it is only limited by the HTTP timeouts.
+	GraphQLTimeout time.Duration `toml:",omitempty"`
+
+	// GraphQLPersistedQueries is the JSON file of GraphQL queries, keyed by their
+	// SHA-256 hash, that clients may reference by hash instead of sending them.
+	GraphQLPersistedQueries string `toml:",omitempty"`
+
+	// GraphQLAutoPersist is the number of GraphQL queries persisted automatically
+	// when clients send them along with their hash. Zero disables it.
+	GraphQLAutoPersist int `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -408,6 +408,7 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			quotas:             n.config.RPCQuotas,
 		}); err != nil {
 			return err
 		}
@@ -424,6 +425,7 @@ func (n *Node) startRPC() error {
 			Modules: n.config.WSModules,
 			Origins: n.config.WSOrigins,
 			prefix:  n.config.WSPathPrefix,
+			quotas:  n.config.RPCQuotas,
 		}); err != nil {
 			return err
 		}
@@ -443,6 +445,7 @@ func (n *Node) startRPC() error {
 			Modules:            DefaultAuthModules,
 			prefix:             DefaultAuthPrefix,
 			jwtSecret:          secret,
+			quotas:             n.config.RPCQuotas,
 		}); err != nil {
 			return err
 		}
@@ -457,6 +460,7 @@ func (n *Node) startRPC() error {
 			Origins:   DefaultAuthOrigins,
 			prefix:    DefaultAuthPrefix,
 			jwtSecret: secret,
+			quotas:    n.config.RPCQuotas,
 		}); err != nil {
 			return err
 		}
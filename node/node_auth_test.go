@@ -193,6 +193,53 @@ func TestAuthEndpoints(t *testing.T) {
 	}
 }
 
+// Tests that the RPC quotas also apply to the authenticated endpoints.
+func TestAuthEndpointQuotas(t *testing.T) {
+	var secret [32]byte
+	if _, err := crand.Read(secret[:]); err != nil {
+		t.Fatalf("failed to create jwt secret: %v", err)
+	}
+	jwtPath := path.Join(t.TempDir(), "jwt_secret")
+	if err := os.WriteFile(jwtPath, []byte(hexutil.Encode(secret[:])), 0600); err != nil {
+		t.Fatalf("failed to prepare jwt secret file: %v", err)
+	}
+	conf := &Config{
+		AuthAddr:  "127.0.0.1",
+		AuthPort:  0,
+		JWTSecret: jwtPath,
+		RPCQuotas: &rpc.QuotaConfig{KeyBy: rpc.QuotaKeyJWT, RequestRate: 0.001, RequestBurst: 1},
+	}
+	node, err := New(conf)
+	if err != nil {
+		t.Fatalf("could not create a new node: %v", err)
+	}
+	node.RegisterAPIs([]rpc.API{{
+		Namespace:     "engine",
+		Service:       helloRPC("hello engine"),
+		Authenticated: true,
+	}})
+	if err := node.Start(); err != nil {
+		t.Fatalf("failed to start test node: %v", err)
+	}
+	defer node.Close()
+
+	for _, endpoint := range []string{node.HTTPAuthEndpoint(), node.WSAuthEndpoint()} {
+		cl, err := rpc.DialOptions(context.Background(), endpoint, rpc.WithHTTPAuth(NewJWTAuth(secret)))
+		if err != nil {
+			t.Fatalf("%s: failed to dial: %v", endpoint, err)
+		}
+		var x string
+		if err := cl.Call(&x, "engine_helloWorld"); err != nil {
+			t.Fatalf("%s: call within quota failed: %v", endpoint, err)
+		}
+		err = cl.Call(&x, "engine_helloWorld")
+		if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != -32005 {
+			t.Fatalf("%s: call beyond quota error mismatch: have %v, want limit exceeded", endpoint, err)
+		}
+		cl.Close()
+	}
+}
+
 func noneAuth(secret [32]byte) rpc.HTTPAuth {
 	return func(header http.Header) error {
 		token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
@@ -0,0 +1,102 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package params
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Destinations of the EIP-1559 base fee selectable by a BaseFeeConfig.
+const (
+	BaseFeeBurn     = "burn"     // Base fee is destroyed, as on Ethereum
+	BaseFeeCoinbase = "coinbase" // Base fee is paid to the miner of the block, along with the tip
+	BaseFeeTreasury = "treasury" // Base fee is paid to a fixed treasury address
+)
+
+// BaseFeeConfig is the destination of the base fee paid by the transactions
+// after the London fork.
+type BaseFeeConfig struct {
+	Recipient string          `json:"recipient"`          // Destination of the base fee (burn, coinbase or treasury)
+	Treasury  *common.Address `json:"treasury,omitempty"` // Address receiving the base fee if sent to the treasury
+}
+
+// String implements the stringer interface, returning a short description of
+// the base fee destination.
+func (c *BaseFeeConfig) String() string {
+	switch c.Recipient {
+	case BaseFeeCoinbase:
+		return "paid to the coinbase"
+	case BaseFeeTreasury:
+		return fmt.Sprintf("paid to the treasury %v", c.Treasury)
+	default:
+		return "burned"
+	}
+}
+
+// validate checks that the base fee destination is well formed.
+func (c *BaseFeeConfig) validate() error {
+	switch c.Recipient {
+	case BaseFeeBurn, BaseFeeCoinbase:
+		if c.Treasury != nil {
+			return fmt.Errorf("invalid base fee destination %q: unexpected treasury address", c.Recipient)
+		}
+		return nil
+	case BaseFeeTreasury:
+		if c.Treasury == nil {
+			return fmt.Errorf("invalid base fee destination %q: missing treasury address", c.Recipient)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown base fee destination %q", c.Recipient)
+	}
+}
+
+// BaseFeeRecipient returns the account receiving the base fee of the blocks
+// mined by the given coinbase, or nil if the base fee is burned.
+func (c *ChainConfig) BaseFeeRecipient(coinbase common.Address) *common.Address {
+	if c.BaseFeeDestination == nil {
+		return nil
+	}
+	switch c.BaseFeeDestination.Recipient {
+	case BaseFeeCoinbase:
+		return &coinbase
+	case BaseFeeTreasury:
+		return c.BaseFeeDestination.Treasury
+	default:
+		return nil
+	}
+}
+
+// isBaseFeeIncompatible returns whether the base fee destinations d1 and d2
+// differ. A nil destination burns the base fee.
+func isBaseFeeIncompatible(d1, d2 *BaseFeeConfig) bool {
+	var (
+		r1, r2 = BaseFeeBurn, BaseFeeBurn
+		t1, t2 common.Address
+	)
+	if d1 != nil {
+		r1 = d1.Recipient
+		if d1.Treasury != nil {
+			t1 = *d1.Treasury
+		}
+	}
+	if d2 != nil {
+		r2 = d2.Recipient
+		if d2.Treasury != nil {
+			t2 = *d2.Treasury
+		}
+	}
+	return r1 != r2 || t1 != t2
+}
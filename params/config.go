@@ -62,6 +62,13 @@ var (
 		PetersburgBlock:     big.NewInt(0),
 		IstanbulBlock:       big.NewInt(0),
 		BerlinBlock:         big.NewInt(0),
+
+		// London is not scheduled on the main network yet: its activation block
+		// and the destination of the base fee are left to be agreed on, after
+		// which they go here. Until then, nodes can only run it through the
+		// --override.london flag, burning the base fee.
+		LondonBlock:        nil,
+		BaseFeeDestination: nil,
 	}
 
 	// MainnetTrustedCheckpoint contains the light client trusted checkpoint for the main network.
@@ -393,6 +400,7 @@ type ChainConfig struct {
 	ArrowGlacierBlock   *big.Int `json:"arrowGlacierBlock,omitempty"`   // Eip-4345 (bomb delay) switch block (nil = no fork, 0 = already activated)
 	GrayGlacierBlock    *big.Int `json:"grayGlacierBlock,omitempty"`    // Eip-5133 (bomb delay) switch block (nil = no fork, 0 = already activated)
 	MergeNetsplitBlock  *big.Int `json:"mergeNetsplitBlock,omitempty"`  // Virtual fork after The Merge to use as a network splitter
+	EVMUpgradeBlock     *big.Int `json:"evmUpgradeBlock,omitempty"`     // Shanghai and Cancun EVM changes without withdrawals, for proof-of-work (nil = no fork)
 
 	// Fork scheduling was switched from blocks to timestamps here
 
@@ -409,13 +417,22 @@ type ChainConfig struct {
 	// even without having seen the TTD locally (safer long term).
 	TerminalTotalDifficultyPassed bool `json:"terminalTotalDifficultyPassed,omitempty"`
 
+	// BaseFeeDestination is where the base fee paid by the transactions goes
+	// after London (nil = burned, as on Ethereum).
+	BaseFeeDestination *BaseFeeConfig `json:"baseFeeDestination,omitempty"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
-type EthashConfig struct{}
+type EthashConfig struct {
+	Emission   *EmissionConfig     `json:"emission,omitempty"`   // Block reward schedule (nil = constant 1 BIT per block)
+	Difficulty []*DifficultyConfig `json:"difficulty,omitempty"` // Difficulty algorithm switches (nil = legacy ethash rules)
+
+	ECIP1099Block *big.Int `json:"ecip1099Block,omitempty"` // Block from which epochs double in length, shrinking the DAG (nil = no fork)
+}
 
 // String implements the stringer interface, returning the consensus engine details.
 func (c *EthashConfig) String() string {
@@ -464,6 +481,24 @@ func (c *ChainConfig) Description() string {
 	default:
 		banner += "Consensus: PoW (Ethash)\n"
 	}
+	if c.Ethash != nil && c.Ethash.Emission != nil {
+		banner += fmt.Sprintf("Emission:  %v\n", c.Ethash.Emission)
+	}
+	if c.Ethash != nil {
+		for _, algo := range c.Ethash.Difficulty {
+			banner += fmt.Sprintf("Retarget:  %v\n", algo)
+		}
+		if c.Ethash.ECIP1099Block != nil {
+			banner += fmt.Sprintf("DAG:       epoch length %d from block %v (ECIP-1099)\n", EpochDurationECIP1099, c.Ethash.ECIP1099Block)
+		}
+	}
+	if c.LondonBlock != nil {
+		base := "burned"
+		if c.BaseFeeDestination != nil {
+			base = c.BaseFeeDestination.String()
+		}
+		banner += fmt.Sprintf("Base fee:  %s from block %v (London)\n", base, c.LondonBlock)
+	}
 	banner += "\n"
 
 	// Create a list of forks with a short description of them. Forks that only
@@ -546,6 +581,12 @@ func (c *ChainConfig) IsGrayGlacier(num *big.Int) bool {
 	return isBlockForked(c.GrayGlacierBlock, num)
 }
 
+// IsEVMUpgrade returns whether num is either equal to the EVM upgrade fork block
+// or greater.
+func (c *ChainConfig) IsEVMUpgrade(num *big.Int) bool {
+	return isBlockForked(c.EVMUpgradeBlock, num)
+}
+
 // IsTerminalPoWBlock returns whether the given block is the last block of PoW stage.
 func (c *ChainConfig) IsTerminalPoWBlock(parentTotalDiff *big.Int, totalDiff *big.Int) bool {
 	if c.TerminalTotalDifficulty == nil {
@@ -620,6 +661,7 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		{name: "arrowGlacierBlock", block: c.ArrowGlacierBlock, optional: true},
 		{name: "grayGlacierBlock", block: c.GrayGlacierBlock, optional: true},
 		{name: "mergeNetsplitBlock", block: c.MergeNetsplitBlock, optional: true},
+		{name: "evmUpgradeBlock", block: c.EVMUpgradeBlock, optional: true},
 		{name: "shanghaiTime", timestamp: c.ShanghaiTime},
 		{name: "cancunTime", timestamp: c.CancunTime, optional: true},
 		{name: "pragueTime", timestamp: c.PragueTime, optional: true},
@@ -658,6 +700,31 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	// Make sure the block reward steps are ordered too
+	if c.Ethash != nil && c.Ethash.Emission != nil {
+		if err := c.Ethash.Emission.validate(); err != nil {
+			return err
+		}
+	}
+	// Make sure the difficulty algorithm switches are ordered too
+	if c.Ethash != nil {
+		if err := validateDifficulty(c.Ethash.Difficulty); err != nil {
+			return err
+		}
+	}
+	// Make sure the epochs are lengthened at an epoch boundary
+	if c.Ethash != nil && c.Ethash.ECIP1099Block != nil {
+		block := c.Ethash.ECIP1099Block
+		if !block.IsUint64() || block.Uint64()%EpochDurationECIP1099 != 0 {
+			return fmt.Errorf("invalid ECIP-1099 fork block %v: not a multiple of %d", block, EpochDurationECIP1099)
+		}
+	}
+	// Make sure the base fee has somewhere to go
+	if c.BaseFeeDestination != nil {
+		if err := c.BaseFeeDestination.validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -708,6 +775,12 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkBlockIncompatible(c.LondonBlock, newcfg.LondonBlock, headNumber) {
 		return newBlockCompatError("London fork block", c.LondonBlock, newcfg.LondonBlock)
 	}
+	if isForkBlockIncompatible(c.EVMUpgradeBlock, newcfg.EVMUpgradeBlock, headNumber) {
+		return newBlockCompatError("EVM upgrade fork block", c.EVMUpgradeBlock, newcfg.EVMUpgradeBlock)
+	}
+	if isBaseFeeIncompatible(c.BaseFeeDestination, newcfg.BaseFeeDestination) && isForkBlockIncompatible(c.LondonBlock, nil, headNumber) {
+		return newBlockCompatError("Base fee destination", c.LondonBlock, newcfg.LondonBlock)
+	}
 	if isForkBlockIncompatible(c.ArrowGlacierBlock, newcfg.ArrowGlacierBlock, headNumber) {
 		return newBlockCompatError("Arrow Glacier fork block", c.ArrowGlacierBlock, newcfg.ArrowGlacierBlock)
 	}
@@ -717,6 +790,15 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkBlockIncompatible(c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock, headNumber) {
 		return newBlockCompatError("Merge netsplit fork block", c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock)
 	}
+	if block, ok := isEmissionIncompatible(c.emission(), newcfg.emission(), headNumber); ok {
+		return newBlockCompatError("Emission schedule", new(big.Int).SetUint64(block), new(big.Int).SetUint64(block))
+	}
+	if block, ok := isDifficultyIncompatible(c.difficulty(), newcfg.difficulty(), headNumber); ok {
+		return newBlockCompatError("Difficulty algorithm", block, block)
+	}
+	if isForkBlockIncompatible(c.ecip1099Block(), newcfg.ecip1099Block(), headNumber) {
+		return newBlockCompatError("ECIP-1099 fork block", c.ecip1099Block(), newcfg.ecip1099Block())
+	}
 	if isForkTimestampIncompatible(c.ShanghaiTime, newcfg.ShanghaiTime, headTimestamp) {
 		return newTimestampCompatError("Shanghai fork timestamp", c.ShanghaiTime, newcfg.ShanghaiTime)
 	}
@@ -729,6 +811,63 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	return nil
 }
 
+// emission returns the configured block reward schedule, if any.
+func (c *ChainConfig) emission() *EmissionConfig {
+	if c.Ethash == nil {
+		return nil
+	}
+	return c.Ethash.Emission
+}
+
+// difficulty returns the configured difficulty algorithm switches, if any.
+func (c *ChainConfig) difficulty() []*DifficultyConfig {
+	if c.Ethash == nil {
+		return nil
+	}
+	return c.Ethash.Difficulty
+}
+
+// ecip1099Block returns the block from which the ethash epochs are lengthened,
+// if any.
+func (c *ChainConfig) ecip1099Block() *big.Int {
+	if c.Ethash == nil {
+		return nil
+	}
+	return c.Ethash.ECIP1099Block
+}
+
+// IsECIP1099 returns whether num is either equal to the ECIP-1099 fork block or
+// greater, doubling the length of the ethash epochs.
+func (c *ChainConfig) IsECIP1099(num *big.Int) bool {
+	return isBlockForked(c.ecip1099Block(), num)
+}
+
+// ECIP1099Block returns the block from which the ethash epochs are lengthened
+// as a plain number, or nil if they never are.
+func (c *ChainConfig) ECIP1099Block() *uint64 {
+	block := c.ecip1099Block()
+	if block == nil {
+		return nil
+	}
+	num := block.Uint64()
+	return &num
+}
+
+// DifficultyAlgorithm returns the difficulty algorithm switch in effect at the
+// given block, or nil if the legacy ethash rules apply.
+func (c *ChainConfig) DifficultyAlgorithm(num *big.Int) *DifficultyConfig {
+	configs := c.difficulty()
+	for i := len(configs) - 1; i >= 0; i-- {
+		if isBlockForked(configs[i].Block, num) {
+			if configs[i].Algorithm == DifficultyLegacy {
+				return nil
+			}
+			return configs[i]
+		}
+	}
+	return nil
+}
+
 // BaseFeeChangeDenominator bounds the amount the base fee can change between blocks.
 func (c *ChainConfig) BaseFeeChangeDenominator() uint64 {
 	return DefaultBaseFeeChangeDenominator
@@ -869,7 +1008,7 @@ type Rules struct {
 	ChainID                                                 *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158               bool
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
-	IsBerlin, IsLondon                                      bool
+	IsBerlin, IsLondon, IsEVMUpgrade                        bool
 	IsMerge, IsShanghai, IsCancun, IsPrague                 bool
 }
 
@@ -891,6 +1030,7 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool, timestamp uint64) Rules
 		IsIstanbul:       c.IsIstanbul(num),
 		IsBerlin:         c.IsBerlin(num),
 		IsLondon:         c.IsLondon(num),
+		IsEVMUpgrade:     c.IsEVMUpgrade(num),
 		IsMerge:          isMerge,
 		IsShanghai:       c.IsShanghai(timestamp),
 		IsCancun:         c.IsCancun(timestamp),
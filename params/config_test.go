@@ -18,6 +18,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 )
 
@@ -88,6 +89,57 @@ func TestCheckCompatible(t *testing.T) {
 				RewindToBlock: 30,
 			},
 		},
+		{
+			stored:    &ChainConfig{Ethash: &EthashConfig{ECIP1099Block: big.NewInt(120000)}},
+			new:       &ChainConfig{Ethash: &EthashConfig{ECIP1099Block: big.NewInt(180000)}},
+			headBlock: 119999,
+			wantErr:   nil,
+		},
+		{
+			stored:    &ChainConfig{Ethash: &EthashConfig{ECIP1099Block: big.NewInt(120000)}},
+			new:       &ChainConfig{Ethash: new(EthashConfig)},
+			headBlock: 120000,
+			wantErr: &ConfigCompatError{
+				What:          "ECIP-1099 fork block",
+				StoredBlock:   big.NewInt(120000),
+				NewBlock:      nil,
+				RewindToBlock: 119999,
+			},
+		},
+		{
+			stored:    &ChainConfig{EVMUpgradeBlock: big.NewInt(30)},
+			new:       &ChainConfig{EVMUpgradeBlock: big.NewInt(40)},
+			headBlock: 35,
+			wantErr: &ConfigCompatError{
+				What:          "EVM upgrade fork block",
+				StoredBlock:   big.NewInt(30),
+				NewBlock:      big.NewInt(40),
+				RewindToBlock: 29,
+			},
+		},
+		{
+			stored:    &ChainConfig{LondonBlock: big.NewInt(100)},
+			new:       &ChainConfig{LondonBlock: big.NewInt(100), BaseFeeDestination: &BaseFeeConfig{Recipient: BaseFeeCoinbase}},
+			headBlock: 99,
+			wantErr:   nil,
+		},
+		{
+			stored:    &ChainConfig{LondonBlock: big.NewInt(100)},
+			new:       &ChainConfig{LondonBlock: big.NewInt(100), BaseFeeDestination: &BaseFeeConfig{Recipient: BaseFeeBurn}},
+			headBlock: 200,
+			wantErr:   nil,
+		},
+		{
+			stored:    &ChainConfig{LondonBlock: big.NewInt(100), BaseFeeDestination: &BaseFeeConfig{Recipient: BaseFeeTreasury, Treasury: &common.Address{1}}},
+			new:       &ChainConfig{LondonBlock: big.NewInt(100), BaseFeeDestination: &BaseFeeConfig{Recipient: BaseFeeTreasury, Treasury: &common.Address{2}}},
+			headBlock: 200,
+			wantErr: &ConfigCompatError{
+				What:          "Base fee destination",
+				StoredBlock:   big.NewInt(100),
+				NewBlock:      big.NewInt(100),
+				RewindToBlock: 99,
+			},
+		},
 		{
 			stored:        &ChainConfig{ShanghaiTime: newUint64(10)},
 			new:           &ChainConfig{ShanghaiTime: newUint64(20)},
@@ -115,6 +167,46 @@ func TestCheckCompatible(t *testing.T) {
 	}
 }
 
+// Tests that the ECIP-1099 fork is only accepted at an epoch boundary.
+func TestCheckECIP1099Block(t *testing.T) {
+	for _, tt := range []struct {
+		block *big.Int
+		valid bool
+	}{
+		{nil, true},
+		{big.NewInt(0), true},
+		{big.NewInt(11_700_000), true},
+		{big.NewInt(30000), false},
+		{big.NewInt(60001), false},
+	} {
+		config := &ChainConfig{Ethash: &EthashConfig{ECIP1099Block: tt.block}}
+		if err := config.CheckConfigForkOrder(); (err == nil) != tt.valid {
+			t.Errorf("block %v: validity mismatch: have %v, want valid %v", tt.block, err, tt.valid)
+		}
+	}
+}
+
+// Tests that the base fee destination is validated.
+func TestCheckBaseFeeDestination(t *testing.T) {
+	for _, tt := range []struct {
+		dest  *BaseFeeConfig
+		valid bool
+	}{
+		{nil, true},
+		{&BaseFeeConfig{Recipient: BaseFeeBurn}, true},
+		{&BaseFeeConfig{Recipient: BaseFeeCoinbase}, true},
+		{&BaseFeeConfig{Recipient: BaseFeeTreasury, Treasury: &common.Address{1}}, true},
+		{&BaseFeeConfig{Recipient: BaseFeeTreasury}, false},
+		{&BaseFeeConfig{Recipient: BaseFeeCoinbase, Treasury: &common.Address{1}}, false},
+		{&BaseFeeConfig{Recipient: "miner"}, false},
+	} {
+		config := &ChainConfig{BaseFeeDestination: tt.dest}
+		if err := config.CheckConfigForkOrder(); (err == nil) != tt.valid {
+			t.Errorf("destination %v: validity mismatch: have %v, want valid %v", tt.dest, err, tt.valid)
+		}
+	}
+}
+
 func TestConfigRules(t *testing.T) {
 	c := &ChainConfig{
 		ShanghaiTime: newUint64(500),
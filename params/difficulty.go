@@ -0,0 +1,147 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package params
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Difficulty adjustment algorithms selectable by a DifficultyConfig.
+const (
+	DifficultyLegacy = "ethash"     // Bomb-free Byzantium rules, adjusting on the parent block only
+	DifficultyLWMA1  = "lwma1"      // Linearly weighted moving average over a window of blocks
+	DifficultyASERT  = "aserti3-2d" // Absolutely scheduled exponential rise targeting, anchored at the fork
+)
+
+const (
+	// DefaultLWMAWindow is the number of blocks averaged by LWMA-1 if the config
+	// does not specify it.
+	DefaultLWMAWindow = 90
+
+	// DefaultASERTHalfLife is the number of target block times after which a
+	// schedule deviation halves or doubles the ASERT difficulty, if the config
+	// does not specify a half-life.
+	DefaultASERTHalfLife = 288
+)
+
+// DifficultyConfig is a switch of the difficulty adjustment algorithm, taking
+// effect from the given block on.
+type DifficultyConfig struct {
+	Block      *big.Int `json:"block"`              // Block number from which the algorithm applies
+	Algorithm  string   `json:"algorithm"`          // Difficulty adjustment algorithm (ethash, lwma1 or aserti3-2d)
+	TargetTime uint64   `json:"targetTime"`         // Target block time in seconds
+	Window     uint64   `json:"window,omitempty"`   // Number of blocks averaged by LWMA-1 (0 = DefaultLWMAWindow)
+	HalfLife   uint64   `json:"halfLife,omitempty"` // ASERT half-life in seconds (0 = DefaultASERTHalfLife target times)
+}
+
+// String implements the stringer interface, returning a short description of
+// the difficulty algorithm.
+func (c *DifficultyConfig) String() string {
+	switch c.Algorithm {
+	case DifficultyLWMA1:
+		return fmt.Sprintf("%s (block %v, target %ds, window %d)", c.Algorithm, c.Block, c.TargetTime, c.WindowSize())
+	case DifficultyASERT:
+		return fmt.Sprintf("%s (block %v, target %ds, half-life %ds)", c.Algorithm, c.Block, c.TargetTime, c.HalfLifeTime())
+	default:
+		return fmt.Sprintf("%s (block %v)", c.Algorithm, c.Block)
+	}
+}
+
+// WindowSize returns the number of blocks averaged by LWMA-1.
+func (c *DifficultyConfig) WindowSize() uint64 {
+	if c.Window == 0 {
+		return DefaultLWMAWindow
+	}
+	return c.Window
+}
+
+// HalfLifeTime returns the ASERT half-life in seconds.
+func (c *DifficultyConfig) HalfLifeTime() uint64 {
+	if c.HalfLife == 0 {
+		return DefaultASERTHalfLife * c.TargetTime
+	}
+	return c.HalfLife
+}
+
+// validate checks that the difficulty algorithm switch is well formed.
+func (c *DifficultyConfig) validate() error {
+	if c.Block == nil || !c.Block.IsUint64() {
+		return fmt.Errorf("invalid difficulty algorithm %q: missing or malformed block", c.Algorithm)
+	}
+	switch c.Algorithm {
+	case DifficultyLegacy:
+		return nil
+	case DifficultyLWMA1, DifficultyASERT:
+		if c.TargetTime == 0 {
+			return fmt.Errorf("invalid difficulty algorithm %q at block %v: zero target time", c.Algorithm, c.Block)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown difficulty algorithm %q at block %v", c.Algorithm, c.Block)
+	}
+}
+
+// equal returns whether the two switches select the same algorithm at the same
+// block with the same parameters.
+func (c *DifficultyConfig) equal(other *DifficultyConfig) bool {
+	if c.Block.Cmp(other.Block) != 0 || c.Algorithm != other.Algorithm {
+		return false
+	}
+	switch c.Algorithm {
+	case DifficultyLWMA1:
+		return c.TargetTime == other.TargetTime && c.WindowSize() == other.WindowSize()
+	case DifficultyASERT:
+		return c.TargetTime == other.TargetTime && c.HalfLifeTime() == other.HalfLifeTime()
+	default:
+		return true
+	}
+}
+
+// validateDifficulty checks that the difficulty algorithm switches are well
+// formed and scheduled in ascending block order.
+func validateDifficulty(configs []*DifficultyConfig) error {
+	for i, config := range configs {
+		if err := config.validate(); err != nil {
+			return err
+		}
+		if i > 0 && configs[i-1].Block.Cmp(config.Block) >= 0 {
+			return fmt.Errorf("unsupported difficulty algorithm ordering: %q at block %v not after %q at block %v",
+				config.Algorithm, config.Block, configs[i-1].Algorithm, configs[i-1].Block)
+		}
+	}
+	return nil
+}
+
+// isDifficultyIncompatible returns the first block at which the difficulty
+// algorithm switches s1 and s2 diverge, if the head is already past it.
+func isDifficultyIncompatible(s1, s2 []*DifficultyConfig, head *big.Int) (*big.Int, bool) {
+	var diverge *big.Int
+	for i := 0; diverge == nil && (i < len(s1) || i < len(s2)); i++ {
+		switch {
+		case i >= len(s1):
+			diverge = s2[i].Block
+		case i >= len(s2):
+			diverge = s1[i].Block
+		case !s1[i].equal(s2[i]):
+			diverge = s1[i].Block
+			if s2[i].Block.Cmp(diverge) < 0 {
+				diverge = s2[i].Block
+			}
+		}
+	}
+	if diverge == nil || head.Cmp(diverge) < 0 {
+		return nil, false
+	}
+	return diverge, true
+}
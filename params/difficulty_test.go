@@ -0,0 +1,104 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package params
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDifficultyAlgorithmSelection(t *testing.T) {
+	config := &ChainConfig{Ethash: &EthashConfig{Difficulty: []*DifficultyConfig{
+		{Block: big.NewInt(10), Algorithm: DifficultyLWMA1, TargetTime: 15},
+		{Block: big.NewInt(20), Algorithm: DifficultyASERT, TargetTime: 15},
+		{Block: big.NewInt(30), Algorithm: DifficultyLegacy},
+	}}}
+	tests := []struct {
+		number uint64
+		want   string
+	}{
+		{0, ""}, {9, ""}, {10, DifficultyLWMA1}, {19, DifficultyLWMA1},
+		{20, DifficultyASERT}, {29, DifficultyASERT}, {30, ""}, {1_000_000, ""},
+	}
+	for _, tt := range tests {
+		var have string
+		if algo := config.DifficultyAlgorithm(new(big.Int).SetUint64(tt.number)); algo != nil {
+			have = algo.Algorithm
+		}
+		if have != tt.want {
+			t.Errorf("block %d: algorithm mismatch: have %q, want %q", tt.number, have, tt.want)
+		}
+	}
+	if algo := (&ChainConfig{}).DifficultyAlgorithm(big.NewInt(1)); algo != nil {
+		t.Errorf("algorithm selected without ethash config: %v", algo)
+	}
+}
+
+func TestDifficultyValidation(t *testing.T) {
+	tests := [][]*DifficultyConfig{
+		{{Algorithm: DifficultyLWMA1, TargetTime: 15}},
+		{{Block: big.NewInt(1), Algorithm: "sha256", TargetTime: 15}},
+		{{Block: big.NewInt(1), Algorithm: DifficultyASERT}},
+		{
+			{Block: big.NewInt(2), Algorithm: DifficultyLWMA1, TargetTime: 15},
+			{Block: big.NewInt(2), Algorithm: DifficultyASERT, TargetTime: 15},
+		},
+	}
+	for i, configs := range tests {
+		chainConfig := &ChainConfig{Ethash: &EthashConfig{Difficulty: configs}}
+		if err := chainConfig.CheckConfigForkOrder(); err == nil {
+			t.Errorf("test %d: invalid difficulty algorithm accepted", i)
+		}
+	}
+}
+
+func TestDifficultyCompatible(t *testing.T) {
+	lwma := &ChainConfig{Ethash: &EthashConfig{Difficulty: []*DifficultyConfig{
+		{Block: big.NewInt(100), Algorithm: DifficultyLWMA1, TargetTime: 15},
+	}}}
+	lwmaWindow := &ChainConfig{Ethash: &EthashConfig{Difficulty: []*DifficultyConfig{
+		{Block: big.NewInt(100), Algorithm: DifficultyLWMA1, TargetTime: 15, Window: DefaultLWMAWindow},
+	}}}
+	lwmaLater := &ChainConfig{Ethash: &EthashConfig{Difficulty: []*DifficultyConfig{
+		{Block: big.NewInt(150), Algorithm: DifficultyLWMA1, TargetTime: 15},
+	}}}
+	asert := &ChainConfig{Ethash: &EthashConfig{Difficulty: []*DifficultyConfig{
+		{Block: big.NewInt(100), Algorithm: DifficultyLWMA1, TargetTime: 15},
+		{Block: big.NewInt(200), Algorithm: DifficultyASERT, TargetTime: 15},
+	}}}
+	legacy := &ChainConfig{Ethash: new(EthashConfig)}
+
+	tests := []struct {
+		stored, new *ChainConfig
+		head        uint64
+		rewind      uint64
+		incompat    bool
+	}{
+		{stored: legacy, new: lwma, head: 99},
+		{stored: legacy, new: lwma, head: 100, rewind: 99, incompat: true},
+		{stored: lwma, new: lwmaWindow, head: 1_000_000},
+		{stored: lwma, new: lwmaLater, head: 120, rewind: 99, incompat: true},
+		{stored: lwma, new: asert, head: 199},
+		{stored: asert, new: lwma, head: 200, rewind: 199, incompat: true},
+	}
+	for i, tt := range tests {
+		err := tt.stored.CheckCompatible(tt.new, tt.head, 0)
+		if (err != nil) != tt.incompat {
+			t.Errorf("test %d: compatibility mismatch: have %v, want incompatible %v", i, err, tt.incompat)
+			continue
+		}
+		if err != nil && err.RewindToBlock != tt.rewind {
+			t.Errorf("test %d: rewind mismatch: have %d, want %d", i, err.RewindToBlock, tt.rewind)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package params
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// EmissionConfig is the block reward schedule of a proof-of-work chain.
+//
+// The base reward starts at InitialReward and is replaced by the reward of each
+// step once its block is reached. Within every such range the reward halves each
+// HalvingInterval blocks, but never drops below TailReward. If MaxSupply is set,
+// no more block rewards are paid once their sum reaches it. Uncle rewards are
+// derived from the block reward, but are not accounted against the cap.
+type EmissionConfig struct {
+	InitialReward      *big.Int       `json:"initialReward"`                // Block reward in wei until the first step
+	HalvingInterval    uint64         `json:"halvingInterval,omitempty"`    // Blocks between reward halvings, counted from the last step (0 = no halvings)
+	Steps              []EmissionStep `json:"steps,omitempty"`              // Block reward changes scheduled at fork blocks
+	TailReward         *big.Int       `json:"tailReward,omitempty"`         // Minimum block reward in wei (nil = no tail emission)
+	MaxSupply          *big.Int       `json:"maxSupply,omitempty"`          // Cap in wei on the sum of all block rewards (nil = no cap)
+	UncleRewardPercent *uint64        `json:"uncleRewardPercent,omitempty"` // Ratio of the standard uncle reward paid to uncles (nil = 100)
+}
+
+// EmissionStep is a block reward change scheduled at a fork block.
+type EmissionStep struct {
+	Block  *big.Int `json:"block"`  // Block number from which the reward applies
+	Reward *big.Int `json:"reward"` // Block reward in wei, halving from this block on
+}
+
+// String implements the stringer interface, returning a short description of
+// the emission schedule.
+func (c *EmissionConfig) String() string {
+	desc := fmt.Sprintf("initial reward %v wei", c.InitialReward)
+	if c.HalvingInterval > 0 {
+		desc += fmt.Sprintf(", halving every %d blocks", c.HalvingInterval)
+	}
+	if len(c.Steps) > 0 {
+		desc += fmt.Sprintf(", %d scheduled steps", len(c.Steps))
+	}
+	if c.TailReward != nil {
+		desc += fmt.Sprintf(", tail reward %v wei", c.TailReward)
+	}
+	if c.MaxSupply != nil {
+		desc += fmt.Sprintf(", capped at %v wei", c.MaxSupply)
+	}
+	return desc
+}
+
+// validate checks that the emission schedule is well formed.
+func (c *EmissionConfig) validate() error {
+	if c.InitialReward == nil || c.InitialReward.Sign() < 0 {
+		return errors.New("invalid emission schedule: missing or negative initial reward")
+	}
+	if c.TailReward != nil && c.TailReward.Sign() < 0 {
+		return errors.New("invalid emission schedule: negative tail reward")
+	}
+	if c.MaxSupply != nil && c.MaxSupply.Sign() < 0 {
+		return errors.New("invalid emission schedule: negative max supply")
+	}
+	for i, step := range c.Steps {
+		if step.Block == nil || !step.Block.IsUint64() || step.Reward == nil || step.Reward.Sign() < 0 {
+			return fmt.Errorf("invalid emission schedule: malformed step %d", i)
+		}
+		if i > 0 && c.Steps[i-1].Block.Cmp(step.Block) >= 0 {
+			return fmt.Errorf("invalid emission schedule: step %d at block %v not after block %v", i, step.Block, c.Steps[i-1].Block)
+		}
+	}
+	return nil
+}
+
+// UncleRewardRatio returns the percentage of the standard uncle reward that is
+// paid to the miners of uncle blocks.
+func (c *EmissionConfig) UncleRewardRatio() uint64 {
+	if c.UncleRewardPercent == nil {
+		return 100
+	}
+	return *c.UncleRewardPercent
+}
+
+// BlockReward returns the reward in wei paid for mining the given block.
+func (c *EmissionConfig) BlockReward(num *big.Int) *big.Int {
+	if num.Sign() <= 0 {
+		return new(big.Int)
+	}
+	if !num.IsUint64() || num.Uint64() == math.MaxUint64 {
+		return c.cappedReward(math.MaxUint64 - 1)
+	}
+	return c.cappedReward(num.Uint64())
+}
+
+// cappedReward returns the block reward of the given block after applying the
+// supply cap. As every capped reward is the scheduled one up to the remaining
+// supply, the capped sum of rewards up to any block is min(cap, scheduled sum).
+func (c *EmissionConfig) cappedReward(num uint64) *big.Int {
+	if c.MaxSupply == nil {
+		return c.scheduledReward(num)
+	}
+	prev, next := c.emitted(num), c.emitted(num+1)
+	if prev.Cmp(c.MaxSupply) > 0 {
+		prev.Set(c.MaxSupply)
+	}
+	if next.Cmp(c.MaxSupply) > 0 {
+		next.Set(c.MaxSupply)
+	}
+	return next.Sub(next, prev)
+}
+
+// scheduledReward returns the block reward of the given block, ignoring the
+// supply cap.
+func (c *EmissionConfig) scheduledReward(num uint64) *big.Int {
+	reward := new(big.Int)
+	c.periods(func(start, end uint64, r *big.Int) bool {
+		if num < end {
+			reward.Set(r)
+			return false
+		}
+		return true
+	})
+	return reward
+}
+
+// emitted returns the sum of the scheduled rewards of all the blocks before the
+// given one, ignoring the supply cap. The genesis block is not rewarded.
+func (c *EmissionConfig) emitted(num uint64) *big.Int {
+	var (
+		total = new(big.Int)
+		count = new(big.Int)
+	)
+	c.periods(func(start, end uint64, r *big.Int) bool {
+		if start == 0 {
+			start = 1
+		}
+		if end > num {
+			end = num
+		}
+		if start < end {
+			count.SetUint64(end - start)
+			total.Add(total, count.Mul(count, r))
+		}
+		return end < num
+	})
+	return total
+}
+
+// periods iterates over the ranges [start, end) of constant scheduled block
+// reward in ascending order, until the callback returns false.
+func (c *EmissionConfig) periods(fn func(start, end uint64, reward *big.Int) bool) {
+	for i := 0; i <= len(c.Steps); i++ {
+		// Resolve the range and reward of the current step
+		var (
+			start, end uint64 = 0, math.MaxUint64
+			base              = c.InitialReward
+		)
+		if i > 0 {
+			start, base = c.Steps[i-1].Block.Uint64(), c.Steps[i-1].Reward
+		}
+		if i < len(c.Steps) {
+			end = c.Steps[i].Block.Uint64()
+		}
+		// Split the step range into its halving periods
+		for halvings := uint64(0); start < end; halvings++ {
+			pend := end
+			if c.HalvingInterval > 0 && end-start > c.HalvingInterval {
+				pend = start + c.HalvingInterval
+			}
+			reward := new(big.Int).Rsh(base, uint(halvings))
+			if c.TailReward != nil && reward.Cmp(c.TailReward) <= 0 {
+				reward.Set(c.TailReward)
+				pend = end // Tail emission is constant for the rest of the range
+			}
+			if reward.Sign() == 0 {
+				pend = end // Nothing more to halve for the rest of the range
+			}
+			if !fn(start, pend, reward) {
+				return
+			}
+			start = pend
+		}
+	}
+}
+
+// changes returns the blocks at which the capped block reward may change.
+func (c *EmissionConfig) changes() []uint64 {
+	changes := []uint64{1}
+	c.periods(func(start, end uint64, r *big.Int) bool {
+		if start > 1 {
+			changes = append(changes, start)
+		}
+		return true
+	})
+	// The reward also changes at the block exceeding the cap, and right after it
+	if c.MaxSupply != nil {
+		var (
+			total = new(big.Int)
+			count = new(big.Int)
+		)
+		c.periods(func(start, end uint64, r *big.Int) bool {
+			if start == 0 {
+				start = 1
+			}
+			if start >= end || r.Sign() == 0 {
+				return true
+			}
+			count.SetUint64(end - start)
+			if next := new(big.Int).Add(total, count.Mul(count, r)); next.Cmp(c.MaxSupply) <= 0 {
+				total = next
+				return true
+			}
+			// The cap is exceeded within this period, find the block doing it
+			blocks := new(big.Int).Sub(c.MaxSupply, total)
+			blocks.Div(blocks, r)
+			if block := start + blocks.Uint64(); block < math.MaxUint64 {
+				changes = append(changes, block, block+1)
+			}
+			return false
+		})
+	}
+	return changes
+}
+
+// firstDifference returns the first block at which the two emission schedules
+// pay different rewards, or false if they are identical.
+func (c *EmissionConfig) firstDifference(other *EmissionConfig) (uint64, bool) {
+	if c.UncleRewardRatio() != other.UncleRewardRatio() {
+		return 1, true
+	}
+	candidates := append(c.changes(), other.changes()...)
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	for _, num := range candidates {
+		if c.cappedReward(num).Cmp(other.cappedReward(num)) != 0 {
+			return num, true
+		}
+	}
+	return 0, false
+}
+
+// isEmissionIncompatible returns the block at which the emission schedules s1
+// and s2 diverge, if the head is already past it. A nil schedule is the legacy
+// constant reward of one coin per block.
+func isEmissionIncompatible(s1, s2 *EmissionConfig, head *big.Int) (uint64, bool) {
+	if s1 == nil {
+		s1 = legacyEmission
+	}
+	if s2 == nil {
+		s2 = legacyEmission
+	}
+	num, diff := s1.firstDifference(s2)
+	if !diff || head.Cmp(new(big.Int).SetUint64(num)) < 0 {
+		return 0, false
+	}
+	return num, true
+}
+
+// legacyEmission is the emission schedule of chains not configuring one.
+var legacyEmission = &EmissionConfig{InitialReward: big.NewInt(Ether)}
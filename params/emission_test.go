@@ -0,0 +1,155 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package params
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestEmissionBlockReward(t *testing.T) {
+	percent := uint64(50)
+	tests := []struct {
+		config  *EmissionConfig
+		rewards map[uint64]int64 // Block number -> expected reward
+	}{
+		// Constant reward
+		{
+			config:  &EmissionConfig{InitialReward: big.NewInt(100)},
+			rewards: map[uint64]int64{0: 0, 1: 100, 1_000_000: 100},
+		},
+		// Halvings without tail emission
+		{
+			config:  &EmissionConfig{InitialReward: big.NewInt(100), HalvingInterval: 10},
+			rewards: map[uint64]int64{1: 100, 9: 100, 10: 50, 19: 50, 20: 25, 30: 12, 70: 0, 1_000_000: 0},
+		},
+		// Halvings with tail emission
+		{
+			config:  &EmissionConfig{InitialReward: big.NewInt(100), HalvingInterval: 10, TailReward: big.NewInt(20)},
+			rewards: map[uint64]int64{1: 100, 10: 50, 20: 25, 30: 20, 1_000_000: 20},
+		},
+		// Scheduled steps restarting the halvings
+		{
+			config: &EmissionConfig{
+				InitialReward:   big.NewInt(100),
+				HalvingInterval: 10,
+				Steps: []EmissionStep{
+					{Block: big.NewInt(15), Reward: big.NewInt(80)},
+					{Block: big.NewInt(40), Reward: big.NewInt(8)},
+				},
+				UncleRewardPercent: &percent,
+			},
+			rewards: map[uint64]int64{10: 50, 14: 50, 15: 80, 24: 80, 25: 40, 35: 20, 39: 20, 40: 8, 50: 4, 80: 0},
+		},
+		// Supply cap hit in the middle of a block
+		{
+			config:  &EmissionConfig{InitialReward: big.NewInt(100), MaxSupply: big.NewInt(450)},
+			rewards: map[uint64]int64{1: 100, 4: 100, 5: 50, 6: 0, 1_000_000: 0},
+		},
+		// Supply cap combined with halvings
+		{
+			config:  &EmissionConfig{InitialReward: big.NewInt(100), HalvingInterval: 2, TailReward: big.NewInt(10), MaxSupply: big.NewInt(300)},
+			rewards: map[uint64]int64{1: 100, 2: 50, 3: 50, 4: 25, 5: 25, 6: 12, 7: 12, 8: 10, 9: 10, 10: 6, 11: 0},
+		},
+	}
+	for i, tt := range tests {
+		if err := tt.config.validate(); err != nil {
+			t.Fatalf("test %d: invalid schedule: %v", i, err)
+		}
+		for num, want := range tt.rewards {
+			if have := tt.config.BlockReward(new(big.Int).SetUint64(num)); have.Int64() != want {
+				t.Errorf("test %d: block %d reward mismatch: have %v, want %v", i, num, have, want)
+			}
+		}
+	}
+}
+
+func TestEmissionValidation(t *testing.T) {
+	tests := []*EmissionConfig{
+		{},
+		{InitialReward: big.NewInt(-1)},
+		{InitialReward: big.NewInt(1), TailReward: big.NewInt(-1)},
+		{InitialReward: big.NewInt(1), Steps: []EmissionStep{{Block: big.NewInt(1)}}},
+		{InitialReward: big.NewInt(1), Steps: []EmissionStep{
+			{Block: big.NewInt(2), Reward: big.NewInt(1)},
+			{Block: big.NewInt(2), Reward: big.NewInt(1)},
+		}},
+	}
+	for i, config := range tests {
+		chainConfig := &ChainConfig{Ethash: &EthashConfig{Emission: config}}
+		if err := chainConfig.CheckConfigForkOrder(); err == nil {
+			t.Errorf("test %d: invalid schedule accepted", i)
+		}
+	}
+}
+
+func TestEmissionJSON(t *testing.T) {
+	percent := uint64(25)
+	config := &ChainConfig{
+		ChainID: big.NewInt(210),
+		Ethash: &EthashConfig{Emission: &EmissionConfig{
+			InitialReward:      big.NewInt(Ether),
+			HalvingInterval:    2_100_000,
+			Steps:              []EmissionStep{{Block: big.NewInt(100), Reward: big.NewInt(2 * Ether)}},
+			TailReward:         big.NewInt(Ether / 10),
+			MaxSupply:          new(big.Int).Mul(big.NewInt(21_000_000), big.NewInt(Ether)),
+			UncleRewardPercent: &percent,
+		}},
+	}
+	blob, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("failed to encode config: %v", err)
+	}
+	var decoded ChainConfig
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	if !reflect.DeepEqual(config.Ethash, decoded.Ethash) {
+		t.Errorf("emission schedule mismatch: have %+v, want %+v", decoded.Ethash.Emission, config.Ethash.Emission)
+	}
+}
+
+func TestEmissionCompatible(t *testing.T) {
+	halving := &ChainConfig{Ethash: &EthashConfig{Emission: &EmissionConfig{InitialReward: big.NewInt(Ether), HalvingInterval: 100}}}
+	stepped := &ChainConfig{Ethash: &EthashConfig{Emission: &EmissionConfig{
+		InitialReward: big.NewInt(Ether),
+		Steps:         []EmissionStep{{Block: big.NewInt(150), Reward: big.NewInt(Ether / 2)}},
+	}}}
+	legacy := &ChainConfig{Ethash: new(EthashConfig)}
+
+	tests := []struct {
+		stored, new *ChainConfig
+		head        uint64
+		rewind      uint64
+		incompat    bool
+	}{
+		{stored: legacy, new: halving, head: 99},
+		{stored: legacy, new: halving, head: 100, rewind: 99, incompat: true},
+		{stored: halving, new: stepped, head: 120, rewind: 99, incompat: true},
+		{stored: stepped, new: legacy, head: 149},
+		{stored: stepped, new: legacy, head: 150, rewind: 149, incompat: true},
+		{stored: legacy, new: &ChainConfig{}, head: 1_000_000},
+	}
+	for i, tt := range tests {
+		err := tt.stored.CheckCompatible(tt.new, tt.head, 0)
+		if (err != nil) != tt.incompat {
+			t.Errorf("test %d: compatibility mismatch: have %v, want incompatible %v", i, err, tt.incompat)
+			continue
+		}
+		if err != nil && err.RewindToBlock != tt.rewind {
+			t.Errorf("test %d: rewind mismatch: have %d, want %d", i, err.RewindToBlock, tt.rewind)
+		}
+	}
+}
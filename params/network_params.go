@@ -28,6 +28,20 @@ const (
 	// considered probably final and its rotated bits are calculated.
 	BloomConfirms = 256
 
+	// AddressIndexBlocks is the number of blocks of a single address index section.
+	AddressIndexBlocks uint64 = 128
+
+	// AddressIndexConfirms is the number of confirmation blocks before an address
+	// index section is considered probably final and indexed.
+	AddressIndexConfirms = 16
+
+	// TraceStoreBlocks is the number of blocks of a single trace store section.
+	TraceStoreBlocks uint64 = 32
+
+	// TraceStoreConfirms is the number of confirmation blocks before a trace store
+	// section is considered probably final and its blocks are traced.
+	TraceStoreConfirms = 16
+
 	// CHTFrequency is the block frequency for creating CHTs
 	CHTFrequency = 32768
 
@@ -65,8 +65,9 @@ const (
 	// Which becomes: 5000 - 2100 + 1900 = 4800
 	SstoreClearsScheduleRefundEIP3529 uint64 = SstoreResetGasEIP2200 - ColdSloadCostEIP2929 + TxAccessListStorageKeyGas
 
-	JumpdestGas   uint64 = 1     // Once per JUMPDEST operation.
-	EpochDuration uint64 = 30000 // Duration between proof-of-work epochs.
+	JumpdestGas           uint64 = 1     // Once per JUMPDEST operation.
+	EpochDuration         uint64 = 30000 // Duration between proof-of-work epochs.
+	EpochDurationECIP1099 uint64 = 60000 // Duration between proof-of-work epochs after the ECIP-1099 fork.
 
 	CreateDataGas         uint64 = 200   //
 	CallCreateDepth       uint64 = 1024  // Maximum depth of call/create stack.
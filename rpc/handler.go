@@ -56,6 +56,7 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	quota          *clientQuota // resources the remote client may consume, nil if unlimited
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -167,6 +168,14 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Reject batches exceeding the client quota as a whole:
+	if err := h.quota.checkBatch(len(msgs)); err != nil {
+		h.startCallProc(func(cp *callProc) {
+			resp := errorMessage(err)
+			h.conn.writeJSON(cp.ctx, resp, true)
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -199,6 +208,7 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			})
 		}
 
+		var size int
 		for {
 			// No need to handle rest of calls if timed out.
 			if cp.ctx.Err() != nil {
@@ -208,7 +218,19 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			if msg == nil {
 				break
 			}
-			resp := h.handleCallMsg(cp, msg)
+			// Once the responses exceed the client quota, fail the remaining calls
+			// without executing them.
+			var resp *jsonrpcMessage
+			if err := h.quota.checkResponse(size); err != nil {
+				if !msg.isNotification() {
+					resp = msg.errorResponse(err)
+				}
+			} else if resp = h.handleCallMsg(cp, msg); resp != nil {
+				size += len(resp.Result)
+				if err := h.quota.checkResponse(size); err != nil {
+					resp = msg.errorResponse(err)
+				}
+			}
 			callBuffer.pushResponse(resp)
 		}
 		if timer != nil {
@@ -253,6 +275,11 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 		if timer != nil {
 			timer.Stop()
 		}
+		if answer != nil {
+			if err := h.quota.checkResponse(len(answer.Result)); err != nil {
+				answer = msg.errorResponse(err)
+			}
+		}
 		h.addSubscriptions(cp.notifiers)
 		if answer != nil {
 			responded.Do(func() {
@@ -435,6 +462,9 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if err := h.quota.take(msg.Method); err != nil {
+		return msg.errorResponse(err)
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
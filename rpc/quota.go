@@ -0,0 +1,287 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Client identities the quotas can be keyed on.
+const (
+	QuotaKeyIP     = "ip"     // Remote IP address of the connection
+	QuotaKeyJWT    = "jwt"    // Subject of the JWT token authenticating the connection
+	QuotaKeyHeader = "header" // API key carried in an HTTP header
+)
+
+const (
+	// quotaClientExpiry is the time after which the quota of an idle client is
+	// forgotten, refilling its rate limits.
+	quotaClientExpiry = 10 * time.Minute
+
+	// quotaMaxClients is the number of clients whose quotas are tracked, beyond
+	// which the least recently active ones are forgotten.
+	quotaMaxClients = 10000
+)
+
+// QuotaConfig limits the resources a single client of the server may consume.
+// Zero values disable the respective limits.
+type QuotaConfig struct {
+	// KeyBy is the identity the clients are told apart by: "ip" (default), "jwt"
+	// or "header". Clients with no JWT subject or known API key fall back to
+	// their IP.
+	KeyBy string `toml:",omitempty"`
+
+	// KeyHeader is the HTTP header carrying the API key when keyed by header.
+	KeyHeader string `toml:",omitempty"`
+
+	// Keys are the API keys given their own quota when keyed by header.
+	Keys []string `toml:",omitempty"`
+
+	// MaxBatchItems is the maximum number of requests in a batch.
+	MaxBatchItems int `toml:",omitempty"`
+
+	// MaxResponseBytes is the maximum size of the result of a request, or of the
+	// results of all the requests of a batch.
+	MaxResponseBytes int `toml:",omitempty"`
+
+	// RequestRate is the number of requests per second a client may send, with
+	// bursts of up to RequestBurst requests.
+	RequestRate  float64 `toml:",omitempty"`
+	RequestBurst int     `toml:",omitempty"`
+
+	// ComputeRate is the number of compute units per second a client may use,
+	// with bursts of up to ComputeBurst units. Each request costs one unit unless
+	// its method, or its namespace as "namespace_*", is weighted in MethodCosts.
+	ComputeRate  float64        `toml:",omitempty"`
+	ComputeBurst int            `toml:",omitempty"`
+	MethodCosts  map[string]int `toml:",omitempty"`
+}
+
+// Validate checks that the quota configuration is well formed.
+func (c *QuotaConfig) Validate() error {
+	switch c.KeyBy {
+	case "", QuotaKeyIP, QuotaKeyJWT:
+	case QuotaKeyHeader:
+		if c.KeyHeader == "" {
+			return fmt.Errorf("missing header for quotas keyed by %q", c.KeyBy)
+		}
+		if len(c.Keys) == 0 {
+			return fmt.Errorf("missing keys for quotas keyed by %q", c.KeyBy)
+		}
+	default:
+		return fmt.Errorf("unknown quota key %q", c.KeyBy)
+	}
+	switch {
+	case c.MaxBatchItems < 0:
+		return fmt.Errorf("invalid batch item limit %d", c.MaxBatchItems)
+	case c.MaxResponseBytes < 0:
+		return fmt.Errorf("invalid response size limit %d", c.MaxResponseBytes)
+	case c.RequestRate < 0 || c.RequestBurst < 0:
+		return fmt.Errorf("invalid request rate limit %v/s, burst %d", c.RequestRate, c.RequestBurst)
+	case c.ComputeRate < 0 || c.ComputeBurst < 0:
+		return fmt.Errorf("invalid compute unit rate limit %v/s, burst %d", c.ComputeRate, c.ComputeBurst)
+	}
+	for method, cost := range c.MethodCosts {
+		if cost < 0 || (c.ComputeBurst > 0 && cost > c.ComputeBurst) {
+			return fmt.Errorf("invalid cost %d of method %s", cost, method)
+		}
+	}
+	return nil
+}
+
+// cost returns the compute units consumed by a call of the given method.
+func (c *QuotaConfig) cost(method string) int {
+	if cost, ok := c.MethodCosts[method]; ok {
+		return cost
+	}
+	if i := strings.Index(method, serviceMethodSeparator); i > 0 {
+		if cost, ok := c.MethodCosts[method[:i]+serviceMethodSeparator+"*"]; ok {
+			return cost
+		}
+	}
+	return 1
+}
+
+// quotaLimiter tracks the quotas of the clients of a server.
+type quotaLimiter struct {
+	config       QuotaConfig
+	keys         map[string]struct{}
+	requestBurst int
+	computeBurst int
+
+	lock    sync.Mutex
+	clients map[string]*clientQuota
+	swept   time.Time
+}
+
+// clientQuota is the remaining quota of a single client. A nil quota is not
+// limited.
+type clientQuota struct {
+	limiter  *quotaLimiter
+	requests *rate.Limiter
+	compute  *rate.Limiter
+	used     atomic.Int64 // Last time the quota was used, in unix nanoseconds
+}
+
+// newQuotaLimiter creates a limiter enforcing the given quotas.
+func newQuotaLimiter(config QuotaConfig) *quotaLimiter {
+	q := &quotaLimiter{
+		config:       config,
+		requestBurst: config.RequestBurst,
+		computeBurst: config.ComputeBurst,
+		keys:         make(map[string]struct{}, len(config.Keys)),
+		clients:      make(map[string]*clientQuota),
+		swept:        time.Now(),
+	}
+	for _, key := range config.Keys {
+		q.keys[key] = struct{}{}
+	}
+	// Default the bursts to a second worth of requests, and make sure the most
+	// expensive method can be called at all.
+	if q.requestBurst == 0 {
+		q.requestBurst = int(math.Max(1, math.Ceil(config.RequestRate)))
+	}
+	if q.computeBurst == 0 {
+		q.computeBurst = int(math.Max(1, math.Ceil(config.ComputeRate)))
+		for _, cost := range config.MethodCosts {
+			if cost > q.computeBurst {
+				q.computeBurst = cost
+			}
+		}
+	}
+	return q
+}
+
+// clientKey returns the identity the quota of the client sending the given
+// request is tracked under.
+func (q *quotaLimiter) clientKey(r *http.Request) string {
+	switch q.config.KeyBy {
+	case QuotaKeyJWT:
+		if subject, ok := r.Context().Value(jwtSubjectContextKey{}).(string); ok && subject != "" {
+			return "jwt:" + subject
+		}
+	case QuotaKeyHeader:
+		if key := r.Header.Get(q.config.KeyHeader); key != "" {
+			if _, ok := q.keys[key]; ok {
+				return "key:" + key
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// client retrieves the quota of the client sending the given request, creating
+// it if the client is not yet known, in which case the least recently active
+// client is forgotten if too many are tracked.
+func (q *quotaLimiter) client(r *http.Request) *clientQuota {
+	if q == nil {
+		return nil
+	}
+	key := q.clientKey(r)
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(q.swept) > quotaClientExpiry {
+		for key, c := range q.clients {
+			if now.Sub(time.Unix(0, c.used.Load())) > quotaClientExpiry {
+				delete(q.clients, key)
+			}
+		}
+		q.swept = now
+	}
+	c := q.clients[key]
+	if c == nil {
+		if len(q.clients) >= quotaMaxClients {
+			var (
+				oldest string
+				used   int64 = math.MaxInt64
+			)
+			for key, c := range q.clients {
+				if t := c.used.Load(); t < used {
+					oldest, used = key, t
+				}
+			}
+			delete(q.clients, oldest)
+		}
+		c = &clientQuota{limiter: q}
+		if q.config.RequestRate > 0 {
+			c.requests = rate.NewLimiter(rate.Limit(q.config.RequestRate), q.requestBurst)
+		}
+		if q.config.ComputeRate > 0 {
+			c.compute = rate.NewLimiter(rate.Limit(q.config.ComputeRate), q.computeBurst)
+		}
+		q.clients[key] = c
+	}
+	c.used.Store(now.UnixNano())
+	return c
+}
+
+// checkBatch returns an error if a batch of the given length is not allowed.
+func (c *clientQuota) checkBatch(items int) error {
+	if c == nil || c.limiter.config.MaxBatchItems == 0 || items <= c.limiter.config.MaxBatchItems {
+		return nil
+	}
+	return &invalidRequestError{fmt.Sprintf("%s (%d>%d)", errMsgBatchTooLarge, items, c.limiter.config.MaxBatchItems)}
+}
+
+// checkResponse returns an error if responses totalling the given size are not
+// allowed.
+func (c *clientQuota) checkResponse(size int) error {
+	if c == nil || c.limiter.config.MaxResponseBytes == 0 || size <= c.limiter.config.MaxResponseBytes {
+		return nil
+	}
+	return &internalServerError{errcodeResponseTooLarge, errMsgResponseTooLarge}
+}
+
+// take consumes the quota of a call of the given method, returning an error if
+// the client exceeded its rate limits.
+func (c *clientQuota) take(method string) error {
+	if c == nil {
+		return nil
+	}
+	now := time.Now()
+	c.used.Store(now.UnixNano())
+
+	if c.requests != nil && !c.requests.AllowN(now, 1) {
+		return &internalServerError{errcodeLimitExceeded, errMsgRequestLimit}
+	}
+	if c.compute != nil && !c.compute.AllowN(now, c.limiter.config.cost(method)) {
+		return &internalServerError{errcodeLimitExceeded, errMsgComputeLimit}
+	}
+	return nil
+}
+
+type jwtSubjectContextKey struct{}
+
+// NewContextWithJWTSubject returns a copy of the given context carrying the
+// subject of the JWT token the request was authenticated with. Quotas keyed by
+// JWT track the request under this subject.
+func NewContextWithJWTSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, jwtSubjectContextKey{}, subject)
+}
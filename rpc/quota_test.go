@@ -0,0 +1,207 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// postQuotaTest sends a raw JSON-RPC message to the server, with the given API
+// key header, and decodes the error codes of the responses.
+func postQuotaTest(t *testing.T, url string, apiKey string, body string) []int {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("content-type", contentType)
+	if apiKey != "" {
+		req.Header.Set("X-Api-Key", apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	var msgs []*jsonrpcMessage
+	if strings.HasPrefix(string(raw), "[") {
+		if err := json.Unmarshal(raw, &msgs); err != nil {
+			t.Fatalf("invalid batch response: %v", err)
+		}
+	} else {
+		msg := new(jsonrpcMessage)
+		if err := json.Unmarshal(raw, msg); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	codes := make([]int, len(msgs))
+	for i, msg := range msgs {
+		if msg.Error != nil {
+			codes[i] = msg.Error.Code
+		}
+	}
+	return codes
+}
+
+func newQuotaTestServer(t *testing.T, config QuotaConfig) *httptest.Server {
+	server := newTestServer()
+	if err := server.SetQuotas(config); err != nil {
+		t.Fatalf("failed to set quotas: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(func() {
+		ts.Close()
+		server.Stop()
+	})
+	return ts
+}
+
+const (
+	quotaTestCall  = `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]}`
+	quotaTestBatch = `[` + quotaTestCall + `,` + quotaTestCall + `,` + quotaTestCall + `]`
+)
+
+func equalCodes(have, want []int) bool {
+	if len(have) != len(want) {
+		return false
+	}
+	for i := range have {
+		if have[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestQuotaBatchItems(t *testing.T) {
+	ts := newQuotaTestServer(t, QuotaConfig{MaxBatchItems: 2})
+
+	if codes := postQuotaTest(t, ts.URL, "", quotaTestBatch); !equalCodes(codes, []int{-32600}) {
+		t.Errorf("oversized batch: error codes mismatch: have %v, want [-32600]", codes)
+	}
+	batch := `[` + quotaTestCall + `,` + quotaTestCall + `]`
+	if codes := postQuotaTest(t, ts.URL, "", batch); !equalCodes(codes, []int{0, 0}) {
+		t.Errorf("batch: error codes mismatch: have %v, want [0 0]", codes)
+	}
+}
+
+func TestQuotaResponseBytes(t *testing.T) {
+	// An echo result is {"String":"x","Int":1,"Args":null}, 34 bytes long
+	ts := newQuotaTestServer(t, QuotaConfig{MaxResponseBytes: 50})
+
+	if codes := postQuotaTest(t, ts.URL, "", quotaTestCall); !equalCodes(codes, []int{0}) {
+		t.Errorf("call: error codes mismatch: have %v, want [0]", codes)
+	}
+	if codes := postQuotaTest(t, ts.URL, "", quotaTestBatch); !equalCodes(codes, []int{0, errcodeResponseTooLarge, errcodeResponseTooLarge}) {
+		t.Errorf("batch: error codes mismatch: have %v", codes)
+	}
+	large := `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["` + strings.Repeat("x", 100) + `",1]}`
+	if codes := postQuotaTest(t, ts.URL, "", large); !equalCodes(codes, []int{errcodeResponseTooLarge}) {
+		t.Errorf("large call: error codes mismatch: have %v", codes)
+	}
+}
+
+func TestQuotaRequestRate(t *testing.T) {
+	ts := newQuotaTestServer(t, QuotaConfig{
+		KeyBy:        QuotaKeyHeader,
+		KeyHeader:    "X-Api-Key",
+		Keys:         []string{"alice", "bob"},
+		RequestRate:  0.001,
+		RequestBurst: 2,
+	})
+	if codes := postQuotaTest(t, ts.URL, "alice", quotaTestBatch); !equalCodes(codes, []int{0, 0, errcodeLimitExceeded}) {
+		t.Errorf("alice: error codes mismatch: have %v", codes)
+	}
+	// Other clients have their own quota
+	if codes := postQuotaTest(t, ts.URL, "bob", quotaTestCall); !equalCodes(codes, []int{0}) {
+		t.Errorf("bob: error codes mismatch: have %v", codes)
+	}
+	if codes := postQuotaTest(t, ts.URL, "alice", quotaTestCall); !equalCodes(codes, []int{errcodeLimitExceeded}) {
+		t.Errorf("alice again: error codes mismatch: have %v", codes)
+	}
+	// Unknown keys share the quota of their IP
+	if codes := postQuotaTest(t, ts.URL, "mallory", quotaTestBatch); !equalCodes(codes, []int{0, 0, errcodeLimitExceeded}) {
+		t.Errorf("mallory: error codes mismatch: have %v", codes)
+	}
+	if codes := postQuotaTest(t, ts.URL, "eve", quotaTestCall); !equalCodes(codes, []int{errcodeLimitExceeded}) {
+		t.Errorf("eve: error codes mismatch: have %v", codes)
+	}
+}
+
+func TestQuotaMaxClients(t *testing.T) {
+	q := newQuotaLimiter(QuotaConfig{RequestRate: 1})
+	request := func(i int) {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = fmt.Sprintf("10.%d.%d.%d:1234", i>>16, (i>>8)&0xff, i&0xff)
+		q.client(r)
+	}
+	for i := 0; i < quotaMaxClients; i++ {
+		request(i)
+	}
+	q.clients["ip:10.0.0.1"].used.Store(0)
+	request(quotaMaxClients)
+
+	if len(q.clients) != quotaMaxClients {
+		t.Fatalf("client count mismatch: have %d, want %d", len(q.clients), quotaMaxClients)
+	}
+	if _, ok := q.clients["ip:10.0.0.1"]; ok {
+		t.Errorf("least recently active client retained")
+	}
+	if _, ok := q.clients[fmt.Sprintf("ip:10.0.%d.%d", quotaMaxClients>>8, quotaMaxClients&0xff)]; !ok {
+		t.Errorf("new client not tracked")
+	}
+}
+
+func TestQuotaComputeUnits(t *testing.T) {
+	ts := newQuotaTestServer(t, QuotaConfig{
+		ComputeRate:  0.001,
+		ComputeBurst: 4,
+		MethodCosts:  map[string]int{"test_echo": 3, "rpc_*": 0},
+	})
+	modules := `{"jsonrpc":"2.0","id":1,"method":"rpc_modules"}`
+	batch := `[` + quotaTestCall + `,` + modules + `,` + quotaTestCall + `]`
+	if codes := postQuotaTest(t, ts.URL, "", batch); !equalCodes(codes, []int{0, 0, errcodeLimitExceeded}) {
+		t.Errorf("error codes mismatch: have %v", codes)
+	}
+}
+
+func TestQuotaConfigValidate(t *testing.T) {
+	tests := []struct {
+		config QuotaConfig
+		valid  bool
+	}{
+		{QuotaConfig{}, true},
+		{QuotaConfig{KeyBy: QuotaKeyJWT}, true},
+		{QuotaConfig{KeyBy: QuotaKeyHeader, KeyHeader: "X-Api-Key", Keys: []string{"alice"}}, true},
+		{QuotaConfig{KeyBy: QuotaKeyHeader, KeyHeader: "X-Api-Key"}, false},
+		{QuotaConfig{KeyBy: QuotaKeyHeader, Keys: []string{"alice"}}, false},
+		{QuotaConfig{KeyBy: "cookie"}, false},
+		{QuotaConfig{MaxBatchItems: -1}, false},
+		{QuotaConfig{RequestRate: -1}, false},
+		{QuotaConfig{ComputeBurst: 2, MethodCosts: map[string]int{"eth_getLogs": 3}}, false},
+	}
+	for i, tt := range tests {
+		if err := tt.config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("test %d: validation mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
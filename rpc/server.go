@@ -45,6 +45,8 @@ type Server struct {
 	mutex  sync.Mutex
 	codecs map[ServerCodec]struct{}
 	run    int32
+
+	quotas *quotaLimiter // Per-client quotas of HTTP and WebSocket connections
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -69,12 +71,27 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetQuotas limits the resources each client of the server may consume over HTTP
+// and WebSocket connections. It must be called before serving any request.
+func (s *Server) SetQuotas(config QuotaConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	s.quotas = newQuotaLimiter(config)
+	return nil
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(codec, nil)
+}
+
+// serveCodec serves the requests read from codec, within the given client quota.
+func (s *Server) serveCodec(codec ServerCodec, quota *clientQuota) {
 	defer codec.close()
 
 	if !s.trackCodec(codec) {
@@ -82,7 +99,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	}
 	defer s.untrackCodec(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, quota)
 	<-codec.closed()
 	c.Close()
 }
@@ -108,7 +125,7 @@ func (s *Server) untrackCodec(codec ServerCodec) {
 // serveSingleRequest reads and processes a single RPC request from the given codec. This
 // is used to serve HTTP connections. Subscriptions and reverse calls are not allowed in
 // this mode.
-func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
+func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, quota *clientQuota) {
 	// Don't serve if server is stopped.
 	if atomic.LoadInt32(&s.run) == 0 {
 		return
@@ -116,6 +133,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 
 	h := newHandler(ctx, codec, s.idgen, &s.services)
 	h.allowSubscribe = false
+	h.quota = quota
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
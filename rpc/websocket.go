@@ -57,7 +57,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn, r.Host, r.Header)
-		s.ServeCodec(codec, 0)
+		s.serveCodec(codec, s.quotas.client(r))
 	})
 }
 
@@ -15,6 +15,8 @@ package tests
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
@@ -24,11 +26,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestState(t *testing.T) {
@@ -261,3 +265,96 @@ func runBenchmark(b *testing.B, t *StateTest) {
 		})
 	}
 }
+
+// evmUpgradeStateTest is a state test calling a contract which exercises the
+// opcodes enabled by the EVM upgrade fork: it stores the value read back from
+// transient storage in slot 1, and the word copied with MCOPY in slot 2.
+const evmUpgradeStateTest = `{
+	"env": {
+		"currentCoinbase": "0x2adc25665018aa1fe0e6bc666dac8fc2697ff9ba",
+		"currentDifficulty": "0x020000",
+		"currentGasLimit": "0x989680",
+		"currentNumber": "%#x",
+		"currentTimestamp": "0x03e8",
+		"currentBaseFee": "0x0a"
+	},
+	"pre": {
+		"0x0000000000000000000000000000000000001000": {
+			"balance": "0x00",
+			"code": "0x602a5f5d5f5c60015561beef5f5260205f60205e60205160025500",
+			"nonce": "0x00",
+			"storage": {}
+		},
+		"0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b": {
+			"balance": "0x0de0b6b3a7640000",
+			"code": "0x",
+			"nonce": "0x00",
+			"storage": {}
+		}
+	},
+	"transaction": {
+		"data": ["0x", "%#x"],
+		"gasLimit": ["0x0f4240", "0x989680"],
+		"gasPrice": "0x0a",
+		"nonce": "0x00",
+		"secretKey": "0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8",
+		"to": "%s",
+		"value": ["0x00"]
+	},
+	"post": {
+		"London": [{"indexes": {"data": %[4]d, "gas": %[4]d, "value": 0}}],
+		"EVMUpgrade": [{"indexes": {"data": %[4]d, "gas": %[4]d, "value": 0}}],
+		"GrayGlacierToEVMUpgradeAt5": [{"indexes": {"data": %[4]d, "gas": %[4]d, "value": 0}}]
+	}
+}`
+
+// Tests that the EVM upgrade fork enables PUSH0, transient storage, MCOPY and
+// initcode metering by block number, without any withdrawals.
+func TestEVMUpgradeState(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0x1000")
+		initcode = make([]byte, params.MaxInitCodeSize+1)
+	)
+	for _, tt := range []struct {
+		fork    string
+		number  uint64
+		enabled bool
+	}{
+		{"London", 5, false},
+		{"EVMUpgrade", 0, true},
+		{"GrayGlacierToEVMUpgradeAt5", 4, false},
+		{"GrayGlacierToEVMUpgradeAt5", 5, true},
+	} {
+		// Call the contract, the new opcodes should only execute after the fork
+		var call StateTest
+		if err := json.Unmarshal([]byte(fmt.Sprintf(evmUpgradeStateTest, tt.number, initcode, contract.Hex(), 0)), &call); err != nil {
+			t.Fatalf("failed to parse state test: %v", err)
+		}
+		_, statedb, _, err := call.RunNoVerify(StateSubtest{Fork: tt.fork}, vm.Config{}, false)
+		if err != nil {
+			t.Fatalf("%s at block %d: failed to call contract: %v", tt.fork, tt.number, err)
+		}
+		var tload, mcopy common.Hash
+		if tt.enabled {
+			tload, mcopy = common.BigToHash(big.NewInt(0x2a)), common.BigToHash(big.NewInt(0xbeef))
+		}
+		if have := statedb.GetState(contract, common.BigToHash(big.NewInt(1))); have != tload {
+			t.Errorf("%s at block %d: transient storage mismatch: have %x, want %x", tt.fork, tt.number, have, tload)
+		}
+		if have := statedb.GetState(contract, common.BigToHash(big.NewInt(2))); have != mcopy {
+			t.Errorf("%s at block %d: memory copy mismatch: have %x, want %x", tt.fork, tt.number, have, mcopy)
+		}
+		// Deploy an oversized initcode, which should only be rejected after the fork
+		var create StateTest
+		if err := json.Unmarshal([]byte(fmt.Sprintf(evmUpgradeStateTest, tt.number, initcode, "", 1)), &create); err != nil {
+			t.Fatalf("failed to parse state test: %v", err)
+		}
+		_, _, _, err = create.RunNoVerify(StateSubtest{Fork: tt.fork}, vm.Config{}, false)
+		if tt.enabled && !errors.Is(err, core.ErrMaxInitCodeSizeExceeded) {
+			t.Errorf("%s at block %d: initcode error mismatch: have %v, want %v", tt.fork, tt.number, err, core.ErrMaxInitCodeSizeExceeded)
+		}
+		if !tt.enabled && err != nil {
+			t.Errorf("%s at block %d: failed to deploy initcode: %v", tt.fork, tt.number, err)
+		}
+	}
+}
@@ -82,6 +82,7 @@ type Database struct {
 	dirtiesSize  common.StorageSize // Storage size of the dirty node cache (exc. metadata)
 	childrenSize common.StorageSize // Storage size of the external children tracking
 	preimages    *preimageStore     // The store for caching preimages
+	path         *pathDB            // Backend of the path-based scheme, nil for the hash-based one
 
 	lock sync.RWMutex
 }
@@ -261,9 +262,10 @@ func expandNode(hash hashNode, n node) node {
 
 // Config defines all necessary options for database.
 type Config struct {
-	Cache     int    // Memory allowance (MB) to use for caching trie nodes in memory
-	Journal   string // Journal of clean cache to survive node restarts
-	Preimages bool   // Flag whether the preimage of trie key is recorded
+	Cache     int         // Memory allowance (MB) to use for caching trie nodes in memory
+	Journal   string      // Journal of clean cache to survive node restarts
+	Preimages bool        // Flag whether the preimage of trie key is recorded
+	PathDB    *PathConfig // Settings of the path-based scheme, the hash-based one is used if nil
 }
 
 // NewDatabase creates a new trie database to store ephemeral trie content before
@@ -297,6 +299,9 @@ func NewDatabaseWithConfig(diskdb ethdb.Database, config *Config) *Database {
 		}},
 		preimages: preimage,
 	}
+	if config != nil && config.PathDB != nil {
+		db.path = newPathDB(diskdb, cleans, config.PathDB)
+	}
 	return db
 }
 
@@ -380,6 +385,10 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 	if hash == (common.Hash{}) {
 		return nil, errors.New("not found")
 	}
+	// Nodes can't be retrieved by hash alone with the path-based scheme
+	if db.path != nil {
+		return nil, errors.New("not supported by the path-based scheme")
+	}
 	// Retrieve the node from the clean cache if available
 	if db.cleans != nil {
 		if enc := db.cleans.Get(nil, hash[:]); enc != nil {
@@ -434,6 +443,9 @@ func (db *Database) Nodes() []common.Hash {
 // and external node(e.g. storage trie root), all internal trie nodes
 // are referenced together by database itself.
 func (db *Database) Reference(child common.Hash, parent common.Hash) {
+	if db.path != nil {
+		return
+	}
 	db.lock.Lock()
 	defer db.lock.Unlock()
 
@@ -468,6 +480,9 @@ func (db *Database) Dereference(root common.Hash) {
 		log.Error("Attempted to dereference the trie cache meta root")
 		return
 	}
+	if db.path != nil {
+		return
+	}
 	db.lock.Lock()
 	defer db.lock.Unlock()
 
@@ -542,6 +557,10 @@ func (db *Database) dereference(child common.Hash, parent common.Hash) {
 // Note, this method is a non-synchronized mutator. It is unsafe to call this
 // concurrently with other mutators.
 func (db *Database) Cap(limit common.StorageSize) error {
+	// The path-based scheme keeps a fixed number of diff layers instead
+	if db.path != nil {
+		return nil
+	}
 	// Create a database batch to flush persistent data out. It is important that
 	// outside code doesn't see an inconsistent state (referenced data removed from
 	// memory cache during commit but not yet in persistent storage). This is ensured
@@ -642,6 +661,18 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 			return err
 		}
 	}
+	// With the path-based scheme, flatten the state into the persistent one
+	if db.path != nil {
+		if err := db.path.commit(node); err != nil {
+			return err
+		}
+		logger := log.Info
+		if !report {
+			logger = log.Debug
+		}
+		logger("Persisted state to disk", "root", node, "time", time.Since(start))
+		return nil
+	}
 	// Move the trie itself into the batch, flushing if enough data is accumulated
 	nodes, storage := len(db.dirties), db.dirtiesSize
 
@@ -766,7 +797,12 @@ func (c *cleaner) Delete(key []byte) error {
 
 // Update inserts the dirty nodes in provided nodeset into database and
 // link the account trie with multiple storage tries if necessary.
+//
+// The path-based scheme needs to know the state transition, use UpdateState.
 func (db *Database) Update(nodes *MergedNodeSet) error {
+	if db.path != nil {
+		return errors.New("state roots required by the path-based scheme")
+	}
 	db.lock.Lock()
 	defer db.lock.Unlock()
 
@@ -811,9 +847,84 @@ func (db *Database) Update(nodes *MergedNodeSet) error {
 	return nil
 }
 
+// UpdateState inserts the dirty nodes of the state transition from parent to
+// root into the database. With the hash-based scheme it is equivalent to Update,
+// with the path-based one the nodes are kept as a diff layer on top of the
+// parent state.
+func (db *Database) UpdateState(root common.Hash, parent common.Hash, nodes *MergedNodeSet) error {
+	if db.path == nil {
+		return db.Update(nodes)
+	}
+	if db.preimages != nil {
+		if err := db.preimages.commit(false); err != nil {
+			return err
+		}
+	}
+	return db.path.update(root, parent, nodes)
+}
+
+// Recoverable returns whether the state with the given root, not available
+// anymore, can be recovered by rolling back the persistent state. It is only
+// supported by the path-based scheme.
+func (db *Database) Recoverable(root common.Hash) bool {
+	if db.path == nil {
+		return false
+	}
+	db.path.lock.RLock()
+	defer db.path.lock.RUnlock()
+
+	return db.path.recoverable(root)
+}
+
+// Recover rolls the persistent state back to the state with the given root
+// using the state history, dropping all the states in memory. It is only
+// supported by the path-based scheme.
+func (db *Database) Recover(root common.Hash) error {
+	if db.path == nil {
+		return errors.New("not supported by the hash-based scheme")
+	}
+	return db.path.recover(root)
+}
+
+// Disable drops all the states of the path-based scheme, as the persistent
+// state is about to be rewritten by state sync. It's a noop with the hash-based
+// scheme.
+func (db *Database) Disable() error {
+	if db.path == nil {
+		return nil
+	}
+	return db.path.disable()
+}
+
+// Enable reopens the persistent state of the path-based scheme after state
+// sync, the given root being the synced state. It's a noop with the hash-based
+// scheme.
+func (db *Database) Enable(root common.Hash) error {
+	if db.path == nil {
+		return nil
+	}
+	return db.path.enable(root)
+}
+
+// Close releases the resources of the database. With the path-based scheme the
+// states in memory are dropped, the wanted one needs to be committed first.
+func (db *Database) Close() error {
+	if db.path == nil {
+		return nil
+	}
+	return db.path.close()
+}
+
 // Size returns the current storage size of the memory cache in front of the
 // persistent database layer.
 func (db *Database) Size() (common.StorageSize, common.StorageSize) {
+	if db.path != nil {
+		var preimageSize common.StorageSize
+		if db.preimages != nil {
+			preimageSize = db.preimages.size()
+		}
+		return db.path.size(), preimageSize
+	}
 	db.lock.RLock()
 	defer db.lock.RUnlock()
 
@@ -831,6 +942,9 @@ func (db *Database) Size() (common.StorageSize, common.StorageSize) {
 
 // GetReader retrieves a node reader belonging to the given state root.
 func (db *Database) GetReader(root common.Hash) Reader {
+	if db.path != nil {
+		return db.path.reader(root)
+	}
 	return newHashReader(db)
 }
 
@@ -912,5 +1026,8 @@ func (db *Database) CommitPreimages() error {
 
 // Scheme returns the node scheme used in the database.
 func (db *Database) Scheme() string {
+	if db.path != nil {
+		return rawdb.PathScheme
+	}
 	return rawdb.HashScheme
 }
@@ -0,0 +1,505 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// pathDiffLayers is the number of recent states kept in memory as diff layers
+// on top of the persistent state, matching the states kept by the blockchain.
+const pathDiffLayers = 128
+
+// errPathDisabled is returned when accessing the path-based database while the
+// persistent state is being rewritten by state sync.
+var errPathDisabled = errors.New("path database disabled")
+
+// PathConfig contains the settings of the path-based state scheme.
+type PathConfig struct {
+	StateHistory uint64 // Number of recent state transitions whose reverse diffs are kept, 0 for all
+}
+
+// stateHistory is the reverse diff of a state transition, holding the previous
+// value of all the trie nodes modified by it. Applying it to the persistent
+// state rolls it back to the parent state.
+type stateHistory struct {
+	Parent common.Hash        // Root hash of the parent state
+	Root   common.Hash        // Root hash of the state
+	Nodes  []stateHistoryNode // Previous values of the modified trie nodes
+}
+
+// stateHistoryNode is the previous value of a trie node, empty if the node did
+// not exist in the parent state.
+type stateHistoryNode struct {
+	Owner common.Hash
+	Path  []byte
+	Prev  []byte
+}
+
+// pathDB is the backend of the trie database for the path-based scheme. Trie
+// nodes are stored keyed by their owner and path, so that only a single state
+// is persisted, updated in place. The most recent states are kept in memory as
+// diff layers on top of it, and the reverse diffs of the persisted transitions
+// are stored in the freezer, allowing the persistent state to be rolled back.
+type pathDB struct {
+	diskdb   ethdb.Database
+	freezer  *rawdb.ResettableFreezer // Store of the reverse diffs, nil if the database has no ancient store
+	config   PathConfig
+	cleans   *fastcache.Cache
+	disabled bool
+
+	base   *diskLayer                // Persistent state
+	layers map[common.Hash]pathLayer // All available states, keyed by root hash
+	lock   sync.RWMutex
+}
+
+// newPathDB opens the persistent state of the path-based scheme stored in the
+// given database, repairing its state history if needed.
+func newPathDB(diskdb ethdb.Database, cleans *fastcache.Cache, config *PathConfig) *pathDB {
+	db := &pathDB{
+		diskdb: diskdb,
+		config: *config,
+		cleans: cleans,
+	}
+	if ancient, err := diskdb.AncientDatadir(); err == nil && ancient != "" {
+		freezer, err := rawdb.NewStateFreezer(ancient, false)
+		if err != nil {
+			log.Crit("Failed to open state history", "err", err)
+		}
+		db.freezer = freezer
+	}
+	id := rawdb.ReadPersistentStateID(diskdb)
+	if db.freezer != nil {
+		// The reverse diff of a transition is written before the transition
+		// itself, drop any diff whose transition wasn't persisted.
+		items, err := db.freezer.Ancients()
+		if err != nil {
+			log.Crit("Failed to read state history", "err", err)
+		}
+		switch {
+		case items > id:
+			log.Warn("Truncating dangling state history", "have", items, "want", id)
+			if err := db.freezer.TruncateHead(id); err != nil {
+				log.Crit("Failed to truncate state history", "err", err)
+			}
+		case items < id:
+			// The state history was lost, restart counting the transitions
+			// from the persistent state.
+			log.Warn("Resetting incomplete state history", "have", items, "want", id)
+			if err := db.freezer.Reset(); err != nil {
+				log.Crit("Failed to reset state history", "err", err)
+			}
+			id = 0
+			rawdb.WritePersistentStateID(diskdb, id)
+		}
+	}
+	db.base = &diskLayer{diskdb: diskdb, cleans: cleans, root: persistentRoot(diskdb), id: id}
+	db.layers = map[common.Hash]pathLayer{db.base.root: db.base}
+	return db
+}
+
+// persistentRoot returns the root hash of the persistent state.
+func persistentRoot(diskdb ethdb.KeyValueReader) common.Hash {
+	blob, hash := rawdb.ReadAccountTrieNode(diskdb, nil)
+	if len(blob) == 0 {
+		return types.EmptyRootHash
+	}
+	return hash
+}
+
+// reader returns the node reader of the state with the given root, or nil if
+// the state is not available.
+func (db *pathDB) reader(root common.Hash) Reader {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	layer := db.layers[root]
+	if layer == nil {
+		if root == (common.Hash{}) || root == types.EmptyRootHash {
+			return emptyPathReader{}
+		}
+		return nil
+	}
+	return &pathReader{layer: layer}
+}
+
+// update adds the state transition from parent to root, made of the given
+// dirty nodes, as a diff layer. The oldest diff layers are flattened into the
+// persistent state so that at most pathDiffLayers are kept in memory.
+func (db *pathDB) update(root common.Hash, parent common.Hash, nodes *MergedNodeSet) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.disabled {
+		return errPathDisabled
+	}
+	if root == parent {
+		return nil
+	}
+	// The same state may be reached through different transitions, keep the
+	// first one.
+	if _, ok := db.layers[root]; ok {
+		return nil
+	}
+	layer := db.layers[parent]
+	if layer == nil {
+		return fmt.Errorf("parent state %x not found", parent)
+	}
+	db.layers[root] = newDiffLayer(layer, root, nodes)
+	return db.cap(root, pathDiffLayers)
+}
+
+// commit flattens all the diff layers up to the state with the given root into
+// the persistent state.
+func (db *pathDB) commit(root common.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.disabled {
+		return errPathDisabled
+	}
+	return db.cap(root, 0)
+}
+
+// cap flattens the diff layers below the given number of layers, starting from
+// the state with the given root, into the persistent state. The states not
+// descending from the new persistent state are dropped.
+//
+// The lock must be held by the caller.
+func (db *pathDB) cap(root common.Hash, layers int) error {
+	layer := db.layers[root]
+	if layer == nil {
+		return fmt.Errorf("state %x not found", root)
+	}
+	var chain []*diffLayer
+	for {
+		diff, ok := layer.(*diffLayer)
+		if !ok {
+			break
+		}
+		chain = append(chain, diff)
+		layer = diff.parentLayer()
+	}
+	if len(chain) <= layers {
+		return nil
+	}
+	for i := len(chain) - 1; i >= layers; i-- {
+		if err := db.flatten(chain[i]); err != nil {
+			return err
+		}
+	}
+	// Drop the states not descending from the new persistent state, and skip
+	// the flattened diff layers kept as parents.
+	descends := map[pathLayer]bool{db.base: true}
+	var walk func(layer pathLayer) bool
+	walk = func(layer pathLayer) bool {
+		if kept, ok := descends[layer]; ok {
+			return kept
+		}
+		diff, ok := layer.(*diffLayer)
+		if !ok {
+			descends[layer] = false
+			return false
+		}
+		descends[layer] = walk(diff.parentLayer())
+		return descends[layer]
+	}
+	for root, layer := range db.layers {
+		if !walk(layer) {
+			delete(db.layers, root)
+			continue
+		}
+		if diff, ok := layer.(*diffLayer); ok {
+			if parent, ok := diff.parentLayer().(*diffLayer); ok && db.layers[parent.root] == db.base {
+				diff.setParent(db.base)
+			}
+		}
+	}
+	return nil
+}
+
+// flatten writes the given diff layer, applied on the persistent state, into
+// the database, storing its reverse diff into the state history first.
+//
+// The lock must be held by the caller.
+func (db *pathDB) flatten(diff *diffLayer) error {
+	// The parent may be a diff layer flattened just before, equivalent to the
+	// persistent state.
+	base := db.base
+	if parent := diff.parentLayer(); parent != base && parent.rootHash() != base.root {
+		return fmt.Errorf("layer %x is not on top of the persistent state", diff.root)
+	}
+	// Collect the modified nodes in a deterministic order, along with their
+	// previous values for the reverse diff.
+	owners := make([]common.Hash, 0, len(diff.nodes))
+	for owner := range diff.nodes {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool { return bytes.Compare(owners[i][:], owners[j][:]) < 0 })
+
+	history := &stateHistory{Parent: base.root, Root: diff.root}
+	batch := db.diskdb.NewBatch()
+	for _, owner := range owners {
+		paths := make([]string, 0, len(diff.nodes[owner]))
+		for path := range diff.nodes[owner] {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			history.Nodes = append(history.Nodes, stateHistoryNode{
+				Owner: owner,
+				Path:  []byte(path),
+				Prev:  readPathNode(db.diskdb, owner, []byte(path)),
+			})
+			if n := diff.nodes[owner][path]; len(n.blob) == 0 {
+				rawdb.DeleteTrieNode(batch, owner, []byte(path), common.Hash{}, rawdb.PathScheme)
+			} else {
+				rawdb.WriteTrieNode(batch, owner, []byte(path), n.hash, n.blob, rawdb.PathScheme)
+			}
+		}
+	}
+	if db.freezer != nil {
+		blob, err := rlp.EncodeToBytes(history)
+		if err != nil {
+			return err
+		}
+		if err := rawdb.WriteStateHistory(db.freezer, diff.id, blob); err != nil {
+			return err
+		}
+		if limit := db.config.StateHistory; limit != 0 && diff.id > limit {
+			if err := db.pruneHistory(batch, diff.id-limit); err != nil {
+				return err
+			}
+		}
+		rawdb.WriteStateID(batch, base.root, base.id)
+		rawdb.WriteStateID(batch, diff.root, diff.id)
+	}
+	rawdb.WritePersistentStateID(batch, diff.id)
+
+	if err := base.markStale(batch.Write); err != nil {
+		return err
+	}
+	db.base = &diskLayer{diskdb: db.diskdb, cleans: db.cleans, root: diff.root, id: diff.id}
+	if db.layers[base.root] == base {
+		delete(db.layers, base.root)
+	}
+	db.layers[diff.root] = db.base
+	diff.setParent(db.base)
+	return nil
+}
+
+// readPathNode retrieves the persisted trie node with the given owner and path.
+func readPathNode(diskdb ethdb.KeyValueReader, owner common.Hash, path []byte) []byte {
+	if owner == (common.Hash{}) {
+		return rawdb.ReadAccountTrieNodeBlob(diskdb, path)
+	}
+	return rawdb.ReadStorageTrieNodeBlob(diskdb, owner, path)
+}
+
+// readHistory retrieves and decodes the reverse diff of the state with the
+// given id.
+func (db *pathDB) readHistory(id uint64) (*stateHistory, error) {
+	blob := rawdb.ReadStateHistory(db.freezer, id)
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("state history #%d not found", id)
+	}
+	history := new(stateHistory)
+	if err := rlp.DecodeBytes(blob, history); err != nil {
+		return nil, fmt.Errorf("invalid state history #%d: %v", id, err)
+	}
+	return history, nil
+}
+
+// pruneHistory drops the state histories up to and including the given id,
+// deleting the ids of the states which can no longer be recovered.
+func (db *pathDB) pruneHistory(batch ethdb.KeyValueWriter, tail uint64) error {
+	oldTail, err := db.freezer.Tail()
+	if err != nil {
+		return err
+	}
+	for id := oldTail; id < tail; id++ {
+		history, err := db.readHistory(id + 1)
+		if err != nil {
+			return err
+		}
+		// The same root may be reached again by a later state, keep its id.
+		if stored := rawdb.ReadStateID(db.diskdb, history.Parent); stored != nil && *stored == id {
+			rawdb.DeleteStateID(batch, history.Parent)
+		}
+	}
+	return db.freezer.TruncateTail(tail)
+}
+
+// recoverable returns whether the persistent state can be rolled back to the
+// state with the given root.
+//
+// The lock must be held by the caller.
+func (db *pathDB) recoverable(root common.Hash) bool {
+	if db.disabled || db.freezer == nil || root == db.base.root {
+		return false
+	}
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil || *id >= db.base.id {
+		return false
+	}
+	tail, err := db.freezer.Tail()
+	if err != nil || *id < tail {
+		return false
+	}
+	// Check that the reverse diff does start from the state.
+	history, err := db.readHistory(*id + 1)
+	return err == nil && history.Parent == root
+}
+
+// recover rolls the persistent state back to the state with the given root,
+// dropping all the diff layers.
+func (db *pathDB) recover(root common.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if !db.recoverable(root) {
+		return fmt.Errorf("state %x is not recoverable", root)
+	}
+	// Check the reverse diffs form a chain before touching the state
+	var (
+		target    = *rawdb.ReadStateID(db.diskdb, root)
+		histories []*stateHistory
+		expect    = db.base.root
+	)
+	for id := db.base.id; id > target; id-- {
+		history, err := db.readHistory(id)
+		if err != nil {
+			return err
+		}
+		if history.Root != expect {
+			return fmt.Errorf("state history #%d mismatch: have root %x, want %x", id, history.Root, expect)
+		}
+		histories = append(histories, history)
+		expect = history.Parent
+	}
+	base := db.base
+	if err := base.markStale(nil); err != nil {
+		return err
+	}
+	// Apply the reverse diffs one by one, so that the persistent state stays
+	// consistent if interrupted.
+	for i, history := range histories {
+		batch := db.diskdb.NewBatch()
+		for _, n := range history.Nodes {
+			if len(n.Prev) == 0 {
+				rawdb.DeleteTrieNode(batch, n.Owner, n.Path, common.Hash{}, rawdb.PathScheme)
+			} else {
+				rawdb.WriteTrieNode(batch, n.Owner, n.Path, common.Hash{}, n.Prev, rawdb.PathScheme)
+			}
+		}
+		rawdb.DeleteStateID(batch, history.Root)
+		rawdb.WritePersistentStateID(batch, base.id-uint64(i)-1)
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	if err := db.freezer.TruncateHead(target); err != nil {
+		return err
+	}
+	db.base = &diskLayer{diskdb: db.diskdb, cleans: db.cleans, root: root, id: target}
+	db.layers = map[common.Hash]pathLayer{root: db.base}
+
+	log.Info("Rolled back persistent state", "root", root, "from", base.id, "to", target)
+	return nil
+}
+
+// disable drops all the states, as the persistent state is about to be
+// rewritten by state sync.
+func (db *pathDB) disable() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.disabled {
+		return nil
+	}
+	if err := db.base.markStale(nil); err != nil {
+		return err
+	}
+	db.disabled = true
+	db.layers = make(map[common.Hash]pathLayer)
+	return nil
+}
+
+// enable reopens the persistent state, as rewritten by state sync, resetting
+// the state history. It's a noop if the database wasn't disabled and the state
+// is available.
+func (db *pathDB) enable(root common.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if !db.disabled {
+		if db.layers[root] != nil {
+			return nil
+		}
+		return errors.New("path database not disabled")
+	}
+	if have := persistentRoot(db.diskdb); have != root {
+		return fmt.Errorf("persistent state mismatch: have %x, want %x", have, root)
+	}
+	if db.freezer != nil {
+		if err := db.freezer.Reset(); err != nil {
+			return err
+		}
+	}
+	rawdb.WritePersistentStateID(db.diskdb, 0)
+
+	db.disabled = false
+	db.base = &diskLayer{diskdb: db.diskdb, cleans: db.cleans, root: root}
+	db.layers = map[common.Hash]pathLayer{root: db.base}
+	return nil
+}
+
+// size returns the memory used by the diff layers.
+func (db *pathDB) size() common.StorageSize {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	var size common.StorageSize
+	for _, layer := range db.layers {
+		if diff, ok := layer.(*diffLayer); ok {
+			size += diff.size
+		}
+	}
+	return size
+}
+
+// close closes the state history. The diff layers are not persisted, the
+// caller is expected to commit the wanted state first.
+func (db *pathDB) close() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.freezer == nil {
+		return nil
+	}
+	err := db.freezer.Close()
+	db.freezer = nil
+	return err
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// errStaleLayer is returned when reading from a layer of the path-based scheme
+// whose state was rolled back or overwritten by flattening newer states.
+var errStaleLayer = errors.New("layer stale")
+
+// pathNode is a trie node of a diff layer, along with its hash. Deleted nodes
+// have an empty hash and blob.
+type pathNode struct {
+	hash common.Hash
+	blob []byte
+}
+
+// pathLayer is a state of the path-based scheme, either the single persistent
+// state or an in-memory diff on top of another layer.
+type pathLayer interface {
+	// rootHash returns the root hash of the state represented by the layer.
+	rootHash() common.Hash
+
+	// stateID returns the id of the state represented by the layer, being the
+	// number of state transitions since the initial state of the database.
+	stateID() uint64
+
+	// node retrieves the RLP encoded trie node with the given owner, path and
+	// hash. No error is returned if the node is not found.
+	node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error)
+}
+
+// diffLayer is a state transition kept in memory, holding the trie nodes
+// modified by it on top of its parent layer.
+type diffLayer struct {
+	root  common.Hash                          // Root hash of the state
+	id    uint64                               // Id of the state
+	nodes map[common.Hash]map[string]*pathNode // Modified trie nodes, keyed by owner and path
+	size  common.StorageSize                   // Approximate memory used by the nodes
+
+	parent pathLayer    // Layer the diff is applied on, replaced once flattened
+	lock   sync.RWMutex // Lock protecting the parent
+}
+
+// newDiffLayer creates a diff layer on top of the given parent from the dirty
+// nodes of a state transition.
+func newDiffLayer(parent pathLayer, root common.Hash, nodes *MergedNodeSet) *diffLayer {
+	dl := &diffLayer{
+		root:   root,
+		id:     parent.stateID() + 1,
+		nodes:  make(map[common.Hash]map[string]*pathNode),
+		parent: parent,
+	}
+	for owner, set := range nodes.sets {
+		subset := make(map[string]*pathNode, len(set.nodes))
+		for path, n := range set.nodes {
+			if n.isDeleted() {
+				subset[path] = &pathNode{}
+			} else {
+				subset[path] = &pathNode{hash: n.hash, blob: n.rlp()}
+			}
+			dl.size += common.StorageSize(common.HashLength + len(path) + len(subset[path].blob))
+		}
+		dl.nodes[owner] = subset
+	}
+	return dl
+}
+
+// rootHash implements pathLayer, returning the root hash of the state.
+func (dl *diffLayer) rootHash() common.Hash {
+	return dl.root
+}
+
+// stateID implements pathLayer, returning the id of the state.
+func (dl *diffLayer) stateID() uint64 {
+	return dl.id
+}
+
+// parentLayer returns the layer the diff is applied on.
+func (dl *diffLayer) parentLayer() pathLayer {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.parent
+}
+
+// setParent replaces the layer the diff is applied on with an equivalent one.
+func (dl *diffLayer) setParent(parent pathLayer) {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.parent = parent
+}
+
+// node implements pathLayer, retrieving the trie node from the diff or from
+// the first ancestor layer modifying it.
+func (dl *diffLayer) node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	var layer pathLayer = dl
+	for {
+		diff, ok := layer.(*diffLayer)
+		if !ok {
+			return layer.node(owner, path, hash)
+		}
+		if n, ok := diff.nodes[owner][string(path)]; ok {
+			if n.hash != hash {
+				return nil, fmt.Errorf("unexpected node: owner %x, path %x, want %x, have %x", owner, path, hash, n.hash)
+			}
+			return n.blob, nil
+		}
+		layer = diff.parentLayer()
+	}
+}
+
+// diskLayer is the single persistent state of the path-based scheme.
+type diskLayer struct {
+	diskdb ethdb.KeyValueReader
+	cleans *fastcache.Cache // Clean node cache, keyed by node hash
+	root   common.Hash      // Root hash of the state
+	id     uint64           // Id of the state
+
+	stale bool         // Flag whether the state was overwritten
+	lock  sync.RWMutex // Lock protecting the stale flag
+}
+
+// rootHash implements pathLayer, returning the root hash of the state.
+func (dl *diskLayer) rootHash() common.Hash {
+	return dl.root
+}
+
+// stateID implements pathLayer, returning the id of the state.
+func (dl *diskLayer) stateID() uint64 {
+	return dl.id
+}
+
+// markStale flags the layer as overwritten, failing any further read. The
+// given function is run with the layer locked, so no read can interleave.
+func (dl *diskLayer) markStale(update func() error) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stale {
+		return errStaleLayer
+	}
+	if update != nil {
+		if err := update(); err != nil {
+			return err
+		}
+	}
+	dl.stale = true
+	return nil
+}
+
+// node implements pathLayer, retrieving the trie node from the clean cache or
+// the database.
+func (dl *diskLayer) node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, errStaleLayer
+	}
+	if dl.cleans != nil {
+		if blob := dl.cleans.Get(nil, hash[:]); blob != nil {
+			memcacheCleanHitMeter.Mark(1)
+			memcacheCleanReadMeter.Mark(int64(len(blob)))
+			return blob, nil
+		}
+		memcacheCleanMissMeter.Mark(1)
+	}
+	var (
+		blob  []byte
+		nHash common.Hash
+	)
+	if owner == (common.Hash{}) {
+		blob, nHash = rawdb.ReadAccountTrieNode(dl.diskdb, path)
+	} else {
+		blob, nHash = rawdb.ReadStorageTrieNode(dl.diskdb, owner, path)
+	}
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	if nHash != hash {
+		return nil, fmt.Errorf("unexpected node: owner %x, path %x, want %x, have %x", owner, path, hash, nHash)
+	}
+	if dl.cleans != nil {
+		dl.cleans.Set(hash[:], blob)
+		memcacheCleanWriteMeter.Mark(int64(len(blob)))
+	}
+	return blob, nil
+}
+
+// pathReader is the node reader of a state of the path-based scheme.
+type pathReader struct {
+	layer pathLayer
+}
+
+// Node retrieves the trie node with the given owner, path and hash.
+// No error will be returned if the node is not found.
+func (r *pathReader) Node(owner common.Hash, path []byte, hash common.Hash) (node, error) {
+	blob, err := r.layer.node(owner, path, hash)
+	if err != nil || len(blob) == 0 {
+		return nil, err
+	}
+	return mustDecodeNode(hash[:], blob), nil
+}
+
+// NodeBlob retrieves the RLP encoded trie node with the given owner, path and
+// hash. No error will be returned if the node is not found.
+func (r *pathReader) NodeBlob(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	return r.layer.node(owner, path, hash)
+}
+
+// emptyPathReader is the node reader of the empty state, which is available
+// whatever the persistent state is as it has no nodes.
+type emptyPathReader struct{}
+
+// Node implements Reader, the empty state has no nodes.
+func (emptyPathReader) Node(owner common.Hash, path []byte, hash common.Hash) (node, error) {
+	return nil, nil
+}
+
+// NodeBlob implements Reader, the empty state has no nodes.
+func (emptyPathReader) NodeBlob(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	return nil, nil
+}
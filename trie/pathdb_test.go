@@ -0,0 +1,288 @@
+// Copyright 2023 Bitnet
+// This file is part of the Bitnet library.
+//
+// This software is provided "as is", without warranty of any kind,
+// express or implied, including but not limited to the warranties
+// of merchantability, fitness for a particular purpose and
+// noninfringement. In no even shall the authors or copyright
+// holders be liable for any claim, damages, or other liability,
+// whether in an action of contract, tort or otherwise, arising
+// from, out of or in connection with the software or the use or
+// other dealings in the software.
+
+package trie
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// pathTestState is a state built by the path database tests, along with the
+// content of its trie.
+type pathTestState struct {
+	root    common.Hash
+	content map[string]string
+}
+
+// newPathTestDisk creates a database with an ancient store for the state
+// history.
+func newPathTestDisk(t *testing.T) ethdb.Database {
+	diskdb, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { diskdb.Close() })
+	return diskdb
+}
+
+// newPathTestDB opens a path-based trie database on top of the given database.
+func newPathTestDB(diskdb ethdb.Database, history uint64) *Database {
+	return NewDatabaseWithConfig(diskdb, &Config{PathDB: &PathConfig{StateHistory: history}})
+}
+
+// applyPathTestState creates a new state on top of the parent one, inserting
+// a key specific to the given number, updating a shared one and deleting the
+// key inserted two states before.
+func applyPathTestState(t *testing.T, db *Database, parent *pathTestState, number int) *pathTestState {
+	t.Helper()
+
+	tr, err := New(TrieID(parent.root), db)
+	if err != nil {
+		t.Fatalf("state %d: failed to open parent: %v", number, err)
+	}
+	content := make(map[string]string)
+	for k, v := range parent.content {
+		content[k] = v
+	}
+	updates := map[string]string{
+		fmt.Sprintf("key-%d", number): fmt.Sprintf("value-%d", number),
+		"shared":                      fmt.Sprintf("shared-%d", number),
+	}
+	for k, v := range updates {
+		tr.MustUpdate([]byte(k), []byte(v))
+		content[k] = v
+	}
+	if deleted := fmt.Sprintf("key-%d", number-2); content[deleted] != "" {
+		tr.MustDelete([]byte(deleted))
+		delete(content, deleted)
+	}
+	root, set := tr.Commit(false)
+	if err := db.UpdateState(root, parent.root, NewWithNodeSet(set)); err != nil {
+		t.Fatalf("state %d: failed to update: %v", number, err)
+	}
+	return &pathTestState{root: root, content: content}
+}
+
+// buildPathTestStates creates a chain of states on top of the empty one.
+func buildPathTestStates(t *testing.T, db *Database, n int) []*pathTestState {
+	states := []*pathTestState{{root: types.EmptyRootHash, content: map[string]string{}}}
+	for i := 1; i <= n; i++ {
+		states = append(states, applyPathTestState(t, db, states[i-1], i))
+	}
+	return states
+}
+
+// checkPathTestState checks the content of the given state.
+func checkPathTestState(t *testing.T, db *Database, state *pathTestState) {
+	t.Helper()
+
+	tr, err := New(TrieID(state.root), db)
+	if err != nil {
+		t.Fatalf("state %x: failed to open: %v", state.root, err)
+	}
+	for k, v := range state.content {
+		have, err := tr.Get([]byte(k))
+		if err != nil {
+			t.Fatalf("state %x: failed to read %s: %v", state.root, k, err)
+		}
+		if string(have) != v {
+			t.Fatalf("state %x: value mismatch for %s: have %q, want %q", state.root, k, have, v)
+		}
+	}
+	if hash := tr.Hash(); hash != state.root {
+		t.Fatalf("state root mismatch: have %x, want %x", hash, state.root)
+	}
+}
+
+// Tests that the recent states are kept in memory and that only the committed
+// one survives a restart.
+func TestPathDBUpdateCommit(t *testing.T) {
+	diskdb := newPathTestDisk(t)
+	db := newPathTestDB(diskdb, 0)
+
+	states := buildPathTestStates(t, db, 10)
+	for _, state := range states {
+		checkPathTestState(t, db, state)
+	}
+	if size, _ := db.Size(); size == 0 {
+		t.Fatalf("no diff layers in memory")
+	}
+	if err := db.Commit(states[7].root, false); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if db.GetReader(states[6].root) != nil {
+		t.Fatalf("state below the persistent one still available")
+	}
+	for _, state := range states[7:] {
+		checkPathTestState(t, db, state)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	db = newPathTestDB(diskdb, 0)
+	defer db.Close()
+
+	checkPathTestState(t, db, states[7])
+	if db.GetReader(states[8].root) != nil {
+		t.Fatalf("uncommitted state survived restart")
+	}
+	if id := rawdb.ReadPersistentStateID(diskdb); id != 7 {
+		t.Fatalf("persistent state id mismatch: have %d, want 7", id)
+	}
+	if scheme := rawdb.ReadStateScheme(diskdb); scheme != rawdb.PathScheme {
+		t.Fatalf("state scheme mismatch: have %q, want %q", scheme, rawdb.PathScheme)
+	}
+}
+
+// Tests that the states not descending from the persistent state are dropped
+// when flattening diff layers.
+func TestPathDBForks(t *testing.T) {
+	db := newPathTestDB(newPathTestDisk(t), 0)
+	defer db.Close()
+
+	states := buildPathTestStates(t, db, 3)
+	fork := applyPathTestState(t, db, states[2], 100)
+	sibling := applyPathTestState(t, db, states[1], 200)
+
+	if err := db.Commit(states[3].root, false); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	checkPathTestState(t, db, states[3])
+	if db.GetReader(fork.root) != nil {
+		t.Fatalf("forked state still available")
+	}
+	if db.GetReader(sibling.root) != nil {
+		t.Fatalf("sibling state still available")
+	}
+}
+
+// Tests that the persistent state can be rolled back with the state history,
+// even after a restart, and that the chain can be extended afterwards.
+func TestPathDBRecover(t *testing.T) {
+	diskdb := newPathTestDisk(t)
+	db := newPathTestDB(diskdb, 0)
+
+	states := buildPathTestStates(t, db, pathDiffLayers+32)
+	if db.GetReader(states[10].root) != nil {
+		t.Fatalf("state beyond the diff layers still available")
+	}
+	if db.Recoverable(states[len(states)-1].root) {
+		t.Fatalf("state in memory reported recoverable")
+	}
+	if !db.Recoverable(states[10].root) {
+		t.Fatalf("persisted state not recoverable")
+	}
+	if err := db.Commit(states[len(states)-1].root, false); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	db.Close()
+
+	db = newPathTestDB(diskdb, 0)
+	defer db.Close()
+
+	if !db.Recoverable(states[10].root) {
+		t.Fatalf("persisted state not recoverable after restart")
+	}
+	if err := db.Recover(states[10].root); err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	checkPathTestState(t, db, states[10])
+	if db.GetReader(states[11].root) != nil {
+		t.Fatalf("state above the recovered one still available")
+	}
+	if db.Recoverable(states[20].root) {
+		t.Fatalf("state above the recovered one reported recoverable")
+	}
+	// Extend the recovered state with a different chain and roll it back again
+	forked := []*pathTestState{states[10]}
+	for i := 1; i <= pathDiffLayers+8; i++ {
+		forked = append(forked, applyPathTestState(t, db, forked[i-1], 1000+i))
+	}
+	checkPathTestState(t, db, forked[len(forked)-1])
+
+	if err := db.Recover(states[5].root); err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	checkPathTestState(t, db, states[5])
+	if err := db.Recover(types.EmptyRootHash); err != nil {
+		t.Fatalf("failed to recover empty state: %v", err)
+	}
+	if blob := rawdb.ReadAccountTrieNodeBlob(diskdb, nil); len(blob) != 0 {
+		t.Fatalf("persistent state not empty after recovery")
+	}
+}
+
+// Tests that the states beyond the state history limit can't be recovered.
+func TestPathDBHistoryLimit(t *testing.T) {
+	diskdb := newPathTestDisk(t)
+	db := newPathTestDB(diskdb, 10)
+	defer db.Close()
+
+	states := buildPathTestStates(t, db, pathDiffLayers+32)
+
+	// The states up to #32 are persisted, only the last 10 transitions are kept
+	if db.Recoverable(states[21].root) {
+		t.Fatalf("state beyond the history limit reported recoverable")
+	}
+	for i := 0; i <= 32; i++ {
+		if id := rawdb.ReadStateID(diskdb, states[i].root); (id == nil) != (i < 22) {
+			t.Fatalf("state %d: id mismatch: have %v, pruned %v", i, id, i < 22)
+		}
+	}
+	if !db.Recoverable(states[22].root) {
+		t.Fatalf("state within the history limit not recoverable")
+	}
+	if err := db.Recover(states[22].root); err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	checkPathTestState(t, db, states[22])
+}
+
+// Tests that disabling the database drops all the states until it's enabled
+// again on top of the persistent state.
+func TestPathDBDisable(t *testing.T) {
+	db := newPathTestDB(newPathTestDisk(t), 0)
+	defer db.Close()
+
+	states := buildPathTestStates(t, db, 5)
+	if err := db.Commit(states[3].root, false); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if err := db.Disable(); err != nil {
+		t.Fatalf("failed to disable: %v", err)
+	}
+	if db.GetReader(states[3].root) != nil {
+		t.Fatalf("state available while disabled")
+	}
+	if err := db.UpdateState(common.Hash{1}, states[5].root, NewMergedNodeSet()); err == nil {
+		t.Fatalf("update succeeded while disabled")
+	}
+	if err := db.Enable(states[4].root); err == nil {
+		t.Fatalf("enabled on top of a mismatching state")
+	}
+	if err := db.Enable(states[3].root); err != nil {
+		t.Fatalf("failed to enable: %v", err)
+	}
+	checkPathTestState(t, db, states[3])
+	if db.Recoverable(states[1].root) {
+		t.Fatalf("state history survived state sync")
+	}
+	next := applyPathTestState(t, db, states[3], 100)
+	checkPathTestState(t, db, next)
+}